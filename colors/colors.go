@@ -36,6 +36,16 @@ func BrightenHexColor(hexString string) string {
 	return "#" + hex.EncodeToString(adjusted)
 }
 
+// LightenHexColor mixes the color with the given percentage of white
+func LightenHexColor(hexString string, percent int) string {
+	colorBytes, _ := hex.DecodeString(hexString[1:])
+	adjusted := make([]byte, 3)
+	for i := 0; i < 3; i++ {
+		adjusted[i] = colorBytes[i] + byte((0xFF-int(colorBytes[i]))*percent/100)
+	}
+	return "#" + hex.EncodeToString(adjusted)
+}
+
 func ColorCriticalRisk(pdf *gofpdf.Fpdf) {
 	pdf.SetTextColor(255, 38, 0)
 }
@@ -0,0 +1,238 @@
+package cleanup_risk_tracking
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+)
+
+func GetMacroDetails() model.MacroDetails {
+	return model.MacroDetails{
+		ID:          "cleanup-risk-tracking",
+		Title:       "Cleanup Risk Tracking",
+		Description: "This model macro reviews the orphaned risk tracking entries (not matching any risk) one by one: each can be re-mapped to a current risk ID (suggested by similarity), deleted, or kept.",
+	}
+}
+
+var macroState = make(map[string][]string)
+var questionsAnswered = make([]string, 0)
+
+const remapPrefix, deleteAnswer, keepAnswer = "re-map to ", "delete", "keep"
+
+// maxSuggestions limits the number of current risk IDs offered for re-mapping an orphaned entry,
+// the most similar one is the default answer when it is clearly the best and at least minDefaultSimilarity similar
+const maxSuggestions, minDefaultSimilarity = 3, 0.25
+
+type suggestion struct {
+	syntheticRiskId string
+	similarity      float64
+}
+
+func GetNextQuestion() (nextQuestion model.MacroQuestion, err error) {
+	orphanedIds := sortedOrphanedRiskTrackingIds()
+	if len(questionsAnswered) >= len(orphanedIds) {
+		return model.NoMoreQuestions(), nil
+	}
+	orphanedId := orphanedIds[len(questionsAnswered)]
+	tracking := model.ParsedModelRoot.OrphanedRiskTracking[orphanedId]
+	description := "Tracked as " + tracking.Status.String()
+	if len(tracking.Justification) > 0 {
+		description += ": " + tracking.Justification
+	}
+	description += "\n"
+	possibleAnswers := make([]string, 0)
+	defaultAnswer := keepAnswer
+	suggestions := suggestionsFor(orphanedId)
+	if len(suggestions) > 0 {
+		description += "The most likely current risk ID is " + suggestions[0].syntheticRiskId +
+			" (" + strconv.Itoa(int(suggestions[0].similarity*100)) + "% similar)."
+		if suggestions[0].similarity >= minDefaultSimilarity && (len(suggestions) == 1 || suggestions[0].similarity > suggestions[1].similarity) {
+			defaultAnswer = remapPrefix + suggestions[0].syntheticRiskId
+		}
+	} else {
+		description += "No current risk of the same category found."
+	}
+	for _, suggestion := range suggestions {
+		possibleAnswers = append(possibleAnswers, remapPrefix+suggestion.syntheticRiskId)
+	}
+	possibleAnswers = append(possibleAnswers, deleteAnswer, keepAnswer)
+	return model.MacroQuestion{
+		ID:              orphanedId,
+		Title:           "What to do with the orphaned risk tracking " + orphanedId + "?",
+		Description:     description,
+		PossibleAnswers: possibleAnswers,
+		MultiSelect:     false,
+		DefaultAnswer:   defaultAnswer,
+	}, nil
+}
+
+func ApplyAnswer(questionID string, answer ...string) (message string, validResult bool, err error) {
+	macroState[questionID] = answer
+	questionsAnswered = append(questionsAnswered, questionID)
+	return "Answer processed", true, nil
+}
+
+func GoBack() (message string, validResult bool, err error) {
+	if len(questionsAnswered) == 0 {
+		return "Cannot go back further", false, nil
+	}
+	lastQuestionID := questionsAnswered[len(questionsAnswered)-1]
+	questionsAnswered = questionsAnswered[:len(questionsAnswered)-1]
+	delete(macroState, lastQuestionID)
+	return "Undo successful", true, nil
+}
+
+func GetFinalChangeImpact(modelInput *model.ModelInput) (changes []string, message string, validResult bool, err error) {
+	changeLogCollector := make([]string, 0)
+	message, validResult, err = applyChange(modelInput, &changeLogCollector, true)
+	return changeLogCollector, message, validResult, err
+}
+
+func Execute(modelInput *model.ModelInput) (message string, validResult bool, err error) {
+	changeLogCollector := make([]string, 0)
+	message, validResult, err = applyChange(modelInput, &changeLogCollector, false)
+	return message, validResult, err
+}
+
+func applyChange(modelInput *model.ModelInput, changeLogCollector *[]string, dryRun bool) (message string, validResult bool, err error) {
+	if len(questionsAnswered) == 0 {
+		return "No orphaned risk tracking found", false, nil
+	}
+	remapped := make(map[string]bool)
+	for _, orphanedId := range questionsAnswered {
+		key, found := keyInModelInput(modelInput, orphanedId)
+		if !found {
+			continue
+		}
+		answer := macroState[orphanedId][0]
+		switch {
+		case answer == deleteAnswer:
+			*changeLogCollector = append(*changeLogCollector, "delete risk tracking "+key)
+			if !dryRun {
+				delete(modelInput.Risk_tracking, key)
+			}
+		case strings.HasPrefix(answer, remapPrefix):
+			syntheticRiskId := strings.TrimPrefix(answer, remapPrefix)
+			if _, exists := modelInput.Risk_tracking[syntheticRiskId]; exists || remapped[syntheticRiskId] {
+				return "Risk tracking of " + syntheticRiskId + " exists already (delete " + key + " instead)", false, nil
+			}
+			remapped[syntheticRiskId] = true
+			*changeLogCollector = append(*changeLogCollector, "re-map risk tracking "+key+" to "+syntheticRiskId)
+			if !dryRun {
+				modelInput.Risk_tracking[syntheticRiskId] = modelInput.Risk_tracking[key]
+				delete(modelInput.Risk_tracking, key)
+			}
+		}
+	}
+	if len(*changeLogCollector) == 0 {
+		return "All orphaned risk tracking kept", false, nil
+	}
+	return "Changeset valid", true, nil
+}
+
+// keyInModelInput finds the entry in the model file, whose key might still use previous ids or surrounding spaces
+func keyInModelInput(modelInput *model.ModelInput, orphanedId string) (string, bool) {
+	currentIdsByPreviousId := model.CurrentIdsByPreviousId(model.ParsedModelRoot)
+	for key := range modelInput.Risk_tracking {
+		if strings.TrimSpace(model.MigrateSyntheticRiskId(key, currentIdsByPreviousId)) == orphanedId {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func sortedOrphanedRiskTrackingIds() []string {
+	result := make([]string, 0, len(model.ParsedModelRoot.OrphanedRiskTracking))
+	for orphanedId := range model.ParsedModelRoot.OrphanedRiskTracking {
+		result = append(result, orphanedId)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// suggestionsFor compares the orphaned id part by part with the ids of the current risks of the same category
+// (keeping its wildcards), so that e.g. the risk of a renamed asset is the most similar one
+func suggestionsFor(orphanedId string) []suggestion {
+	orphanedParts := strings.Split(orphanedId, "@")
+	similarities := make(map[string]float64)
+	for syntheticRiskId := range model.GeneratedRisksBySyntheticId {
+		parts := strings.Split(syntheticRiskId, "@")
+		if parts[0] != orphanedParts[0] || len(parts) != len(orphanedParts) {
+			continue
+		}
+		sum, compared := 0.0, 0
+		for i := 1; i < len(parts); i++ {
+			if orphanedParts[i] == "*" {
+				parts[i] = "*"
+			} else {
+				sum += similarity(orphanedParts[i], parts[i])
+				compared++
+			}
+		}
+		if compared == 0 {
+			continue
+		}
+		candidate := strings.Join(parts, "@")
+		if candidateSimilarity := sum / float64(compared); candidateSimilarity > similarities[candidate] {
+			similarities[candidate] = candidateSimilarity
+		}
+	}
+	result := make([]suggestion, 0, len(similarities))
+	for candidate, candidateSimilarity := range similarities {
+		result = append(result, suggestion{syntheticRiskId: candidate, similarity: candidateSimilarity})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].similarity != result[j].similarity {
+			return result[i].similarity > result[j].similarity
+		}
+		return result[i].syntheticRiskId < result[j].syntheticRiskId
+	})
+	if len(result) > maxSuggestions {
+		result = result[:maxSuggestions]
+	}
+	return result
+}
+
+// similarity is one minus the levenshtein distance relative to the longer string
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	longer := len(a)
+	if len(b) > longer {
+		longer = len(b)
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longer)
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minimum(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minimum(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+package enrich_from_openapi
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/openapi"
+)
+
+func GetMacroDetails() model.MacroDetails {
+	return model.MacroDetails{
+		ID:          "enrich-from-openapi",
+		Title:       "Enrich from OpenAPI",
+		Description: "This model macro reads the OpenAPI 3 document of a technical asset to set its accepted data formats and to upgrade the authentication of its incoming communication links, and proposes data assets from its schemas.",
+	}
+}
+
+var macroState = make(map[string][]string)
+var questionsAnswered = make([]string, 0)
+var document openapi.Document
+
+func GetNextQuestion() (nextQuestion model.MacroQuestion, err error) {
+	switch len(questionsAnswered) {
+	case 0:
+		return model.MacroQuestion{
+			ID:              "openapi-file",
+			Title:           "Which OpenAPI 3 document (YAML or JSON file) describes the API?",
+			Description:     "The request content types, security schemes, and schemas of this document are used.",
+			PossibleAnswers: nil,
+			MultiSelect:     false,
+			DefaultAnswer:   "",
+		}, nil
+	case 1:
+		possibleAnswers := make([]string, 0)
+		for id := range model.ParsedModelRoot.TechnicalAssets {
+			possibleAnswers = append(possibleAnswers, id)
+		}
+		sort.Strings(possibleAnswers)
+		if len(possibleAnswers) > 0 {
+			return model.MacroQuestion{
+				ID:              "technical-asset",
+				Title:           "Which technical asset provides the API?",
+				Description:     "This technical asset and its incoming communication links are enriched.",
+				PossibleAnswers: possibleAnswers,
+				MultiSelect:     false,
+				DefaultAnswer:   "",
+			}, nil
+		}
+	}
+	return model.NoMoreQuestions(), nil
+}
+
+func ApplyAnswer(questionID string, answer ...string) (message string, validResult bool, err error) {
+	if questionID == "openapi-file" {
+		data, err := ioutil.ReadFile(answer[0])
+		if err != nil {
+			return "Unable to read the OpenAPI document: " + err.Error(), false, nil
+		}
+		parsed, err := openapi.Parse(data)
+		if err != nil {
+			return "Unable to parse the OpenAPI document: " + err.Error(), false, nil
+		}
+		if !strings.HasPrefix(parsed.OpenAPI, "3.") {
+			return "Not an OpenAPI 3 document: " + answer[0], false, nil
+		}
+		document = parsed
+	}
+	macroState[questionID] = answer
+	questionsAnswered = append(questionsAnswered, questionID)
+	return "Answer processed", true, nil
+}
+
+func GoBack() (message string, validResult bool, err error) {
+	if len(questionsAnswered) == 0 {
+		return "Cannot go back further", false, nil
+	}
+	lastQuestionID := questionsAnswered[len(questionsAnswered)-1]
+	questionsAnswered = questionsAnswered[:len(questionsAnswered)-1]
+	delete(macroState, lastQuestionID)
+	return "Undo successful", true, nil
+}
+
+func GetFinalChangeImpact(modelInput *model.ModelInput) (changes []string, message string, validResult bool, err error) {
+	changeLogCollector := make([]string, 0)
+	message, validResult, err = applyChange(modelInput, &changeLogCollector, true)
+	return changeLogCollector, message, validResult, err
+}
+
+func Execute(modelInput *model.ModelInput) (message string, validResult bool, err error) {
+	changeLogCollector := make([]string, 0)
+	message, validResult, err = applyChange(modelInput, &changeLogCollector, false)
+	return message, validResult, err
+}
+
+func applyChange(modelInput *model.ModelInput, changeLogCollector *[]string, dryRun bool) (message string, validResult bool, err error) {
+	if len(macroState["technical-asset"]) == 0 {
+		return "No technical asset selected", false, nil
+	}
+	assetID := macroState["technical-asset"][0]
+	assetTitle := model.ParsedModelRoot.TechnicalAssets[assetID].Title
+
+	// the authentication of the incoming communication links, only upgraded (a stronger one might be enforced in front of the API)
+	if authentication, ok := document.Authentication(); ok {
+		titles := make([]string, 0)
+		for title := range modelInput.Technical_assets {
+			titles = append(titles, title)
+		}
+		sort.Strings(titles)
+		for _, title := range titles {
+			source := modelInput.Technical_assets[title]
+			linkTitles := make([]string, 0)
+			for linkTitle := range source.Communication_links {
+				linkTitles = append(linkTitles, linkTitle)
+			}
+			sort.Strings(linkTitles)
+			for _, linkTitle := range linkTitles {
+				link := source.Communication_links[linkTitle]
+				if link.Target != assetID {
+					continue
+				}
+				if existing, err := model.ParseAuthentication(link.Authentication); err == nil && existing >= authentication {
+					continue
+				}
+				*changeLogCollector = append(*changeLogCollector, "setting authentication of communication link '"+linkTitle+"' of technical asset "+source.ID+": "+authentication.String())
+				if !dryRun {
+					link.Authentication = authentication.String()
+					source.Communication_links[linkTitle] = link
+				}
+			}
+		}
+	}
+
+	techAsset := modelInput.Technical_assets[assetTitle]
+	for _, dataFormat := range document.DataFormats() {
+		if !model.Contains(techAsset.Data_formats_accepted, dataFormat.String()) {
+			*changeLogCollector = append(*changeLogCollector, "adding data format accepted to technical asset "+assetID+": "+dataFormat.String())
+			techAsset.Data_formats_accepted = append(techAsset.Data_formats_accepted, dataFormat.String())
+		}
+	}
+
+	dataAssets := document.DataAssets()
+	dataAssetTitles := make([]string, 0)
+	for title := range dataAssets {
+		dataAssetTitles = append(dataAssetTitles, title)
+	}
+	sort.Strings(dataAssetTitles)
+	for _, title := range dataAssetTitles {
+		dataAsset := dataAssets[title]
+		_, existsByID := model.ParsedModelRoot.DataAssets[dataAsset.ID]
+		existing, existsByTitle := modelInput.Data_assets[title]
+		if existsByTitle {
+			dataAsset.ID = existing.ID
+		} else if !existsByID {
+			*changeLogCollector = append(*changeLogCollector, "adding data asset: "+dataAsset.ID+" ("+dataAsset.Confidentiality+")")
+			if !dryRun {
+				if modelInput.Data_assets == nil {
+					modelInput.Data_assets = make(map[string]model.InputDataAsset)
+				}
+				modelInput.Data_assets[title] = dataAsset
+			}
+		}
+		if !model.Contains(techAsset.Data_assets_processed, dataAsset.ID) && !model.Contains(techAsset.Data_assets_stored, dataAsset.ID) {
+			*changeLogCollector = append(*changeLogCollector, "adding data asset processed to technical asset "+assetID+": "+dataAsset.ID)
+			techAsset.Data_assets_processed = append(techAsset.Data_assets_processed, dataAsset.ID)
+		}
+	}
+	if !dryRun {
+		modelInput.Technical_assets[assetTitle] = techAsset
+	}
+	return "Changeset valid", true, nil
+}
@@ -0,0 +1,83 @@
+package migrate_risk_tracking
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/otyg/threagile/model"
+)
+
+func GetMacroDetails() model.MacroDetails {
+	return model.MacroDetails{
+		ID:          "migrate-risk-tracking",
+		Title:       "Migrate Risk Tracking",
+		Description: "This model macro renames the risk tracking entries still using previous IDs of renamed elements (as given by their previous_ids) to the current IDs.",
+	}
+}
+
+func GetNextQuestion() (nextQuestion model.MacroQuestion, err error) {
+	return model.NoMoreQuestions(), nil
+}
+
+func ApplyAnswer(questionID string, answer ...string) (message string, validResult bool, err error) {
+	return "Answer processed", true, nil
+}
+
+func GoBack() (message string, validResult bool, err error) {
+	return "Cannot go back further", false, nil
+}
+
+func GetFinalChangeImpact(modelInput *model.ModelInput) (changes []string, message string, validResult bool, err error) {
+	changes = make([]string, 0)
+	migrations, trackingIdsByMigratedId := migrationsOf(modelInput)
+	for _, previousId := range sortedKeys(migrations) {
+		if trackingId := trackingIdsByMigratedId[migrations[previousId]]; trackingId == migrations[previousId] {
+			changes = append(changes, "remove risk tracking "+previousId+" (as "+migrations[previousId]+" is tracked as well)")
+		} else if trackingId != previousId {
+			changes = append(changes, "remove risk tracking "+previousId+" (as "+trackingId+" is renamed to "+migrations[previousId]+" as well)")
+		} else {
+			changes = append(changes, "rename risk tracking "+previousId+" to "+migrations[previousId])
+		}
+	}
+	if len(changes) == 0 {
+		return changes, "No risk tracking using previous IDs found", false, nil
+	}
+	return changes, "Changeset valid", true, err
+}
+
+func Execute(modelInput *model.ModelInput) (message string, validResult bool, err error) {
+	migrations, trackingIdsByMigratedId := migrationsOf(modelInput)
+	if len(migrations) == 0 {
+		return "No risk tracking using previous IDs found", true, nil
+	}
+	riskTracking := make(map[string]model.InputRiskTracking, len(trackingIdsByMigratedId))
+	for migratedId, trackingId := range trackingIdsByMigratedId {
+		riskTracking[migratedId] = modelInput.Risk_tracking[trackingId]
+	}
+	modelInput.Risk_tracking = riskTracking
+	return "Model file migration of " + strconv.Itoa(len(migrations)) + " risk tracking entries successful", true, nil
+}
+
+// migrationsOf maps the ids of the risk tracking entries using previous ids onto their migrated ids,
+// along with the migrated ids onto the ids of the entries kept for them
+func migrationsOf(modelInput *model.ModelInput) (migrations map[string]string, trackingIdsByMigratedId map[string]string) {
+	migrations = make(map[string]string)
+	currentIdsByPreviousId := model.CurrentIdsByPreviousId(model.ParsedModelRoot)
+	syntheticRiskIds := make([]string, 0, len(modelInput.Risk_tracking))
+	for syntheticRiskId := range modelInput.Risk_tracking {
+		syntheticRiskIds = append(syntheticRiskIds, syntheticRiskId)
+		if migratedId := model.MigrateSyntheticRiskId(syntheticRiskId, currentIdsByPreviousId); migratedId != syntheticRiskId {
+			migrations[syntheticRiskId] = migratedId
+		}
+	}
+	return migrations, model.RiskTrackingIdsByMigratedId(syntheticRiskIds, currentIdsByPreviousId)
+}
+
+func sortedKeys(migrations map[string]string) []string {
+	result := make([]string, 0, len(migrations))
+	for previousId := range migrations {
+		result = append(result, previousId)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -11,6 +11,9 @@ import (
 
 	add_build_pipeline "github.com/otyg/threagile/macros/built-in/add-build-pipeline"
 	add_vault "github.com/otyg/threagile/macros/built-in/add-vault"
+	cleanup_risk_tracking "github.com/otyg/threagile/macros/built-in/cleanup-risk-tracking"
+	enrich_from_openapi "github.com/otyg/threagile/macros/built-in/enrich-from-openapi"
+	migrate_risk_tracking "github.com/otyg/threagile/macros/built-in/migrate-risk-tracking"
 	pretty_print "github.com/otyg/threagile/macros/built-in/pretty-print"
 	remove_unused_tags "github.com/otyg/threagile/macros/built-in/remove-unused-tags"
 	seed_risk_tracking "github.com/otyg/threagile/macros/built-in/seed-risk-tracking"
@@ -27,6 +30,12 @@ func ExecuteModelMacro(executeModelMacro *string, modelInput model.ModelInput, i
 		macroDetails = add_build_pipeline.GetMacroDetails()
 	case add_vault.GetMacroDetails().ID:
 		macroDetails = add_vault.GetMacroDetails()
+	case cleanup_risk_tracking.GetMacroDetails().ID:
+		macroDetails = cleanup_risk_tracking.GetMacroDetails()
+	case enrich_from_openapi.GetMacroDetails().ID:
+		macroDetails = enrich_from_openapi.GetMacroDetails()
+	case migrate_risk_tracking.GetMacroDetails().ID:
+		macroDetails = migrate_risk_tracking.GetMacroDetails()
 	case pretty_print.GetMacroDetails().ID:
 		macroDetails = pretty_print.GetMacroDetails()
 	case remove_unused_tags.GetMacroDetails().ID:
@@ -57,6 +66,12 @@ func ExecuteModelMacro(executeModelMacro *string, modelInput model.ModelInput, i
 			nextQuestion, err = add_build_pipeline.GetNextQuestion()
 		case add_vault.GetMacroDetails().ID:
 			nextQuestion, err = add_vault.GetNextQuestion()
+		case cleanup_risk_tracking.GetMacroDetails().ID:
+			nextQuestion, err = cleanup_risk_tracking.GetNextQuestion()
+		case enrich_from_openapi.GetMacroDetails().ID:
+			nextQuestion, err = enrich_from_openapi.GetNextQuestion()
+		case migrate_risk_tracking.GetMacroDetails().ID:
+			nextQuestion, err = migrate_risk_tracking.GetNextQuestion()
 		case pretty_print.GetMacroDetails().ID:
 			nextQuestion, err = pretty_print.GetNextQuestion()
 		case remove_unused_tags.GetMacroDetails().ID:
@@ -158,6 +173,12 @@ func ExecuteModelMacro(executeModelMacro *string, modelInput model.ModelInput, i
 					message, validResult, err = add_build_pipeline.GoBack()
 				case add_vault.GetMacroDetails().ID:
 					message, validResult, err = add_vault.GoBack()
+				case cleanup_risk_tracking.GetMacroDetails().ID:
+					message, validResult, err = cleanup_risk_tracking.GoBack()
+				case enrich_from_openapi.GetMacroDetails().ID:
+					message, validResult, err = enrich_from_openapi.GoBack()
+				case migrate_risk_tracking.GetMacroDetails().ID:
+					message, validResult, err = migrate_risk_tracking.GoBack()
 				case pretty_print.GetMacroDetails().ID:
 					message, validResult, err = pretty_print.GoBack()
 				case remove_unused_tags.GetMacroDetails().ID:
@@ -181,6 +202,12 @@ func ExecuteModelMacro(executeModelMacro *string, modelInput model.ModelInput, i
 					message, validResult, err = add_build_pipeline.ApplyAnswer(nextQuestion.ID, answer)
 				case add_vault.GetMacroDetails().ID:
 					message, validResult, err = add_vault.ApplyAnswer(nextQuestion.ID, answer)
+				case cleanup_risk_tracking.GetMacroDetails().ID:
+					message, validResult, err = cleanup_risk_tracking.ApplyAnswer(nextQuestion.ID, answer)
+				case enrich_from_openapi.GetMacroDetails().ID:
+					message, validResult, err = enrich_from_openapi.ApplyAnswer(nextQuestion.ID, answer)
+				case migrate_risk_tracking.GetMacroDetails().ID:
+					message, validResult, err = migrate_risk_tracking.ApplyAnswer(nextQuestion.ID, answer)
 				case pretty_print.GetMacroDetails().ID:
 					message, validResult, err = pretty_print.ApplyAnswer(nextQuestion.ID, answer)
 				case remove_unused_tags.GetMacroDetails().ID:
@@ -197,6 +224,12 @@ func ExecuteModelMacro(executeModelMacro *string, modelInput model.ModelInput, i
 				message, validResult, err = add_build_pipeline.ApplyAnswer(nextQuestion.ID, resultingMultiValueSelection...)
 			case add_vault.GetMacroDetails().ID:
 				message, validResult, err = add_vault.ApplyAnswer(nextQuestion.ID, resultingMultiValueSelection...)
+			case cleanup_risk_tracking.GetMacroDetails().ID:
+				message, validResult, err = cleanup_risk_tracking.ApplyAnswer(nextQuestion.ID, resultingMultiValueSelection...)
+			case enrich_from_openapi.GetMacroDetails().ID:
+				message, validResult, err = enrich_from_openapi.ApplyAnswer(nextQuestion.ID, resultingMultiValueSelection...)
+			case migrate_risk_tracking.GetMacroDetails().ID:
+				message, validResult, err = migrate_risk_tracking.ApplyAnswer(nextQuestion.ID, resultingMultiValueSelection...)
 			case pretty_print.GetMacroDetails().ID:
 				message, validResult, err = pretty_print.ApplyAnswer(nextQuestion.ID, resultingMultiValueSelection...)
 			case remove_unused_tags.GetMacroDetails().ID:
@@ -232,6 +265,12 @@ func ExecuteModelMacro(executeModelMacro *string, modelInput model.ModelInput, i
 			changes, message, validResult, err = add_build_pipeline.GetFinalChangeImpact(&modelInput)
 		case add_vault.GetMacroDetails().ID:
 			changes, message, validResult, err = add_vault.GetFinalChangeImpact(&modelInput)
+		case cleanup_risk_tracking.GetMacroDetails().ID:
+			changes, message, validResult, err = cleanup_risk_tracking.GetFinalChangeImpact(&modelInput)
+		case enrich_from_openapi.GetMacroDetails().ID:
+			changes, message, validResult, err = enrich_from_openapi.GetFinalChangeImpact(&modelInput)
+		case migrate_risk_tracking.GetMacroDetails().ID:
+			changes, message, validResult, err = migrate_risk_tracking.GetFinalChangeImpact(&modelInput)
 		case pretty_print.GetMacroDetails().ID:
 			changes, message, validResult, err = pretty_print.GetFinalChangeImpact(&modelInput)
 		case remove_unused_tags.GetMacroDetails().ID:
@@ -268,6 +307,12 @@ func ExecuteModelMacro(executeModelMacro *string, modelInput model.ModelInput, i
 				message, validResult, err = add_build_pipeline.Execute(&modelInput)
 			case add_vault.GetMacroDetails().ID:
 				message, validResult, err = add_vault.Execute(&modelInput)
+			case cleanup_risk_tracking.GetMacroDetails().ID:
+				message, validResult, err = cleanup_risk_tracking.Execute(&modelInput)
+			case enrich_from_openapi.GetMacroDetails().ID:
+				message, validResult, err = enrich_from_openapi.Execute(&modelInput)
+			case migrate_risk_tracking.GetMacroDetails().ID:
+				message, validResult, err = migrate_risk_tracking.Execute(&modelInput)
 			case pretty_print.GetMacroDetails().ID:
 				message, validResult, err = pretty_print.Execute(&modelInput)
 			case remove_unused_tags.GetMacroDetails().ID:
@@ -304,7 +349,6 @@ func ExecuteModelMacro(executeModelMacro *string, modelInput model.ModelInput, i
 			return
 		}
 	}
-	return
 }
 func printBorder(length int, bold bool) {
 	char := "-"
@@ -4,13 +4,14 @@ import (
 	"archive/zip"
 	"bytes"
 	"compress/gzip"
+	ctx "context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -21,7 +22,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"plugin"
 	"regexp"
 	"sort"
 	"strconv"
@@ -34,6 +34,9 @@ import (
 	"github.com/otyg/threagile/macros"
 	add_build_pipeline "github.com/otyg/threagile/macros/built-in/add-build-pipeline"
 	add_vault "github.com/otyg/threagile/macros/built-in/add-vault"
+	cleanup_risk_tracking "github.com/otyg/threagile/macros/built-in/cleanup-risk-tracking"
+	enrich_from_openapi "github.com/otyg/threagile/macros/built-in/enrich-from-openapi"
+	migrate_risk_tracking "github.com/otyg/threagile/macros/built-in/migrate-risk-tracking"
 	pretty_print "github.com/otyg/threagile/macros/built-in/pretty-print"
 	remove_unused_tags "github.com/otyg/threagile/macros/built-in/remove-unused-tags"
 	seed_risk_tracking "github.com/otyg/threagile/macros/built-in/seed-risk-tracking"
@@ -42,9 +45,20 @@ import (
 	"github.com/otyg/threagile/model/confidentiality"
 	"github.com/otyg/threagile/model/core"
 	"github.com/otyg/threagile/model/criticality"
+	"github.com/otyg/threagile/pkg/baseline"
+	"github.com/otyg/threagile/pkg/compose"
+	"github.com/otyg/threagile/pkg/jwtauth"
+	"github.com/otyg/threagile/pkg/kubernetes"
+	"github.com/otyg/threagile/pkg/otm"
+	"github.com/otyg/threagile/pkg/policy"
+	"github.com/otyg/threagile/pkg/skeleton"
+	"github.com/otyg/threagile/pkg/storage"
+	"github.com/otyg/threagile/pkg/terraform"
+	"github.com/otyg/threagile/pkg/threagile"
+	"github.com/otyg/threagile/pkg/tm7"
+	"github.com/otyg/threagile/pkg/webhook"
 	"github.com/otyg/threagile/report"
 	"github.com/otyg/threagile/support"
-	"github.com/santhosh-tekuri/jsonschema/v5"
 
 	"golang.org/x/crypto/argon2"
 	"gopkg.in/yaml.v3"
@@ -53,100 +67,31 @@ import (
 const keepDiagramSourceFiles = false
 const defaultGraphvizDPI, maxGraphvizDPI = 120, 240
 
-const backupHistoryFilesToKeep = 50
+const otmImportFilename = "threagile-model-from-otm.yaml"
+const tm7ImportFilename, tm7ImportReportFilename = "threagile-model-from-tm7.yaml", "tm7-import-report.txt"
+const kubernetesImportFilename, dockerComposeImportFilename = "threagile-model-from-kubernetes.yaml", "threagile-model-from-docker-compose.yaml"
+const terraformImportFilename = "threagile-model-from-terraform.yaml"
+const boltStorageFilename = "threagile.db"
+const exitCodeExpiredRiskTracking, exitCodePolicyViolation, exitCodeNewRisks = 3, 4, 5
 
-const baseFolder, reportFilename, excelRisksFilename, excelTagsFilename, jsonRisksFilename, jsonTechnicalAssetsFilename, jsonStatsFilename, dataFlowDiagramFilenameDOT, dataFlowDiagramFilenamePNG, dataAssetDiagramFilenameDOT, dataAssetDiagramFilenamePNG, graphvizDataFlowDiagramConversionCall, graphvizDataAssetDiagramConversionCall = "/data", "report.pdf", "risks.xlsx", "tags.xlsx", "risks.json", "technical-assets.json", "stats.json", "data-flow-diagram.gv", "data-flow-diagram.png", "data-asset-diagram.gv", "data-asset-diagram.png", "render-data-flow-diagram.sh", "render-data-asset-diagram.sh"
+const baseFolder, reportFilename, reportFilenameHTML, excelRisksFilename, excelTagsFilename, jsonRisksFilename, jsonTechnicalAssetsFilename, jsonStatsFilename, dataFlowDiagramFilenameDOT, dataFlowDiagramFilenamePNG, dataAssetDiagramFilenameDOT, dataAssetDiagramFilenamePNG, graphvizDataFlowDiagramConversionCall, graphvizDataAssetDiagramConversionCall = "/data", "report.pdf", "report.html", "risks.xlsx", "tags.xlsx", "risks.json", "technical-assets.json", "stats.json", "data-flow-diagram.gv", "data-flow-diagram.png", "data-asset-diagram.gv", "data-asset-diagram.png", "render-data-flow-diagram.sh", "render-data-asset-diagram.sh"
 
 var globalLock sync.Mutex
 var successCount, errorCount = 0, 0
 
-var modelInput model.ModelInput
-
 var drawSpaceLinesForLayoutUnfortunatelyFurtherSeparatesAllRanks = true
 
 var buildTimestamp = ""
 
 var modelFilename, templateFilename /*, diagramFilename, reportFilename, graphvizConversion*/ *string
-var createExampleModel, createStubModel, createEditingSupport, verbose, ignoreOrphanedRiskTracking, generateDataFlowDiagram, generateDataAssetDiagram, generateRisksJSON, generateTechnicalAssetsJSON, generateStatsJSON, generateRisksExcel, generateTagsExcel, generateReportPDF, generateDefectdojoGeneric *bool
-var outputDir, raaPlugin, skipRiskRules, riskRulesPlugins, executeModelMacro *string
-var builtinRiskRulesPlugins map[string]model.RiskRule
-var diagramDPI, serverPort *int
-
-var deferredRiskTrackingDueToWildcardMatching = make(map[string]model.RiskTracking)
-
-func applyRiskGeneration() {
-	if *verbose {
-		fmt.Println("Applying risk generation")
-	}
-	skippedRules := make(map[string]interface{})
-	if len(*skipRiskRules) > 0 {
-		for _, id := range strings.Split(*skipRiskRules, ",") {
-			skippedRules[id] = true
-		}
-	}
-
-	for id, riskPlugin := range builtinRiskRulesPlugins {
-		if _, ok := skippedRules[riskPlugin.Category().Id]; ok {
-			fmt.Println("Skipping risk rule:", id)
-			delete(skippedRules, id)
-		} else {
-			model.AddToListOfSupportedTags(riskPlugin.SupportedTags())
-			risks := riskPlugin.GenerateRisks()
-			if len(risks) > 0 {
-				model.GeneratedRisksByCategory[riskPlugin.Category()] = risks
-			}
-		}
-	}
-
-	if len(skippedRules) > 0 {
-		keys := make([]string, 0)
-		for k := range skippedRules {
-			keys = append(keys, k)
-		}
-		if len(keys) > 0 {
-			log.Println("Unknown risk rules to skip:", keys)
-		}
-	}
-
-	// save also in map keyed by synthetic risk-id
-	for _, category := range model.SortedRiskCategories() {
-		risks := model.SortedRisksOfCategory(category)
-		for _, risk := range risks {
-			model.GeneratedRisksBySyntheticId[strings.ToLower(risk.SyntheticId)] = risk
-		}
-	}
-}
-
-func checkRiskTracking() {
-	if *verbose {
-		fmt.Println("Checking risk tracking")
-	}
-	for _, tracking := range model.ParsedModelRoot.RiskTracking {
-		if _, ok := model.GeneratedRisksBySyntheticId[tracking.SyntheticRiskId]; !ok {
-			if *ignoreOrphanedRiskTracking {
-				fmt.Println("Risk tracking references unknown risk (risk id not found): " + tracking.SyntheticRiskId)
-			} else {
-				panic(errors.New("Risk tracking references unknown risk (risk id not found) - you might want to use the option -ignore-orphaned-risk-tracking: " + tracking.SyntheticRiskId +
-					"\n\nNOTE: For risk tracking each risk-id needs to be defined (the string with the @ sign in it). " +
-					"These unique risk IDs are visible in the PDF report (the small grey string under each risk), " +
-					"the Excel (column \"ID\"), as well as the JSON responses. Some risk IDs have only one @ sign in them, " +
-					"while others multiple. The idea is to allow for unique but still speaking IDs. Therefore each risk instance " +
-					"creates its individual ID by taking all affected elements causing the risk to be within an @-delimited part. " +
-					"Using wildcards (the * sign) for parts delimited by @ signs allows to handle groups of certain risks at once. " +
-					"Best is to lookup the IDs to use in the created Excel file. Alternatively a model macro \"seed-risk-tracking\" " +
-					"is available that helps in initially seeding the risk tracking part here based on already identified and not yet handled risks."))
-			}
-		}
-	}
-
-	// save also the risk-category-id and risk-status directly in the risk for better JSON marshalling
-	for category, _ := range model.GeneratedRisksByCategory {
-		for i, _ := range model.GeneratedRisksByCategory[category] {
-			model.GeneratedRisksByCategory[category][i].CategoryId = category.Id
-			model.GeneratedRisksByCategory[category][i].RiskStatus = model.GeneratedRisksByCategory[category][i].GetRiskTrackingStatusDefaultingUnchecked()
-		}
-	}
-}
+var createExampleModel, createStubModel, createEditingSupport, diffModels, verbose, ignoreOrphanedRiskTracking, restrictIncludes, generateDataFlowDiagram, generateDataAssetDiagram, generateRisksJSON, generateTechnicalAssetsJSON, generateStatsJSON, generateRisksExcel, generateTagsExcel, generateReportPDF, generateReportHTML, generateDefectdojoGeneric, generateDataFlowDiagramMermaid, generateDataFlowDiagramPlantUML, diagramSVG, diagramRiskOverlay, importMerge *bool
+var outputDir, raaPlugin, skipRiskRules, riskRulesPlugins, customRiskRulesFile, executeModelMacro, diffFormat, importOTM, exportOTM, importTM7, tm7MappingFile, importKubernetes, importDockerCompose, importTerraform *string
+var diagramDPI, serverPort, serverHistoryCount, serverHistoryMaxAge *int
+var serverStorage, serverJWTConfig, expiredRiskTrackingStatus, failOn, policyFile, baselineFile *string
+var failOnExpiredRiskTracking, updateBaseline, failOnNewRisks *bool
+var modelStorage storage.Storage
+var jwtAuthenticator *jwtauth.Authenticator // only set when authenticating via JWTs, which then all share the configured key
+var jwtKey []byte
 
 // === Error handling stuff ========================================
 
@@ -154,6 +99,8 @@ func main() {
 	parseCommandlineArgs()
 	if *serverPort > 0 {
 		startServer()
+	} else if *diffModels {
+		doDiff(flag.Arg(0), flag.Arg(1))
 	} else {
 		doIt(*modelFilename, *outputDir)
 	}
@@ -262,6 +209,51 @@ func addFileToZip(zipWriter *zip.Writer, filename string) error {
 	return err
 }
 
+func doDiff(oldFilename, newFilename string) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := r.(error)
+			if *verbose {
+				log.Println(err)
+			}
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+	}()
+	oldResult, newResult := analyzeForDiff(oldFilename), analyzeForDiff(newFilename)
+	diff := threagile.Diff(oldResult, newResult)
+	switch *diffFormat {
+	case "text":
+		fmt.Print(diff.Text())
+	case "markdown":
+		fmt.Print(diff.Markdown())
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		support.CheckErr(encoder.Encode(diff))
+	default:
+		panic(errors.New("unknown diff format (use text, json, or markdown): " + *diffFormat))
+	}
+}
+
+func analyzeForDiff(inputFilename string) *threagile.Result {
+	if *verbose {
+		fmt.Println("Parsing model:", inputFilename)
+	}
+	modelYaml, err := ioutil.ReadFile(inputFilename)
+	support.CheckErr(err)
+	result, err := threagile.Analyze(ctx.Background(), modelYaml, analysisOptions(inputFilename))
+	var diagnostics model.Diagnostics
+	if errors.As(err, &diagnostics) {
+		os.Stderr.WriteString(diagnostics.Format(inputFilename))
+		os.Exit(2)
+	}
+	support.CheckErr(err)
+	os.Stderr.WriteString(result.Diagnostics().Format(inputFilename))
+	return result
+}
+
 func doIt(inputFilename string, outputDirectory string) {
 	defer func() {
 		var err error
@@ -282,18 +274,145 @@ func doIt(inputFilename string, outputDirectory string) {
 		}
 	}
 
-	model.Init()
-	parseModel(inputFilename)
-	introTextRAA := applyRAA()
-	loadRiskRulePlugins()
-	applyRiskGeneration()
-	applyWildcardRiskTrackingEvaluation()
-	checkRiskTracking()
+	failPolicy := loadFailPolicy()
+
+	if *verbose {
+		fmt.Println("Parsing model:", inputFilename)
+	}
+	modelYaml, err := ioutil.ReadFile(inputFilename)
+	support.CheckErr(err)
+	options := analysisOptions(inputFilename)
+	if len(*executeModelMacro) > 0 { // model macros (like the cleanup of orphaned risk tracking) are also executable on models with orphaned risk tracking
+		options.IgnoreOrphanedRiskTracking = true
+	}
+	result, err := threagile.Analyze(ctx.Background(), modelYaml, options)
+	var diagnostics model.Diagnostics
+	if errors.As(err, &diagnostics) {
+		os.Stderr.WriteString(diagnostics.Format(inputFilename))
+		os.Exit(2)
+	}
+	support.CheckErr(err)
+	os.Stderr.WriteString(result.Diagnostics().Format(inputFilename))
 
 	if len(*executeModelMacro) > 0 {
-		macros.ExecuteModelMacro(executeModelMacro, modelInput, inputFilename)
+		support.CheckErr(result.WithModel(func() {
+			macros.ExecuteModelMacro(executeModelMacro, result.ModelInput(), inputFilename)
+		}))
 	}
 
+	risks, baselinedRisks := result.Risks(), map[string]bool(nil)
+	if len(*baselineFile) > 0 {
+		risks, baselinedRisks = applyBaseline(result)
+	}
+	policyViolations := failPolicy.Evaluate(risks, result.ParsedModel())
+	support.CheckErr(result.WithModel(func() {
+		render(result, inputFilename, outputDirectory, policy.RulesBySyntheticId(policyViolations), baselinedRisks)
+	}))
+
+	if len(*exportOTM) > 0 {
+		if *verbose {
+			fmt.Println("Writing otm:", *exportOTM)
+		}
+		exportOTMFile(result, *exportOTM)
+	}
+
+	exitCode := 0
+	if *failOnExpiredRiskTracking {
+		expired := 0
+		for _, tracking := range result.RiskTracking() {
+			if tracking.Expired {
+				expired++
+			}
+		}
+		if expired > 0 {
+			os.Stderr.WriteString("risks with expired risk tracking: " + strconv.Itoa(expired) + "\n")
+			exitCode = exitCodeExpiredRiskTracking
+		}
+	}
+	if len(*baselineFile) > 0 && !*updateBaseline {
+		newRisks := 0
+		for _, risk := range risks {
+			if risk.RiskStatus.IsStillAtRisk() {
+				newRisks++
+				os.Stderr.WriteString("new risk: " + risk.Severity.String() + " " + risk.SyntheticId + "\n")
+			}
+		}
+		os.Stderr.WriteString("new risks compared to baseline: " + strconv.Itoa(newRisks) + "\n")
+		if *failOnNewRisks && newRisks > 0 {
+			exitCode = exitCodeNewRisks
+		}
+	}
+	if len(policyViolations) > 0 {
+		os.Stderr.WriteString(policy.Summary(policyViolations))
+		exitCode = exitCodePolicyViolation
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// applyBaseline returns the risks not contained in the baseline along with the synthetic ids of the contained ones,
+// when updating the baseline all current risks are written into it (and none is new)
+func applyBaseline(result *threagile.Result) ([]model.Risk, map[string]bool) {
+	var known baseline.Baseline
+	if *updateBaseline {
+		known = baseline.New(result.Risks())
+		support.CheckErr(known.Write(*baselineFile))
+		if *verbose {
+			fmt.Println("Writing baseline:", *baselineFile)
+		}
+	} else {
+		var err error
+		known, err = baseline.Load(*baselineFile)
+		if os.IsNotExist(err) {
+			err = errors.New("baseline not found (create it via -update-baseline): " + *baselineFile)
+		}
+		support.CheckErr(err)
+		known = known.Migrate(model.CurrentIdsByPreviousId(result.ParsedModel()))
+	}
+	newRisks := known.NewRisks(result.Risks())
+	baselinedRisks := make(map[string]bool)
+	for _, risk := range result.Risks() {
+		baselinedRisks[risk.SyntheticId] = true
+	}
+	for _, risk := range newRisks {
+		delete(baselinedRisks, risk.SyntheticId)
+	}
+	return newRisks, baselinedRisks
+}
+
+// loadFailPolicy combines the rules given via -fail-on and -policy-file (none when both are empty)
+func loadFailPolicy() policy.Policy {
+	result, err := policy.ParseFailOn(*failOn)
+	support.CheckErr(err)
+	if len(*policyFile) > 0 {
+		filePolicy, err := policy.LoadPolicy(*policyFile)
+		support.CheckErr(err)
+		result.Rules = append(result.Rules, filePolicy.Rules...)
+	}
+	return result
+}
+
+func exportOTMFile(result *threagile.Result, filename string) {
+	data, err := otm.Marshal(otm.FromModelInput(result.MergedModelInput(), result.Risks(), result.RiskTracking()), otm.IsYAMLFilename(filename))
+	support.CheckErr(err)
+	support.CheckErr(ioutil.WriteFile(filename, data, 0644))
+}
+
+// modelYAMLOfOTM converts the OTM document (JSON or YAML) into the yaml of a model
+func modelYAMLOfOTM(data []byte) ([]byte, error) {
+	document, err := otm.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	modelInput, err := otm.ToModelInput(document)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(&modelInput)
+}
+
+func render(result *threagile.Result, inputFilename string, outputDirectory string, policyViolations map[string][]string, baselinedRisks map[string]bool) {
 	renderDataFlowDiagram, renderDataAssetDiagram, renderRisksJSON, renderTechnicalAssetsJSON, renderStatsJSON, renderRisksExcel, renderTagsExcel, renderPDF, renderDefectDojo := *generateDataFlowDiagram, *generateDataAssetDiagram, *generateRisksJSON, *generateTechnicalAssetsJSON, *generateStatsJSON, *generateRisksExcel, *generateTagsExcel, *generateReportPDF, *generateDefectdojoGeneric
 	if renderPDF { // as the PDF report includes both diagrams
 		renderDataFlowDiagram, renderDataAssetDiagram = true, true
@@ -301,18 +420,31 @@ func doIt(inputFilename string, outputDirectory string) {
 
 	// Data-flow Diagram rendering
 	if renderDataFlowDiagram {
-		report.RenderDataFlowDiagram(outputDirectory, keepDiagramSourceFiles, diagramDPI, verbose)
+		report.RenderDataFlowDiagram(outputDirectory, keepDiagramSourceFiles, diagramDPI, *diagramRiskOverlay, verbose)
+		if *diagramSVG {
+			report.RenderDataFlowDiagramSVG(outputDirectory, *diagramRiskOverlay, verbose)
+		}
+	}
+	// Data-flow Diagram as text (for markdown, no graphviz required)
+	if *generateDataFlowDiagramMermaid {
+		report.WriteDataFlowDiagramMermaid(outputDirectory, verbose)
+	}
+	if *generateDataFlowDiagramPlantUML {
+		report.WriteDataFlowDiagramPlantUML(outputDirectory, verbose)
 	}
 	// Data Asset Diagram rendering
 	if renderDataAssetDiagram {
-		report.RenderDataAssetDiagram(outputDirectory, dataAssetDiagramFilenameDOT, keepDiagramSourceFiles, diagramDPI, verbose)
+		report.RenderDataAssetDiagram(outputDirectory, dataAssetDiagramFilenameDOT, keepDiagramSourceFiles, diagramDPI, *diagramRiskOverlay, verbose)
+		if *diagramSVG {
+			report.RenderDataAssetDiagramSVG(outputDirectory, *diagramRiskOverlay, verbose)
+		}
 	}
 	if renderDefectDojo {
 		if *verbose {
 			fmt.Println("Writing risks defectdojo generic json")
 		}
 		report.WriteDefectdojoGeneric(outputDirectory + "/defectdojo.json")
-		report.WriteOpenSarif(outputDirectory + "/risks.sarif")
+		report.WriteOpenSarif(outputDirectory+"/risks.sarif", policyViolations, baselinedRisks)
 	}
 	// risks as risks json
 	if renderRisksJSON {
@@ -355,15 +487,6 @@ func doIt(inputFilename string, outputDirectory string) {
 	}
 
 	if renderPDF {
-		// hash the YAML input file
-		f, err := os.Open(inputFilename)
-		support.CheckErr(err)
-		defer f.Close()
-		hasher := sha256.New()
-		if _, err := io.Copy(hasher, f); err != nil {
-			panic(err)
-		}
-		modelHash := hex.EncodeToString(hasher.Sum(nil))
 		// report PDF
 		if *verbose {
 			fmt.Println("Writing report pdf")
@@ -375,58 +498,23 @@ func doIt(inputFilename string, outputDirectory string) {
 			inputFilename,
 			*skipRiskRules,
 			buildTimestamp,
-			modelHash,
-			introTextRAA,
-			builtinRiskRulesPlugins)
+			result.ModelHash(),
+			result.IntroTextRAA(),
+			result.RiskRules())
 	}
-}
 
-func applyRAA() string {
-	if *verbose {
-		fmt.Println("Applying RAA calculation:", *raaPlugin)
-	}
-	// determine plugin to load
-	// load plugin: open the ".so" file to load the symbols
-	plug, err := plugin.Open(*raaPlugin)
-	support.CheckErr(err)
-	// look up a symbol (an exported function or variable): in this case, function CalculateRAA
-	symCalculateRAA, err := plug.Lookup("CalculateRAA")
-	support.CheckErr(err)
-	// use the plugin
-	raaCalcFunc, ok := symCalculateRAA.(func() string) // symCalculateRAA.(func(model.ParsedModel) string)
-	if !ok {
-		panic(errors.New("RAA plugin has no 'CalculateRAA() string' function"))
-	}
-	// call it
-	return raaCalcFunc()
-}
-func loadRiskRulePlugins() {
-	builtinRiskRulesPlugins = make(map[string]model.RiskRule)
-	pluginFiles, err := filepath.Glob("risk-plugins/*.so")
-	if err != nil {
-		panic(errors.New(err.Error()))
-	}
-	for _, pluginFile := range pluginFiles {
-		_, err := os.Stat(pluginFile)
-		if os.IsNotExist(err) {
-			log.Fatal("Risk rule implementation file not found: ", pluginFile)
-		}
-		plug, err := plugin.Open(pluginFile)
-		support.CheckErr(err)
-		// look up a symbol (an exported function or variable): in this case variable CustomRiskRule
-		symRiskRule, err := plug.Lookup("RiskRule")
-		support.CheckErr(err)
-		// register the risk rule plugin for later use: in this case interface type model.RiskRule (defined above)
-		symRiskRuleVar, ok := symRiskRule.(model.RiskRule)
-		if !ok {
-			panic(errors.New("Risk rule plugin has no 'RiskRule' variable" + symRiskRuleVar.Category().Id))
-		}
-		// simply add to a map (just convenience) where key is the category id and value the rule's execution function
-		ruleID := symRiskRuleVar.Category().Id
-		builtinRiskRulesPlugins[ruleID] = symRiskRuleVar
+	if *generateReportHTML {
+		// report HTML
 		if *verbose {
-			fmt.Println("Risk rule loaded:", ruleID)
+			fmt.Println("Writing report html")
 		}
+		report.WriteReportHTML(outputDirectory+"/"+reportFilenameHTML,
+			report.DataFlowDiagramSVG(*diagramRiskOverlay, verbose),
+			inputFilename,
+			*skipRiskRules,
+			buildTimestamp,
+			result.ModelHash(),
+			result.RiskRules())
 	}
 }
 
@@ -437,7 +525,8 @@ func check(context *gin.Context) {
 	_, ok := execute(context, true)
 	if ok {
 		context.JSON(http.StatusOK, gin.H{
-			"message": "model is ok",
+			"message":     "model is ok",
+			"diagnostics": context.MustGet("diagnostics"),
 		})
 	}
 }
@@ -449,9 +538,17 @@ func execute(context *gin.Context, dryRun bool) (yamlContent []byte, ok bool) {
 			errorCount++
 			err = r.(error)
 			log.Println(err)
-			context.JSON(http.StatusBadRequest, gin.H{
-				"error": strings.TrimSpace(err.Error()),
-			})
+			var diagnostics model.Diagnostics
+			if errors.As(err, &diagnostics) {
+				context.JSON(http.StatusBadRequest, gin.H{
+					"error":       "model is not ok",
+					"diagnostics": diagnostics,
+				})
+			} else {
+				context.JSON(http.StatusBadRequest, gin.H{
+					"error": strings.TrimSpace(err.Error()),
+				})
+			}
 			ok = false
 		}
 	}()
@@ -514,7 +611,13 @@ func execute(context *gin.Context, dryRun bool) (yamlContent []byte, ok bool) {
 	defer os.Remove(tmpResultFile.Name())
 
 	if dryRun {
-		doItViaRuntimeCall(yamlFile, tmpOutputDir, *executeModelMacro, *raaPlugin, *skipRiskRules, *ignoreOrphanedRiskTracking, false, false, false, false, false, true, true, true, true, 40)
+		modelYaml, err := ioutil.ReadFile(yamlFile)
+		support.CheckErr(err)
+		options := analysisOptions(yamlFile)
+		options.RestrictIncludesToModelDirectory = true
+		result, err := threagile.Analyze(context.Request.Context(), modelYaml, options)
+		support.CheckErr(err)
+		context.Set("diagnostics", result.Diagnostics())
 	} else {
 		doItViaRuntimeCall(yamlFile, tmpOutputDir, *executeModelMacro, *raaPlugin, *skipRiskRules, *ignoreOrphanedRiskTracking, true, true, true, true, true, true, true, true, true, dpi)
 	}
@@ -558,10 +661,13 @@ func doItViaRuntimeCall(modelFile string, outputDir string, executeModelMacro st
 	dpi int) {
 	// Remember to also add the same args to the exec based sub-process calls!
 	var cmd *exec.Cmd
-	args := []string{"-model", modelFile, "-output", outputDir, "-execute-model-macro", executeModelMacro, "-raa-plugin", raaPlugin, "-skip-risk-rules", skipRiskRules, "-diagram-dpi", strconv.Itoa(dpi)}
+	args := []string{"-model", modelFile, "-output", outputDir, "-execute-model-macro", executeModelMacro, "-raa-plugin", raaPlugin, "-skip-risk-rules", skipRiskRules, "-diagram-dpi", strconv.Itoa(dpi), "-restrict-includes"}
 	if *verbose {
 		args = append(args, "-verbose")
 	}
+	if len(*customRiskRulesFile) > 0 {
+		args = append(args, "-custom-risk-rules-file", *customRiskRulesFile)
+	}
 	if ignoreOrphanedRiskTracking { // TODO why add all them as arguments, when they are also variables on outer level?
 		args = append(args, "-ignore-orphaned-risk-tracking")
 	}
@@ -607,7 +713,24 @@ func doItViaRuntimeCall(modelFile string, outputDir string, executeModelMacro st
 }
 
 func startServer() {
+	switch *serverStorage {
+	case "filesystem":
+		modelStorage = storage.NewFileSystem(baseFolder)
+	case "bolt":
+		boltStorage, err := storage.OpenBolt(baseFolder + "/" + boltStorageFilename)
+		support.CheckErr(err)
+		defer boltStorage.Close()
+		modelStorage = boltStorage
+	default:
+		panic(errors.New("unknown server storage (use filesystem or bolt): " + *serverStorage))
+	}
+	if len(*serverJWTConfig) > 0 {
+		setupJWTAuthentication(*serverJWTConfig)
+	}
 	router := gin.Default()
+	if jwtAuthenticator != nil {
+		router.Use(authorizeJWT) // before registering the routes, as the middleware is only applied to those registered afterwards
+	}
 	router.LoadHTMLGlob("server/static/*.html")
 	router.GET("/", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "index.html", gin.H{})
@@ -684,16 +807,30 @@ func startServer() {
 	router.POST("/direct/check", check)
 	router.GET("/direct/stub", stubFile)
 
-	router.POST("/auth/keys", createKey)
-	router.DELETE("/auth/keys", deleteKey)
-	router.POST("/auth/tokens", createToken)
-	router.DELETE("/auth/tokens", deleteToken)
+	if jwtAuthenticator == nil {
+		router.POST("/auth/keys", createKey)
+		router.DELETE("/auth/keys", deleteKey)
+		router.POST("/auth/tokens", createToken)
+		router.DELETE("/auth/tokens", deleteToken)
+	}
+
+	router.GET("/webhooks", listWebhooks)
+	router.POST("/webhooks", createNewWebhook)
+	router.GET("/webhooks/:webhook-id", getWebhook)
+	router.PUT("/webhooks/:webhook-id", setWebhook)
+	router.DELETE("/webhooks/:webhook-id", deleteWebhook)
 
 	router.POST("/models", createNewModel)
 	router.GET("/models", listModels)
 	router.DELETE("/models/:model-id", deleteModel)
 	router.GET("/models/:model-id", getModel)
 	router.PUT("/models/:model-id", importModel)
+	router.GET("/models/:model-id/otm", getModelOTM)
+	router.PUT("/models/:model-id/otm", importModelOTM)
+	router.GET("/models/:model-id/history", listModelHistory)
+	router.GET("/models/:model-id/history/:history-id", getModelHistoryVersion)
+	router.GET("/models/:model-id/history/:history-id/diff", diffModelHistoryVersion)
+	router.POST("/models/:model-id/history/:history-id/restore", restoreModelHistoryVersion)
 	router.GET("/models/:model-id/data-flow-diagram", streamDataFlowDiagram)
 	router.GET("/models/:model-id/data-asset-diagram", streamDataAssetDiagram)
 	router.GET("/models/:model-id/report-pdf", streamReportPDF)
@@ -708,14 +845,14 @@ func startServer() {
 	router.PUT("/models/:model-id/cover", setCover)
 	router.GET("/models/:model-id/overview", getOverview)
 	router.PUT("/models/:model-id/overview", setOverview)
-	//router.GET("/models/:model-id/questions", getQuestions)
-	//router.PUT("/models/:model-id/questions", setQuestions)
+	router.GET("/models/:model-id/questions", getQuestions)
+	router.PUT("/models/:model-id/questions", setQuestions)
 	router.GET("/models/:model-id/abuse-cases", getAbuseCases)
 	router.PUT("/models/:model-id/abuse-cases", setAbuseCases)
 	router.GET("/models/:model-id/security-requirements", getSecurityRequirements)
 	router.PUT("/models/:model-id/security-requirements", setSecurityRequirements)
-	//router.GET("/models/:model-id/tags", getTags)
-	//router.PUT("/models/:model-id/tags", setTags)
+	router.GET("/models/:model-id/tags", getTags)
+	router.PUT("/models/:model-id/tags", setTags)
 
 	router.GET("/models/:model-id/data-assets", getDataAssets)
 	router.POST("/models/:model-id/data-assets", createNewDataAsset)
@@ -723,11 +860,22 @@ func startServer() {
 	router.PUT("/models/:model-id/data-assets/:data-asset-id", setDataAsset)
 	router.DELETE("/models/:model-id/data-assets/:data-asset-id", deleteDataAsset)
 
+	router.POST("/models/:model-id/technical-assets", createNewTechnicalAsset)
+	router.GET("/models/:model-id/technical-assets/:technical-asset-id", getTechnicalAsset)
+	router.PUT("/models/:model-id/technical-assets/:technical-asset-id", setTechnicalAsset)
+	router.DELETE("/models/:model-id/technical-assets/:technical-asset-id", deleteTechnicalAsset)
+
+	router.GET("/models/:model-id/technical-assets/:technical-asset-id/communication-links", getCommunicationLinks)
+	router.POST("/models/:model-id/technical-assets/:technical-asset-id/communication-links", createNewCommunicationLink)
+	router.GET("/models/:model-id/technical-assets/:technical-asset-id/communication-links/:communication-link-id", getCommunicationLink)
+	router.PUT("/models/:model-id/technical-assets/:technical-asset-id/communication-links/:communication-link-id", setCommunicationLink)
+	router.DELETE("/models/:model-id/technical-assets/:technical-asset-id/communication-links/:communication-link-id", deleteCommunicationLink)
+
 	router.GET("/models/:model-id/trust-boundaries", getTrustBoundaries)
-	//	router.POST("/models/:model-id/trust-boundaries", createNewTrustBoundary)
-	//	router.GET("/models/:model-id/trust-boundaries/:trust-boundary-id", getTrustBoundary)
-	//	router.PUT("/models/:model-id/trust-boundaries/:trust-boundary-id", setTrustBoundary)
-	//	router.DELETE("/models/:model-id/trust-boundaries/:trust-boundary-id", deleteTrustBoundary)
+	router.POST("/models/:model-id/trust-boundaries", createNewTrustBoundary)
+	router.GET("/models/:model-id/trust-boundaries/:trust-boundary-id", getTrustBoundary)
+	router.PUT("/models/:model-id/trust-boundaries/:trust-boundary-id", setTrustBoundary)
+	router.DELETE("/models/:model-id/trust-boundaries/:trust-boundary-id", deleteTrustBoundary)
 
 	router.GET("/models/:model-id/shared-runtimes", getSharedRuntimes)
 	router.POST("/models/:model-id/shared-runtimes", createNewSharedRuntime)
@@ -735,6 +883,18 @@ func startServer() {
 	router.PUT("/models/:model-id/shared-runtimes/:shared-runtime-id", setSharedRuntime)
 	router.DELETE("/models/:model-id/shared-runtimes/:shared-runtime-id", deleteSharedRuntime)
 
+	router.GET("/models/:model-id/individual-risk-categories", getIndividualRiskCategories)
+	router.POST("/models/:model-id/individual-risk-categories", createNewIndividualRiskCategory)
+	router.GET("/models/:model-id/individual-risk-categories/:individual-risk-category-id", getIndividualRiskCategory)
+	router.PUT("/models/:model-id/individual-risk-categories/:individual-risk-category-id", setIndividualRiskCategory)
+	router.DELETE("/models/:model-id/individual-risk-categories/:individual-risk-category-id", deleteIndividualRiskCategory)
+
+	router.GET("/models/:model-id/risk-tracking", getRiskTracking)
+	router.PUT("/models/:model-id/risk-tracking", setRiskTracking)
+	router.GET("/models/:model-id/risks/:synthetic-id/tracking", getRiskTrackingOfRisk)
+	router.PUT("/models/:model-id/risks/:synthetic-id/tracking", setRiskTrackingOfRisk)
+	router.DELETE("/models/:model-id/risks/:synthetic-id/tracking", deleteRiskTrackingOfRisk)
+
 	fmt.Println("Threagile server running...")
 	router.Run(":" + strconv.Itoa(*serverPort)) // listen and serve on 0.0.0.0:8080 or whatever port was specified
 }
@@ -753,8 +913,10 @@ func stubFile(context *gin.Context) {
 
 func addSupportedTags(input []byte) []byte {
 	// add distinct tags as "tags_available"
+	riskRules, err := threagile.LoadRiskRules(threagile.DefaultOptions().RiskRulesPlugins, *verbose)
+	support.CheckErr(err)
 	supportedTags := make(map[string]bool, 0)
-	for _, riskRule := range builtinRiskRulesPlugins {
+	for _, riskRule := range riskRules {
 		for _, tag := range riskRule.SupportedTags() {
 			supportedTags[strings.ToLower(tag)] = true
 		}
@@ -1153,7 +1315,7 @@ func importModel(context *gin.Context) {
 		yamlContent, ok := execute(context, true)
 		if ok {
 			// if we're here, then no problem was raised, so ok to proceed
-			ok = writeModelYAML(context, string(yamlContent), key, folderNameForModel(folderNameOfKey, uuid), "Model Import", false)
+			ok = writeModelYAML(context, string(yamlContent), key, folderNameOfKey, uuid, "Model Import")
 			if ok {
 				context.JSON(http.StatusCreated, gin.H{
 					"message": "model imported",
@@ -1163,9 +1325,102 @@ func importModel(context *gin.Context) {
 	}
 }
 
+// streams the model including its risks as open threat model (otm): json, or yaml via query parameter format=yaml
+func getModelOTM(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	_, yamlText, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if !ok {
+		return
+	}
+	result, ok := analyzeModelYAML(context, []byte(yamlText))
+	if !ok {
+		return
+	}
+	asYAML := context.Query("format") == "yaml"
+	data, err := otm.Marshal(otm.FromModelInput(result.MergedModelInput(), result.Risks(), result.RiskTracking()), asYAML)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return
+	}
+	if asYAML {
+		context.Data(http.StatusOK, "application/x-yaml", data)
+	} else {
+		context.Data(http.StatusOK, "application/json", data)
+	}
+}
+
+// fully replaces threagile.yaml in sub-folder given by UUID with the model converted from the open threat model (json or yaml) in the request body
+func importModelOTM(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+
+	uuid := context.Param("model-id")
+	_, _, ok = readModel(context, uuid, key, folderNameOfKey)
+	if !ok {
+		return
+	}
+	data, err := ioutil.ReadAll(http.MaxBytesReader(context.Writer, context.Request.Body, 50000000))
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return
+	}
+	modelYaml, err := modelYAMLOfOTM(data)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return
+	}
+	// analyze it first (just discard the result) to ensure that everything would work
+	if _, ok = analyzeModelYAML(context, modelYaml); !ok {
+		return
+	}
+	ok = writeModelYAML(context, string(modelYaml), key, folderNameOfKey, uuid, "OTM Import")
+	if ok {
+		context.JSON(http.StatusCreated, gin.H{
+			"message": "model imported",
+		})
+	}
+}
+
+// analyzeModelYAML runs the analysis in-process (with includes restricted to an empty directory) and responds with the problems found
+func analyzeModelYAML(context *gin.Context, modelYaml []byte) (result *threagile.Result, ok bool) {
+	result, err := analyzeModelYAMLRestricted(context.Request.Context(), modelYaml)
+	var diagnostics model.Diagnostics
+	if errors.As(err, &diagnostics) {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error":       "model is not ok",
+			"diagnostics": diagnostics,
+		})
+		return nil, false
+	} else if err != nil {
+		handleErrorInServiceCall(err, context)
+		return nil, false
+	}
+	return result, true
+}
+
+func analyzeModelYAMLRestricted(context ctx.Context, modelYaml []byte) (*threagile.Result, error) {
+	tmpInputDir, err := ioutil.TempDir(model.TempFolder, "threagile-input-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpInputDir)
+	options := analysisOptions(tmpInputDir + "/threagile.yaml")
+	options.RestrictIncludesToModelDirectory = true
+	return threagile.Analyze(context, modelYaml, options)
+}
+
 func stats(context *gin.Context) {
 	keyCount, modelCount := 0, 0
-	keyFolders, err := ioutil.ReadDir(baseFolder)
+	keys, err := modelStorage.Keys()
 	if err != nil {
 		log.Println(err)
 		context.JSON(http.StatusInternalServerError, gin.H{
@@ -1173,23 +1428,17 @@ func stats(context *gin.Context) {
 		})
 		return
 	}
-	for _, keyFolder := range keyFolders {
-		if len(keyFolder.Name()) == 128 { // it's a sha512 token hash probably, so count it as token folder for the stats
-			keyCount++
-			modelFolders, err := ioutil.ReadDir(baseFolder + "/" + keyFolder.Name())
-			if err != nil {
-				log.Println(err)
-				context.JSON(http.StatusInternalServerError, gin.H{
-					"error": "unable to collect stats",
-				})
-				return
-			}
-			for _, modelFolder := range modelFolders {
-				if len(modelFolder.Name()) == 36 { // it's a uuid model folder probably, so count it as model folder for the stats
-					modelCount++
-				}
-			}
+	for _, folderNameOfKey := range keys {
+		keyCount++
+		models, err := modelStorage.Models(folderNameOfKey)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusInternalServerError, gin.H{
+				"error": "unable to collect stats",
+			})
+			return
 		}
+		modelCount += len(models)
 	}
 	// TODO collect and deliver more stats (old model count?) and health info
 	context.JSON(http.StatusOK, gin.H{
@@ -1352,6 +1601,7 @@ func setSharedRuntime(context *gin.Context) {
 				if !ok {
 					return
 				}
+				sharedRuntimeInput.Previous_ids = updatedPreviousIds(payload.Previous_ids, sharedRuntime.Previous_ids, sharedRuntime.ID, sharedRuntimeInput.ID)
 				// in order to also update the title, remove the shared runtime from the map and re-insert it (with new key)
 				delete(modelInput.Shared_runtimes, title)
 				modelInput.Shared_runtimes[payload.Title] = sharedRuntimeInput
@@ -1411,6 +1661,7 @@ func setDataAsset(context *gin.Context) {
 				if !ok {
 					return
 				}
+				dataAssetInput.Previous_ids = updatedPreviousIds(payload.Previous_ids, dataAsset.Previous_ids, dataAsset.ID, dataAssetInput.ID)
 				// in order to also update the title, remove the asset from the map and re-insert it (with new key)
 				delete(modelInput.Data_assets, title)
 				modelInput.Data_assets[payload.Title] = dataAssetInput
@@ -1579,8 +1830,12 @@ func checkTechnicalAssetsExisting(modelInput model.ModelInput, techAssetIDs []st
 }
 
 func populateSharedRuntime(context *gin.Context, payload payloadSharedRuntime) (sharedRuntimeInput model.InputSharedRuntime, ok bool) {
+	if !checkTitleAndID(context, payload.Title, payload.Id, payload.Previous_ids) {
+		return sharedRuntimeInput, false
+	}
 	sharedRuntimeInput = model.InputSharedRuntime{
 		ID:                       payload.Id,
+		Previous_ids:             payload.Previous_ids,
 		Description:              payload.Description,
 		Tags:                     support.LowerCaseAndTrim(payload.Tags),
 		Technical_assets_running: payload.Technical_assets_running,
@@ -1686,6 +1941,9 @@ func createNewDataAsset(context *gin.Context) {
 }
 
 func populateDataAsset(context *gin.Context, payload payloadDataAsset) (dataAssetInput model.InputDataAsset, ok bool) {
+	if !checkTitleAndID(context, payload.Title, payload.Id, payload.Previous_ids) {
+		return dataAssetInput, false
+	}
 	usage, err := model.ParseUsage(payload.Usage)
 	if err != nil {
 		handleErrorInServiceCall(err, context)
@@ -1713,6 +1971,7 @@ func populateDataAsset(context *gin.Context, payload payloadDataAsset) (dataAsse
 	}
 	dataAssetInput = model.InputDataAsset{
 		ID:                       payload.Id,
+		Previous_ids:             payload.Previous_ids,
 		Description:              payload.Description,
 		Usage:                    usage.String(),
 		Tags:                     support.LowerCaseAndTrim(payload.Tags),
@@ -1766,86 +2025,90 @@ func getSharedRuntimes(context *gin.Context) {
 	}
 }
 
-func arrayOfStringValues(values []core.TypeEnum) []string {
-	result := make([]string, 0)
-	for _, value := range values {
-		result = append(result, value.String())
+func getTechnicalAsset(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, techAsset := range modelInput.Technical_assets {
+			if techAsset.ID == context.Param("technical-asset-id") {
+				context.JSON(http.StatusOK, gin.H{
+					title: techAsset,
+				})
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "technical asset not found",
+		})
 	}
-	return result
 }
 
-func getModel(context *gin.Context) {
+func createNewTechnicalAsset(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
 	}
 	lockFolder(folderNameOfKey)
 	defer unlockFolder(folderNameOfKey)
-	_, yamlText, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
 	if ok {
-		tmpResultFile, err := ioutil.TempFile(model.TempFolder, "threagile-*.yaml")
-		support.CheckErr(err)
-		err = ioutil.WriteFile(tmpResultFile.Name(), []byte(yamlText), 0400)
+		payload := payloadTechnicalAsset{}
+		err := context.BindJSON(&payload)
 		if err != nil {
 			log.Println(err)
-			context.JSON(http.StatusInternalServerError, gin.H{
-				"error": "unable to stream model file",
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
 			})
 			return
 		}
-		defer os.Remove(tmpResultFile.Name())
-		context.FileAttachment(tmpResultFile.Name(), "threagile.yaml")
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		if _, exists := modelInput.Technical_assets[payload.Title]; exists {
+			context.JSON(http.StatusConflict, gin.H{
+				"error": "technical asset with this title already exists",
+			})
+			return
+		}
+		// but later it will in memory keyed by it's "id", so do this uniqueness check also
+		for _, asset := range modelInput.Technical_assets {
+			if asset.ID == payload.Id {
+				context.JSON(http.StatusConflict, gin.H{
+					"error": "technical asset with this id already exists",
+				})
+				return
+			}
+		}
+		if !checkDataAssetsExisting(modelInput, payload.Data_assets_processed) || !checkDataAssetsExisting(modelInput, payload.Data_assets_stored) {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "referenced data asset does not exist",
+			})
+			return
+		}
+		techAssetInput, ok := populateTechnicalAsset(context, payload)
+		if !ok {
+			return
+		}
+		techAssetInput.Communication_links = make(map[string]model.InputCommunicationLink)
+		if modelInput.Technical_assets == nil {
+			modelInput.Technical_assets = make(map[string]model.InputTechnicalAsset)
+		}
+		modelInput.Technical_assets[payload.Title] = techAssetInput
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Technical Asset Creation")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "technical asset created",
+				"id":      techAssetInput.ID,
+			})
+		}
 	}
 }
 
-type payloadModels struct {
-	ID                 string    `json:"id"`
-	Title              string    `json:"title"`
-	Timestamp_created  time.Time `json:"timestamp_created"`
-	Timestamp_modified time.Time `json:"timestamp_modified"`
-}
-
-type payloadCover struct {
-	Title  string       `json:"title"`
-	Date   time.Time    `json:"date"`
-	Author model.Author `json:"author"`
-}
-
-type payloadOverview struct {
-	Management_summary_comment string         `json:"management_summary_comment"`
-	Business_criticality       string         `json:"business_criticality"`
-	Business_overview          model.Overview `json:"business_overview"`
-	Technical_overview         model.Overview `json:"technical_overview"`
-}
-
-type payloadAbuseCases map[string]string
-
-type payloadSecurityRequirements map[string]string
-
-type payloadDataAsset struct {
-	Title                    string   `json:"title"`
-	Id                       string   `json:"id"`
-	Description              string   `json:"description"`
-	Usage                    string   `json:"usage"`
-	Tags                     []string `json:"tags"`
-	Origin                   string   `json:"origin"`
-	Owner                    string   `json:"owner"`
-	Quantity                 string   `json:"quantity"`
-	Confidentiality          string   `json:"confidentiality"`
-	Integrity                string   `json:"integrity"`
-	Availability             string   `json:"availability"`
-	Justification_cia_rating string   `json:"justification_cia_rating"`
-}
-
-type payloadSharedRuntime struct {
-	Title                    string   `json:"title"`
-	Id                       string   `json:"id"`
-	Description              string   `json:"description"`
-	Tags                     []string `json:"tags"`
-	Technical_assets_running []string `json:"technical_assets_running"`
-}
-
-func setSecurityRequirements(context *gin.Context) {
+func setTechnicalAsset(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
@@ -1854,39 +2117,298 @@ func setSecurityRequirements(context *gin.Context) {
 	defer unlockFolder(folderNameOfKey)
 	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
 	if ok {
-		payload := payloadSecurityRequirements{}
-		err := context.BindJSON(&payload)
-		if err != nil {
-			log.Println(err)
-			context.JSON(http.StatusBadRequest, gin.H{
-				"error": "unable to parse request payload",
-			})
-			return
-		}
-		modelInput.Security_requirements = payload
-		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Security Requirements Update")
-		if ok {
-			context.JSON(http.StatusOK, gin.H{
-				"message": "model updated",
-			})
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, techAsset := range modelInput.Technical_assets {
+			if techAsset.ID == context.Param("technical-asset-id") {
+				payload := payloadTechnicalAsset{}
+				err := context.BindJSON(&payload)
+				if err != nil {
+					log.Println(err)
+					context.JSON(http.StatusBadRequest, gin.H{
+						"error": "unable to parse request payload",
+					})
+					return
+				}
+				for otherTitle, other := range modelInput.Technical_assets {
+					if otherTitle != title && (otherTitle == payload.Title || other.ID == payload.Id) {
+						context.JSON(http.StatusConflict, gin.H{
+							"error": "technical asset with this title or id already exists",
+						})
+						return
+					}
+				}
+				if !checkDataAssetsExisting(modelInput, payload.Data_assets_processed) || !checkDataAssetsExisting(modelInput, payload.Data_assets_stored) {
+					context.JSON(http.StatusBadRequest, gin.H{
+						"error": "referenced data asset does not exist",
+					})
+					return
+				}
+				techAssetInput, ok := populateTechnicalAsset(context, payload)
+				if !ok {
+					return
+				}
+				techAssetInput.Previous_ids = updatedPreviousIds(payload.Previous_ids, techAsset.Previous_ids, techAsset.ID, techAssetInput.ID)
+				// the communication links are maintained via their own endpoints
+				techAssetInput.Communication_links = techAsset.Communication_links
+				// in order to also update the title, remove the asset from the map and re-insert it (with new key)
+				delete(modelInput.Technical_assets, title)
+				modelInput.Technical_assets[payload.Title] = techAssetInput
+				idChanged := techAssetInput.ID != techAsset.ID
+				if idChanged { // ID-CHANGE-PROPAGATION
+					changeTechnicalAssetID(&modelInput, techAsset.ID, techAssetInput.ID)
+				}
+				ok = writeModel(context, key, folderNameOfKey, &modelInput, "Technical Asset Update")
+				if ok {
+					context.JSON(http.StatusOK, gin.H{
+						"message":    "technical asset updated",
+						"id":         techAssetInput.ID,
+						"id_changed": idChanged, // in order to signal to clients, that other model parts might've received updates as well and should be reloaded
+					})
+				}
+				return
+			}
 		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "technical asset not found",
+		})
 	}
 }
 
-func getSecurityRequirements(context *gin.Context) {
+func deleteTechnicalAsset(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
 	}
 	lockFolder(folderNameOfKey)
 	defer unlockFolder(folderNameOfKey)
-	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
 	if ok {
-		context.JSON(http.StatusOK, model.Security_requirements)
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, techAsset := range modelInput.Technical_assets {
+			if techAsset.ID == context.Param("technical-asset-id") {
+				// remove it itself (along with its outgoing communication links) and then all usages of it !!
+				delete(modelInput.Technical_assets, title)
+				referencesDeleted := removeTechnicalAssetReferences(&modelInput, techAsset.ID)
+				ok = writeModel(context, key, folderNameOfKey, &modelInput, "Technical Asset Deletion")
+				if ok {
+					context.JSON(http.StatusOK, gin.H{
+						"message":            "technical asset deleted",
+						"id":                 techAsset.ID,
+						"references_deleted": referencesDeleted, // in order to signal to clients, that other model parts might've been deleted as well
+					})
+				}
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "technical asset not found",
+		})
 	}
 }
 
-func setAbuseCases(context *gin.Context) {
+// changeTechnicalAssetID points all usages of a technical asset to its new (changed) ID
+func changeTechnicalAssetID(modelInput *model.ModelInput, oldID, newID string) {
+	for _, techAsset := range modelInput.Technical_assets {
+		for title, commLink := range techAsset.Communication_links {
+			if commLink.Target == oldID {
+				commLink.Target = newID
+				techAsset.Communication_links[title] = commLink
+			}
+		}
+	}
+	for _, trustBoundary := range modelInput.Trust_boundaries {
+		replaceValue(trustBoundary.Technical_assets_inside, oldID, newID)
+	}
+	for _, sharedRuntime := range modelInput.Shared_runtimes {
+		replaceValue(sharedRuntime.Technical_assets_running, oldID, newID)
+	}
+	updateIndividualRisks(modelInput, func(risk *model.InputRiskIdentified) bool {
+		if risk.Most_relevant_technical_asset == oldID {
+			risk.Most_relevant_technical_asset = newID
+		}
+		if strings.HasPrefix(risk.Most_relevant_communication_link, oldID+">") {
+			risk.Most_relevant_communication_link = newID + strings.TrimPrefix(risk.Most_relevant_communication_link, oldID)
+		}
+		replaceValue(risk.Data_breach_technical_assets, oldID, newID)
+		return true
+	})
+	for _, tweak := range [][]string{modelInput.Diagram_tweak_invisible_connections_between_assets, modelInput.Diagram_tweak_same_rank_assets} {
+		for i, assetIDs := range tweak {
+			parts := strings.Split(assetIDs, ":")
+			replaceValue(parts, oldID, newID)
+			tweak[i] = strings.Join(parts, ":")
+		}
+	}
+}
+
+// removeTechnicalAssetReferences removes all usages of a (deleted) technical asset, including the communication links targeting it
+func removeTechnicalAssetReferences(modelInput *model.ModelInput, techAssetID string) (referencesDeleted bool) {
+	removedCommLinkIDs := make([]string, 0)
+	for _, techAsset := range modelInput.Technical_assets {
+		for title, commLink := range techAsset.Communication_links {
+			if commLink.Target == techAssetID { // apply the removal
+				referencesDeleted = true
+				removedCommLinkIDs = append(removedCommLinkIDs, techAsset.ID+">"+model.MakeID(title))
+				delete(techAsset.Communication_links, title)
+			}
+		}
+	}
+	var removed bool
+	for title, trustBoundary := range modelInput.Trust_boundaries {
+		if trustBoundary.Technical_assets_inside, removed = removeValue(trustBoundary.Technical_assets_inside, techAssetID); removed {
+			referencesDeleted = true
+			modelInput.Trust_boundaries[title] = trustBoundary
+		}
+	}
+	for title, sharedRuntime := range modelInput.Shared_runtimes {
+		if sharedRuntime.Technical_assets_running, removed = removeValue(sharedRuntime.Technical_assets_running, techAssetID); removed {
+			referencesDeleted = true
+			modelInput.Shared_runtimes[title] = sharedRuntime
+		}
+	}
+	if updateIndividualRisks(modelInput, func(risk *model.InputRiskIdentified) (changed bool) {
+		if risk.Most_relevant_technical_asset == techAssetID {
+			risk.Most_relevant_technical_asset = ""
+			changed = true
+		}
+		if strings.HasPrefix(risk.Most_relevant_communication_link, techAssetID+">") || model.Contains(removedCommLinkIDs, risk.Most_relevant_communication_link) {
+			risk.Most_relevant_communication_link = ""
+			changed = true
+		}
+		if risk.Data_breach_technical_assets, removed = removeValue(risk.Data_breach_technical_assets, techAssetID); removed {
+			changed = true
+		}
+		return changed
+	}) {
+		referencesDeleted = true
+	}
+	removeTweak := func(tweak []string) []string {
+		result := make([]string, 0, len(tweak))
+		for _, assetIDs := range tweak {
+			if model.Contains(strings.Split(assetIDs, ":"), techAssetID) {
+				referencesDeleted = true
+			} else {
+				result = append(result, assetIDs)
+			}
+		}
+		return result
+	}
+	modelInput.Diagram_tweak_invisible_connections_between_assets = removeTweak(modelInput.Diagram_tweak_invisible_connections_between_assets)
+	modelInput.Diagram_tweak_same_rank_assets = removeTweak(modelInput.Diagram_tweak_same_rank_assets)
+	return referencesDeleted
+}
+
+func populateTechnicalAsset(context *gin.Context, payload payloadTechnicalAsset) (techAssetInput model.InputTechnicalAsset, ok bool) {
+	if !checkTitleAndID(context, payload.Title, payload.Id, payload.Previous_ids) {
+		return techAssetInput, false
+	}
+	techAssetType, err := model.ParseTechnicalAssetType(payload.Type)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	usage, err := model.ParseUsage(payload.Usage)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	size, err := model.ParseTechnicalAssetSize(payload.Size)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	technology, err := model.ParseTechnicalAssetTechnology(payload.Technology)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	machine, err := model.ParseTechnicalAssetMachine(payload.Machine)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	encryption, err := model.ParseEncryptionStyle(payload.Encryption)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	confidentiality, err := confidentiality.ParseConfidentiality(payload.Confidentiality)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	integrity, err := criticality.ParseCriticality(payload.Integrity)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	availability, err := criticality.ParseCriticality(payload.Availability)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return techAssetInput, false
+	}
+	dataFormatsAccepted := make([]string, 0)
+	for _, value := range payload.Data_formats_accepted {
+		dataFormat, err := model.ParseDataFormatName(value)
+		if err != nil {
+			handleErrorInServiceCall(err, context)
+			return techAssetInput, false
+		}
+		dataFormatsAccepted = append(dataFormatsAccepted, dataFormat.String())
+	}
+	techAssetInput = model.InputTechnicalAsset{
+		ID:                         payload.Id,
+		Previous_ids:               payload.Previous_ids,
+		Description:                payload.Description,
+		Type:                       techAssetType.String(),
+		Usage:                      usage.String(),
+		Used_as_client_by_human:    payload.Used_as_client_by_human,
+		Out_of_scope:               payload.Out_of_scope,
+		Justification_out_of_scope: payload.Justification_out_of_scope,
+		Size:                       size.String(),
+		Technology:                 technology.String(),
+		Tags:                       support.LowerCaseAndTrim(payload.Tags),
+		Internet:                   payload.Internet,
+		Machine:                    machine.String(),
+		Encryption:                 encryption.String(),
+		Owner:                      payload.Owner,
+		Confidentiality:            confidentiality.String(),
+		Integrity:                  integrity.String(),
+		Availability:               availability.String(),
+		Justification_cia_rating:   payload.Justification_cia_rating,
+		Multi_tenant:               payload.Multi_tenant,
+		Redundant:                  payload.Redundant,
+		Custom_developed_parts:     payload.Custom_developed_parts,
+		Data_assets_processed:      payload.Data_assets_processed,
+		Data_assets_stored:         payload.Data_assets_stored,
+		Data_formats_accepted:      dataFormatsAccepted,
+		Diagram_tweak_order:        payload.Diagram_tweak_order,
+	}
+	return techAssetInput, true
+}
+
+func checkDataAssetsExisting(modelInput model.ModelInput, dataAssetIDs []string) (ok bool) {
+	for _, dataAssetID := range dataAssetIDs {
+		exists := false
+		for _, val := range modelInput.Data_assets {
+			if val.ID == dataAssetID {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// communicationLinkMatches checks the ID of a communication link (with or without the ID of its source technical asset as prefix)
+func communicationLinkMatches(sourceID, title, commLinkID string) bool {
+	return model.MakeID(title) == commLinkID || sourceID+">"+model.MakeID(title) == commLinkID
+}
+
+func getCommunicationLinks(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
@@ -1895,39 +2417,46 @@ func setAbuseCases(context *gin.Context) {
 	defer unlockFolder(folderNameOfKey)
 	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
 	if ok {
-		payload := payloadAbuseCases{}
-		err := context.BindJSON(&payload)
-		if err != nil {
-			log.Println(err)
-			context.JSON(http.StatusBadRequest, gin.H{
-				"error": "unable to parse request payload",
-			})
-			return
-		}
-		modelInput.Abuse_cases = payload
-		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Abuse Cases Update")
-		if ok {
-			context.JSON(http.StatusOK, gin.H{
-				"message": "model updated",
-			})
+		for _, techAsset := range modelInput.Technical_assets {
+			if techAsset.ID == context.Param("technical-asset-id") {
+				context.JSON(http.StatusOK, techAsset.Communication_links)
+				return
+			}
 		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "technical asset not found",
+		})
 	}
 }
 
-func getAbuseCases(context *gin.Context) {
+func getCommunicationLink(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
 	}
 	lockFolder(folderNameOfKey)
 	defer unlockFolder(folderNameOfKey)
-	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
 	if ok {
-		context.JSON(http.StatusOK, model.Abuse_cases)
+		for _, techAsset := range modelInput.Technical_assets {
+			if techAsset.ID == context.Param("technical-asset-id") {
+				for title, commLink := range techAsset.Communication_links {
+					if communicationLinkMatches(techAsset.ID, title, context.Param("communication-link-id")) {
+						context.JSON(http.StatusOK, gin.H{
+							title: commLink,
+						})
+						return
+					}
+				}
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "communication link not found",
+		})
 	}
 }
 
-func setOverview(context *gin.Context) {
+func createNewCommunicationLink(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
@@ -1936,437 +2465,2299 @@ func setOverview(context *gin.Context) {
 	defer unlockFolder(folderNameOfKey)
 	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
 	if ok {
-		payload := payloadOverview{}
-		err := context.BindJSON(&payload)
-		if err != nil {
-			log.Println(err)
-			context.JSON(http.StatusBadRequest, gin.H{
+		for techAssetTitle, techAsset := range modelInput.Technical_assets {
+			if techAsset.ID == context.Param("technical-asset-id") {
+				payload := payloadCommunicationLink{}
+				err := context.BindJSON(&payload)
+				if err != nil {
+					log.Println(err)
+					context.JSON(http.StatusBadRequest, gin.H{
+						"error": "unable to parse request payload",
+					})
+					return
+				}
+				// the ID of a communication link is derived from its title
+				for title := range techAsset.Communication_links {
+					if model.MakeID(title) == model.MakeID(payload.Title) {
+						context.JSON(http.StatusConflict, gin.H{
+							"error": "communication link with this title already exists",
+						})
+						return
+					}
+				}
+				commLinkInput, ok := populateCommunicationLink(context, modelInput, payload)
+				if !ok {
+					return
+				}
+				if techAsset.Communication_links == nil {
+					techAsset.Communication_links = make(map[string]model.InputCommunicationLink)
+					modelInput.Technical_assets[techAssetTitle] = techAsset
+				}
+				techAsset.Communication_links[payload.Title] = commLinkInput
+				ok = writeModel(context, key, folderNameOfKey, &modelInput, "Communication Link Creation")
+				if ok {
+					context.JSON(http.StatusOK, gin.H{
+						"message": "communication link created",
+						"id":      techAsset.ID + ">" + model.MakeID(payload.Title),
+					})
+				}
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "technical asset not found",
+		})
+	}
+}
+
+func setCommunicationLink(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		for _, techAsset := range modelInput.Technical_assets {
+			if techAsset.ID == context.Param("technical-asset-id") {
+				for title := range techAsset.Communication_links {
+					if communicationLinkMatches(techAsset.ID, title, context.Param("communication-link-id")) {
+						payload := payloadCommunicationLink{}
+						err := context.BindJSON(&payload)
+						if err != nil {
+							log.Println(err)
+							context.JSON(http.StatusBadRequest, gin.H{
+								"error": "unable to parse request payload",
+							})
+							return
+						}
+						for otherTitle := range techAsset.Communication_links {
+							if otherTitle != title && model.MakeID(otherTitle) == model.MakeID(payload.Title) {
+								context.JSON(http.StatusConflict, gin.H{
+									"error": "communication link with this title already exists",
+								})
+								return
+							}
+						}
+						commLinkInput, ok := populateCommunicationLink(context, modelInput, payload)
+						if !ok {
+							return
+						}
+						oldID, newID := techAsset.ID+">"+model.MakeID(title), techAsset.ID+">"+model.MakeID(payload.Title)
+						commLinkInput.Previous_ids = updatedPreviousIds(payload.Previous_ids, techAsset.Communication_links[title].Previous_ids, oldID, newID)
+						// in order to also update the title, remove the link from the map and re-insert it (with new key)
+						delete(techAsset.Communication_links, title)
+						techAsset.Communication_links[payload.Title] = commLinkInput
+						idChanged := oldID != newID
+						if idChanged { // ID-CHANGE-PROPAGATION
+							updateIndividualRisks(&modelInput, func(risk *model.InputRiskIdentified) bool {
+								if risk.Most_relevant_communication_link == oldID {
+									risk.Most_relevant_communication_link = newID
+								}
+								return true
+							})
+						}
+						ok = writeModel(context, key, folderNameOfKey, &modelInput, "Communication Link Update")
+						if ok {
+							context.JSON(http.StatusOK, gin.H{
+								"message":    "communication link updated",
+								"id":         newID,
+								"id_changed": idChanged, // in order to signal to clients, that other model parts might've received updates as well and should be reloaded
+							})
+						}
+						return
+					}
+				}
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "communication link not found",
+		})
+	}
+}
+
+func deleteCommunicationLink(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		for _, techAsset := range modelInput.Technical_assets {
+			if techAsset.ID == context.Param("technical-asset-id") {
+				for title := range techAsset.Communication_links {
+					if communicationLinkMatches(techAsset.ID, title, context.Param("communication-link-id")) {
+						commLinkID := techAsset.ID + ">" + model.MakeID(title)
+						// also remove all usages of this communication link !!
+						referencesDeleted := updateIndividualRisks(&modelInput, func(risk *model.InputRiskIdentified) bool {
+							if risk.Most_relevant_communication_link == commLinkID { // apply the removal
+								risk.Most_relevant_communication_link = ""
+								return true
+							}
+							return false
+						})
+						// remove it itself
+						delete(techAsset.Communication_links, title)
+						ok = writeModel(context, key, folderNameOfKey, &modelInput, "Communication Link Deletion")
+						if ok {
+							context.JSON(http.StatusOK, gin.H{
+								"message":            "communication link deleted",
+								"id":                 commLinkID,
+								"references_deleted": referencesDeleted, // in order to signal to clients, that other model parts might've been deleted as well
+							})
+						}
+						return
+					}
+				}
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "communication link not found",
+		})
+	}
+}
+
+func populateCommunicationLink(context *gin.Context, modelInput model.ModelInput, payload payloadCommunicationLink) (commLinkInput model.InputCommunicationLink, ok bool) {
+	if !checkTitle(context, payload.Title) {
+		return commLinkInput, false
+	}
+	if !checkTechnicalAssetsExisting(modelInput, []string{payload.Target}) {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error": "referenced technical asset does not exist",
+		})
+		return commLinkInput, false
+	}
+	if !checkDataAssetsExisting(modelInput, payload.Data_assets_sent) || !checkDataAssetsExisting(modelInput, payload.Data_assets_received) {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error": "referenced data asset does not exist",
+		})
+		return commLinkInput, false
+	}
+	protocol, err := model.ParseProtocol(payload.Protocol)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return commLinkInput, false
+	}
+	authentication, err := model.ParseAuthentication(payload.Authentication)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return commLinkInput, false
+	}
+	authorization, err := model.ParseAuthorization(payload.Authorization)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return commLinkInput, false
+	}
+	usage, err := model.ParseUsage(payload.Usage)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return commLinkInput, false
+	}
+	commLinkInput = model.InputCommunicationLink{
+		Previous_ids:             payload.Previous_ids,
+		Target:                   payload.Target,
+		Description:              payload.Description,
+		Protocol:                 protocol.String(),
+		Authentication:           authentication.String(),
+		Authorization:            authorization.String(),
+		Tags:                     support.LowerCaseAndTrim(payload.Tags),
+		VPN:                      payload.VPN,
+		IP_filtered:              payload.IP_filtered,
+		Readonly:                 payload.Readonly,
+		Usage:                    usage.String(),
+		Data_assets_sent:         payload.Data_assets_sent,
+		Data_assets_received:     payload.Data_assets_received,
+		Diagram_tweak_weight:     payload.Diagram_tweak_weight,
+		Diagram_tweak_constraint: payload.Diagram_tweak_constraint,
+	}
+	return commLinkInput, true
+}
+
+func getTrustBoundary(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, trustBoundary := range modelInput.Trust_boundaries {
+			if trustBoundary.ID == context.Param("trust-boundary-id") {
+				context.JSON(http.StatusOK, gin.H{
+					title: trustBoundary,
+				})
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "trust boundary not found",
+		})
+	}
+}
+
+func createNewTrustBoundary(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadTrustBoundary{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
 				"error": "unable to parse request payload",
 			})
 			return
 		}
-		criticality, err := criticality.ParseCriticality(payload.Business_criticality)
-		if err != nil {
-			handleErrorInServiceCall(err, context)
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		if _, exists := modelInput.Trust_boundaries[payload.Title]; exists {
+			context.JSON(http.StatusConflict, gin.H{
+				"error": "trust boundary with this title already exists",
+			})
 			return
 		}
-		modelInput.Management_summary_comment = payload.Management_summary_comment
-		modelInput.Business_criticality = criticality.String()
-		modelInput.Business_overview.Description = payload.Business_overview.Description
-		modelInput.Business_overview.Images = payload.Business_overview.Images
-		modelInput.Technical_overview.Description = payload.Technical_overview.Description
-		modelInput.Technical_overview.Images = payload.Technical_overview.Images
-		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Overview Update")
+		// but later it will in memory keyed by it's "id", so do this uniqueness check also
+		for _, trustBoundary := range modelInput.Trust_boundaries {
+			if trustBoundary.ID == payload.Id {
+				context.JSON(http.StatusConflict, gin.H{
+					"error": "trust boundary with this id already exists",
+				})
+				return
+			}
+		}
+		trustBoundaryInput, ok := populateTrustBoundary(context, modelInput, payload)
+		if !ok {
+			return
+		}
+		if modelInput.Trust_boundaries == nil {
+			modelInput.Trust_boundaries = make(map[string]model.InputTrustBoundary)
+		}
+		modelInput.Trust_boundaries[payload.Title] = trustBoundaryInput
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Trust Boundary Creation")
 		if ok {
 			context.JSON(http.StatusOK, gin.H{
-				"message": "model updated",
+				"message": "trust boundary created",
+				"id":      trustBoundaryInput.ID,
+			})
+		}
+	}
+}
+
+func setTrustBoundary(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, trustBoundary := range modelInput.Trust_boundaries {
+			if trustBoundary.ID == context.Param("trust-boundary-id") {
+				payload := payloadTrustBoundary{}
+				err := context.BindJSON(&payload)
+				if err != nil {
+					log.Println(err)
+					context.JSON(http.StatusBadRequest, gin.H{
+						"error": "unable to parse request payload",
+					})
+					return
+				}
+				for otherTitle, other := range modelInput.Trust_boundaries {
+					if otherTitle != title && (otherTitle == payload.Title || other.ID == payload.Id) {
+						context.JSON(http.StatusConflict, gin.H{
+							"error": "trust boundary with this title or id already exists",
+						})
+						return
+					}
+				}
+				trustBoundaryInput, ok := populateTrustBoundary(context, modelInput, payload)
+				if !ok {
+					return
+				}
+				trustBoundaryInput.Previous_ids = updatedPreviousIds(payload.Previous_ids, trustBoundary.Previous_ids, trustBoundary.ID, trustBoundaryInput.ID)
+				// in order to also update the title, remove the trust boundary from the map and re-insert it (with new key)
+				delete(modelInput.Trust_boundaries, title)
+				modelInput.Trust_boundaries[payload.Title] = trustBoundaryInput
+				idChanged := trustBoundaryInput.ID != trustBoundary.ID
+				if idChanged { // ID-CHANGE-PROPAGATION
+					for _, other := range modelInput.Trust_boundaries {
+						replaceValue(other.Trust_boundaries_nested, trustBoundary.ID, trustBoundaryInput.ID)
+					}
+					updateIndividualRisks(&modelInput, func(risk *model.InputRiskIdentified) bool {
+						if risk.Most_relevant_trust_boundary == trustBoundary.ID { // apply the ID change
+							risk.Most_relevant_trust_boundary = trustBoundaryInput.ID
+						}
+						return true
+					})
+				}
+				ok = writeModel(context, key, folderNameOfKey, &modelInput, "Trust Boundary Update")
+				if ok {
+					context.JSON(http.StatusOK, gin.H{
+						"message":    "trust boundary updated",
+						"id":         trustBoundaryInput.ID,
+						"id_changed": idChanged, // in order to signal to clients, that other model parts might've received updates as well and should be reloaded
+					})
+				}
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "trust boundary not found",
+		})
+	}
+}
+
+func deleteTrustBoundary(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		referencesDeleted := false
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, trustBoundary := range modelInput.Trust_boundaries {
+			if trustBoundary.ID == context.Param("trust-boundary-id") {
+				// also remove all usages of this trust boundary !!
+				for otherTitle, other := range modelInput.Trust_boundaries {
+					var removed bool
+					if other.Trust_boundaries_nested, removed = removeValue(other.Trust_boundaries_nested, trustBoundary.ID); removed {
+						referencesDeleted = true
+						modelInput.Trust_boundaries[otherTitle] = other
+					}
+				}
+				if updateIndividualRisks(&modelInput, func(risk *model.InputRiskIdentified) bool {
+					if risk.Most_relevant_trust_boundary == trustBoundary.ID { // apply the removal
+						risk.Most_relevant_trust_boundary = ""
+						return true
+					}
+					return false
+				}) {
+					referencesDeleted = true
+				}
+				// remove it itself
+				delete(modelInput.Trust_boundaries, title)
+				ok = writeModel(context, key, folderNameOfKey, &modelInput, "Trust Boundary Deletion")
+				if ok {
+					context.JSON(http.StatusOK, gin.H{
+						"message":            "trust boundary deleted",
+						"id":                 trustBoundary.ID,
+						"references_deleted": referencesDeleted, // in order to signal to clients, that other model parts might've been deleted as well
+					})
+				}
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "trust boundary not found",
+		})
+	}
+}
+
+func populateTrustBoundary(context *gin.Context, modelInput model.ModelInput, payload payloadTrustBoundary) (trustBoundaryInput model.InputTrustBoundary, ok bool) {
+	if !checkTitleAndID(context, payload.Title, payload.Id, payload.Previous_ids) {
+		return trustBoundaryInput, false
+	}
+	if !checkTechnicalAssetsExisting(modelInput, payload.Technical_assets_inside) {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error": "referenced technical asset does not exist",
+		})
+		return trustBoundaryInput, false
+	}
+	for _, nestedID := range payload.Trust_boundaries_nested {
+		exists := false
+		for _, val := range modelInput.Trust_boundaries {
+			exists = exists || (val.ID == nestedID && nestedID != payload.Id)
+		}
+		if !exists {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "referenced trust boundary does not exist",
 			})
+			return trustBoundaryInput, false
 		}
 	}
+	trustBoundaryType, err := model.ParseTrustBoundaryType(payload.Type)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return trustBoundaryInput, false
+	}
+	trustBoundaryInput = model.InputTrustBoundary{
+		ID:                      payload.Id,
+		Previous_ids:            payload.Previous_ids,
+		Description:             payload.Description,
+		Type:                    trustBoundaryType.String(),
+		Tags:                    support.LowerCaseAndTrim(payload.Tags),
+		Technical_assets_inside: payload.Technical_assets_inside,
+		Trust_boundaries_nested: payload.Trust_boundaries_nested,
+	}
+	return trustBoundaryInput, true
+}
+
+func getIndividualRiskCategories(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		context.JSON(http.StatusOK, model.Individual_risk_categories)
+	}
+}
+
+func getIndividualRiskCategory(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, riskCategory := range modelInput.Individual_risk_categories {
+			if riskCategory.ID == context.Param("individual-risk-category-id") {
+				context.JSON(http.StatusOK, gin.H{
+					title: riskCategory,
+				})
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "individual risk category not found",
+		})
+	}
+}
+
+func createNewIndividualRiskCategory(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadIndividualRiskCategory{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		if _, exists := modelInput.Individual_risk_categories[payload.Title]; exists {
+			context.JSON(http.StatusConflict, gin.H{
+				"error": "individual risk category with this title already exists",
+			})
+			return
+		}
+		// but later it will in memory keyed by it's "id", so do this uniqueness check also
+		for _, riskCategory := range modelInput.Individual_risk_categories {
+			if riskCategory.ID == payload.Id {
+				context.JSON(http.StatusConflict, gin.H{
+					"error": "individual risk category with this id already exists",
+				})
+				return
+			}
+		}
+		riskCategoryInput, ok := populateIndividualRiskCategory(context, payload)
+		if !ok {
+			return
+		}
+		if modelInput.Individual_risk_categories == nil {
+			modelInput.Individual_risk_categories = make(map[string]model.InputIndividualRiskCategory)
+		}
+		modelInput.Individual_risk_categories[payload.Title] = riskCategoryInput
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Individual Risk Category Creation")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "individual risk category created",
+				"id":      riskCategoryInput.ID,
+			})
+		}
+	}
+}
+
+func setIndividualRiskCategory(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, riskCategory := range modelInput.Individual_risk_categories {
+			if riskCategory.ID == context.Param("individual-risk-category-id") {
+				payload := payloadIndividualRiskCategory{}
+				err := context.BindJSON(&payload)
+				if err != nil {
+					log.Println(err)
+					context.JSON(http.StatusBadRequest, gin.H{
+						"error": "unable to parse request payload",
+					})
+					return
+				}
+				for otherTitle, other := range modelInput.Individual_risk_categories {
+					if otherTitle != title && (otherTitle == payload.Title || other.ID == payload.Id) {
+						context.JSON(http.StatusConflict, gin.H{
+							"error": "individual risk category with this title or id already exists",
+						})
+						return
+					}
+				}
+				riskCategoryInput, ok := populateIndividualRiskCategory(context, payload)
+				if !ok {
+					return
+				}
+				// in order to also update the title, remove the risk category from the map and re-insert it (with new key)
+				delete(modelInput.Individual_risk_categories, title)
+				modelInput.Individual_risk_categories[payload.Title] = riskCategoryInput
+				ok = writeModel(context, key, folderNameOfKey, &modelInput, "Individual Risk Category Update")
+				if ok {
+					context.JSON(http.StatusOK, gin.H{
+						"message":    "individual risk category updated",
+						"id":         riskCategoryInput.ID,
+						"id_changed": riskCategoryInput.ID != riskCategory.ID, // the risk tracking of the risks is keyed by their synthetic IDs containing the category ID
+					})
+				}
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "individual risk category not found",
+		})
+	}
+}
+
+func deleteIndividualRiskCategory(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		// yes, here keyed by title in YAML for better readability in the YAML file itself
+		for title, riskCategory := range modelInput.Individual_risk_categories {
+			if riskCategory.ID == context.Param("individual-risk-category-id") {
+				delete(modelInput.Individual_risk_categories, title)
+				ok = writeModel(context, key, folderNameOfKey, &modelInput, "Individual Risk Category Deletion")
+				if ok {
+					context.JSON(http.StatusOK, gin.H{
+						"message": "individual risk category deleted",
+						"id":      riskCategory.ID,
+					})
+				}
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "individual risk category not found",
+		})
+	}
+}
+
+func populateIndividualRiskCategory(context *gin.Context, payload payloadIndividualRiskCategory) (riskCategoryInput model.InputIndividualRiskCategory, ok bool) {
+	if !checkTitleAndID(context, payload.Title, payload.Id, nil) {
+		return riskCategoryInput, false
+	}
+	function, err := model.ParseRiskFunction(payload.Function)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return riskCategoryInput, false
+	}
+	stride, err := model.ParseStride(payload.STRIDE)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return riskCategoryInput, false
+	}
+	risksIdentified := make(map[string]model.InputRiskIdentified)
+	for title, risk := range payload.Risks_identified {
+		severity, err := model.ParseRiskSeverity(risk.Severity)
+		if err != nil {
+			handleErrorInServiceCall(err, context)
+			return riskCategoryInput, false
+		}
+		likelihood, err := model.ParseRiskExploitationLikelihood(risk.Exploitation_likelihood)
+		if err != nil {
+			handleErrorInServiceCall(err, context)
+			return riskCategoryInput, false
+		}
+		impact, err := model.ParseRiskExploitationImpact(risk.Exploitation_impact)
+		if err != nil {
+			handleErrorInServiceCall(err, context)
+			return riskCategoryInput, false
+		}
+		probability, err := model.ParseDataBreachProbability(risk.Data_breach_probability)
+		if err != nil {
+			handleErrorInServiceCall(err, context)
+			return riskCategoryInput, false
+		}
+		risk.Severity, risk.Exploitation_likelihood, risk.Exploitation_impact, risk.Data_breach_probability =
+			severity.String(), likelihood.String(), impact.String(), probability.String()
+		risksIdentified[title] = risk
+	}
+	riskCategoryInput = model.InputIndividualRiskCategory{
+		ID:                            payload.Id,
+		Description:                   payload.Description,
+		Impact:                        payload.Impact,
+		CRE:                           payload.CRE,
+		ASVS:                          payload.ASVS,
+		Cheat_sheet:                   payload.Cheat_sheet,
+		Testing_guide:                 payload.Testing_guide,
+		Action:                        payload.Action,
+		Mitigation:                    payload.Mitigation,
+		Check:                         payload.Check,
+		Function:                      function.String(),
+		STRIDE:                        stride.String(),
+		Detection_logic:               payload.Detection_logic,
+		Risk_assessment:               payload.Risk_assessment,
+		False_positives:               payload.False_positives,
+		Model_failure_possible_reason: payload.Model_failure_possible_reason,
+		CWE:                           payload.CWE,
+		Risks_identified:              risksIdentified,
+	}
+	return riskCategoryInput, true
+}
+
+// updateIndividualRisks applies the update to all individually identified risks, true when any of them was changed
+func updateIndividualRisks(modelInput *model.ModelInput, update func(risk *model.InputRiskIdentified) (changed bool)) (changed bool) {
+	for _, riskCategory := range modelInput.Individual_risk_categories {
+		for title, risk := range riskCategory.Risks_identified {
+			if update(&risk) {
+				changed = true
+				riskCategory.Risks_identified[title] = risk
+			}
+		}
+	}
+	return changed
+}
+
+func removeValue(values []string, value string) (result []string, removed bool) {
+	result = make([]string, 0, len(values))
+	for _, candidate := range values {
+		if candidate == value {
+			removed = true
+		} else {
+			result = append(result, candidate)
+		}
+	}
+	return result, removed
+}
+
+// checkTitle responds 400 unless the title is given
+func checkTitle(context *gin.Context, title string) bool {
+	if len(strings.TrimSpace(title)) == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error": "title missing",
+		})
+		return false
+	}
+	return true
+}
+
+// checkTitleAndID responds 400 unless the title is given and the id (along with the previous ones) uses the valid syntax,
+// as the model could not be parsed otherwise
+func checkTitleAndID(context *gin.Context, title, id string, previousIds []string) bool {
+	if !checkTitle(context, title) {
+		return false
+	}
+	for _, candidate := range append([]string{id}, previousIds...) {
+		if !support.IsValidIdSyntax(candidate) {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid id syntax used (only letters, numbers, and hyphen allowed): " + candidate,
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// updatedPreviousIds are the previous ids of an updated element: the ones of the payload (or the existing ones, when the payload has none)
+// along with its former id, when it changed, so that the risk tracking using it is migrated
+func updatedPreviousIds(payloadPreviousIds, existingPreviousIds []string, oldID, newID string) []string {
+	previousIds := existingPreviousIds
+	if payloadPreviousIds != nil {
+		previousIds = payloadPreviousIds
+	}
+	result, _ := removeValue(previousIds, newID) // an element renamed back to a previous id no longer has it as previous one
+	if oldID != newID && !model.Contains(result, oldID) {
+		result = append(result, oldID)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+func replaceValue(values []string, oldValue, newValue string) {
+	for i, candidate := range values {
+		if candidate == oldValue {
+			values[i] = newValue
+		}
+	}
+}
+
+func setQuestions(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadQuestions{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		modelInput.Questions = payload
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Questions Update")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "model updated",
+			})
+		}
+	}
+}
+
+func getQuestions(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		context.JSON(http.StatusOK, model.Questions)
+	}
+}
+
+func setTags(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadTags{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		tags := support.LowerCaseAndTrim(payload)
+		for _, tag := range tagsInUse(modelInput) {
+			if !model.Contains(tags, tag) {
+				context.JSON(http.StatusBadRequest, gin.H{
+					"error": "tag is still in use: " + tag,
+				})
+				return
+			}
+		}
+		sort.Strings(tags)
+		modelInput.Tags_available = tags
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Tags Update")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "model updated",
+			})
+		}
+	}
+}
+
+func getTags(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		context.JSON(http.StatusOK, model.Tags_available)
+	}
+}
+
+// tagsInUse are the tags of all data assets, technical assets, communication links, trust boundaries, and shared runtimes
+func tagsInUse(modelInput model.ModelInput) []string {
+	result := make([]string, 0)
+	add := func(tags []string) {
+		for _, tag := range support.LowerCaseAndTrim(tags) {
+			if !model.Contains(result, tag) {
+				result = append(result, tag)
+			}
+		}
+	}
+	for _, dataAsset := range modelInput.Data_assets {
+		add(dataAsset.Tags)
+	}
+	for _, techAsset := range modelInput.Technical_assets {
+		add(techAsset.Tags)
+		for _, commLink := range techAsset.Communication_links {
+			add(commLink.Tags)
+		}
+	}
+	for _, trustBoundary := range modelInput.Trust_boundaries {
+		add(trustBoundary.Tags)
+	}
+	for _, sharedRuntime := range modelInput.Shared_runtimes {
+		add(sharedRuntime.Tags)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func setRiskTracking(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadRiskTracking{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		riskTracking := make(map[string]model.InputRiskTracking)
+		for syntheticRiskID, tracking := range payload {
+			tracking, ok := populateRiskTracking(context, tracking)
+			if !ok {
+				return
+			}
+			riskTracking[syntheticRiskID] = tracking
+		}
+		modelInput.Risk_tracking = riskTracking
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Risk Tracking Update")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "model updated",
+			})
+		}
+	}
+}
+
+func getRiskTracking(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		context.JSON(http.StatusOK, model.Risk_tracking)
+	}
+}
+
+// getRiskTrackingOfRisk delivers the risk tracking of a risk, falling back to the first (by pattern) wildcard risk tracking matching it
+func getRiskTrackingOfRisk(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		syntheticRiskID := strings.TrimSpace(context.Param("synthetic-id"))
+		if riskTracking, exists := modelInput.Risk_tracking[syntheticRiskID]; exists {
+			context.JSON(http.StatusOK, gin.H{
+				syntheticRiskID: riskTracking,
+			})
+			return
+		}
+		patterns := make([]string, 0)
+		for pattern := range modelInput.Risk_tracking {
+			if strings.Contains(pattern, "*") {
+				patterns = append(patterns, pattern)
+			}
+		}
+		sort.Strings(patterns)
+		for _, pattern := range patterns {
+			if regexp.MustCompile(strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `[^@]+`)).MatchString(syntheticRiskID) {
+				context.JSON(http.StatusOK, gin.H{
+					pattern: modelInput.Risk_tracking[pattern],
+				})
+				return
+			}
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "risk tracking not found",
+		})
+	}
+}
+
+// setRiskTrackingOfRisk creates or replaces the risk tracking of a risk (or of a wildcard pattern)
+func setRiskTrackingOfRisk(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, yamlText, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := model.InputRiskTracking{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		riskTracking, ok := populateRiskTracking(context, payload)
+		if !ok {
+			return
+		}
+		syntheticRiskID := strings.TrimSpace(context.Param("synthetic-id"))
+		if len(syntheticRiskID) == 0 {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "synthetic risk id missing",
+			})
+			return
+		}
+		// risk tracking not matching any risk would be orphaned, which makes the analysis of the model fail
+		result, ok := analyzeModelYAML(context, []byte(yamlText))
+		if !ok {
+			return
+		}
+		if !matchesAnyRisk(result, syntheticRiskID) {
+			context.JSON(http.StatusNotFound, gin.H{
+				"error": "risk not found",
+			})
+			return
+		}
+		_, existed := modelInput.Risk_tracking[syntheticRiskID]
+		if modelInput.Risk_tracking == nil {
+			modelInput.Risk_tracking = make(map[string]model.InputRiskTracking)
+		}
+		modelInput.Risk_tracking[syntheticRiskID] = riskTracking
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Risk Tracking of "+syntheticRiskID+" set to "+riskTracking.Status)
+		if ok {
+			message := "risk tracking created"
+			if existed {
+				message = "risk tracking updated"
+			}
+			context.JSON(http.StatusOK, gin.H{
+				"message": message,
+				"id":      syntheticRiskID,
+			})
+		}
+	}
+}
+
+// matchesAnyRisk checks whether risk tracking of the synthetic risk id (or wildcard pattern, maybe using previous ids) applies to any risk of the analyzed model
+func matchesAnyRisk(result *threagile.Result, syntheticRiskID string) bool {
+	syntheticRiskID = model.MigrateSyntheticRiskId(syntheticRiskID, model.CurrentIdsByPreviousId(result.ParsedModel()))
+	if !strings.Contains(syntheticRiskID, "*") {
+		_, found := result.RiskBySyntheticId(syntheticRiskID)
+		return found
+	}
+	pattern := regexp.MustCompile(strings.ReplaceAll(regexp.QuoteMeta(syntheticRiskID), `\*`, `[^@]+`))
+	for _, risk := range result.Risks() {
+		if pattern.MatchString(strings.ToLower(risk.SyntheticId)) {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteRiskTrackingOfRisk(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		syntheticRiskID := strings.TrimSpace(context.Param("synthetic-id"))
+		if _, exists := modelInput.Risk_tracking[syntheticRiskID]; !exists {
+			context.JSON(http.StatusNotFound, gin.H{
+				"error": "risk tracking not found",
+			})
+			return
+		}
+		delete(modelInput.Risk_tracking, syntheticRiskID)
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Risk Tracking of "+syntheticRiskID+" deleted")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "risk tracking deleted",
+				"id":      syntheticRiskID,
+			})
+		}
+	}
+}
+
+// populateRiskTracking checks the status and date of a risk tracking, a missing date defaults to today
+func populateRiskTracking(context *gin.Context, payload model.InputRiskTracking) (riskTracking model.InputRiskTracking, ok bool) {
+	status, err := model.ParseRiskStatus(payload.Status)
+	if err != nil {
+		handleErrorInServiceCall(err, context)
+		return riskTracking, false
+	}
+	date := time.Now()
+	if len(payload.Date) > 0 {
+		date, err = time.Parse("2006-01-02", payload.Date)
+		if err != nil {
+			handleErrorInServiceCall(err, context)
+			return riskTracking, false
+		}
+	}
+	for _, optionalDate := range []string{payload.Expires, payload.Review_by} {
+		if len(optionalDate) > 0 {
+			if _, err = time.Parse("2006-01-02", optionalDate); err != nil {
+				handleErrorInServiceCall(err, context)
+				return riskTracking, false
+			}
+		}
+	}
+	riskTracking = model.InputRiskTracking{
+		Status:        status.String(),
+		Justification: payload.Justification,
+		Ticket:        payload.Ticket,
+		Date:          date.Format("2006-01-02"),
+		Checked_by:    payload.Checked_by,
+		Expires:       payload.Expires,
+		Review_by:     payload.Review_by,
+	}
+	return riskTracking, true
+}
+
+func arrayOfStringValues(values []core.TypeEnum) []string {
+	result := make([]string, 0)
+	for _, value := range values {
+		result = append(result, value.String())
+	}
+	return result
+}
+
+func getModel(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	_, yamlText, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		tmpResultFile, err := ioutil.TempFile(model.TempFolder, "threagile-*.yaml")
+		support.CheckErr(err)
+		err = ioutil.WriteFile(tmpResultFile.Name(), []byte(yamlText), 0400)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusInternalServerError, gin.H{
+				"error": "unable to stream model file",
+			})
+			return
+		}
+		defer os.Remove(tmpResultFile.Name())
+		context.FileAttachment(tmpResultFile.Name(), "threagile.yaml")
+	}
+}
+
+type payloadModels struct {
+	ID                 string    `json:"id"`
+	Title              string    `json:"title"`
+	Timestamp_created  time.Time `json:"timestamp_created"`
+	Timestamp_modified time.Time `json:"timestamp_modified"`
+}
+
+type payloadCover struct {
+	Title  string       `json:"title"`
+	Date   time.Time    `json:"date"`
+	Author model.Author `json:"author"`
+}
+
+type payloadOverview struct {
+	Management_summary_comment string         `json:"management_summary_comment"`
+	Business_criticality       string         `json:"business_criticality"`
+	Business_overview          model.Overview `json:"business_overview"`
+	Technical_overview         model.Overview `json:"technical_overview"`
+}
+
+type payloadAbuseCases map[string]string
+
+type payloadSecurityRequirements map[string]string
+
+type payloadDataAsset struct {
+	Title                    string   `json:"title"`
+	Id                       string   `json:"id"`
+	Previous_ids             []string `json:"previous_ids"`
+	Description              string   `json:"description"`
+	Usage                    string   `json:"usage"`
+	Tags                     []string `json:"tags"`
+	Origin                   string   `json:"origin"`
+	Owner                    string   `json:"owner"`
+	Quantity                 string   `json:"quantity"`
+	Confidentiality          string   `json:"confidentiality"`
+	Integrity                string   `json:"integrity"`
+	Availability             string   `json:"availability"`
+	Justification_cia_rating string   `json:"justification_cia_rating"`
+}
+
+type payloadSharedRuntime struct {
+	Title                    string   `json:"title"`
+	Id                       string   `json:"id"`
+	Previous_ids             []string `json:"previous_ids"`
+	Description              string   `json:"description"`
+	Tags                     []string `json:"tags"`
+	Technical_assets_running []string `json:"technical_assets_running"`
+}
+
+type payloadQuestions map[string]string
+
+type payloadTags []string
+
+type payloadRiskTracking map[string]model.InputRiskTracking
+
+type payloadTechnicalAsset struct {
+	Title                      string   `json:"title"`
+	Id                         string   `json:"id"`
+	Previous_ids               []string `json:"previous_ids"`
+	Description                string   `json:"description"`
+	Type                       string   `json:"type"`
+	Usage                      string   `json:"usage"`
+	Used_as_client_by_human    bool     `json:"used_as_client_by_human"`
+	Out_of_scope               bool     `json:"out_of_scope"`
+	Justification_out_of_scope string   `json:"justification_out_of_scope"`
+	Size                       string   `json:"size"`
+	Technology                 string   `json:"technology"`
+	Tags                       []string `json:"tags"`
+	Internet                   bool     `json:"internet"`
+	Machine                    string   `json:"machine"`
+	Encryption                 string   `json:"encryption"`
+	Owner                      string   `json:"owner"`
+	Confidentiality            string   `json:"confidentiality"`
+	Integrity                  string   `json:"integrity"`
+	Availability               string   `json:"availability"`
+	Justification_cia_rating   string   `json:"justification_cia_rating"`
+	Multi_tenant               bool     `json:"multi_tenant"`
+	Redundant                  bool     `json:"redundant"`
+	Custom_developed_parts     bool     `json:"custom_developed_parts"`
+	Data_assets_processed      []string `json:"data_assets_processed"`
+	Data_assets_stored         []string `json:"data_assets_stored"`
+	Data_formats_accepted      []string `json:"data_formats_accepted"`
+	Diagram_tweak_order        int      `json:"diagram_tweak_order"`
+}
+
+type payloadCommunicationLink struct {
+	Title                    string   `json:"title"`
+	Previous_ids             []string `json:"previous_ids"`
+	Target                   string   `json:"target"`
+	Description              string   `json:"description"`
+	Protocol                 string   `json:"protocol"`
+	Authentication           string   `json:"authentication"`
+	Authorization            string   `json:"authorization"`
+	Tags                     []string `json:"tags"`
+	VPN                      bool     `json:"vpn"`
+	IP_filtered              bool     `json:"ip_filtered"`
+	Readonly                 bool     `json:"readonly"`
+	Usage                    string   `json:"usage"`
+	Data_assets_sent         []string `json:"data_assets_sent"`
+	Data_assets_received     []string `json:"data_assets_received"`
+	Diagram_tweak_weight     int      `json:"diagram_tweak_weight"`
+	Diagram_tweak_constraint bool     `json:"diagram_tweak_constraint"`
+}
+
+type payloadTrustBoundary struct {
+	Title                   string   `json:"title"`
+	Id                      string   `json:"id"`
+	Previous_ids            []string `json:"previous_ids"`
+	Description             string   `json:"description"`
+	Type                    string   `json:"type"`
+	Tags                    []string `json:"tags"`
+	Technical_assets_inside []string `json:"technical_assets_inside"`
+	Trust_boundaries_nested []string `json:"trust_boundaries_nested"`
+}
+
+type payloadIndividualRiskCategory struct {
+	Title                         string                               `json:"title"`
+	Id                            string                               `json:"id"`
+	Description                   string                               `json:"description"`
+	Impact                        string                               `json:"impact"`
+	CRE                           string                               `json:"cre"`
+	ASVS                          string                               `json:"asvs"`
+	Cheat_sheet                   string                               `json:"cheat_sheet"`
+	Testing_guide                 string                               `json:"testing_guide"`
+	Action                        string                               `json:"action"`
+	Mitigation                    string                               `json:"mitigation"`
+	Check                         string                               `json:"check"`
+	Function                      string                               `json:"function"`
+	STRIDE                        string                               `json:"stride"`
+	Detection_logic               string                               `json:"detection_logic"`
+	Risk_assessment               string                               `json:"risk_assessment"`
+	False_positives               string                               `json:"false_positives"`
+	Model_failure_possible_reason bool                                 `json:"model_failure_possible_reason"`
+	CWE                           int                                  `json:"cwe"`
+	Risks_identified              map[string]model.InputRiskIdentified `json:"risks_identified"`
+}
+
+func setSecurityRequirements(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadSecurityRequirements{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		modelInput.Security_requirements = payload
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Security Requirements Update")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "model updated",
+			})
+		}
+	}
+}
+
+func getSecurityRequirements(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		context.JSON(http.StatusOK, model.Security_requirements)
+	}
+}
+
+func setAbuseCases(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadAbuseCases{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		modelInput.Abuse_cases = payload
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Abuse Cases Update")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "model updated",
+			})
+		}
+	}
+}
+
+func getAbuseCases(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		context.JSON(http.StatusOK, model.Abuse_cases)
+	}
+}
+
+func setOverview(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadOverview{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		criticality, err := criticality.ParseCriticality(payload.Business_criticality)
+		if err != nil {
+			handleErrorInServiceCall(err, context)
+			return
+		}
+		modelInput.Management_summary_comment = payload.Management_summary_comment
+		modelInput.Business_criticality = criticality.String()
+		modelInput.Business_overview.Description = payload.Business_overview.Description
+		modelInput.Business_overview.Images = payload.Business_overview.Images
+		modelInput.Technical_overview.Description = payload.Technical_overview.Description
+		modelInput.Technical_overview.Images = payload.Technical_overview.Images
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Overview Update")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "model updated",
+			})
+		}
+	}
+}
+
+func handleErrorInServiceCall(err error, context *gin.Context) {
+	log.Println(err)
+	context.JSON(http.StatusBadRequest, gin.H{
+		"error": strings.TrimSpace(err.Error()),
+	})
+}
+
+func getOverview(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		context.JSON(http.StatusOK, gin.H{
+			"management_summary_comment": model.Management_summary_comment,
+			"business_criticality":       model.Business_criticality,
+			"business_overview":          model.Business_overview,
+			"technical_overview":         model.Technical_overview,
+		})
+	}
+}
+
+func setCover(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		payload := payloadCover{}
+		err := context.BindJSON(&payload)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{
+				"error": "unable to parse request payload",
+			})
+			return
+		}
+		modelInput.Title = payload.Title
+		if !payload.Date.IsZero() {
+			modelInput.Date = payload.Date.Format("2006-01-02")
+		}
+		modelInput.Author.Name = payload.Author.Name
+		modelInput.Author.Homepage = payload.Author.Homepage
+		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Cover Update")
+		if ok {
+			context.JSON(http.StatusOK, gin.H{
+				"message": "model updated",
+			})
+		}
+	}
+}
+
+func getCover(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	if ok {
+		context.JSON(http.StatusOK, gin.H{
+			"title":  model.Title,
+			"date":   model.Date,
+			"author": model.Author,
+		})
+	}
+}
+
+// creates a sub-folder (named by a new UUID) inside the token folder
+func createNewModel(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	ok = checkObjectCreationThrottler(context, "MODEL")
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+
+	uuid := uuid.New().String()
+	yaml := `title: New Threat Model
+threagile_version: ` + model.ThreagileVersion + `
+author:
+  name: ""
+  homepage: ""
+date:
+business_overview:
+  description: ""
+  images: []
+technical_overview:
+  description: ""
+  images: []
+business_criticality: ""
+management_summary_comment: ""
+questions: {}
+abuse_cases: {}
+security_requirements: {}
+tags_available: []
+data_assets: {}
+technical_assets: {}
+trust_boundaries: {}
+shared_runtimes: {}
+individual_risk_categories: {}
+risk_tracking: {}
+diagram_tweak_nodesep: 2
+diagram_tweak_ranksep: 2
+diagram_tweak_edge_layout: ""
+diagram_tweak_suppress_edge_labels: false
+diagram_tweak_invisible_connections_between_assets: []
+diagram_tweak_same_rank_assets: []`
+
+	data, err := encrypt([]byte(yaml), key)
+	if err == nil {
+		_, err = modelStorage.CreateModel(folderNameOfKey, uuid, data)
+	}
+	if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"error": "unable to create model",
+		})
+		return
+	}
+	notifyWebhooks(folderNameOfKey, key, webhook.ModelCreated, uuid, "New Model Creation")
+	context.JSON(http.StatusCreated, gin.H{
+		"message": "model created",
+		"id":      uuid,
+	})
+}
+
+func listModels(context *gin.Context) { // TODO currently returns error when any model is no longer valid in syntax, so eventually have some fallback to not just bark on an invalid model...
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+
+	result := make([]payloadModels, 0)
+	models, err := modelStorage.Models(folderNameOfKey)
+	if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "unable to list model",
+		})
+		return
+	}
+	for _, modelInfo := range models {
+		model, _, ok := readModel(context, modelInfo.ID, key, folderNameOfKey)
+		if !ok {
+			return
+		}
+		result = append(result, payloadModels{
+			ID:                 modelInfo.ID,
+			Title:              model.Title,
+			Timestamp_created:  modelInfo.Created,
+			Timestamp_modified: modelInfo.Modified,
+		})
+	}
+	context.JSON(http.StatusOK, result)
+}
+
+func deleteModel(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	modelID, ok := checkModelFolder(context, context.Param("model-id"), folderNameOfKey)
+	if ok {
+		err := modelStorage.DeleteModel(folderNameOfKey, modelID)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusNotFound, gin.H{
+				"error": "model not found",
+			})
+			return
+		}
+		notifyWebhooks(folderNameOfKey, key, webhook.ModelDeleted, modelID, "Model Deletion")
+		context.JSON(http.StatusOK, gin.H{
+			"message": "model deleted",
+		})
+	}
+}
+
+// checkModelFolder checks that the model exists (in the folder of the key) and returns its syntactically validated UUID
+func checkModelFolder(context *gin.Context, modelUUID string, folderNameOfKey string) (modelID string, ok bool) {
+	uuidParsed, err := uuid.Parse(modelUUID)
+	if err != nil {
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "model not found",
+		})
+		return modelID, false
+	}
+	modelID = uuidParsed.String()
+	if exists, err := modelStorage.ModelExists(folderNameOfKey, modelID); err != nil || !exists {
+		if err != nil {
+			log.Println(err)
+		}
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "model not found",
+		})
+		return modelID, false
+	}
+	return modelID, true
+}
+
+func readModel(context *gin.Context, modelUUID string, key []byte, folderNameOfKey string) (modelInputResult model.ModelInput, yamlText string, ok bool) {
+	modelID, ok := checkModelFolder(context, modelUUID, folderNameOfKey)
+	if !ok {
+		return modelInputResult, yamlText, false
+	}
+	data, version, err := modelStorage.ReadModel(folderNameOfKey, modelID)
+	if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"error": "unable to open model",
+		})
+		return modelInputResult, yamlText, false
+	}
+	context.Header("ETag", strconv.Quote(version))
+	yamlBytes, err := decrypt(data, key)
+	if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"error": "unable to open model",
+		})
+		return modelInputResult, yamlText, false
+	}
+	modelInput := model.ModelInput{}
+	err = yaml.Unmarshal(yamlBytes, &modelInput)
+	if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"error": "unable to open model",
+		})
+		return modelInputResult, yamlText, false
+	}
+	return modelInput, string(yamlBytes), true
+}
+
+// decrypt decompresses and decrypts data stored by the server (like a model, one of its history, or the webhooks of a key)
+func decrypt(data []byte, key []byte) ([]byte, error) {
+	cryptoKey := generateKeyFromAlreadyStrongRandomInput(key)
+	block, err := aes.NewCipher(cryptoKey)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 {
+		return nil, errors.New("encrypted data too short")
+	}
+	nonce := data[0:12]
+	ciphertext := data[12:]
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	_, err = buf.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encrypt compresses and encrypts data to be stored by the server (the counterpart of decrypt)
+func encrypt(data []byte, key []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	plaintext := b.Bytes()
+	cryptoKey := generateKeyFromAlreadyStrongRandomInput(key)
+	block, err := aes.NewCipher(cryptoKey)
+	if err != nil {
+		return nil, err
+	}
+	// Never use more than 2^32 random nonces with a given key because of the risk of a repeat.
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aesgcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func writeModel(context *gin.Context, key []byte, folderNameOfKey string, modelInput *model.ModelInput, changeReasonForHistory string) (ok bool) {
+	modelID, ok := checkModelFolder(context, context.Param("model-id"), folderNameOfKey)
+	if ok {
+		modelInput.Threagile_version = model.ThreagileVersion
+		yamlBytes, err := yaml.Marshal(modelInput)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusInternalServerError, gin.H{
+				"error": "unable to write model",
+			})
+			return false
+		}
+		/*
+			yamlBytes = model.ReformatYAML(yamlBytes)
+		*/
+		return writeModelYAML(context, string(yamlBytes), key, folderNameOfKey, modelID, changeReasonForHistory)
+	}
+	return false
+}
+
+// writeModelYAML replaces the model (keeping the replaced one in its history), when an ETag is given via If-Match
+// only if the model is still of that version, so that concurrent changes can't silently overwrite each other
+func writeModelYAML(context *gin.Context, yaml string, key []byte, folderNameOfKey string, modelID string, changeReasonForHistory string) (ok bool) {
+	if *verbose {
+		fmt.Println("about to write " + strconv.Itoa(len(yaml)) + " bytes of yaml into model: " + modelID)
+	}
+	previousData, _, err := modelStorage.ReadModel(folderNameOfKey, modelID)
+	if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"error": "unable to write model",
+		})
+		return false
+	}
+	previousYaml, _ := decrypt(previousData, key)
+	if !checkRiskAcceptance(context, previousYaml, []byte(yaml)) {
+		return false
+	}
+	data, err := encrypt([]byte(yaml), key)
+	if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"error": "unable to write model",
+		})
+		return false
+	}
+	version, err := modelStorage.WriteModel(folderNameOfKey, modelID, data, expectedModelVersion(context), storage.NewHistoryEntry(time.Now(), changeReasonForHistory))
+	if err == storage.ErrVersionMismatch {
+		context.JSON(http.StatusPreconditionFailed, gin.H{
+			"error": "model was changed in the meantime",
+		})
+		return false
+	} else if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"error": "unable to write model",
+		})
+		return false
+	}
+	context.Header("ETag", strconv.Quote(version))
+	if err = pruneModelHistory(folderNameOfKey, modelID); err != nil {
+		log.Println(err)
+	}
+	notifyWebhooksOfUpdate(folderNameOfKey, key, modelID, changeReasonForHistory, previousYaml, []byte(yaml))
+	return true
+}
+
+// expectedModelVersion is the version of the model given via If-Match (empty when any version may be replaced)
+func expectedModelVersion(context *gin.Context) string {
+	ifMatch := strings.TrimPrefix(strings.TrimSpace(context.GetHeader("If-Match")), "W/")
+	if ifMatch == "*" {
+		return ""
+	}
+	return strings.Trim(ifMatch, `"`)
+}
+
+// pruneModelHistory deletes the previous versions of a model exceeding the limits to keep
+func pruneModelHistory(folderNameOfKey string, modelID string) error {
+	entries, err := modelStorage.History(folderNameOfKey, modelID)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		tooOld := *serverHistoryMaxAge > 0 && time.Since(entry.Timestamp) > time.Duration(*serverHistoryMaxAge)*24*time.Hour
+		if i >= *serverHistoryCount || tooOld {
+			err = modelStorage.DeleteHistory(folderNameOfKey, modelID, entry.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type historyEntry struct {
+	ID            string    `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Change_reason string    `json:"change_reason"`
+	name          string
 }
 
-func handleErrorInServiceCall(err error, context *gin.Context) {
-	log.Println(err)
-	context.JSON(http.StatusBadRequest, gin.H{
-		"error": strings.TrimSpace(err.Error()),
-	})
+// modelHistory lists the previous versions of a model (newest first), each being the version replaced by the change named
+func modelHistory(folderNameOfKey string, modelID string) ([]historyEntry, error) {
+	entries, err := modelStorage.History(folderNameOfKey, modelID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]historyEntry, 0)
+	for _, entry := range entries {
+		hash := sha512.Sum512([]byte(entry.Name))
+		result = append(result, historyEntry{
+			ID:            hex.EncodeToString(hash[:8]),
+			Timestamp:     entry.Timestamp,
+			Change_reason: entry.ChangeReason,
+			name:          entry.Name,
+		})
+	}
+	return result, nil
 }
 
-func getOverview(context *gin.Context) {
-	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+func listModelHistory(context *gin.Context) {
+	folderNameOfKey, _, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
 	}
 	lockFolder(folderNameOfKey)
 	defer unlockFolder(folderNameOfKey)
-	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	modelID, ok := checkModelFolder(context, context.Param("model-id"), folderNameOfKey)
 	if ok {
-		context.JSON(http.StatusOK, gin.H{
-			"management_summary_comment": model.Management_summary_comment,
-			"business_criticality":       model.Business_criticality,
-			"business_overview":          model.Business_overview,
-			"technical_overview":         model.Technical_overview,
-		})
+		entries, err := modelHistory(folderNameOfKey, modelID)
+		if err != nil {
+			log.Println(err)
+			context.JSON(http.StatusInternalServerError, gin.H{
+				"error": "unable to list model history",
+			})
+			return
+		}
+		context.JSON(http.StatusOK, entries)
 	}
 }
 
-func setCover(context *gin.Context) {
-	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+// readModelHistoryVersion reads a previous version of a model by its history id, or the current one for "current"
+func readModelHistoryVersion(context *gin.Context, historyID string, key []byte, folderNameOfKey string) (entry historyEntry, yamlBytes []byte, ok bool) {
+	modelID, ok := checkModelFolder(context, context.Param("model-id"), folderNameOfKey)
 	if !ok {
-		return
+		return entry, nil, false
 	}
-	lockFolder(folderNameOfKey)
-	defer unlockFolder(folderNameOfKey)
-	modelInput, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
-	if ok {
-		payload := payloadCover{}
-		err := context.BindJSON(&payload)
+	var data []byte
+	var err error
+	if historyID == "current" {
+		entry = historyEntry{ID: historyID, Change_reason: "Current Version"}
+		data, _, err = modelStorage.ReadModel(folderNameOfKey, modelID)
+	} else {
+		var entries []historyEntry
+		entries, err = modelHistory(folderNameOfKey, modelID)
 		if err != nil {
-			context.JSON(http.StatusBadRequest, gin.H{
-				"error": "unable to parse request payload",
+			log.Println(err)
+			context.JSON(http.StatusInternalServerError, gin.H{
+				"error": "unable to open model history",
 			})
-			return
+			return entry, nil, false
 		}
-		modelInput.Title = payload.Title
-		if !payload.Date.IsZero() {
-			modelInput.Date = payload.Date.Format("2006-01-02")
+		found := false
+		for _, candidate := range entries {
+			if candidate.ID == historyID {
+				entry, found = candidate, true
+				break
+			}
 		}
-		modelInput.Author.Name = payload.Author.Name
-		modelInput.Author.Homepage = payload.Author.Homepage
-		ok = writeModel(context, key, folderNameOfKey, &modelInput, "Cover Update")
-		if ok {
-			context.JSON(http.StatusOK, gin.H{
-				"message": "model updated",
+		if !found {
+			context.JSON(http.StatusNotFound, gin.H{
+				"error": "history entry not found",
 			})
+			return entry, nil, false
 		}
+		data, err = modelStorage.ReadHistory(folderNameOfKey, modelID, entry.name)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{
+			"error": "history entry not found",
+		})
+		return entry, nil, false
+	}
+	if err == nil {
+		yamlBytes, err = decrypt(data, key)
+	}
+	if err != nil {
+		log.Println(err)
+		context.JSON(http.StatusInternalServerError, gin.H{
+			"error": "unable to open model",
+		})
+		return entry, nil, false
 	}
+	return entry, yamlBytes, true
 }
 
-func getCover(context *gin.Context) {
+func getModelHistoryVersion(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
 	}
 	lockFolder(folderNameOfKey)
 	defer unlockFolder(folderNameOfKey)
-	model, _, ok := readModel(context, context.Param("model-id"), key, folderNameOfKey)
+	_, yamlBytes, ok := readModelHistoryVersion(context, context.Param("history-id"), key, folderNameOfKey)
 	if ok {
-		context.JSON(http.StatusOK, gin.H{
-			"title":  model.Title,
-			"date":   model.Date,
-			"author": model.Author,
-		})
+		context.Header("Content-Disposition", "attachment; filename=threagile.yaml")
+		context.Data(http.StatusOK, "application/x-yaml", yamlBytes)
 	}
 }
 
-// creates a sub-folder (named by a new UUID) inside the token folder
-func createNewModel(context *gin.Context) {
+// diffModelHistoryVersion compares a previous version of a model with another one (given by the query parameter "to", defaulting to the current version)
+func diffModelHistoryVersion(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
 	}
-	ok = checkObjectCreationThrottler(context, "MODEL")
-	if !ok {
+	format := context.DefaultQuery("format", "json")
+	if format != "json" && format != "text" && format != "markdown" {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error": "unknown diff format (use text, json, or markdown)",
+		})
 		return
 	}
 	lockFolder(folderNameOfKey)
 	defer unlockFolder(folderNameOfKey)
-
-	uuid := uuid.New().String()
-	err := os.Mkdir(folderNameForModel(folderNameOfKey, uuid), 0700)
-	if err != nil {
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to create model",
-		})
+	_, oldYaml, ok := readModelHistoryVersion(context, context.Param("history-id"), key, folderNameOfKey)
+	if !ok {
 		return
 	}
-
-	yaml := `title: New Threat Model
-threagile_version: ` + model.ThreagileVersion + `
-author:
-  name: ""
-  homepage: ""
-date:
-business_overview:
-  description: ""
-  images: []
-technical_overview:
-  description: ""
-  images: []
-business_criticality: ""
-management_summary_comment: ""
-questions: {}
-abuse_cases: {}
-security_requirements: {}
-tags_available: []
-data_assets: {}
-technical_assets: {}
-trust_boundaries: {}
-shared_runtimes: {}
-individual_risk_categories: {}
-risk_tracking: {}
-diagram_tweak_nodesep: ""
-diagram_tweak_ranksep: ""
-diagram_tweak_edge_layout: ""
-diagram_tweak_suppress_edge_labels: false
-diagram_tweak_invisible_connections_between_assets: []
-diagram_tweak_same_rank_assets: []`
-
-	ok = writeModelYAML(context, yaml, key, folderNameForModel(folderNameOfKey, uuid), "New Model Creation", true)
-	if ok {
-		context.JSON(http.StatusCreated, gin.H{
-			"message": "model created",
-			"id":      uuid,
-		})
+	_, newYaml, ok := readModelHistoryVersion(context, context.DefaultQuery("to", "current"), key, folderNameOfKey)
+	if !ok {
+		return
+	}
+	oldResult, ok := analyzeModelYAML(context, oldYaml)
+	if !ok {
+		return
+	}
+	newResult, ok := analyzeModelYAML(context, newYaml)
+	if !ok {
+		return
+	}
+	diff := threagile.Diff(oldResult, newResult)
+	switch format {
+	case "text":
+		context.String(http.StatusOK, diff.Text())
+	case "markdown":
+		context.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(diff.Markdown()))
+	default:
+		context.JSON(http.StatusOK, diff)
 	}
 }
 
-func listModels(context *gin.Context) { // TODO currently returns error when any model is no longer valid in syntax, so eventually have some fallback to not just bark on an invalid model...
+// restoreModelHistoryVersion makes a previous version of a model the current one (keeping the replaced one in the history as well)
+func restoreModelHistoryVersion(context *gin.Context) {
 	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
 	}
 	lockFolder(folderNameOfKey)
 	defer unlockFolder(folderNameOfKey)
-
-	result := make([]payloadModels, 0)
-	modelFolders, err := ioutil.ReadDir(folderNameOfKey)
-	if err != nil {
-		log.Println(err)
-		context.JSON(http.StatusNotFound, gin.H{
-			"error": "token not found",
+	historyID := context.Param("history-id")
+	if historyID == "current" {
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error": "the current version can't be restored",
 		})
 		return
 	}
-	for _, fileInfo := range modelFolders {
-		if fileInfo.IsDir() {
-			modelStat, err := os.Stat(folderNameOfKey + "/" + fileInfo.Name() + "/threagile.yaml")
-			if err != nil {
-				log.Println(err)
-				context.JSON(http.StatusNotFound, gin.H{
-					"error": "unable to list model",
-				})
-				return
-			}
-			model, _, ok := readModel(context, fileInfo.Name(), key, folderNameOfKey)
-			if !ok {
-				return
-			}
-			result = append(result, payloadModels{
-				ID:                 fileInfo.Name(),
-				Title:              model.Title,
-				Timestamp_created:  fileInfo.ModTime(),
-				Timestamp_modified: modelStat.ModTime(),
-			})
-		}
+	entry, yamlBytes, ok := readModelHistoryVersion(context, historyID, key, folderNameOfKey)
+	if !ok {
+		return
+	}
+	modelID, ok := checkModelFolder(context, context.Param("model-id"), folderNameOfKey)
+	if !ok {
+		return
+	}
+	ok = writeModelYAML(context, string(yamlBytes), key, folderNameOfKey, modelID, "Restore of "+entry.Timestamp.Format("2006-01-02 15.04.05"))
+	if ok {
+		context.JSON(http.StatusOK, gin.H{
+			"message": "model restored",
+			"id":      historyID,
+		})
 	}
-	context.JSON(http.StatusOK, result)
 }
 
-func deleteModel(context *gin.Context) {
-	folderNameOfKey, _, ok := checkTokenToFolderName(context)
+var webhookSender = webhook.DefaultSender()
+
+// readWebhooks reads the (encrypted) webhooks registered for a key
+func readWebhooks(folderNameOfKey string, key []byte) ([]webhook.Webhook, error) {
+	result := make([]webhook.Webhook, 0)
+	data, err := modelStorage.ReadKeyData(folderNameOfKey, "webhooks.json")
+	if err != nil || data == nil {
+		return result, err
+	}
+	data, err = decrypt(data, key)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func writeWebhooks(folderNameOfKey string, key []byte, webhooks []webhook.Webhook) error {
+	data, err := json.Marshal(webhooks)
+	if err == nil {
+		data, err = encrypt(data, key)
+	}
+	if err != nil {
+		return err
+	}
+	return modelStorage.WriteKeyData(folderNameOfKey, "webhooks.json", data)
+}
+
+type payloadWebhook struct {
+	URL              string   `json:"url"`
+	Secret           string   `json:"secret"`
+	Events           []string `json:"events"`
+	Minimum_severity string   `json:"minimum_severity"`
+}
+
+func listWebhooks(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
 		return
 	}
 	lockFolder(folderNameOfKey)
 	defer unlockFolder(folderNameOfKey)
-	folder, ok := checkModelFolder(context, context.Param("model-id"), folderNameOfKey)
+	webhooks, ok := readWebhooksOfKey(context, folderNameOfKey, key)
 	if ok {
-		err := os.RemoveAll(folder)
-		if err != nil {
-			context.JSON(http.StatusNotFound, gin.H{
-				"error": "model not found",
-			})
+		for i := range webhooks {
+			webhooks[i].Secret = ""
 		}
-		context.JSON(http.StatusOK, gin.H{
-			"message": "model deleted",
-		})
+		context.JSON(http.StatusOK, webhooks)
 	}
 }
 
-func checkModelFolder(context *gin.Context, modelUUID string, folderNameOfKey string) (modelFolder string, ok bool) {
-	uuidParsed, err := uuid.Parse(modelUUID)
-	if err != nil {
-		context.JSON(http.StatusNotFound, gin.H{
-			"error": "model not found",
-		})
-		return modelFolder, false
+func getWebhook(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
 	}
-	modelFolder = folderNameForModel(folderNameOfKey, uuidParsed.String())
-	if _, err := os.Stat(modelFolder); os.IsNotExist(err) {
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	webhooks, ok := readWebhooksOfKey(context, folderNameOfKey, key)
+	if ok {
+		for _, existing := range webhooks {
+			if existing.ID == context.Param("webhook-id") {
+				existing.Secret = ""
+				context.JSON(http.StatusOK, existing)
+				return
+			}
+		}
 		context.JSON(http.StatusNotFound, gin.H{
-			"error": "model not found",
+			"error": "webhook not found",
 		})
-		return modelFolder, false
 	}
-	return modelFolder, true
 }
 
-func readModel(context *gin.Context, modelUUID string, key []byte, folderNameOfKey string) (modelInputResult model.ModelInput, yamlText string, ok bool) {
-	modelFolder, ok := checkModelFolder(context, modelUUID, folderNameOfKey)
+// createNewWebhook registers a webhook, responding with its secret (generated when none is given) only this once
+func createNewWebhook(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
 	if !ok {
-		return modelInputResult, yamlText, false
+		return
 	}
-	cryptoKey := generateKeyFromAlreadyStrongRandomInput(key)
-	block, err := aes.NewCipher(cryptoKey)
-	if err != nil {
-		log.Println(err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to open model",
-		})
-		return modelInputResult, yamlText, false
+	ok = checkObjectCreationThrottler(context, "WEBHOOK")
+	if !ok {
+		return
 	}
-	aesgcm, err := cipher.NewGCM(block)
-	if err != nil {
-		log.Println(err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to open model",
-		})
-		return modelInputResult, yamlText, false
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	webhooks, ok := readWebhooksOfKey(context, folderNameOfKey, key)
+	if !ok {
+		return
 	}
-
-	fileBytes, err := ioutil.ReadFile(modelFolder + "/threagile.yaml")
+	payload := payloadWebhook{}
+	err := context.BindJSON(&payload)
 	if err != nil {
 		log.Println(err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to open model",
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error": "unable to parse request payload",
 		})
-		return modelInputResult, yamlText, false
+		return
 	}
-
-	nonce := fileBytes[0:12]
-	ciphertext := fileBytes[12:]
-	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		log.Println(err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to open model",
+	if len(payload.Secret) == 0 {
+		secret := make([]byte, keySize)
+		_, err = rand.Read(secret)
+		if err != nil {
+			handleErrorInServiceCall(err, context)
+			return
+		}
+		payload.Secret = hex.EncodeToString(secret)
+	}
+	newWebhook, ok := populateWebhook(context, uuid.New().String(), payload)
+	if !ok {
+		return
+	}
+	webhooks = append(webhooks, newWebhook)
+	ok = writeWebhooksOfKey(context, folderNameOfKey, key, webhooks)
+	if ok {
+		context.JSON(http.StatusCreated, gin.H{
+			"message": "webhook created",
+			"id":      newWebhook.ID,
+			"secret":  newWebhook.Secret,
 		})
-		return modelInputResult, yamlText, false
 	}
+}
 
-	r, err := gzip.NewReader(bytes.NewReader(plaintext))
-	if err != nil {
-		log.Println(err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to open model",
-		})
-		return modelInputResult, yamlText, false
+// setWebhook replaces a webhook, keeping its secret when none is given
+func setWebhook(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
 	}
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(r)
-	modelInput := model.ModelInput{}
-	yamlBytes := buf.Bytes()
-	err = yaml.Unmarshal(yamlBytes, &modelInput)
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	webhooks, ok := readWebhooksOfKey(context, folderNameOfKey, key)
+	if !ok {
+		return
+	}
+	payload := payloadWebhook{}
+	err := context.BindJSON(&payload)
 	if err != nil {
 		log.Println(err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to open model",
+		context.JSON(http.StatusBadRequest, gin.H{
+			"error": "unable to parse request payload",
 		})
-		return modelInputResult, yamlText, false
+		return
 	}
-	return modelInput, string(yamlBytes), true
+	for i, existing := range webhooks {
+		if existing.ID == context.Param("webhook-id") {
+			if len(payload.Secret) == 0 {
+				payload.Secret = existing.Secret
+			}
+			webhooks[i], ok = populateWebhook(context, existing.ID, payload)
+			if !ok {
+				return
+			}
+			ok = writeWebhooksOfKey(context, folderNameOfKey, key, webhooks)
+			if ok {
+				context.JSON(http.StatusOK, gin.H{
+					"message": "webhook updated",
+					"id":      existing.ID,
+				})
+			}
+			return
+		}
+	}
+	context.JSON(http.StatusNotFound, gin.H{
+		"error": "webhook not found",
+	})
 }
 
-func writeModel(context *gin.Context, key []byte, folderNameOfKey string, modelInput *model.ModelInput, changeReasonForHistory string) (ok bool) {
-	modelFolder, ok := checkModelFolder(context, context.Param("model-id"), folderNameOfKey)
-	if ok {
-		modelInput.Threagile_version = model.ThreagileVersion
-		yamlBytes, err := yaml.Marshal(modelInput)
-		if err != nil {
-			log.Println(err)
-			context.JSON(http.StatusInternalServerError, gin.H{
-				"error": "unable to write model",
-			})
-			return false
+func deleteWebhook(context *gin.Context) {
+	folderNameOfKey, key, ok := checkTokenToFolderName(context)
+	if !ok {
+		return
+	}
+	lockFolder(folderNameOfKey)
+	defer unlockFolder(folderNameOfKey)
+	webhooks, ok := readWebhooksOfKey(context, folderNameOfKey, key)
+	if !ok {
+		return
+	}
+	for i, existing := range webhooks {
+		if existing.ID == context.Param("webhook-id") {
+			webhooks = append(webhooks[:i], webhooks[i+1:]...)
+			ok = writeWebhooksOfKey(context, folderNameOfKey, key, webhooks)
+			if ok {
+				context.JSON(http.StatusOK, gin.H{
+					"message": "webhook deleted",
+					"id":      existing.ID,
+				})
+			}
+			return
 		}
-		/*
-			yamlBytes = model.ReformatYAML(yamlBytes)
-		*/
-		return writeModelYAML(context, string(yamlBytes), key, modelFolder, changeReasonForHistory, false)
 	}
-	return false
+	context.JSON(http.StatusNotFound, gin.H{
+		"error": "webhook not found",
+	})
 }
 
-func writeModelYAML(context *gin.Context, yaml string, key []byte, modelFolder string, changeReasonForHistory string, skipBackup bool) (ok bool) {
-	if *verbose {
-		fmt.Println("about to write " + strconv.Itoa(len(yaml)) + " bytes of yaml into model folder: " + modelFolder)
+func populateWebhook(context *gin.Context, id string, payload payloadWebhook) (result webhook.Webhook, ok bool) {
+	result = webhook.Webhook{
+		ID:              id,
+		URL:             payload.URL,
+		Secret:          payload.Secret,
+		Events:          payload.Events,
+		MinimumSeverity: payload.Minimum_severity,
 	}
-	var b bytes.Buffer
-	w := gzip.NewWriter(&b)
-	w.Write([]byte(yaml))
-	w.Close()
-	plaintext := b.Bytes()
-	cryptoKey := generateKeyFromAlreadyStrongRandomInput(key)
-	block, err := aes.NewCipher(cryptoKey)
-	if err != nil {
-		log.Println(err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to write model",
-		})
-		return false
+	if result.Events == nil {
+		result.Events = make([]string, 0)
 	}
-	// Never use more than 2^32 random nonces with a given key because of the risk of a repeat.
-	nonce := make([]byte, 12)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		log.Println(err)
-		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to write model",
-		})
-		return false
+	if len(result.MinimumSeverity) == 0 {
+		result.MinimumSeverity = model.ElevatedSeverity.String()
 	}
-	aesgcm, err := cipher.NewGCM(block)
+	if err := result.Check(); err != nil {
+		handleErrorInServiceCall(err, context)
+		return result, false
+	}
+	return result, true
+}
+
+func readWebhooksOfKey(context *gin.Context, folderNameOfKey string, key []byte) (webhooks []webhook.Webhook, ok bool) {
+	webhooks, err := readWebhooks(folderNameOfKey, key)
 	if err != nil {
 		log.Println(err)
 		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to write model",
+			"error": "unable to read webhooks",
 		})
-		return false
-	}
-	ciphertext := aesgcm.Seal(nil, nonce, plaintext, nil)
-	if !skipBackup {
-		err = backupModelToHistory(modelFolder, changeReasonForHistory)
-		if err != nil {
-			log.Println(err)
-			context.JSON(http.StatusInternalServerError, gin.H{
-				"error": "unable to write model",
-			})
-			return false
-		}
+		return nil, false
 	}
-	f, err := os.Create(modelFolder + "/threagile.yaml")
+	return webhooks, true
+}
+
+func writeWebhooksOfKey(context *gin.Context, folderNameOfKey string, key []byte, webhooks []webhook.Webhook) (ok bool) {
+	err := writeWebhooks(folderNameOfKey, key, webhooks)
 	if err != nil {
 		log.Println(err)
 		context.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unable to write model",
+			"error": "unable to write webhooks",
 		})
 		return false
 	}
-	f.Write(nonce)
-	f.Write(ciphertext)
-	f.Close()
 	return true
 }
 
-func backupModelToHistory(modelFolder string, changeReasonForHistory string) (err error) {
-	historyFolder := modelFolder + "/history"
-	if _, err := os.Stat(historyFolder); os.IsNotExist(err) {
-		err = os.Mkdir(historyFolder, 0700)
-		if err != nil {
-			return err
-		}
+// notifyWebhooks delivers an event of a model in the background to the webhooks registered for the key
+func notifyWebhooks(folderNameOfKey string, key []byte, event string, modelID string, changeReason string) {
+	webhooks, err := readWebhooks(folderNameOfKey, key)
+	if err != nil {
+		log.Println(err)
+		return
 	}
-	input, err := ioutil.ReadFile(modelFolder + "/threagile.yaml")
+	webhookSender.Notify(webhooks, webhook.Payload{
+		Event:        event,
+		Delivery:     uuid.New().String(),
+		Timestamp:    time.Now(),
+		ModelId:      modelID,
+		ChangeReason: changeReason,
+	})
+}
+
+// notifyWebhooksOfUpdate notifies of a model update and (after re-analyzing both versions in the background) of new unchecked risks
+func notifyWebhooksOfUpdate(folderNameOfKey string, key []byte, modelID string, changeReason string, previousYaml, yaml []byte) {
+	webhooks, err := readWebhooks(folderNameOfKey, key)
 	if err != nil {
-		return err
+		log.Println(err)
+		return
+	}
+	payload := webhook.Payload{
+		Event:        webhook.ModelUpdated,
+		Delivery:     uuid.New().String(),
+		Timestamp:    time.Now(),
+		ModelId:      modelID,
+		ChangeReason: changeReason,
+	}
+	webhookSender.Notify(webhooks, payload)
+	for _, registered := range webhooks {
+		if registered.Subscribes(webhook.NewRisks) {
+			go func() {
+				payload.Event = webhook.NewRisks
+				payload.Delivery = uuid.New().String()
+				payload.Risks = newUncheckedRisks(previousYaml, yaml)
+				if len(payload.Risks) > 0 {
+					webhookSender.Notify(webhooks, payload)
+				}
+			}()
+			return
+		}
 	}
-	historyFile := historyFolder + "/" + time.Now().Format("2006-01-02 15:04:05") + " " + changeReasonForHistory + ".backup"
-	err = ioutil.WriteFile(historyFile, input, 0400)
+}
+
+// newUncheckedRisks are the unchecked risks of a model not found in its previous version (all of them when the previous version is not ok)
+func newUncheckedRisks(previousYaml, yaml []byte) []webhook.Risk {
+	result := make([]webhook.Risk, 0)
+	newResult, err := analyzeModelYAMLRestricted(ctx.Background(), yaml)
 	if err != nil {
-		return err
+		return result
 	}
-	// now delete any old files if over limit to keep
-	files, err := ioutil.ReadDir(historyFolder)
+	previousResult, err := analyzeModelYAMLRestricted(ctx.Background(), previousYaml)
 	if err != nil {
-		return err
+		previousResult = nil
 	}
-	if len(files) > backupHistoryFilesToKeep {
-		requiredToDelete := len(files) - backupHistoryFilesToKeep
-		sort.Slice(files, func(i, j int) bool {
-			return files[i].Name() < files[j].Name()
-		})
-		for _, file := range files {
-			requiredToDelete--
-			err = os.Remove(historyFolder + "/" + file.Name())
-			if err != nil {
-				return err
-			}
-			if requiredToDelete <= 0 {
-				break
+	riskTracking := newResult.ParsedModel().RiskTracking
+	for _, risk := range newResult.Risks() {
+		if tracking, tracked := riskTracking[risk.SyntheticId]; tracked && tracking.Status != model.Unchecked {
+			continue
+		}
+		if previousResult != nil {
+			if _, existed := previousResult.RiskBySyntheticId(risk.SyntheticId); existed {
+				continue
 			}
 		}
+		result = append(result, webhook.Risk{
+			SyntheticId: risk.SyntheticId,
+			Category:    risk.Category.Id,
+			Title:       risk.Title,
+			Severity:    risk.Severity.String(),
+		})
 	}
-	return
+	return result
 }
 
 type argon2Params struct {
@@ -2392,10 +4783,6 @@ func generateKeyFromAlreadyStrongRandomInput(alreadyRandomInput []byte) []byte {
 	return hash
 }
 
-func folderNameForModel(folderNameOfKey string, uuid string) string {
-	return folderNameOfKey + "/" + uuid
-}
-
 var throttlerLock sync.Mutex
 var createdObjectsThrottler = make(map[string][]int64)
 
@@ -2442,6 +4829,7 @@ func checkObjectCreationThrottler(context *gin.Context, typeName string) bool {
 	return false
 }
 
+// locksByFolderName serializes the changes per key within this process only (not across several replicas of the server)
 var locksByFolderName = make(map[string]*sync.Mutex)
 
 func lockFolder(folderName string) {
@@ -2468,9 +4856,9 @@ type keyHeader struct {
 	Key string `header:"key"`
 }
 
+// folderNameFromKey is the name of the key in the storage (being a folder in the filesystem storage)
 func folderNameFromKey(key []byte) string {
-	sha512Hash := hashSHA256(key)
-	return baseFolder + "/" + sha512Hash
+	return hashSHA256(key)
 }
 
 func hashSHA256(key []byte) string {
@@ -2496,7 +4884,7 @@ func createKey(context *gin.Context) {
 		})
 		return
 	}
-	err = os.Mkdir(folderNameFromKey(keyBytesArr), 0700)
+	err = modelStorage.CreateKey(folderNameFromKey(keyBytesArr))
 	if err != nil {
 		log.Println(err)
 		context.JSON(http.StatusInternalServerError, gin.H{
@@ -2510,6 +4898,9 @@ func createKey(context *gin.Context) {
 }
 
 func checkTokenToFolderName(context *gin.Context) (folderNameOfKey string, key []byte, ok bool) {
+	if jwtAuthenticator != nil { // already authenticated by the middleware
+		return folderNameFromKey(jwtKey), jwtKey, true
+	}
 	header := tokenHeader{}
 	if err := context.ShouldBindHeader(&header); err != nil {
 		log.Println(err)
@@ -2536,8 +4927,10 @@ func checkTokenToFolderName(context *gin.Context) (folderNameOfKey string, key [
 		// re-create the key from token
 		key := xor(token, timeoutStruct.xorRand)
 		folderNameOfKey := folderNameFromKey(key)
-		if _, err := os.Stat(folderNameOfKey); os.IsNotExist(err) {
-			log.Println(err)
+		if exists, err := modelStorage.KeyExists(folderNameOfKey); err != nil || !exists {
+			if err != nil {
+				log.Println(err)
+			}
 			context.JSON(http.StatusNotFound, gin.H{
 				"error": "token not found",
 			})
@@ -2553,6 +4946,124 @@ func checkTokenToFolderName(context *gin.Context) (folderNameOfKey string, key [
 	}
 }
 
+const principalContextKey = "principal"
+
+// setupJWTAuthentication replaces the keys and tokens by bearer JWTs, all models being encrypted with the key of the config
+func setupJWTAuthentication(configFilename string) {
+	config, err := jwtauth.LoadConfig(configFilename)
+	support.CheckErr(err)
+	if len(config.KeyFile) == 0 {
+		panic(errors.New("jwt config requires a key_file holding the key to encrypt the models: " + configFilename))
+	}
+	keyData, err := ioutil.ReadFile(config.KeyFile)
+	support.CheckErr(err)
+	jwtKey, err = base64.RawURLEncoding.DecodeString(strings.TrimSpace(string(keyData)))
+	support.CheckErr(err)
+	if len(jwtKey) < keySize {
+		panic(errors.New("key_file must hold a key (base64 url encoded) of at least " + strconv.Itoa(keySize) + " bytes: " + config.KeyFile))
+	}
+	exists, err := modelStorage.KeyExists(folderNameFromKey(jwtKey))
+	support.CheckErr(err)
+	if !exists {
+		support.CheckErr(modelStorage.CreateKey(folderNameFromKey(jwtKey)))
+	}
+	jwtAuthenticator, err = jwtauth.NewAuthenticator(config)
+	support.CheckErr(err)
+}
+
+// authorizeJWT authenticates the requests regarding the stored models and webhooks via bearer JWT and checks the role required by the endpoint
+func authorizeJWT(context *gin.Context) {
+	requiredRoles := requiredRolesOfEndpoint(context.Request.Method, context.FullPath())
+	if len(requiredRoles) == 0 {
+		return
+	}
+	authorization := strings.TrimSpace(context.GetHeader("Authorization"))
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		context.Header("WWW-Authenticate", `Bearer realm="threagile"`)
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "bearer token required",
+		})
+		return
+	}
+	principal, err := jwtAuthenticator.Authenticate(strings.TrimSpace(authorization[7:]))
+	if err != nil {
+		log.Println(err)
+		context.Header("WWW-Authenticate", `Bearer realm="threagile", error="invalid_token"`)
+		context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid token",
+		})
+		return
+	}
+	if !principal.HasAnyRole(requiredRoles...) {
+		context.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "requires role " + strings.Join(requiredRoles, " or "),
+		})
+		return
+	}
+	context.Set(principalContextKey, principal)
+}
+
+// requiredRolesOfEndpoint is any of the roles allowed to call the endpoint (none for the endpoints not regarding the stored models)
+func requiredRolesOfEndpoint(method string, path string) []string {
+	switch {
+	case path == "/webhooks" || strings.HasPrefix(path, "/webhooks/"):
+		return []string{jwtauth.Admin}
+	case path != "/models" && !strings.HasPrefix(path, "/models/"):
+		return nil
+	case method == http.MethodGet || method == http.MethodHead:
+		return []string{jwtauth.Viewer}
+	case method == http.MethodDelete && path == "/models/:model-id":
+		return []string{jwtauth.Admin}
+	case strings.HasSuffix(path, "/risk-tracking") || strings.HasSuffix(path, "/tracking"):
+		return []string{jwtauth.Editor, jwtauth.RiskApprover}
+	default:
+		return []string{jwtauth.Editor}
+	}
+}
+
+// checkRiskAcceptance lets only risk-approvers accept risks or mark them as false positives (or change or remove such risk tracking)
+// when authenticating via JWTs, regardless of the endpoint changing the model (like the risk tracking endpoints, a model import, or a restore)
+func checkRiskAcceptance(context *gin.Context, previousYaml []byte, newYaml []byte) bool {
+	principal, authenticated := context.Get(principalContextKey)
+	if !authenticated || principal.(jwtauth.Principal).HasRole(jwtauth.RiskApprover) {
+		return true
+	}
+	previousInput, newInput := model.ModelInput{}, model.ModelInput{}
+	if err := yaml.Unmarshal(previousYaml, &previousInput); err != nil {
+		log.Println(err)
+	}
+	if err := yaml.Unmarshal(newYaml, &newInput); err != nil {
+		handleErrorInServiceCall(err, context)
+		return false
+	}
+	isAccepted := func(riskTracking model.InputRiskTracking) bool {
+		status := strings.TrimSpace(riskTracking.Status)
+		return status == model.Accepted.String() || status == model.FalsePositive.String()
+	}
+	// compare the risk tracking as applied to the risks, so that previous ids can't move risk tracking onto other elements
+	// (thus renaming elements having accepted risks requires a risk-approver as well)
+	previousRiskTracking := model.MigrateRiskTracking(previousInput.Risk_tracking, model.CurrentIdsByPreviousIdOfInput(previousInput))
+	newRiskTracking := model.MigrateRiskTracking(newInput.Risk_tracking, model.CurrentIdsByPreviousIdOfInput(newInput))
+	syntheticRiskIds := make(map[string]bool)
+	for syntheticRiskId := range previousRiskTracking {
+		syntheticRiskIds[syntheticRiskId] = true
+	}
+	for syntheticRiskId := range newRiskTracking {
+		syntheticRiskIds[syntheticRiskId] = true
+	}
+	for syntheticRiskId := range syntheticRiskIds {
+		previous, existed := previousRiskTracking[syntheticRiskId]
+		current, exists := newRiskTracking[syntheticRiskId]
+		if (isAccepted(previous) || isAccepted(current)) && (existed != exists || previous != current) {
+			context.JSON(http.StatusForbidden, gin.H{
+				"error": "only a " + jwtauth.RiskApprover + " may accept risks (or mark them as false positives) or change accepted ones: " + syntheticRiskId,
+			})
+			return false
+		}
+	}
+	return true
+}
+
 func checkKeyToFolderName(context *gin.Context) (folderNameOfKey string, key []byte, ok bool) {
 	header := keyHeader{}
 	if err := context.ShouldBindHeader(&header); err != nil {
@@ -2573,8 +5084,10 @@ func checkKeyToFolderName(context *gin.Context) (folderNameOfKey string, key []b
 		return folderNameOfKey, key, false
 	}
 	folderNameOfKey = folderNameFromKey(key)
-	if _, err := os.Stat(folderNameOfKey); os.IsNotExist(err) {
-		log.Println(err)
+	if exists, err := modelStorage.KeyExists(folderNameOfKey); err != nil || !exists {
+		if err != nil {
+			log.Println(err)
+		}
 		context.JSON(http.StatusNotFound, gin.H{
 			"error": "key not found",
 		})
@@ -2590,7 +5103,7 @@ func deleteKey(context *gin.Context) {
 	}
 	globalLock.Lock()
 	defer globalLock.Unlock()
-	err := os.RemoveAll(folderName)
+	err := modelStorage.DeleteKey(folderName)
 	if err != nil {
 		log.Println("error during key delete: " + err.Error())
 		context.JSON(http.StatusNotFound, gin.H{
@@ -2612,21 +5125,49 @@ func parseCommandlineArgs() {
 	createStubModel = flag.Bool("create-stub-model", false, "just create a minimal stub model named threagile-stub-model.yaml in the output directory")
 	createEditingSupport = flag.Bool("create-editing-support", false, "just create some editing support stuff in the output directory")
 	serverPort = flag.Int("server", 0, "start a server (instead of commandline execution) on the given port")
+	serverHistoryCount = flag.Int("server-history-count", 50, "number of previous versions kept per model by the server")
+	serverHistoryMaxAge = flag.Int("server-history-max-age", 0, "maximum age (in days) of the previous versions kept per model by the server (0 for no limit)")
+	serverJWTConfig = flag.String("server-jwt-config", "", "YAML file configuring the authentication of the server via bearer JWTs (validated against a JWKS file or the JWKS of an OIDC issuer) with roles instead of keys and tokens")
+	serverStorage = flag.String("server-storage", "filesystem", "storage of the models by the server: filesystem (folders in "+baseFolder+") or bolt (database file "+baseFolder+"/"+boltStorageFilename+")")
+	diffModels = flag.Bool("diff", false, "compare two models and their risks (instead of commandline execution): threagile -diff old.yaml new.yaml")
+	diffFormat = flag.String("diff-format", "text", "output format of the comparison: text, json, or markdown")
+	importOTM = flag.String("import-otm", "", "just convert the given open threat model (otm) file (json or yaml) into a model named "+otmImportFilename+" in the output directory")
+	exportOTM = flag.String("export-otm", "", "export the model including its risks as open threat model (otm) file with the given name (json, or yaml for a .yaml/.yml extension)")
+	importTM7 = flag.String("import-tm7", "", "just convert the given microsoft threat modeling tool (.tm7) file into a model named "+tm7ImportFilename+" in the output directory (along with a report of the unmapped properties named "+tm7ImportReportFilename+")")
+	tm7MappingFile = flag.String("tm7-mapping-file", "", "YAML file mapping the stencil types of the microsoft threat modeling tool onto technologies, protocols, and trust boundary types (extending the built-in mapping)")
+	importKubernetes = flag.String("import-kubernetes", "", "just generate a model skeleton named "+kubernetesImportFilename+" in the output directory from the kubernetes manifests (like the output of helm template) of the given directory or file (- for stdin)")
+	importDockerCompose = flag.String("import-docker-compose", "", "just generate a model skeleton named "+dockerComposeImportFilename+" in the output directory from the given docker-compose file")
+	importTerraform = flag.String("import-terraform", "", "just generate a model skeleton named "+terraformImportFilename+" in the output directory from the given output of terraform show -json (of a state or plan)")
+	importMerge = flag.Bool("import-merge", false, "merge the generated model skeleton into the model file (given by -model) instead, keeping its hand-written parts like CIA ratings and risk tracking")
 	templateFilename = flag.String("background", "background.pdf", "background pdf file")
 	generateDataFlowDiagram = flag.Bool("generate-data-flow-diagram", true, "generate data-flow diagram")
 	generateDataAssetDiagram = flag.Bool("generate-data-asset-diagram", true, "generate data asset diagram")
+	generateDataFlowDiagramMermaid = flag.Bool("generate-data-flow-diagram-mermaid", false, "generate data-flow diagram as mermaid (.mmd) file")
+	generateDataFlowDiagramPlantUML = flag.Bool("generate-data-flow-diagram-plantuml", false, "generate data-flow diagram as plantuml (.puml) file")
 	generateRisksJSON = flag.Bool("generate-risks-json", true, "generate risks json")
 	generateTechnicalAssetsJSON = flag.Bool("generate-technical-assets-json", true, "generate technical assets json")
 	generateStatsJSON = flag.Bool("generate-stats-json", true, "generate stats json")
 	generateRisksExcel = flag.Bool("generate-risks-excel", true, "generate risks excel")
 	generateTagsExcel = flag.Bool("generate-tags-excel", true, "generate tags excel")
 	generateReportPDF = flag.Bool("generate-report-pdf", true, "generate report pdf, including diagrams")
+	generateReportHTML = flag.Bool("generate-report-html", false, "generate self-contained report html, including the data-flow diagram as svg")
 	generateDefectdojoGeneric = flag.Bool("generate-defectdojo-json", true, "generate defectdojo generic json")
+	diagramSVG = flag.Bool("diagram-svg", false, "additionally render the generated diagrams as svg with clickable elements (linking into the report html)")
+	diagramRiskOverlay = flag.Bool("diagram-risk-overlay", false, "color the technical assets in the diagrams by their highest severity still at risk and badge them with their number of open risks")
 	diagramDPI = flag.Int("diagram-dpi", defaultGraphvizDPI, "DPI used to render: maximum is "+strconv.Itoa(maxGraphvizDPI)+"")
 	skipRiskRules = flag.String("skip-risk-rules", "", "comma-separated list of risk rules (by their ID) to skip")
 	riskRulesPlugins = flag.String("custom-risk-rules-plugins", "", "comma-separated list of plugins (.so shared object) file names with custom risk rules to load")
+	customRiskRulesFile = flag.String("custom-risk-rules-file", "", "YAML file with custom risk rules (defined via expressions instead of plugins) to load")
 	verbose = flag.Bool("verbose", false, "verbose output")
 	ignoreOrphanedRiskTracking = flag.Bool("ignore-orphaned-risk-tracking", false, "ignore orphaned risk tracking (just log them) not matching a concrete risk")
+	expiredRiskTrackingStatus = flag.String("expired-risk-tracking-status", model.Unchecked.String(), "status of risk tracking past its expiry date (expires)")
+	failOnExpiredRiskTracking = flag.Bool("fail-on-expired-risk-tracking", false, "exit with code "+strconv.Itoa(exitCodeExpiredRiskTracking)+" when any risk tracking (like an acceptance) is past its expiry date")
+	failOn = flag.String("fail-on", "", "exit with code "+strconv.Itoa(exitCodePolicyViolation)+" when any risk matches one of the comma-separated severities (* for all) optionally restricted to a status, like: critical,high:unchecked (without status: risks not yet handled)")
+	policyFile = flag.String("policy-file", "", "YAML file with rules (by severities, statuses, categories, and tags) risks must not match, otherwise exiting with code "+strconv.Itoa(exitCodePolicyViolation))
+	baselineFile = flag.String("baseline", "", "JSON file recording the known risks (by fingerprint): only the new risks are reported (in the risks sarif and on stderr) and checked against -fail-on and -policy-file")
+	updateBaseline = flag.Bool("update-baseline", false, "(re)generate the file given via -baseline from the current risks")
+	failOnNewRisks = flag.Bool("fail-on-new-risks", false, "exit with code "+strconv.Itoa(exitCodeNewRisks)+" when any risk not yet handled is new compared to the baseline (given via -baseline)")
+	restrictIncludes = flag.Bool("restrict-includes", false, "only allow includes of files within the directory of the model file")
 	version := flag.Bool("version", false, "print version")
 	listTypes := flag.Bool("list-types", false, "print type information (enum values to be used in models)")
 	listRiskRules := flag.Bool("list-risk-rules", false, "print risk rules")
@@ -2673,6 +5214,10 @@ func parseCommandlineArgs() {
 		printLogo()
 		os.Exit(0)
 	}
+	if *diffModels && flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Please specify the old and the new model to compare: threagile -diff old.yaml new.yaml")
+		os.Exit(2)
+	}
 	if *listTypes {
 		printLogo()
 		fmt.Println("The following types are available (can be extended for custom rules):")
@@ -2737,6 +5282,9 @@ func parseCommandlineArgs() {
 		fmt.Println("----------------------")
 		fmt.Println(add_build_pipeline.GetMacroDetails().ID, "-->", add_build_pipeline.GetMacroDetails().Title)
 		fmt.Println(add_vault.GetMacroDetails().ID, "-->", add_vault.GetMacroDetails().Title)
+		fmt.Println(cleanup_risk_tracking.GetMacroDetails().ID, "-->", cleanup_risk_tracking.GetMacroDetails().Title)
+		fmt.Println(enrich_from_openapi.GetMacroDetails().ID, "-->", enrich_from_openapi.GetMacroDetails().Title)
+		fmt.Println(migrate_risk_tracking.GetMacroDetails().ID, "-->", migrate_risk_tracking.GetMacroDetails().Title)
 		fmt.Println(pretty_print.GetMacroDetails().ID, "-->", pretty_print.GetMacroDetails().Title)
 		fmt.Println(remove_unused_tags.GetMacroDetails().ID, "-->", remove_unused_tags.GetMacroDetails().Title)
 		fmt.Println(seed_risk_tracking.GetMacroDetails().ID, "-->", seed_risk_tracking.GetMacroDetails().Title)
@@ -2748,8 +5296,16 @@ func parseCommandlineArgs() {
 		printLogo()
 		fmt.Println("The following risk rules are available (can be extended via custom risk rules):")
 		fmt.Println()
-		loadRiskRulePlugins()
-		for _, riskRule := range builtinRiskRulesPlugins {
+		riskRules, err := threagile.LoadRiskRules(threagile.DefaultOptions().RiskRulesPlugins, *verbose)
+		support.CheckErr(err)
+		if len(*customRiskRulesFile) > 0 {
+			customRiskRules, err := threagile.LoadCustomRiskRules(*customRiskRulesFile, *verbose)
+			support.CheckErr(err)
+			for id, riskRule := range customRiskRules {
+				riskRules[id] = riskRule
+			}
+		}
+		for _, riskRule := range riskRules {
 			fmt.Println(riskRule.Category().Id, "-->", riskRule.Category().Title, "--> with tags:", riskRule.SupportedTags())
 		}
 		fmt.Println()
@@ -2768,6 +5324,7 @@ func parseCommandlineArgs() {
 		fmt.Println(" - google-uuid (BSD License): https://github.com/google/uuid/blob/master/LICENSE")
 		fmt.Println(" - gin-gonic (MIT License): https://github.com/gin-gonic/gin/blob/master/LICENSE")
 		fmt.Println(" - swagger-ui (Apache License): https://swagger.io/license/")
+		fmt.Println(" - expr (MIT License): https://github.com/antonmedv/expr/blob/master/LICENSE")
 		fmt.Println()
 		os.Exit(0)
 	}
@@ -2797,6 +5354,55 @@ func parseCommandlineArgs() {
 		fmt.Println()
 		os.Exit(0)
 	}
+	if len(*importOTM) > 0 {
+		importOTMFile(*importOTM)
+		printLogo()
+		fmt.Println("A model was created named " + otmImportFilename + " in the output directory.")
+		fmt.Println()
+		os.Exit(0)
+	}
+	if len(*importTM7) > 0 {
+		unmapped := importTM7File(*importTM7)
+		printLogo()
+		fmt.Println("A model was created named " + tm7ImportFilename + " in the output directory.")
+		fmt.Println(strconv.Itoa(unmapped) + " unmapped properties and elements are listed in " + tm7ImportReportFilename + " in the output directory.")
+		fmt.Println()
+		os.Exit(0)
+	}
+	if len(*importKubernetes) > 0 {
+		manifests, err := kubernetes.Read(*importKubernetes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to import "+*importKubernetes+": "+err.Error())
+			os.Exit(2)
+		}
+		writeModelSkeleton(kubernetes.ToModelInput(manifests), kubernetesImportFilename, kubernetes.ProvenanceTag)
+		fmt.Println()
+		os.Exit(0)
+	}
+	if len(*importDockerCompose) > 0 {
+		data, err := ioutil.ReadFile(*importDockerCompose)
+		support.CheckErr(err)
+		composeFile, err := compose.Parse(data)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to import "+*importDockerCompose+": "+err.Error())
+			os.Exit(2)
+		}
+		writeModelSkeleton(compose.ToModelInput(composeFile), dockerComposeImportFilename, compose.ProvenanceTag)
+		fmt.Println()
+		os.Exit(0)
+	}
+	if len(*importTerraform) > 0 {
+		data, err := ioutil.ReadFile(*importTerraform)
+		support.CheckErr(err)
+		show, err := terraform.Parse(data)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to import "+*importTerraform+": "+err.Error())
+			os.Exit(2)
+		}
+		writeModelSkeleton(terraform.ToModelInput(show), terraformImportFilename, terraform.ProvenanceTag)
+		fmt.Println()
+		os.Exit(0)
+	}
 	if *createEditingSupport {
 		createEditingSupportFiles()
 		printLogo()
@@ -2843,6 +5449,85 @@ func createStubModelFile() {
 	support.CheckErr(err)
 }
 
+func importOTMFile(filename string) {
+	data, err := ioutil.ReadFile(filename)
+	support.CheckErr(err)
+	modelYaml, err := modelYAMLOfOTM(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to import "+filename+": "+err.Error())
+		os.Exit(2)
+	}
+	support.CheckErr(ioutil.WriteFile(*outputDir+"/"+otmImportFilename, modelYaml, 0644))
+}
+
+func importTM7File(filename string) (unmapped int) {
+	mapping := tm7.DefaultMapping()
+	if len(*tm7MappingFile) > 0 {
+		var err error
+		if mapping, err = tm7.LoadMapping(*tm7MappingFile); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to load "+*tm7MappingFile+": "+err.Error())
+			os.Exit(2)
+		}
+	}
+	data, err := ioutil.ReadFile(filename)
+	support.CheckErr(err)
+	threatModel, err := tm7.Parse(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to import "+filename+": "+err.Error())
+		os.Exit(2)
+	}
+	modelInput, report := tm7.ToModelInput(threatModel, mapping)
+	modelYaml, err := yaml.Marshal(&modelInput)
+	support.CheckErr(err)
+	support.CheckErr(ioutil.WriteFile(*outputDir+"/"+tm7ImportFilename, modelYaml, 0644))
+	support.CheckErr(ioutil.WriteFile(*outputDir+"/"+tm7ImportReportFilename, []byte(report.String()), 0644))
+	return len(report)
+}
+
+// writeModelSkeleton writes the generated model skeleton into the output directory, or merges it into the model file
+// (updating the elements generated before when they carry the provenance tag)
+func writeModelSkeleton(generated model.ModelInput, filename, provenance string) {
+	if !*importMerge {
+		modelYaml, err := yaml.Marshal(&generated)
+		support.CheckErr(err)
+		support.CheckErr(ioutil.WriteFile(*outputDir+"/"+filename, modelYaml, 0644))
+		printLogo()
+		fmt.Println("A model skeleton was created named " + filename + " in the output directory.")
+		return
+	}
+	data, err := ioutil.ReadFile(*modelFilename)
+	existing, resolved := model.ModelInput{}, model.ModelInput{}
+	if err == nil {
+		err = yaml.Unmarshal(data, &existing)
+	}
+	if err == nil {
+		err = yaml.Unmarshal(data, &resolved)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Unable to merge into "+*modelFilename+": "+err.Error())
+		os.Exit(2)
+	}
+	// the elements of the included files are matched as well, but only the model file itself is written back
+	fragments, diagnostics := model.ResolveIncludes(&resolved, *modelFilename, false)
+	if diagnostics.HasErrors() {
+		diagnostics.Locate(data, fragments...)
+		fmt.Fprint(os.Stderr, "Unable to merge into "+*modelFilename+" due to its includes:\n"+diagnostics.Format(*modelFilename))
+		os.Exit(2)
+	}
+	merged, notWrittenBack := skeleton.MergeWithIncludes(existing, resolved, generated, provenance)
+	modelYaml, err := yaml.Marshal(&merged)
+	support.CheckErr(err)
+	backupFilename := *modelFilename + ".backup"
+	_, err = support.CopyFile(*modelFilename, backupFilename)
+	support.CheckErr(err)
+	support.CheckErr(ioutil.WriteFile(*modelFilename, modelYaml, 0644))
+	printLogo()
+	fmt.Println("The generated model skeleton was merged into " + *modelFilename + " (the previous version was saved as " + backupFilename + ").")
+	if len(notWrittenBack) > 0 {
+		fmt.Println("The changes of these elements of included files were not merged (update them by hand): " + strings.Join(notWrittenBack, ", "))
+	}
+}
+
 func createEditingSupportFiles() {
 	support.CopyFile("/app/schema.json", *outputDir+"/schema.json")
 	support.CopyFile("/app/live-templates.txt", *outputDir+"/live-templates.txt")
@@ -2882,70 +5567,19 @@ func printTypes(title string, value interface{}) {
 	fmt.Println(fmt.Sprintf("  %v: %v", title, value))
 }
 
-func parseModel(inputFilename string) {
-	if *verbose {
-		fmt.Println("Parsing model:", inputFilename)
+func analysisOptions(modelFilename string) threagile.Options {
+	options := threagile.DefaultOptions()
+	options.ModelFilename = modelFilename
+	options.RestrictIncludesToModelDirectory = *restrictIncludes
+	options.RAAPlugin = *raaPlugin
+	if len(*skipRiskRules) > 0 {
+		options.SkipRiskRules = strings.Split(*skipRiskRules, ",")
 	}
-	modelYaml, err := ioutil.ReadFile(inputFilename)
-	support.CheckErr(err)
-	var validatorYaml interface{}
-	support.CheckErr(err)
-	err = yaml.Unmarshal([]byte(modelYaml), &validatorYaml)
-	support.CheckErr(err)
-	validatorYaml, err = support.ToStringKeys(validatorYaml)
+	options.CustomRiskRulesFile = *customRiskRulesFile
+	options.IgnoreOrphanedRiskTracking = *ignoreOrphanedRiskTracking
+	status, err := model.ParseRiskStatus(*expiredRiskTrackingStatus)
 	support.CheckErr(err)
-	compiler := jsonschema.NewCompiler()
-	compiler.Draft = jsonschema.Draft2020
-	compiler.AssertContent = true
-	compiler.AssertFormat = true
-	schemaFile, err := ioutil.ReadFile("schema.json")
-	support.CheckErr(err)
-	if err := compiler.AddResource("schema.json", strings.NewReader(string(schemaFile))); err != nil {
-		panic(err)
-	}
-	schema, err := compiler.Compile("schema.json")
-	if err != nil {
-		panic(err)
-	}
-	if err := schema.Validate(validatorYaml); err != nil {
-		panic(err)
-	}
-	model.ParsedModelRoot = model.ParseModel(modelYaml, deferredRiskTrackingDueToWildcardMatching)
-}
-
-func applyWildcardRiskTrackingEvaluation() {
-	if *verbose {
-		fmt.Println("Executing risk tracking evaluation")
-	}
-	for syntheticRiskIdPattern, riskTracking := range deferredRiskTrackingDueToWildcardMatching {
-		foundSome := false
-		var matchingRiskIdExpression = regexp.MustCompile(strings.ReplaceAll(regexp.QuoteMeta(syntheticRiskIdPattern), `\*`, `[^@]+`))
-		for syntheticRiskId, _ := range model.GeneratedRisksBySyntheticId {
-			if matchingRiskIdExpression.Match([]byte(syntheticRiskId)) && hasNotYetAnyDirectNonWildcardRiskTrackings(syntheticRiskId) {
-				foundSome = true
-				model.ParsedModelRoot.RiskTracking[syntheticRiskId] = model.RiskTracking{
-					SyntheticRiskId: strings.TrimSpace(syntheticRiskId),
-					Justification:   riskTracking.Justification,
-					CheckedBy:       riskTracking.CheckedBy,
-					Ticket:          riskTracking.Ticket,
-					Status:          riskTracking.Status,
-					Date:            riskTracking.Date,
-				}
-			}
-		}
-		if !foundSome {
-			if *ignoreOrphanedRiskTracking {
-				fmt.Println("Wildcard risk tracking does not match any risk id: " + syntheticRiskIdPattern)
-			} else {
-				panic(errors.New("wildcard risk tracking does not match any risk id: " + syntheticRiskIdPattern))
-			}
-		}
-	}
-}
-
-func hasNotYetAnyDirectNonWildcardRiskTrackings(syntheticRiskId string) bool {
-	if _, ok := model.ParsedModelRoot.RiskTracking[syntheticRiskId]; ok {
-		return false
-	}
-	return true
+	options.ExpiredRiskTrackingStatus = status
+	options.Verbose = *verbose
+	return options
 }
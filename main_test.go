@@ -0,0 +1,454 @@
+package main
+
+import (
+	ctx "context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/jwtauth"
+	"github.com/otyg/threagile/pkg/storage"
+	"github.com/otyg/threagile/pkg/threagile"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRequiredRolesOfEndpoint(t *testing.T) {
+	for _, test := range []struct {
+		method, path string
+		want         []string
+	}{
+		{http.MethodPost, "/direct/analyze", nil},
+		{http.MethodGet, "/meta/version", nil},
+		{http.MethodGet, "/models", []string{jwtauth.Viewer}},
+		{http.MethodPost, "/models", []string{jwtauth.Editor}},
+		{http.MethodGet, "/models/:model-id", []string{jwtauth.Viewer}},
+		{http.MethodHead, "/models/:model-id", []string{jwtauth.Viewer}},
+		{http.MethodPut, "/models/:model-id", []string{jwtauth.Editor}},
+		{http.MethodDelete, "/models/:model-id", []string{jwtauth.Admin}},
+		{http.MethodDelete, "/models/:model-id/data-assets/:data-asset-id", []string{jwtauth.Editor}},
+		{http.MethodPost, "/models/:model-id/history/:history-id/restore", []string{jwtauth.Editor}},
+		{http.MethodGet, "/models/:model-id/risk-tracking", []string{jwtauth.Viewer}},
+		{http.MethodPut, "/models/:model-id/risk-tracking", []string{jwtauth.Editor, jwtauth.RiskApprover}},
+		{http.MethodPut, "/models/:model-id/risks/:synthetic-id/tracking", []string{jwtauth.Editor, jwtauth.RiskApprover}},
+		{http.MethodDelete, "/models/:model-id/risks/:synthetic-id/tracking", []string{jwtauth.Editor, jwtauth.RiskApprover}},
+		{http.MethodGet, "/webhooks", []string{jwtauth.Admin}},
+		{http.MethodPost, "/webhooks", []string{jwtauth.Admin}},
+		{http.MethodDelete, "/webhooks/:webhook-id", []string{jwtauth.Admin}},
+	} {
+		if got := requiredRolesOfEndpoint(test.method, test.path); strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("requiredRolesOfEndpoint(%v, %v) = %v, want %v", test.method, test.path, got, test.want)
+		}
+	}
+}
+
+func TestCheckRiskAcceptance(t *testing.T) {
+	const mitigated = "risk_tracking:\n  some-rule@some-asset:\n    status: mitigated\n"
+	const accepted = "risk_tracking:\n  some-rule@some-asset:\n    status: accepted\n"
+	const falsePositive = "risk_tracking:\n  some-rule@some-asset:\n    status: false-positive\n"
+	const acceptedJustified = "risk_tracking:\n  some-rule@some-asset:\n    status: accepted\n    justification: changed\n"
+	const wildcardInDiscussion = "risk_tracking:\n  some-rule@*:\n    status: in-discussion\n"
+	const wildcardAccepted = "risk_tracking:\n  some-rule@*:\n    status: accepted\n"
+	const orphanedAcceptance = "technical_assets:\n  Other Asset:\n    id: other-asset\n" + accepted
+	const orphanedAcceptanceMoved = "technical_assets:\n  Other Asset:\n    id: other-asset\n    previous_ids: [ some-asset ]\n" + accepted
+	for _, test := range []struct {
+		name          string
+		roles         []string // nil when not authenticated via JWTs
+		previous, new string
+		want          bool
+	}{
+		{"editor mitigating", []string{jwtauth.Editor}, "", mitigated, true},
+		{"editor accepting", []string{jwtauth.Editor}, mitigated, accepted, false},
+		{"editor marking as false positive", []string{jwtauth.Editor}, "", falsePositive, false},
+		{"editor changing an acceptance", []string{jwtauth.Editor}, accepted, acceptedJustified, false},
+		{"editor removing an acceptance", []string{jwtauth.Editor}, accepted, "", false},
+		{"editor un-accepting", []string{jwtauth.Editor}, accepted, mitigated, false},
+		{"editor keeping an acceptance", []string{jwtauth.Editor}, accepted, accepted, true},
+		{"editor tracking a wildcard", []string{jwtauth.Editor}, "", wildcardInDiscussion, true},
+		{"editor accepting a wildcard", []string{jwtauth.Editor}, wildcardInDiscussion, wildcardAccepted, false},
+		{"editor moving an acceptance via previous ids", []string{jwtauth.Editor}, orphanedAcceptance, orphanedAcceptanceMoved, false},
+		{"risk-approver moving an acceptance via previous ids", []string{jwtauth.RiskApprover}, orphanedAcceptance, orphanedAcceptanceMoved, true},
+		{"risk-approver accepting", []string{jwtauth.RiskApprover}, mitigated, accepted, true},
+		{"risk-approver accepting a wildcard", []string{jwtauth.RiskApprover}, "", wildcardAccepted, true},
+		{"admin marking as false positive", []string{jwtauth.Admin}, "", falsePositive, true},
+		{"not authenticated via JWTs", nil, "", accepted, true},
+	} {
+		context, _ := gin.CreateTestContext(httptest.NewRecorder())
+		if test.roles != nil {
+			context.Set(principalContextKey, jwtauth.Principal{Subject: "someone", Roles: test.roles})
+		}
+		if got := checkRiskAcceptance(context, []byte(test.previous), []byte(test.new)); got != test.want {
+			t.Errorf("checkRiskAcceptance of %v = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// TestWriteModelYAMLChecksRiskAcceptance replaces a model like an import or a restore does
+func TestWriteModelYAMLChecksRiskAcceptance(t *testing.T) {
+	verbose, serverHistoryCount, serverHistoryMaxAge = new(bool), new(int), new(int)
+	*serverHistoryCount = 10
+	modelStorage = storage.NewFileSystem(t.TempDir())
+	key := []byte(strings.Repeat("k", keySize))
+	folderNameOfKey, modelID := folderNameFromKey(key), "00000000-0000-0000-0000-000000000001"
+	if err := modelStorage.CreateKey(folderNameOfKey); err != nil {
+		t.Fatal(err)
+	}
+	original := "title: Some Model\nrisk_tracking:\n  some-rule@some-asset:\n    status: in-discussion\n"
+	data, err := encrypt([]byte(original), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := modelStorage.CreateModel(folderNameOfKey, modelID, data); err != nil {
+		t.Fatal(err)
+	}
+	accepting := "title: Some Model\nrisk_tracking:\n  some-rule@some-asset:\n    status: accepted\n"
+
+	for _, test := range []struct {
+		role, changeReason string
+		yaml               string
+		wantStatus         int
+		wantModel          string
+	}{
+		{jwtauth.Editor, "Model Import", accepting, http.StatusForbidden, original},
+		{jwtauth.RiskApprover, "Model Import", accepting, http.StatusOK, accepting},
+		{jwtauth.Editor, "Restore of 2024-01-02 03.04.05", original, http.StatusForbidden, accepting},
+		{jwtauth.RiskApprover, "Restore of 2024-01-02 03.04.05", original, http.StatusOK, original},
+	} {
+		recorder := httptest.NewRecorder()
+		context, _ := gin.CreateTestContext(recorder)
+		context.Request = httptest.NewRequest(http.MethodPut, "/models/"+modelID, nil)
+		context.Set(principalContextKey, jwtauth.Principal{Subject: "someone", Roles: []string{test.role}})
+		writeModelYAML(context, test.yaml, key, folderNameOfKey, modelID, test.changeReason)
+		context.Writer.WriteHeaderNow()
+		if recorder.Code != test.wantStatus {
+			t.Errorf("status of %v by %v = %v, want %v", test.changeReason, test.role, recorder.Code, test.wantStatus)
+		}
+		data, _, err := modelStorage.ReadModel(folderNameOfKey, modelID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := decrypt(data, key); err != nil || string(got) != test.wantModel {
+			t.Errorf("model after %v by %v = %q (%v), want %q", test.changeReason, test.role, got, err, test.wantModel)
+		}
+	}
+}
+
+// failingHistory fails reading any previous version of a model (like when it was deleted in the meantime)
+type failingHistory struct {
+	storage.Storage
+	err error
+}
+
+func (what failingHistory) ReadHistory(key, modelID, name string) ([]byte, error) {
+	return nil, what.err
+}
+
+func TestReadModelHistoryVersion(t *testing.T) {
+	fileSystem := storage.NewFileSystem(t.TempDir())
+	key := []byte(strings.Repeat("k", keySize))
+	folderNameOfKey, modelID := folderNameFromKey(key), "00000000-0000-0000-0000-000000000001"
+	if err := fileSystem.CreateKey(folderNameOfKey); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := encrypt([]byte("title: Previous Model\n"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := encrypt([]byte("title: Current Model\n"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fileSystem.CreateModel(folderNameOfKey, modelID, previous); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fileSystem.WriteModel(folderNameOfKey, modelID, current, "", storage.NewHistoryEntry(time.Now(), "Model Update")); err != nil {
+		t.Fatal(err)
+	}
+	modelStorage = fileSystem
+	entries, err := modelHistory(folderNameOfKey, modelID)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("history = %+v (%v), want one entry", entries, err)
+	}
+
+	for _, test := range []struct {
+		name       string
+		storage    storage.Storage
+		historyID  string
+		wantStatus int
+		wantYaml   string
+	}{
+		{"current version", fileSystem, "current", http.StatusOK, "title: Current Model\n"},
+		{"previous version", fileSystem, entries[0].ID, http.StatusOK, "title: Previous Model\n"},
+		{"unknown history id", fileSystem, "0000000000000000", http.StatusNotFound, ""},
+		{"history entry deleted in the meantime", failingHistory{fileSystem, storage.ErrNotFound}, entries[0].ID, http.StatusNotFound, ""},
+		{"history entry unreadable", failingHistory{fileSystem, errors.New("disk failure")}, entries[0].ID, http.StatusInternalServerError, ""},
+	} {
+		modelStorage = test.storage
+		recorder := httptest.NewRecorder()
+		context, _ := gin.CreateTestContext(recorder)
+		context.Params = gin.Params{{Key: "model-id", Value: modelID}}
+		_, yamlBytes, ok := readModelHistoryVersion(context, test.historyID, key, folderNameOfKey)
+		context.Writer.WriteHeaderNow()
+		if recorder.Code != test.wantStatus || ok != (test.wantStatus == http.StatusOK) || string(yamlBytes) != test.wantYaml {
+			t.Errorf("reading the %v = %v (%q), want %v (%q)", test.name, recorder.Code, yamlBytes, test.wantStatus, test.wantYaml)
+		}
+	}
+}
+
+func TestUpdatedPreviousIds(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		payload, existing []string
+		oldID, newID      string
+		want              []string
+	}{
+		{"unchanged without previous ids", nil, nil, "asset", "asset", nil},
+		{"kept when not in the payload", nil, []string{"older"}, "asset", "asset", []string{"older"}},
+		{"replaced by the payload", []string{"other"}, []string{"older"}, "asset", "asset", []string{"other"}},
+		{"cleared by the payload", []string{}, []string{"older"}, "asset", "asset", nil},
+		{"former id added", nil, []string{"older"}, "asset", "renamed", []string{"older", "asset"}},
+		{"former id added once", []string{"asset"}, nil, "asset", "renamed", []string{"asset"}},
+		{"renamed back", nil, []string{"older"}, "asset", "older", []string{"asset"}},
+	} {
+		if got := updatedPreviousIds(test.payload, test.existing, test.oldID, test.newID); strings.Join(got, ",") != strings.Join(test.want, ",") || (got == nil) != (test.want == nil) {
+			t.Errorf("%v: updatedPreviousIds = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+type riskPerTechnicalAsset struct{}
+
+func (r riskPerTechnicalAsset) Category() model.RiskCategory {
+	return model.RiskCategory{Id: "some-rule", Title: "Some Rule"}
+}
+
+func (r riskPerTechnicalAsset) SupportedTags() []string {
+	return []string{}
+}
+
+func (r riskPerTechnicalAsset) GenerateRisks() []model.Risk {
+	risks := make([]model.Risk, 0)
+	for _, id := range model.SortedTechnicalAssetIDs() {
+		risks = append(risks, model.Risk{
+			Category:                     r.Category(),
+			Severity:                     model.MediumSeverity,
+			MostRelevantTechnicalAssetId: id,
+			SyntheticId:                  r.Category().Id + "@" + id,
+		})
+	}
+	return risks
+}
+
+func TestMatchesAnyRisk(t *testing.T) {
+	model.ThreagileVersion = "test"
+	modelYaml := `title: Some Model
+date: "2024-01-02"
+business_criticality: important
+technical_assets:
+  Some Asset:
+    id: some-asset
+    previous_ids: [ former-asset ]
+    type: process
+    usage: business
+    size: component
+    technology: web-server
+    internet: false
+    machine: container
+    encryption: none
+    owner: team
+    confidentiality: internal
+    integrity: operational
+    availability: operational
+`
+	result, err := threagile.Analyze(ctx.Background(), []byte(modelYaml), threagile.Options{
+		RAA:       func() string { return "" },
+		RiskRules: map[string]model.RiskRule{"some-rule": riskPerTechnicalAsset{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		syntheticRiskID string
+		want            bool
+	}{
+		{"some-rule@some-asset", true},
+		{"Some-Rule@Some-Asset", true},
+		{"some-rule@former-asset", true},
+		{"some-rule@*", true},
+		{"some-rule@some-assets", false},
+		{"other-rule@some-asset", false},
+		{"other-rule@*", false},
+	} {
+		if got := matchesAnyRisk(result, test.syntheticRiskID); got != test.want {
+			t.Errorf("matchesAnyRisk(%v) = %v, want %v", test.syntheticRiskID, got, test.want)
+		}
+	}
+}
+
+const referencingModel = `technical_assets:
+  Web:
+    id: web
+    communication_links:
+      Database Traffic:
+        target: db
+  Database:
+    id: db
+    communication_links:
+      Web Callback:
+        target: web
+trust_boundaries:
+  Network:
+    id: network
+    technical_assets_inside: [ web, db ]
+shared_runtimes:
+  Cluster:
+    id: cluster
+    technical_assets_running: [ db ]
+individual_risk_categories:
+  Some Category:
+    id: some-category
+    risks_identified:
+      Some Risk:
+        most_relevant_technical_asset: db
+        most_relevant_communication_link: db>web-callback
+        data_breach_technical_assets: [ db, web ]
+      Other Risk:
+        most_relevant_communication_link: web>database-traffic
+diagram_tweak_invisible_connections_between_assets: [ web:db ]
+diagram_tweak_same_rank_assets: [ web:db ]
+`
+
+// referencingModelInput is the model referencing the technical asset "db" in every possible way,
+// along with the accessors of the references by their name
+func referencingModelInput(t *testing.T) (model.ModelInput, map[string]func(model.ModelInput) interface{}) {
+	modelInput := model.ModelInput{}
+	if err := yaml.Unmarshal([]byte(referencingModel), &modelInput); err != nil {
+		t.Fatal(err)
+	}
+	risks := func(modelInput model.ModelInput) map[string]model.InputRiskIdentified {
+		return modelInput.Individual_risk_categories["Some Category"].Risks_identified
+	}
+	return modelInput, map[string]func(model.ModelInput) interface{}{
+		"link targets": func(modelInput model.ModelInput) interface{} {
+			return modelInput.Technical_assets["Web"].Communication_links
+		},
+		"trust boundaries": func(modelInput model.ModelInput) interface{} {
+			return modelInput.Trust_boundaries["Network"].Technical_assets_inside
+		},
+		"shared runtimes": func(modelInput model.ModelInput) interface{} {
+			return modelInput.Shared_runtimes["Cluster"].Technical_assets_running
+		},
+		"most relevant technical asset": func(modelInput model.ModelInput) interface{} {
+			return risks(modelInput)["Some Risk"].Most_relevant_technical_asset
+		},
+		"most relevant communication link of the asset": func(modelInput model.ModelInput) interface{} {
+			return risks(modelInput)["Some Risk"].Most_relevant_communication_link
+		},
+		"most relevant communication link targeting the asset": func(modelInput model.ModelInput) interface{} {
+			return risks(modelInput)["Other Risk"].Most_relevant_communication_link
+		},
+		"data breach technical assets": func(modelInput model.ModelInput) interface{} {
+			return risks(modelInput)["Some Risk"].Data_breach_technical_assets
+		},
+		"invisible connections": func(modelInput model.ModelInput) interface{} {
+			return modelInput.Diagram_tweak_invisible_connections_between_assets
+		},
+		"same rank assets": func(modelInput model.ModelInput) interface{} {
+			return modelInput.Diagram_tweak_same_rank_assets
+		},
+	}
+}
+
+func TestChangeTechnicalAssetID(t *testing.T) {
+	modelInput, references := referencingModelInput(t)
+	changeTechnicalAssetID(&modelInput, "db", "database")
+	for _, test := range []struct {
+		reference, want string
+	}{
+		{"link targets", "map[Database Traffic:{database}]"},
+		{"trust boundaries", "[web database]"},
+		{"shared runtimes", "[database]"},
+		{"most relevant technical asset", "database"},
+		{"most relevant communication link of the asset", "database>web-callback"},
+		{"most relevant communication link targeting the asset", "web>database-traffic"},
+		{"data breach technical assets", "[database web]"},
+		{"invisible connections", "[web:database]"},
+		{"same rank assets", "[web:database]"},
+	} {
+		if got := fmt.Sprintf("%v", linkTargets(references[test.reference](modelInput))); got != test.want {
+			t.Errorf("%v after changing the id = %v, want %v", test.reference, got, test.want)
+		}
+	}
+}
+
+func TestRemoveTechnicalAssetReferences(t *testing.T) {
+	modelInput, references := referencingModelInput(t)
+	delete(modelInput.Technical_assets, "Database")
+	if !removeTechnicalAssetReferences(&modelInput, "db") {
+		t.Errorf("removing the references of the asset reported none deleted")
+	}
+	for _, test := range []struct {
+		reference, want string
+	}{
+		{"link targets", "map[]"},
+		{"trust boundaries", "[web]"},
+		{"shared runtimes", "[]"},
+		{"most relevant technical asset", ""},
+		{"most relevant communication link of the asset", ""},
+		{"most relevant communication link targeting the asset", ""},
+		{"data breach technical assets", "[web]"},
+		{"invisible connections", "[]"},
+		{"same rank assets", "[]"},
+	} {
+		if got := fmt.Sprintf("%v", linkTargets(references[test.reference](modelInput))); got != test.want {
+			t.Errorf("%v after removing the asset = %v, want %v", test.reference, got, test.want)
+		}
+	}
+
+	unchanged, _ := referencingModelInput(t)
+	if removeTechnicalAssetReferences(&unchanged, "unknown") {
+		t.Errorf("removing the references of an unreferenced asset reported some deleted")
+	}
+}
+
+// linkTargets reduces communication links to their targets for comparison
+func linkTargets(value interface{}) interface{} {
+	if links, ok := value.(map[string]model.InputCommunicationLink); ok {
+		result := make(map[string]struct{ target string })
+		for title, link := range links {
+			result[title] = struct{ target string }{link.Target}
+		}
+		return result
+	}
+	return value
+}
+
+func TestPopulateChecksTitleAndID(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		payload payloadSharedRuntime
+		wantOk  bool
+	}{
+		{"valid", payloadSharedRuntime{Title: "Cluster", Id: "cluster", Previous_ids: []string{"old-cluster"}}, true},
+		{"title missing", payloadSharedRuntime{Title: " ", Id: "cluster"}, false},
+		{"id missing", payloadSharedRuntime{Title: "Cluster"}, false},
+		{"invalid id", payloadSharedRuntime{Title: "Cluster", Id: "the cluster"}, false},
+		{"invalid previous id", payloadSharedRuntime{Title: "Cluster", Id: "cluster", Previous_ids: []string{"old cluster"}}, false},
+	} {
+		recorder := httptest.NewRecorder()
+		context, _ := gin.CreateTestContext(recorder)
+		if _, ok := populateSharedRuntime(context, test.payload); ok != test.wantOk || (!ok && recorder.Code != http.StatusBadRequest) {
+			t.Errorf("%v: populateSharedRuntime = %v (status %v), want %v", test.name, ok, recorder.Code, test.wantOk)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	context, _ := gin.CreateTestContext(recorder)
+	if _, ok := populateCommunicationLink(context, model.ModelInput{}, payloadCommunicationLink{Target: "web"}); ok || recorder.Code != http.StatusBadRequest {
+		t.Errorf("communication link without title = %v (status %v), want rejection", ok, recorder.Code)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 type InputCommunicationLink struct {
 	Target                   string   `json:"target"`
+	Previous_ids             []string `json:"previous_ids"`
 	Description              string   `json:"description"`
 	Protocol                 string   `json:"protocol"`
 	Authentication           string   `json:"authentication"`
@@ -27,6 +28,7 @@ type InputCommunicationLink struct {
 }
 type CommunicationLink struct {
 	Id, SourceId, TargetId, Title, Description string
+	PreviousIds                                []string // ids before renaming (the asset or the link), risk tracking of those is migrated
 	Protocol                                   Protocol
 	Tags                                       []string
 	VPN, IpFiltered, Readonly                  bool
@@ -9,6 +9,7 @@ import (
 
 type InputDataAsset struct {
 	ID                       string   `json:"id"`
+	Previous_ids             []string `json:"previous_ids"`
 	Description              string   `json:"description"`
 	Usage                    string   `json:"usage"`
 	Tags                     []string `json:"tags"`
@@ -31,6 +32,7 @@ type DataAsset struct {
 	Confidentiality         confidentiality.Confidentiality
 	Integrity, Availability criticality.Criticality
 	JustificationCiaRating  string
+	PreviousIds             []string // ids before renaming, risk tracking of those is migrated
 }
 
 func (what DataAsset) IsTaggedWithAny(tags ...string) bool {
@@ -0,0 +1,225 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otyg/threagile/model/core"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+type DiagnosticSeverity int
+
+const (
+	ErrorDiagnostic DiagnosticSeverity = iota
+	WarningDiagnostic
+)
+
+func (what DiagnosticSeverity) String() string {
+	return [...]string{"error", "warning"}[what]
+}
+
+func (what DiagnosticSeverity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(what.String())
+}
+
+// Diagnostic is a single problem found in a model, located by its YAML path (and, when known, line and column)
+type Diagnostic struct {
+	Severity DiagnosticSeverity `json:"severity"`
+	File     string             `json:"file,omitempty"` // only set when found in an included fragment
+	Path     string             `json:"path"`
+	Line     int                `json:"line,omitempty"`
+	Column   int                `json:"column,omitempty"`
+	Message  string             `json:"message"`
+	Fix      string             `json:"fix,omitempty"`
+	keys     []string
+}
+
+// Diagnostics collects all problems found in a model. As an error it is only returned when it contains errors (not only warnings).
+type Diagnostics []Diagnostic
+
+func (what Diagnostics) Error() string {
+	lines := make([]string, 0, len(what))
+	for _, diagnostic := range what {
+		lines = append(lines, diagnostic.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (what Diagnostics) HasErrors() bool {
+	for _, diagnostic := range what {
+		if diagnostic.Severity == ErrorDiagnostic {
+			return true
+		}
+	}
+	return false
+}
+
+func (what Diagnostics) Warnings() Diagnostics {
+	result := make(Diagnostics, 0)
+	for _, diagnostic := range what {
+		if diagnostic.Severity == WarningDiagnostic {
+			result = append(result, diagnostic)
+		}
+	}
+	return result
+}
+
+// Format renders the diagnostics compiler-style (file:line:column: severity: message) for the given model file name
+func (what Diagnostics) Format(filename string) string {
+	var result strings.Builder
+	errorCount := 0
+	for _, diagnostic := range what {
+		if len(diagnostic.File) > 0 {
+			result.WriteString(diagnostic.File)
+		} else {
+			result.WriteString(filename)
+		}
+		if diagnostic.Line > 0 {
+			result.WriteString(":" + strconv.Itoa(diagnostic.Line) + ":" + strconv.Itoa(diagnostic.Column))
+		}
+		result.WriteString(": " + diagnostic.String() + "\n")
+		if len(diagnostic.Fix) > 0 {
+			result.WriteString("    fix: " + diagnostic.Fix + "\n")
+		}
+		if diagnostic.Severity == ErrorDiagnostic {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		result.WriteString(fmt.Sprintf("%d error(s), %d warning(s)\n", errorCount, len(what)-errorCount))
+	}
+	return result.String()
+}
+
+func (what Diagnostic) String() string {
+	if len(what.Path) > 0 {
+		return what.Severity.String() + ": " + what.Path + ": " + what.Message
+	}
+	return what.Severity.String() + ": " + what.Message
+}
+
+func (what *Diagnostics) AddError(message, fix string, keys ...string) {
+	what.add(ErrorDiagnostic, message, fix, keys)
+}
+
+func (what *Diagnostics) AddWarning(message, fix string, keys ...string) {
+	what.add(WarningDiagnostic, message, fix, keys)
+}
+
+func (what *Diagnostics) add(severity DiagnosticSeverity, message, fix string, keys []string) {
+	*what = append(*what, Diagnostic{
+		Severity: severity,
+		Path:     strings.Join(keys, "."),
+		Message:  message,
+		Fix:      fix,
+		keys:     append([]string(nil), keys...),
+	})
+}
+
+// Locate fills in line and column of the diagnostics by looking up their YAML path in the model source (or in the
+// included fragment the element came from) and sorts them by position (as the model is mostly processed in random map order)
+func (what Diagnostics) Locate(modelYaml []byte, fragments ...Fragment) {
+	documents := make(map[string]*yamlv3.Node) // parsed sources by file name (empty for the model itself)
+	for i := range what {
+		if what[i].Line > 0 || len(what[i].File) > 0 {
+			continue
+		}
+		source := modelYaml
+		for _, fragment := range fragments {
+			if fragment.contains(what[i].keys) {
+				source = fragment.Yaml
+				what[i].File = fragment.Filename
+				break
+			}
+		}
+		document, parsed := documents[what[i].File]
+		if !parsed {
+			document = &yamlv3.Node{}
+			if err := yamlv3.Unmarshal(source, document); err != nil || len(document.Content) == 0 {
+				document = nil
+			}
+			documents[what[i].File] = document
+		}
+		if document != nil {
+			node := lookupYamlNode(document.Content[0], what[i].keys)
+			what[i].Line, what[i].Column = node.Line, node.Column
+		}
+	}
+	sort.SliceStable(what, func(i, j int) bool {
+		if what[i].File != what[j].File {
+			return what[i].File < what[j].File
+		}
+		if what[i].Line != what[j].Line {
+			return what[i].Line < what[j].Line
+		}
+		if what[i].Column != what[j].Column {
+			return what[i].Column < what[j].Column
+		}
+		return what[i].Path < what[j].Path
+	})
+}
+
+// the deepest node found along the path (so a missing leaf still points at its parent)
+func lookupYamlNode(node *yamlv3.Node, keys []string) *yamlv3.Node {
+	for _, key := range keys {
+		var next *yamlv3.Node
+		switch node.Kind {
+		case yamlv3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					next = node.Content[i]
+					if i+1 < len(node.Content) && (node.Content[i+1].Kind == yamlv3.MappingNode || node.Content[i+1].Kind == yamlv3.SequenceNode) {
+						next = node.Content[i+1]
+						next.Line, next.Column = node.Content[i].Line, node.Content[i].Column
+					}
+					break
+				}
+			}
+		case yamlv3.SequenceNode:
+			if index, err := strconv.Atoi(key); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// DiagnosticsOfYamlError turns the error of unmarshalling a model (which might contain several problems) into diagnostics
+func DiagnosticsOfYamlError(err error) Diagnostics {
+	messages := []string{err.Error()}
+	var typeError *yaml.TypeError
+	if errors.As(err, &typeError) {
+		messages = typeError.Errors
+	}
+	result := make(Diagnostics, 0, len(messages))
+	for _, message := range messages {
+		diagnostic := Diagnostic{Severity: ErrorDiagnostic, Message: message, Fix: "correct the YAML syntax or value type"}
+		if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+			diagnostic.Line, _ = strconv.Atoi(match[1])
+			diagnostic.Message = match[2]
+		}
+		result = append(result, diagnostic)
+	}
+	return result
+}
+
+func fixOneOf(values []core.TypeEnum) string {
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		names = append(names, value.String())
+	}
+	return "use one of: " + strings.Join(names, ", ")
+}
@@ -0,0 +1,250 @@
+package model
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Fragment is a model file merged into the model via "includes"
+type Fragment struct {
+	Filename string
+	Yaml     []byte
+	elements map[string]bool // section and title (or risk id) of the elements merged from this fragment
+}
+
+func (what Fragment) contains(keys []string) bool {
+	return len(keys) >= 2 && what.elements[keys[0]+"\x00"+keys[1]]
+}
+
+type includeResolver struct {
+	modelInput     *ModelInput
+	restrictToDir  string
+	visited        map[string]bool
+	fragments      []Fragment
+	diagnostics    Diagnostics
+	originsByTitle map[string]string // section and title --> file name
+	originsById    map[string]string // section and id --> file name
+	titlesById     map[string]string // section and id --> title
+}
+
+// ResolveIncludes loads the fragments referenced by "includes" (file paths or glob patterns relative to the including file, nested includes
+// are resolved as well) and merges their data assets, technical assets, trust boundaries, shared runtimes, risk tracking and available tags
+// into the model input. Conflicting (duplicate) titles and ids are reported along with the files defining them.
+// When restrictToModelDirectory is set, fragments outside the directory of the model file are refused (like for uploaded models).
+func ResolveIncludes(modelInput *ModelInput, modelFilename string, restrictToModelDirectory bool) ([]Fragment, Diagnostics) {
+	if len(modelFilename) == 0 {
+		modelFilename = "threagile.yaml"
+	}
+	resolver := includeResolver{
+		modelInput:     modelInput,
+		visited:        make(map[string]bool),
+		fragments:      make([]Fragment, 0),
+		diagnostics:    make(Diagnostics, 0),
+		originsByTitle: make(map[string]string),
+		originsById:    make(map[string]string),
+		titlesById:     make(map[string]string),
+	}
+	modelDirectory, err := realPath(filepath.Dir(modelFilename))
+	if err != nil {
+		resolver.diagnostics.AddError("unable to determine directory of model file: "+err.Error(), "", "includes")
+		return resolver.fragments, resolver.diagnostics
+	}
+	if restrictToModelDirectory {
+		resolver.restrictToDir = modelDirectory
+	}
+	if absoluteFilename, err := realPath(modelFilename); err == nil {
+		resolver.visited[absoluteFilename] = true
+	}
+	resolver.register(*modelInput, modelFilename)
+	resolver.include(modelInput.Includes, filepath.Dir(modelFilename), nil)
+	return resolver.fragments, resolver.diagnostics
+}
+
+// realPath is the absolute path with all symbolic links resolved, so that links can't point outside of the model directory
+func realPath(filename string) (string, error) {
+	absoluteFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(absoluteFilename)
+}
+
+// include the files matching the patterns, the including fragment is nil for the model itself
+func (what *includeResolver) include(patterns []string, directory string, includingFragment *Fragment) {
+	for i, pattern := range patterns {
+		keys := []string{"includes", strconv.Itoa(i)}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(directory, pattern)
+		}
+		filenames, err := filepath.Glob(pattern)
+		if err != nil {
+			what.addError(includingFragment, "invalid include pattern: "+err.Error(), "", keys...)
+			continue
+		}
+		if len(filenames) == 0 {
+			what.addError(includingFragment, "include does not match any file: "+patterns[i], "correct the path (relative to the including file)", keys...)
+			continue
+		}
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			what.includeFile(filename, includingFragment, keys)
+		}
+	}
+}
+
+func (what *includeResolver) includeFile(filename string, includingFragment *Fragment, keys []string) {
+	absoluteFilename, err := realPath(filename)
+	if err != nil {
+		what.addError(includingFragment, "unable to resolve include: "+err.Error(), "", keys...)
+		return
+	}
+	if len(what.restrictToDir) > 0 && !strings.HasPrefix(absoluteFilename, what.restrictToDir+string(filepath.Separator)) {
+		what.addError(includingFragment, "include outside of the model directory is not allowed: "+filename, "only include files within the directory of the model", keys...)
+		return
+	}
+	if what.visited[absoluteFilename] {
+		return // already merged (or including itself)
+	}
+	what.visited[absoluteFilename] = true
+
+	fragmentYaml, err := ioutil.ReadFile(absoluteFilename)
+	if err != nil {
+		what.addError(includingFragment, "unable to read include: "+err.Error(), "", keys...)
+		return
+	}
+	fragment := Fragment{Filename: filename, Yaml: fragmentYaml, elements: make(map[string]bool)}
+	fragmentInput := ModelInput{}
+	if err := yaml.Unmarshal(fragmentYaml, &fragmentInput); err != nil {
+		for _, diagnostic := range DiagnosticsOfYamlError(err) {
+			diagnostic.File = filename
+			what.diagnostics = append(what.diagnostics, diagnostic)
+		}
+		return
+	}
+
+	fragmentDiagnostics := make(Diagnostics, 0)
+	what.merge(fragmentInput, &fragment, &fragmentDiagnostics)
+	fragmentDiagnostics.Locate(fragmentYaml)
+	for _, diagnostic := range fragmentDiagnostics {
+		diagnostic.File = filename
+		what.diagnostics = append(what.diagnostics, diagnostic)
+	}
+	what.fragments = append(what.fragments, fragment)
+
+	// nested includes are resolved relative to the fragment
+	what.include(fragmentInput.Includes, filepath.Dir(filename), &fragment)
+}
+
+// problems of the model itself are located later on (together with the other diagnostics of the model)
+func (what *includeResolver) addError(includingFragment *Fragment, message, fix string, keys ...string) {
+	if includingFragment == nil {
+		what.diagnostics.AddError(message, fix, keys...)
+		return
+	}
+	diagnostics := make(Diagnostics, 0, 1)
+	diagnostics.AddError(message, fix, keys...)
+	diagnostics.Locate(includingFragment.Yaml)
+	diagnostics[0].File = includingFragment.Filename
+	what.diagnostics = append(what.diagnostics, diagnostics[0])
+}
+
+// register the elements of the model itself as origins to detect conflicts with fragments
+func (what *includeResolver) register(modelInput ModelInput, origin string) {
+	for title, asset := range modelInput.Data_assets {
+		what.originsByTitle["data_assets\x00"+title] = origin
+		what.registerId("data_assets", asset.ID, title, origin)
+	}
+	for title, asset := range modelInput.Technical_assets {
+		what.originsByTitle["technical_assets\x00"+title] = origin
+		what.registerId("technical_assets", asset.ID, title, origin)
+	}
+	for title, boundary := range modelInput.Trust_boundaries {
+		what.originsByTitle["trust_boundaries\x00"+title] = origin
+		what.registerId("trust_boundaries", boundary.ID, title, origin)
+	}
+	for title, runtime := range modelInput.Shared_runtimes {
+		what.originsByTitle["shared_runtimes\x00"+title] = origin
+		what.registerId("shared_runtimes", runtime.ID, title, origin)
+	}
+	for syntheticRiskId := range modelInput.Risk_tracking {
+		what.originsByTitle["risk_tracking\x00"+syntheticRiskId] = origin
+	}
+}
+
+func (what *includeResolver) registerId(section, id, title, origin string) {
+	what.originsById[section+"\x00"+id] = origin
+	what.titlesById[section+"\x00"+id] = title
+}
+
+func (what *includeResolver) merge(fragmentInput ModelInput, fragment *Fragment, diagnostics *Diagnostics) {
+	for title, asset := range fragmentInput.Data_assets {
+		if what.checkConflicts("data_assets", "data asset", title, asset.ID, fragment, diagnostics) {
+			if what.modelInput.Data_assets == nil {
+				what.modelInput.Data_assets = make(map[string]InputDataAsset)
+			}
+			what.modelInput.Data_assets[title] = asset
+		}
+	}
+	for title, asset := range fragmentInput.Technical_assets {
+		if what.checkConflicts("technical_assets", "technical asset", title, asset.ID, fragment, diagnostics) {
+			if what.modelInput.Technical_assets == nil {
+				what.modelInput.Technical_assets = make(map[string]InputTechnicalAsset)
+			}
+			what.modelInput.Technical_assets[title] = asset
+		}
+	}
+	for title, boundary := range fragmentInput.Trust_boundaries {
+		if what.checkConflicts("trust_boundaries", "trust boundary", title, boundary.ID, fragment, diagnostics) {
+			if what.modelInput.Trust_boundaries == nil {
+				what.modelInput.Trust_boundaries = make(map[string]InputTrustBoundary)
+			}
+			what.modelInput.Trust_boundaries[title] = boundary
+		}
+	}
+	for title, runtime := range fragmentInput.Shared_runtimes {
+		if what.checkConflicts("shared_runtimes", "shared runtime", title, runtime.ID, fragment, diagnostics) {
+			if what.modelInput.Shared_runtimes == nil {
+				what.modelInput.Shared_runtimes = make(map[string]InputSharedRuntime)
+			}
+			what.modelInput.Shared_runtimes[title] = runtime
+		}
+	}
+	for syntheticRiskId, riskTracking := range fragmentInput.Risk_tracking {
+		if what.checkConflicts("risk_tracking", "risk tracking", syntheticRiskId, "", fragment, diagnostics) {
+			if what.modelInput.Risk_tracking == nil {
+				what.modelInput.Risk_tracking = make(map[string]InputRiskTracking)
+			}
+			what.modelInput.Risk_tracking[syntheticRiskId] = riskTracking
+		}
+	}
+	for _, tag := range fragmentInput.Tags_available {
+		if !Contains(what.modelInput.Tags_available, tag) {
+			what.modelInput.Tags_available = append(what.modelInput.Tags_available, tag)
+		}
+	}
+}
+
+// checkConflicts reports duplicate titles (or risk ids) and duplicate ids and registers the element when there are none
+func (what *includeResolver) checkConflicts(section, kind, title, id string, fragment *Fragment, diagnostics *Diagnostics) bool {
+	if origin, exists := what.originsByTitle[section+"\x00"+title]; exists {
+		diagnostics.AddError("duplicate "+kind+" '"+title+"' (already defined in "+origin+")",
+			"rename or remove one of them", section, title)
+		return false
+	}
+	if len(id) > 0 {
+		if origin, exists := what.originsById[section+"\x00"+id]; exists {
+			diagnostics.AddError("duplicate "+kind+" id '"+id+"' (already used by '"+what.titlesById[section+"\x00"+id]+"' defined in "+origin+")",
+				"use a unique id", section, title, "id")
+			return false
+		}
+		what.registerId(section, id, title, fragment.Filename)
+	}
+	what.originsByTitle[section+"\x00"+title] = fragment.Filename
+	fragment.elements[section+"\x00"+title] = true
+	return true
+}
@@ -8,6 +8,7 @@ import (
 
 type ModelInput struct { // TODO: Eventually remove this and directly use ParsedModelRoot? But then the error messages for model errors are not quite as good anymore...
 	Threagile_version                                  string
+	Includes                                           []string
 	Title                                              string
 	Author                                             Author
 	Date                                               string
@@ -53,6 +54,7 @@ type ParsedModel struct {
 	SharedRuntimes                                map[string]SharedRuntime
 	IndividualRiskCategories                      map[string]RiskCategory
 	RiskTracking                                  map[string]RiskTracking
+	OrphanedRiskTracking                          map[string]RiskTracking // not matching any risk (keyed by synthetic risk id or wildcard pattern), set by the analysis
 	DiagramTweakNodesep, DiagramTweakRanksep      int
 	DiagramTweakEdgeLayout                        string
 	DiagramTweakSuppressEdgeLabels                bool
@@ -5,38 +5,49 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/otyg/threagile/model/confidentiality"
+	"github.com/otyg/threagile/model/core"
 	"github.com/otyg/threagile/model/criticality"
 	"github.com/otyg/threagile/support"
 	"gopkg.in/yaml.v2"
 )
 
-func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[string]RiskTracking) ParsedModel {
+// ParseModel parses the model and collects all problems found as diagnostics (located in the model source)
+func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[string]RiskTracking) (ParsedModel, Diagnostics) {
 	modelInput := ModelInput{}
-	var yamlError error = yaml.Unmarshal(modelYaml, &modelInput)
-	support.CheckErr(yamlError)
+	if err := yaml.Unmarshal(modelYaml, &modelInput); err != nil {
+		return ParsedModel{}, DiagnosticsOfYamlError(err)
+	}
+	parsedModel, diagnostics := ParseModelInput(modelInput, deferredRiskTrackingDueToWildcardMatching)
+	diagnostics.Locate(modelYaml)
+	return parsedModel, diagnostics
+}
+
+func ParseModelInput(modelInput ModelInput, deferredRiskTrackingDueToWildcardMatching map[string]RiskTracking) (ParsedModel, Diagnostics) {
+	diagnostics := make(Diagnostics, 0)
 	if ThreagileVersion != "test" {
-		model_version, error := version.NewVersion(modelInput.Threagile_version)
-		support.CheckErr(error)
-		main_version, error := version.NewVersion(ThreagileVersion)
-		support.CheckErr(error)
-		if model_version.GreaterThan(main_version) {
-			panic("Model file is created for a newer version of threagile: " + main_version.Original())
+		model_version, err := version.NewVersion(modelInput.Threagile_version)
+		if err != nil {
+			diagnostics.AddError("unable to parse version: "+err.Error(), "use a version like "+ThreagileVersion, "threagile_version")
+		} else if main_version, err := version.NewVersion(ThreagileVersion); err == nil && model_version.GreaterThan(main_version) {
+			diagnostics.AddError("model file is created for a newer version of threagile: "+main_version.Original(), "update threagile or lower the version of the model", "threagile_version")
 		}
 	}
 
 	var businessCriticality, err = criticality.ParseCriticality(withDefault(modelInput.Business_criticality, "unknown"))
-	support.CheckErr(err)
+	checkValue(err, criticality.CriticalityValues(), &diagnostics, "business_criticality")
 
 	reportDate := time.Now()
 	if len(modelInput.Date) > 0 {
 		reportDate, err = time.Parse("2006-01-02", modelInput.Date)
 		if err != nil {
-			panic(errors.New("unable to parse 'date' value of model file"))
+			diagnostics.AddError("unable to parse 'date' value of model file: "+modelInput.Date, "use the format YYYY-MM-DD", "date")
 		}
 	}
 
@@ -74,27 +85,28 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 		id := fmt.Sprintf("%v", asset.ID)
 
 		usage, err := ParseUsage(withDefault(asset.Usage, getDefaultIfPresent("usage")))
-		support.CheckErr(err)
+		checkValue(err, UsageValues(), &diagnostics, "data_assets", title, "usage")
 		quantity, err := ParseQuantity(asset.Quantity)
-		support.CheckErr(err)
+		checkValue(err, QuantityValues(), &diagnostics, "data_assets", title, "quantity")
 		confidentiality, err := confidentiality.ParseConfidentiality(withDefault(asset.Confidentiality, getDefaultIfPresent("confidentiality")))
-		support.CheckErr(err)
+		checkValue(err, confidentialityValues, &diagnostics, "data_assets", title, "confidentiality")
 		integrity, err := criticality.ParseCriticality(withDefault(asset.Integrity, getDefaultIfPresent("integrity")))
-		support.CheckErr(err)
+		checkValue(err, criticality.CriticalityValues(), &diagnostics, "data_assets", title, "integrity")
 		availability, err := criticality.ParseCriticality(withDefault(asset.Availability, getDefaultIfPresent("availability")))
-		support.CheckErr(err)
+		checkValue(err, criticality.CriticalityValues(), &diagnostics, "data_assets", title, "availability")
 
-		support.CheckIdSyntax(id)
+		checkId(id, &diagnostics, "data_assets", title, "id")
 		if _, exists := ParsedModelRoot.DataAssets[id]; exists {
-			panic(errors.New("duplicate id used: " + id))
+			diagnostics.AddError("duplicate id used: "+id, "use a unique id", "data_assets", title, "id")
 		}
 		ParsedModelRoot.DataAssets[id] = DataAsset{
 			Id:                     id,
 			Title:                  title,
+			PreviousIds:            asset.Previous_ids,
 			Usage:                  usage,
 			Description:            withDefault(fmt.Sprintf("%v", asset.Description), title),
 			Quantity:               quantity,
-			Tags:                   checkTags(support.LowerCaseAndTrim(asset.Tags), "data asset '"+title+"'"),
+			Tags:                   checkTags(support.LowerCaseAndTrim(asset.Tags), &diagnostics, "data_assets", title, "tags"),
 			Origin:                 fmt.Sprintf("%v", asset.Origin),
 			Owner:                  fmt.Sprintf("%v", asset.Owner),
 			Confidentiality:        confidentiality,
@@ -110,14 +122,14 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 		id := fmt.Sprintf("%v", asset.ID)
 
 		usage, err := ParseUsage(withDefault(asset.Usage, getDefaultIfPresent("usage")))
-		support.CheckErr(err)
+		checkValue(err, UsageValues(), &diagnostics, "technical_assets", title, "usage")
 
 		var dataAssetsProcessed = make([]string, 0)
 		if asset.Data_assets_processed != nil {
 			dataAssetsProcessed = make([]string, len(asset.Data_assets_processed))
 			for i, parsedProcessedAsset := range asset.Data_assets_processed {
 				referencedAsset := fmt.Sprintf("%v", parsedProcessedAsset)
-				checkDataAssetTargetExists(referencedAsset, "technical asset '"+title+"'")
+				checkDataAssetTargetExists(referencedAsset, &diagnostics, "technical_assets", title, "data_assets_processed", strconv.Itoa(i))
 				dataAssetsProcessed[i] = referencedAsset
 			}
 		}
@@ -127,38 +139,38 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 			dataAssetsStored = make([]string, len(asset.Data_assets_stored))
 			for i, parsedStoredAssets := range asset.Data_assets_stored {
 				referencedAsset := fmt.Sprintf("%v", parsedStoredAssets)
-				checkDataAssetTargetExists(referencedAsset, "technical asset '"+title+"'")
+				checkDataAssetTargetExists(referencedAsset, &diagnostics, "technical_assets", title, "data_assets_stored", strconv.Itoa(i))
 				dataAssetsStored[i] = referencedAsset
 			}
 		}
 
 		technicalAssetType, err := ParseTechnicalAssetType(withDefault(asset.Type, getDefaultIfPresent("technical_asset_type")))
-		support.CheckErr(err)
+		checkValue(err, TechnicalAssetTypeValues(), &diagnostics, "technical_assets", title, "type")
 
 		technicalAssetSize, err := ParseTechnicalAssetSize(withDefault(asset.Size, getDefaultIfPresent("technical_asset_size")))
-		support.CheckErr(err)
+		checkValue(err, TechnicalAssetSizeValues(), &diagnostics, "technical_assets", title, "size")
 
 		technicalAssetTechnology, err := ParseTechnicalAssetTechnology(asset.Technology)
-		support.CheckErr(err)
+		checkValue(err, TechnicalAssetTechnologyValues(), &diagnostics, "technical_assets", title, "technology")
 
 		encryption, err := ParseEncryptionStyle(withDefault(asset.Encryption, getDefaultIfPresent("technical_asset_encryption")))
-		support.CheckErr(err)
+		checkValue(err, EncryptionStyleValues(), &diagnostics, "technical_assets", title, "encryption")
 
 		technicalAssetMachine, err := ParseTechnicalAssetMachine(withDefault(asset.Machine, getDefaultIfPresent("technical_asset_machine")))
-		support.CheckErr(err)
+		checkValue(err, TechnicalAssetMachineValues(), &diagnostics, "technical_assets", title, "machine")
 
 		confidentiality, err := confidentiality.ParseConfidentiality(withDefault(asset.Confidentiality, getDefaultIfPresent("confidentiality")))
-		support.CheckErr(err)
+		checkValue(err, confidentialityValues, &diagnostics, "technical_assets", title, "confidentiality")
 		integrity, err := criticality.ParseCriticality(withDefault(asset.Integrity, getDefaultIfPresent("integrity")))
-		support.CheckErr(err)
+		checkValue(err, criticality.CriticalityValues(), &diagnostics, "technical_assets", title, "integrity")
 		availability, err := criticality.ParseCriticality(withDefault(asset.Availability, getDefaultIfPresent("availability")))
-		support.CheckErr(err)
+		checkValue(err, criticality.CriticalityValues(), &diagnostics, "technical_assets", title, "availability")
 
 		dataFormatsAccepted := make([]DataFormat, 0)
 		if asset.Data_formats_accepted != nil {
-			for _, dataFormatName := range asset.Data_formats_accepted {
+			for i, dataFormatName := range asset.Data_formats_accepted {
 				parsedDataFormat, err := ParseDataFormatName(dataFormatName)
-				support.CheckErr(err)
+				checkValue(err, DataFormatValues(), &diagnostics, "technical_assets", title, "data_formats_accepted", strconv.Itoa(i))
 				dataFormatsAccepted = append(dataFormatsAccepted, parsedDataFormat)
 			}
 		}
@@ -169,28 +181,28 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 				constraint := true
 				weight := 1
 				authentication, err := ParseAuthentication(withDefault(commLink.Authentication, getDefaultIfPresent("authentication")))
-				support.CheckErr(err)
+				checkValue(err, AuthenticationValues(), &diagnostics, "technical_assets", title, "communication_links", commLinkTitle, "authentication")
 				authorization, err := ParseAuthorization(withDefault(commLink.Authorization, getDefaultIfPresent("authorization")))
-				support.CheckErr(err)
+				checkValue(err, AuthorizationValues(), &diagnostics, "technical_assets", title, "communication_links", commLinkTitle, "authorization")
 				usage, err := ParseUsage(withDefault(asset.Usage, getDefaultIfPresent("usage")))
-				support.CheckErr(err)
+				checkValue(err, UsageValues(), &diagnostics, "technical_assets", title, "communication_links", commLinkTitle, "usage")
 				protocol, err := ParseProtocol(withDefault(commLink.Protocol, getDefaultIfPresent("protocol")))
-				support.CheckErr(err)
+				checkValue(err, ProtocolValues(), &diagnostics, "technical_assets", title, "communication_links", commLinkTitle, "protocol")
 				var dataAssetsSent []string
 				var dataAssetsReceived []string
 
 				if commLink.Data_assets_sent != nil {
-					for _, dataAssetSent := range commLink.Data_assets_sent {
+					for i, dataAssetSent := range commLink.Data_assets_sent {
 						referencedAsset := fmt.Sprintf("%v", dataAssetSent)
-						checkDataAssetTargetExists(referencedAsset, "communication link '"+commLinkTitle+"' of technical asset '"+title+"'")
+						checkDataAssetTargetExists(referencedAsset, &diagnostics, "technical_assets", title, "communication_links", commLinkTitle, "data_assets_sent", strconv.Itoa(i))
 						dataAssetsSent = append(dataAssetsSent, referencedAsset)
 					}
 				}
 
 				if commLink.Data_assets_received != nil {
-					for _, dataAssetReceived := range commLink.Data_assets_received {
+					for i, dataAssetReceived := range commLink.Data_assets_received {
 						referencedAsset := fmt.Sprintf("%v", dataAssetReceived)
-						checkDataAssetTargetExists(referencedAsset, "communication link '"+commLinkTitle+"' of technical asset '"+title+"'")
+						checkDataAssetTargetExists(referencedAsset, &diagnostics, "technical_assets", title, "communication_links", commLinkTitle, "data_assets_received", strconv.Itoa(i))
 						dataAssetsReceived = append(dataAssetsReceived, referencedAsset)
 					}
 				}
@@ -201,11 +213,10 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 
 				constraint = !commLink.Diagram_tweak_constraint
 
-				support.CheckErr(err)
-
 				dataFlowTitle := fmt.Sprintf("%v", commLinkTitle)
 				commLink := CommunicationLink{
 					Id:                     createDataFlowId(id, dataFlowTitle),
+					PreviousIds:            previousIdsOfDataFlow(asset.Previous_ids, commLink.Previous_ids, dataFlowTitle),
 					SourceId:               id,
 					TargetId:               commLink.Target,
 					Title:                  dataFlowTitle,
@@ -214,7 +225,7 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 					Authentication:         authentication,
 					Authorization:          authorization,
 					Usage:                  usage,
-					Tags:                   checkTags(support.LowerCaseAndTrim(commLink.Tags), "communication link '"+commLinkTitle+"' of technical asset '"+title+"'"),
+					Tags:                   checkTags(support.LowerCaseAndTrim(commLink.Tags), &diagnostics, "technical_assets", title, "communication_links", commLinkTitle, "tags"),
 					VPN:                    commLink.VPN,
 					IpFiltered:             commLink.IP_filtered,
 					Readonly:               commLink.Readonly,
@@ -232,19 +243,20 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 			}
 		}
 
-		support.CheckIdSyntax(id)
+		checkId(id, &diagnostics, "technical_assets", title, "id")
 		if _, exists := ParsedModelRoot.TechnicalAssets[id]; exists {
-			panic(errors.New("duplicate id used: " + id))
+			diagnostics.AddError("duplicate id used: "+id, "use a unique id", "technical_assets", title, "id")
 		}
 		ParsedModelRoot.TechnicalAssets[id] = TechnicalAsset{
 			Id:                      id,
+			PreviousIds:             asset.Previous_ids,
 			Usage:                   usage,
 			Title:                   title, //fmt.Sprintf("%v", asset["title"]),
 			Description:             withDefault(fmt.Sprintf("%v", asset.Description), title),
 			Type:                    technicalAssetType,
 			Size:                    technicalAssetSize,
 			Technology:              technicalAssetTechnology,
-			Tags:                    checkTags(support.LowerCaseAndTrim(asset.Tags), "technical asset '"+title+"'"),
+			Tags:                    checkTags(support.LowerCaseAndTrim(asset.Tags), &diagnostics, "technical_assets", title, "tags"),
 			Machine:                 technicalAssetMachine,
 			Internet:                asset.Internet,
 			Encryption:              encryption,
@@ -279,12 +291,11 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 			technicalAssetsInside = make([]string, len(parsedInsideAssets))
 			for i, parsedInsideAsset := range parsedInsideAssets {
 				technicalAssetsInside[i] = fmt.Sprintf("%v", parsedInsideAsset)
-				_, found := ParsedModelRoot.TechnicalAssets[technicalAssetsInside[i]]
-				if !found {
-					panic(errors.New("missing referenced technical asset " + technicalAssetsInside[i] + " at trust boundary '" + title + "'"))
-				}
+				checkTechnicalAssetExists(technicalAssetsInside[i], &diagnostics, "trust_boundaries", title, "technical_assets_inside", strconv.Itoa(i))
 				if checklistToAvoidAssetBeingModeledInMultipleTrustBoundaries[technicalAssetsInside[i]] {
-					panic(errors.New("referenced technical asset " + technicalAssetsInside[i] + " at trust boundary '" + title + "' is modeled in multiple trust boundaries"))
+					diagnostics.AddError("referenced technical asset "+technicalAssetsInside[i]+" is modeled in multiple trust boundaries",
+						"place the technical asset inside one trust boundary only (use nested trust boundaries instead)",
+						"trust_boundaries", title, "technical_assets_inside", strconv.Itoa(i))
 				}
 				checklistToAvoidAssetBeingModeledInMultipleTrustBoundaries[technicalAssetsInside[i]] = true
 				//fmt.Println("asset "+technicalAssetsInside[i]+" at i="+strconv.Itoa(i))
@@ -301,19 +312,20 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 		}
 
 		trustBoundaryType, err := ParseTrustBoundaryType(boundary.Type)
-		support.CheckErr(err)
+		checkValue(err, TrustBoundaryTypeValues(), &diagnostics, "trust_boundaries", title, "type")
 		trustBoundary := TrustBoundary{
 			Id:                    id,
+			PreviousIds:           boundary.Previous_ids,
 			Title:                 title, //fmt.Sprintf("%v", boundary["title"]),
 			Description:           withDefault(fmt.Sprintf("%v", boundary.Description), title),
 			Type:                  trustBoundaryType,
-			Tags:                  checkTags(support.LowerCaseAndTrim(boundary.Tags), "trust boundary '"+title+"'"),
+			Tags:                  checkTags(support.LowerCaseAndTrim(boundary.Tags), &diagnostics, "trust_boundaries", title, "tags"),
 			TechnicalAssetsInside: technicalAssetsInside,
 			TrustBoundariesNested: trustBoundariesNested,
 		}
-		support.CheckIdSyntax(id)
+		checkId(id, &diagnostics, "trust_boundaries", title, "id")
 		if _, exists := ParsedModelRoot.TrustBoundaries[id]; exists {
-			panic(errors.New("duplicate id used: " + id))
+			diagnostics.AddError("duplicate id used: "+id, "use a unique id", "trust_boundaries", title, "id")
 		}
 		ParsedModelRoot.TrustBoundaries[id] = trustBoundary
 		for _, technicalAsset := range trustBoundary.TechnicalAssetsInside {
@@ -321,7 +333,8 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 			//fmt.Println("Asset "+technicalAsset+" is directly in trust boundary "+trustBoundary.Id)
 		}
 	}
-	checkNestedTrustBoundariesExisting()
+	checkNestedTrustBoundariesExisting(modelInput, &diagnostics)
+	checkPreviousIds(modelInput, &diagnostics)
 
 	// Shared Runtime ===============================================================================
 	ParsedModelRoot.SharedRuntimes = make(map[string]SharedRuntime)
@@ -334,7 +347,7 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 			technicalAssetsRunning = make([]string, len(parsedRunningAssets))
 			for i, parsedRunningAsset := range parsedRunningAssets {
 				assetId := fmt.Sprintf("%v", parsedRunningAsset)
-				CheckTechnicalAssetExists(assetId, "shared runtime '"+title+"'", false)
+				checkTechnicalAssetExists(assetId, &diagnostics, "shared_runtimes", title, "technical_assets_running", strconv.Itoa(i))
 				technicalAssetsRunning[i] = assetId
 			}
 		}
@@ -343,12 +356,13 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 			Id:                     id,
 			Title:                  title, //fmt.Sprintf("%v", boundary["title"]),
 			Description:            withDefault(fmt.Sprintf("%v", runtime.Description), title),
-			Tags:                   checkTags((runtime.Tags), "shared runtime '"+title+"'"),
+			PreviousIds:            runtime.Previous_ids,
+			Tags:                   checkTags((runtime.Tags), &diagnostics, "shared_runtimes", title, "tags"),
 			TechnicalAssetsRunning: technicalAssetsRunning,
 		}
-		support.CheckIdSyntax(id)
+		checkId(id, &diagnostics, "shared_runtimes", title, "id")
 		if _, exists := ParsedModelRoot.SharedRuntimes[id]; exists {
-			panic(errors.New("duplicate id used: " + id))
+			diagnostics.AddError("duplicate id used: "+id, "use a unique id", "shared_runtimes", title, "id")
 		}
 		ParsedModelRoot.SharedRuntimes[id] = sharedRuntime
 		for _, technicalAssetId := range sharedRuntime.TechnicalAssetsRunning {
@@ -362,10 +376,10 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 		id := fmt.Sprintf("%v", indivCat.ID)
 
 		function, err := ParseRiskFunction(indivCat.Function)
-		support.CheckErr(err)
+		checkValue(err, RiskFunctionValues(), &diagnostics, "individual_risk_categories", title, "function")
 
 		stride, err := ParseStride(indivCat.STRIDE)
-		support.CheckErr(err)
+		checkValue(err, STRIDEValues(), &diagnostics, "individual_risk_categories", title, "stride")
 
 		cat := RiskCategory{
 			Id:                         id,
@@ -386,63 +400,63 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 			ModelFailurePossibleReason: indivCat.Model_failure_possible_reason,
 			CWE:                        indivCat.CWE,
 		}
-		support.CheckIdSyntax(id)
+		checkId(id, &diagnostics, "individual_risk_categories", title, "id")
 		if _, exists := ParsedModelRoot.IndividualRiskCategories[id]; exists {
-			panic(errors.New("duplicate id used: " + id))
+			diagnostics.AddError("duplicate id used: "+id, "use a unique id", "individual_risk_categories", title, "id")
 		}
 		ParsedModelRoot.IndividualRiskCategories[id] = cat
 
 		// NOW THE INDIVIDUAL RISK INSTANCES:
 		//individualRiskInstances := make([]Risk, 0)
-		if indivCat.Risks_identified != nil { // TODO: also add syntax checks of input YAML when linked asset is not found or when syntehtic-id is already used...
+		if indivCat.Risks_identified != nil { // TODO: also add syntax checks of input YAML when syntehtic-id is already used...
+			categoryTitle := title
 			for title, indivRiskInstance := range indivCat.Risks_identified {
+				keys := []string{"individual_risk_categories", categoryTitle, "risks_identified", title}
 				severity, err := ParseRiskSeverity(indivRiskInstance.Severity)
-				support.CheckErr(err)
+				checkValue(err, RiskSeverityValues(), &diagnostics, append(keys, "severity")...)
 				exploitationLikelihood, err := ParseRiskExploitationLikelihood(indivRiskInstance.Exploitation_likelihood)
-				support.CheckErr(err)
+				checkValue(err, RiskExploitationLikelihoodValues(), &diagnostics, append(keys, "exploitation_likelihood")...)
 				exploitationImpact, err := ParseRiskExploitationImpact(indivRiskInstance.Exploitation_impact)
-				support.CheckErr(err)
+				checkValue(err, RiskExploitationImpactValues(), &diagnostics, append(keys, "exploitation_impact")...)
 				dataBreachProbability, err := ParseDataBreachProbability(indivRiskInstance.Data_breach_probability)
-				support.CheckErr(err)
+				checkValue(err, DataBreachProbabilityValues(), &diagnostics, append(keys, "data_breach_probability")...)
 				var mostRelevantDataAssetId, mostRelevantTechnicalAssetId, mostRelevantCommunicationLinkId, mostRelevantTrustBoundaryId, mostRelevantSharedRuntimeId string
 				var dataBreachTechnicalAssetIDs []string
 
 				if len(indivRiskInstance.Most_relevant_data_asset) > 0 {
 					mostRelevantDataAssetId = fmt.Sprintf("%v", indivRiskInstance.Most_relevant_data_asset)
-					checkDataAssetTargetExists(mostRelevantDataAssetId, "individual risk '"+title+"'")
+					checkDataAssetTargetExists(mostRelevantDataAssetId, &diagnostics, append(keys, "most_relevant_data_asset")...)
 				}
 
 				if len(indivRiskInstance.Most_relevant_technical_asset) > 0 {
 					mostRelevantTechnicalAssetId = fmt.Sprintf("%v", indivRiskInstance.Most_relevant_technical_asset)
-					CheckTechnicalAssetExists(mostRelevantTechnicalAssetId, "individual risk '"+title+"'", false)
+					checkTechnicalAssetExists(mostRelevantTechnicalAssetId, &diagnostics, append(keys, "most_relevant_technical_asset")...)
 				}
 
 				if len(indivRiskInstance.Most_relevant_communication_link) > 0 {
 					mostRelevantCommunicationLinkId = fmt.Sprintf("%v", indivRiskInstance.Most_relevant_communication_link)
-					checkCommunicationLinkExists(mostRelevantCommunicationLinkId, "individual risk '"+title+"'")
+					checkCommunicationLinkExists(mostRelevantCommunicationLinkId, &diagnostics, append(keys, "most_relevant_communication_link")...)
 				}
 
 				if len(indivRiskInstance.Most_relevant_trust_boundary) > 0 {
 					mostRelevantTrustBoundaryId = fmt.Sprintf("%v", indivRiskInstance.Most_relevant_trust_boundary)
-					checkTrustBoundaryExists(mostRelevantTrustBoundaryId, "individual risk '"+title+"'")
+					checkTrustBoundaryExists(mostRelevantTrustBoundaryId, &diagnostics, append(keys, "most_relevant_trust_boundary")...)
 				}
 
 				if len(indivRiskInstance.Most_relevant_shared_runtime) > 0 {
 					mostRelevantSharedRuntimeId = fmt.Sprintf("%v", indivRiskInstance.Most_relevant_shared_runtime)
-					checkSharedRuntimeExists(mostRelevantSharedRuntimeId, "individual risk '"+title+"'")
+					checkSharedRuntimeExists(mostRelevantSharedRuntimeId, &diagnostics, append(keys, "most_relevant_shared_runtime")...)
 				}
 
 				if indivRiskInstance.Data_breach_technical_assets != nil {
 					dataBreachTechnicalAssetIDs = make([]string, len(indivRiskInstance.Data_breach_technical_assets))
 					for i, parsedReferencedAsset := range indivRiskInstance.Data_breach_technical_assets {
 						assetId := fmt.Sprintf("%v", parsedReferencedAsset)
-						CheckTechnicalAssetExists(assetId, "data breach technical assets of individual risk '"+title+"'", false)
+						checkTechnicalAssetExists(assetId, &diagnostics, append(keys, "data_breach_technical_assets", strconv.Itoa(i))...)
 						dataBreachTechnicalAssetIDs[i] = assetId
 					}
 				}
 
-				support.CheckErr(err)
-
 				indivRiskInstance := Risk{
 					SyntheticId:                     createSyntheticId(cat.Id, mostRelevantDataAssetId, mostRelevantTechnicalAssetId, mostRelevantCommunicationLinkId, mostRelevantTrustBoundaryId, mostRelevantSharedRuntimeId),
 					Title:                           fmt.Sprintf("%v", title),
@@ -465,7 +479,25 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 
 	// Risk Tracking ===============================================================================
 	ParsedModelRoot.RiskTracking = make(map[string]RiskTracking)
+	ParsedModelRoot.OrphanedRiskTracking = make(map[string]RiskTracking)
+	currentIdsByPreviousId := CurrentIdsByPreviousId(ParsedModelRoot)
+	trackingIdsByMigratedId := RiskTrackingIdsByMigratedId(riskTrackingIds(modelInput.Risk_tracking), currentIdsByPreviousId)
 	for syntheticRiskId, riskTracking := range modelInput.Risk_tracking {
+		trackedRiskId := syntheticRiskId
+		if migratedId := MigrateSyntheticRiskId(syntheticRiskId, currentIdsByPreviousId); migratedId != syntheticRiskId {
+			if trackingId := trackingIdsByMigratedId[migratedId]; trackingId == migratedId {
+				diagnostics.AddWarning("risk tracking under a previous id is ignored, as "+migratedId+" is tracked as well: "+syntheticRiskId,
+					"remove the entry", "risk_tracking", syntheticRiskId)
+				continue
+			} else if trackingId != syntheticRiskId {
+				diagnostics.AddWarning("risk tracking under a previous id is ignored, as "+trackingId+" migrates to "+migratedId+" as well: "+syntheticRiskId,
+					"remove the entry", "risk_tracking", syntheticRiskId)
+				continue
+			}
+			diagnostics.AddWarning("risk tracking under a previous id migrated to "+migratedId+": "+syntheticRiskId,
+				"rename the entry (the model macro \"migrate-risk-tracking\" renames all of them)", "risk_tracking", syntheticRiskId)
+			trackedRiskId = migratedId
+		}
 		justification := fmt.Sprintf("%v", riskTracking.Justification)
 		checkedBy := fmt.Sprintf("%v", riskTracking.Checked_by)
 		ticket := fmt.Sprintf("%v", riskTracking.Ticket)
@@ -473,53 +505,80 @@ func ParseModel(modelYaml []byte, deferredRiskTrackingDueToWildcardMatching map[
 		if len(riskTracking.Date) > 0 {
 			date, err = time.Parse("2006-01-02", riskTracking.Date)
 			if err != nil {
-				panic(errors.New("unable to parse 'date' of risk tracking '" + syntheticRiskId + "': " + riskTracking.Date))
+				diagnostics.AddError("unable to parse 'date' of risk tracking: "+riskTracking.Date, "use the format YYYY-MM-DD", "risk_tracking", syntheticRiskId, "date")
+			}
+		}
+
+		var expires, reviewBy time.Time
+		if len(riskTracking.Expires) > 0 {
+			expires, err = time.Parse("2006-01-02", riskTracking.Expires)
+			if err != nil {
+				diagnostics.AddError("unable to parse 'expires' of risk tracking: "+riskTracking.Expires, "use the format YYYY-MM-DD", "risk_tracking", syntheticRiskId, "expires")
+			}
+		}
+		if len(riskTracking.Review_by) > 0 {
+			reviewBy, err = time.Parse("2006-01-02", riskTracking.Review_by)
+			if err != nil {
+				diagnostics.AddError("unable to parse 'review_by' of risk tracking: "+riskTracking.Review_by, "use the format YYYY-MM-DD", "risk_tracking", syntheticRiskId, "review_by")
 			}
 		}
 
 		status, err := ParseRiskStatus(riskTracking.Status)
-		support.CheckErr(err)
+		checkValue(err, RiskStatusValues(), &diagnostics, "risk_tracking", syntheticRiskId, "status")
 
 		tracking := RiskTracking{
-			SyntheticRiskId: strings.TrimSpace(syntheticRiskId),
+			SyntheticRiskId: strings.TrimSpace(trackedRiskId),
 			Justification:   justification,
 			CheckedBy:       checkedBy,
 			Ticket:          ticket,
 			Date:            date,
+			Expires:         expires,
+			ReviewBy:        reviewBy,
 			Status:          status,
 		}
-		if strings.Contains(syntheticRiskId, "*") { // contains a wildcard char
-			deferredRiskTrackingDueToWildcardMatching[syntheticRiskId] = tracking
+		if strings.Contains(trackedRiskId, "*") { // contains a wildcard char
+			deferredRiskTrackingDueToWildcardMatching[trackedRiskId] = tracking
 		} else {
-			ParsedModelRoot.RiskTracking[syntheticRiskId] = tracking
+			ParsedModelRoot.RiskTracking[trackedRiskId] = tracking
 		}
 	}
 
 	// ====================== model consistency check (linking)
-	for _, technicalAsset := range ParsedModelRoot.TechnicalAssets {
-		for _, commLink := range technicalAsset.CommunicationLinks {
-			CheckTechnicalAssetExists(commLink.TargetId, "communication link '"+commLink.Title+"' of technical asset '"+technicalAsset.Title+"'", false)
+	for title, technicalAsset := range modelInput.Technical_assets {
+		for commLinkTitle, commLink := range technicalAsset.Communication_links {
+			checkTechnicalAssetExists(commLink.Target, &diagnostics, "technical_assets", title, "communication_links", commLinkTitle, "target")
 		}
 	}
-	return ParsedModelRoot
+	for i, invisibleConnections := range ParsedModelRoot.DiagramTweakInvisibleConnectionsBetweenAssets {
+		if assetIDs := strings.Split(invisibleConnections, ":"); len(assetIDs) == 2 {
+			checkTechnicalAssetExists(assetIDs[0], &diagnostics, "diagram_tweak_invisible_connections_between_assets", strconv.Itoa(i))
+			checkTechnicalAssetExists(assetIDs[1], &diagnostics, "diagram_tweak_invisible_connections_between_assets", strconv.Itoa(i))
+		}
+	}
+	for i, sameRank := range ParsedModelRoot.DiagramTweakSameRankAssets {
+		for _, assetId := range strings.Split(sameRank, ":") {
+			checkTechnicalAssetExists(assetId, &diagnostics, "diagram_tweak_same_rank_assets", strconv.Itoa(i))
+		}
+	}
+	return ParsedModelRoot, diagnostics
 }
 
-func checkTags(tags []string, where string) []string {
+func checkTags(tags []string, diagnostics *Diagnostics, keys ...string) []string {
 	var tagsUsed = make([]string, 0)
 	if tags != nil {
 		tagsUsed = make([]string, len(tags))
 		for i, parsedEntry := range tags {
 			referencedTag := fmt.Sprintf("%v", parsedEntry)
-			checkTagExists(referencedTag, where)
+			checkTagExists(referencedTag, diagnostics, append(keys, strconv.Itoa(i))...)
 			tagsUsed[i] = referencedTag
 		}
 	}
 	return tagsUsed
 }
 
-func checkTagExists(referencedTag, where string) {
+func checkTagExists(referencedTag string, diagnostics *Diagnostics, keys ...string) {
 	if !Contains(ParsedModelRoot.TagsAvailable, referencedTag) {
-		panic(errors.New("missing referenced tag in overall tag list at " + where + ": " + referencedTag))
+		diagnostics.AddError("missing referenced tag in overall tag list: "+referencedTag, "add '"+referencedTag+"' to tags_available", keys...)
 	}
 }
 
@@ -571,50 +630,255 @@ func withDefault(value string, defaultWhenEmpty string) string {
 	return strings.TrimSpace(defaultWhenEmpty)
 }
 
-func checkDataAssetTargetExists(referencedAsset, where string) {
+// the confidentiality package is shadowed by local variables inside ParseModelInput
+var confidentialityValues = confidentiality.ConfidentialityValues()
+
+func checkValue(err error, values []core.TypeEnum, diagnostics *Diagnostics, keys ...string) {
+	if err != nil {
+		diagnostics.AddError(err.Error(), fixOneOf(values), keys...)
+	}
+}
+
+func checkId(id string, diagnostics *Diagnostics, keys ...string) {
+	if !support.IsValidIdSyntax(id) {
+		diagnostics.AddError("invalid id syntax used: "+id, "only use letters, numbers, and hyphen", keys...)
+	}
+}
+
+func checkDataAssetTargetExists(referencedAsset string, diagnostics *Diagnostics, keys ...string) {
 	if _, ok := ParsedModelRoot.DataAssets[referencedAsset]; !ok {
-		panic(errors.New("missing referenced data asset target at " + where + ": " + referencedAsset))
+		diagnostics.AddError("missing referenced data asset target: "+referencedAsset, "define a data asset with id '"+referencedAsset+"' or correct the reference", keys...)
 	}
 }
 
-func checkTrustBoundaryExists(referencedId, where string) {
+func checkTrustBoundaryExists(referencedId string, diagnostics *Diagnostics, keys ...string) {
 	if _, ok := ParsedModelRoot.TrustBoundaries[referencedId]; !ok {
-		panic(errors.New("missing referenced trust boundary at " + where + ": " + referencedId))
+		diagnostics.AddError("missing referenced trust boundary: "+referencedId, "define a trust boundary with id '"+referencedId+"' or correct the reference", keys...)
 	}
 }
 
-func checkSharedRuntimeExists(referencedId, where string) {
+func checkSharedRuntimeExists(referencedId string, diagnostics *Diagnostics, keys ...string) {
 	if _, ok := ParsedModelRoot.SharedRuntimes[referencedId]; !ok {
-		panic(errors.New("missing referenced shared runtime at " + where + ": " + referencedId))
+		diagnostics.AddError("missing referenced shared runtime: "+referencedId, "define a shared runtime with id '"+referencedId+"' or correct the reference", keys...)
 	}
 }
 
-func checkCommunicationLinkExists(referencedId, where string) {
+func checkCommunicationLinkExists(referencedId string, diagnostics *Diagnostics, keys ...string) {
 	if _, ok := CommunicationLinks[referencedId]; !ok {
-		panic(errors.New("missing referenced communication link at " + where + ": " + referencedId))
+		diagnostics.AddError("missing referenced communication link: "+referencedId, "reference the link as <source-asset-id>><link-title-as-id>", keys...)
 	}
 }
 
-func CheckTechnicalAssetExists(referencedAsset, where string, onlyForTweak bool) {
+func checkTechnicalAssetExists(referencedAsset string, diagnostics *Diagnostics, keys ...string) {
+	if _, ok := ParsedModelRoot.TechnicalAssets[referencedAsset]; !ok {
+		diagnostics.AddError("missing referenced technical asset target: "+referencedAsset, "define a technical asset with id '"+referencedAsset+"' or correct the reference", keys...)
+	}
+}
+
+func CheckTechnicalAssetExists(referencedAsset, where string, onlyForTweak bool) error {
 	if _, ok := ParsedModelRoot.TechnicalAssets[referencedAsset]; !ok {
 		suffix := ""
 		if onlyForTweak {
 			suffix = " (only referenced in diagram tweak)"
 		}
-		panic(errors.New("missing referenced technical asset target" + suffix + " at " + where + ": " + referencedAsset))
+		return errors.New("missing referenced technical asset target" + suffix + " at " + where + ": " + referencedAsset)
 	}
+	return nil
 }
 
-func checkNestedTrustBoundariesExisting() {
-	for _, trustBoundary := range ParsedModelRoot.TrustBoundaries {
-		for _, nestedId := range trustBoundary.TrustBoundariesNested {
+func checkNestedTrustBoundariesExisting(modelInput ModelInput, diagnostics *Diagnostics) {
+	for title, trustBoundary := range modelInput.Trust_boundaries {
+		for i, nestedId := range trustBoundary.Trust_boundaries_nested {
 			if _, ok := ParsedModelRoot.TrustBoundaries[nestedId]; !ok {
-				panic(errors.New("missing referenced nested trust boundary: " + nestedId))
+				diagnostics.AddError("missing referenced nested trust boundary: "+nestedId, "define a trust boundary with id '"+nestedId+"' or correct the reference", "trust_boundaries", title, "trust_boundaries_nested", strconv.Itoa(i))
 			}
 		}
 	}
 }
 
+// checkPreviousIds requires the previous ids to be unique and not to be in use by any element (as they are replaced in the ids of tracked risks)
+func checkPreviousIds(modelInput ModelInput, diagnostics *Diagnostics) {
+	currentIds := make(map[string]bool)
+	for id := range ParsedModelRoot.DataAssets {
+		currentIds[id] = true
+	}
+	for id, technicalAsset := range ParsedModelRoot.TechnicalAssets {
+		currentIds[id] = true
+		for _, communicationLink := range technicalAsset.CommunicationLinks {
+			currentIds[communicationLink.Id] = true
+		}
+	}
+	for id := range ParsedModelRoot.TrustBoundaries {
+		currentIds[id] = true
+	}
+	for _, sharedRuntime := range modelInput.Shared_runtimes {
+		currentIds[sharedRuntime.ID] = true
+	}
+	previousIds := make(map[string]bool)
+	checkPreviousId := func(previousId string, keys ...string) {
+		if currentIds[previousId] {
+			diagnostics.AddError("previous id still in use: "+previousId, "remove the previous id (or rename the element using it)", keys...)
+		} else if previousIds[previousId] {
+			diagnostics.AddError("duplicate previous id used: "+previousId, "use each previous id only once", keys...)
+		}
+		previousIds[previousId] = true
+	}
+	for title, dataAsset := range modelInput.Data_assets {
+		for i, previousId := range dataAsset.Previous_ids {
+			checkId(previousId, diagnostics, "data_assets", title, "previous_ids", strconv.Itoa(i))
+			checkPreviousId(previousId, "data_assets", title, "previous_ids", strconv.Itoa(i))
+		}
+	}
+	for title, technicalAsset := range modelInput.Technical_assets {
+		for i, previousId := range technicalAsset.Previous_ids {
+			checkId(previousId, diagnostics, "technical_assets", title, "previous_ids", strconv.Itoa(i))
+			checkPreviousId(previousId, "technical_assets", title, "previous_ids", strconv.Itoa(i))
+		}
+		for commLinkTitle, commLink := range technicalAsset.Communication_links {
+			for i, previousId := range commLink.Previous_ids {
+				if !strings.Contains(previousId, ">") {
+					diagnostics.AddError("invalid previous id of communication link: "+previousId, "use the id the communication link had before (like source-asset>link-title)",
+						"technical_assets", title, "communication_links", commLinkTitle, "previous_ids", strconv.Itoa(i))
+				}
+				checkPreviousId(previousId, "technical_assets", title, "communication_links", commLinkTitle, "previous_ids", strconv.Itoa(i))
+			}
+		}
+	}
+	for title, trustBoundary := range modelInput.Trust_boundaries {
+		for i, previousId := range trustBoundary.Previous_ids {
+			checkId(previousId, diagnostics, "trust_boundaries", title, "previous_ids", strconv.Itoa(i))
+			checkPreviousId(previousId, "trust_boundaries", title, "previous_ids", strconv.Itoa(i))
+		}
+	}
+	for title, sharedRuntime := range modelInput.Shared_runtimes {
+		for i, previousId := range sharedRuntime.Previous_ids {
+			checkId(previousId, diagnostics, "shared_runtimes", title, "previous_ids", strconv.Itoa(i))
+			checkPreviousId(previousId, "shared_runtimes", title, "previous_ids", strconv.Itoa(i))
+		}
+	}
+}
+
+// previousIdsOfDataFlow are the given ones along with those the data flow had before its source asset was renamed
+func previousIdsOfDataFlow(previousSourceAssetIds, previousIds []string, title string) []string {
+	result := append([]string(nil), previousIds...)
+	for _, previousSourceAssetId := range previousSourceAssetIds {
+		result = append(result, createDataFlowId(previousSourceAssetId, title))
+	}
+	return result
+}
+
+// CurrentIdsByPreviousId maps the previous ids of all elements onto their current ones
+func CurrentIdsByPreviousId(parsedModel ParsedModel) map[string]string {
+	result := make(map[string]string)
+	for _, dataAsset := range parsedModel.DataAssets {
+		for _, previousId := range dataAsset.PreviousIds {
+			result[previousId] = dataAsset.Id
+		}
+	}
+	for _, technicalAsset := range parsedModel.TechnicalAssets {
+		for _, previousId := range technicalAsset.PreviousIds {
+			result[previousId] = technicalAsset.Id
+		}
+		for _, communicationLink := range technicalAsset.CommunicationLinks {
+			for _, previousId := range communicationLink.PreviousIds {
+				result[previousId] = communicationLink.Id
+			}
+		}
+	}
+	for _, trustBoundary := range parsedModel.TrustBoundaries {
+		for _, previousId := range trustBoundary.PreviousIds {
+			result[previousId] = trustBoundary.Id
+		}
+	}
+	for _, sharedRuntime := range parsedModel.SharedRuntimes {
+		for _, previousId := range sharedRuntime.PreviousIds {
+			result[previousId] = sharedRuntime.Id
+		}
+	}
+	return result
+}
+
+// CurrentIdsByPreviousIdOfInput is like CurrentIdsByPreviousId, but without parsing the model (which changes the global state)
+func CurrentIdsByPreviousIdOfInput(modelInput ModelInput) map[string]string {
+	result := make(map[string]string)
+	for _, dataAsset := range modelInput.Data_assets {
+		for _, previousId := range dataAsset.Previous_ids {
+			result[previousId] = dataAsset.ID
+		}
+	}
+	for _, technicalAsset := range modelInput.Technical_assets {
+		for _, previousId := range technicalAsset.Previous_ids {
+			result[previousId] = technicalAsset.ID
+		}
+		for title, communicationLink := range technicalAsset.Communication_links {
+			for _, previousId := range previousIdsOfDataFlow(technicalAsset.Previous_ids, communicationLink.Previous_ids, title) {
+				result[previousId] = createDataFlowId(technicalAsset.ID, title)
+			}
+		}
+	}
+	for _, trustBoundary := range modelInput.Trust_boundaries {
+		for _, previousId := range trustBoundary.Previous_ids {
+			result[previousId] = trustBoundary.ID
+		}
+	}
+	for _, sharedRuntime := range modelInput.Shared_runtimes {
+		for _, previousId := range sharedRuntime.Previous_ids {
+			result[previousId] = sharedRuntime.ID
+		}
+	}
+	return result
+}
+
+// MigrateRiskTracking keys the risk tracking by the migrated synthetic risk ids like the analysis does
+// (ignoring the entries under previous ids not used according to RiskTrackingIdsByMigratedId)
+func MigrateRiskTracking(riskTracking map[string]InputRiskTracking, currentIdsByPreviousId map[string]string) map[string]InputRiskTracking {
+	trackingIdsByMigratedId := RiskTrackingIdsByMigratedId(riskTrackingIds(riskTracking), currentIdsByPreviousId)
+	result := make(map[string]InputRiskTracking, len(trackingIdsByMigratedId))
+	for migratedId, trackingId := range trackingIdsByMigratedId {
+		result[migratedId] = riskTracking[trackingId]
+	}
+	return result
+}
+
+// RiskTrackingIdsByMigratedId maps the migrated synthetic risk ids onto the id of the risk tracking entry used for them:
+// an entry under the current id takes precedence over those under previous ids, of which the first one in sorted order is used
+func RiskTrackingIdsByMigratedId(syntheticRiskIds []string, currentIdsByPreviousId map[string]string) map[string]string {
+	sortedIds := append([]string(nil), syntheticRiskIds...)
+	sort.Strings(sortedIds)
+	result := make(map[string]string, len(sortedIds))
+	for _, syntheticRiskId := range sortedIds {
+		if MigrateSyntheticRiskId(syntheticRiskId, currentIdsByPreviousId) == syntheticRiskId {
+			result[syntheticRiskId] = syntheticRiskId
+		}
+	}
+	for _, syntheticRiskId := range sortedIds {
+		migratedId := MigrateSyntheticRiskId(syntheticRiskId, currentIdsByPreviousId)
+		if _, used := result[migratedId]; !used {
+			result[migratedId] = syntheticRiskId
+		}
+	}
+	return result
+}
+
+func riskTrackingIds(riskTracking map[string]InputRiskTracking) []string {
+	result := make([]string, 0, len(riskTracking))
+	for syntheticRiskId := range riskTracking {
+		result = append(result, syntheticRiskId)
+	}
+	return result
+}
+
+// MigrateSyntheticRiskId replaces the previous ids of elements within the synthetic risk id by their current ones
+func MigrateSyntheticRiskId(syntheticRiskId string, currentIdsByPreviousId map[string]string) string {
+	parts := strings.Split(syntheticRiskId, "@")
+	for i := 1; i < len(parts); i++ { // the first part being the category
+		if currentId, renamed := currentIdsByPreviousId[strings.TrimSpace(parts[i])]; renamed {
+			parts[i] = currentId
+		}
+	}
+	return strings.Join(parts, "@")
+}
+
 func createDataFlowId(sourceAssetId, title string) string {
 	reg, err := regexp.Compile("[^A-Za-z0-9]+")
 	support.CheckErr(err)
@@ -18,6 +18,8 @@ type Risk struct {
 	MostRelevantCommunicationLinkId string                     `json:"most_relevant_communication_link"`
 	DataBreachProbability           DataBreachProbability      `json:"data_breach_probability"`
 	DataBreachTechnicalAssetIDs     []string                   `json:"data_breach_technical_assets"`
+	RiskTrackingExpired             bool                       `json:"risk_tracking_expired,omitempty"` // assigned in risk evaluation phase automatically
+	RiskReviewOverdue               bool                       `json:"risk_review_overdue,omitempty"`   // assigned in risk evaluation phase automatically
 	// TODO: refactor all "Id" here to "ID"?
 }
 
@@ -6,6 +6,9 @@ type RiskTracking struct {
 	SyntheticRiskId, Justification, Ticket, CheckedBy string
 	Status                                            RiskStatus
 	Date                                              time.Time
+	Expires, ReviewBy                                 time.Time // optional (zero when not set)
+	Expired                                           bool      // when expired, the status fell back from the expired status during the analysis
+	ExpiredStatus                                     RiskStatus
 }
 type InputRiskTracking struct {
 	Status        string `json:"status"`
@@ -13,4 +16,20 @@ type InputRiskTracking struct {
 	Ticket        string `json:"ticket"`
 	Date          string `json:"date"`
 	Checked_by    string `json:"checked_by"`
+	Expires       string `json:"expires" yaml:",omitempty"`
+	Review_by     string `json:"review_by" yaml:",omitempty"`
+}
+
+// IsExpired is true after the day of expiry of the risk tracking (if any)
+func (what RiskTracking) IsExpired(now time.Time) bool {
+	return isAfterDay(now, what.Expires)
+}
+
+// IsReviewOverdue is true after the day the risk tracking should have been reviewed by (if any)
+func (what RiskTracking) IsReviewOverdue(now time.Time) bool {
+	return isAfterDay(now, what.ReviewBy)
+}
+
+func isAfterDay(now time.Time, day time.Time) bool {
+	return !day.IsZero() && now.Format("2006-01-02") > day.Format("2006-01-02")
 }
@@ -9,12 +9,14 @@ import (
 
 type InputSharedRuntime struct {
 	ID                       string   `json:"id"`
+	Previous_ids             []string `json:"previous_ids"`
 	Description              string   `json:"description"`
 	Tags                     []string `json:"tags"`
 	Technical_assets_running []string `json:"technical_assets_running"`
 }
 type SharedRuntime struct {
 	Id, Title, Description string
+	PreviousIds            []string // ids before renaming, risk tracking of those is migrated
 	Tags                   []string
 	TechnicalAssetsRunning []string
 }
@@ -11,6 +11,7 @@ import (
 
 type InputTechnicalAsset struct {
 	ID                         string                            `json:"id"`
+	Previous_ids               []string                          `json:"previous_ids"`
 	Description                string                            `json:"description"`
 	Type                       string                            `json:"type"`
 	Usage                      string                            `json:"usage"`
@@ -39,6 +40,7 @@ type InputTechnicalAsset struct {
 }
 type TechnicalAsset struct {
 	Id, Title, Description                                                                  string
+	PreviousIds                                                                             []string // ids before renaming, risk tracking of those is migrated
 	Usage                                                                                   Usage
 	Type                                                                                    TechnicalAssetType
 	Size                                                                                    TechnicalAssetSize
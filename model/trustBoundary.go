@@ -12,6 +12,7 @@ import (
 
 type InputTrustBoundary struct {
 	ID                      string   `json:"id"`
+	Previous_ids            []string `json:"previous_ids"`
 	Description             string   `json:"description"`
 	Type                    string   `json:"type"`
 	Tags                    []string `json:"tags"`
@@ -21,6 +22,7 @@ type InputTrustBoundary struct {
 
 type TrustBoundary struct {
 	Id, Title, Description string
+	PreviousIds            []string // ids before renaming, risk tracking of those is migrated
 	Type                   TrustBoundaryType
 	Tags                   []string
 	TechnicalAssetsInside  []string
@@ -0,0 +1,101 @@
+// Package baseline records the risks of a model (like SAST tools their existing findings), so that later runs only report the new ones.
+package baseline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+
+	"github.com/otyg/threagile/model"
+)
+
+type Entry struct {
+	SyntheticId string `json:"synthetic_id"`
+	Fingerprint string `json:"fingerprint"`
+	Category    string `json:"category"`
+	Severity    string `json:"severity"`
+	Title       string `json:"title"` // just for reviewing the baseline
+}
+
+type Baseline struct {
+	Risks []Entry `json:"risks"`
+}
+
+// Fingerprint identifies a risk by its category, synthetic id, and severity (so a risk whose severity changed is new)
+func Fingerprint(risk model.Risk) string {
+	return fingerprint(risk.CategoryId, risk.SyntheticId, risk.Severity.String())
+}
+
+func fingerprint(categoryId, syntheticId, severity string) string {
+	hash := sha256.Sum256([]byte(categoryId + "\n" + syntheticId + "\n" + severity))
+	return hex.EncodeToString(hash[:])
+}
+
+// New records all given risks sorted by their synthetic id
+func New(risks []model.Risk) Baseline {
+	result := Baseline{Risks: make([]Entry, 0, len(risks))}
+	for _, risk := range risks {
+		result.Risks = append(result.Risks, Entry{
+			SyntheticId: risk.SyntheticId,
+			Fingerprint: Fingerprint(risk),
+			Category:    risk.CategoryId,
+			Severity:    risk.Severity.String(),
+			Title:       risk.Title,
+		})
+	}
+	sort.Slice(result.Risks, func(i, j int) bool {
+		return result.Risks[i].SyntheticId < result.Risks[j].SyntheticId
+	})
+	return result
+}
+
+func Load(filename string) (Baseline, error) {
+	result := Baseline{}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func (what Baseline) Write(filename string) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(what); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data.Bytes(), 0644)
+}
+
+// Migrate replaces the previous ids of renamed elements within the synthetic ids (and thus the fingerprints) of the entries
+func (what Baseline) Migrate(currentIdsByPreviousId map[string]string) Baseline {
+	result := Baseline{Risks: make([]Entry, 0, len(what.Risks))}
+	for _, entry := range what.Risks {
+		if syntheticId := model.MigrateSyntheticRiskId(entry.SyntheticId, currentIdsByPreviousId); syntheticId != entry.SyntheticId {
+			entry.SyntheticId, entry.Fingerprint = syntheticId, fingerprint(entry.Category, syntheticId, entry.Severity)
+		}
+		result.Risks = append(result.Risks, entry)
+	}
+	return result
+}
+
+// NewRisks returns the risks not contained in the baseline
+func (what Baseline) NewRisks(risks []model.Risk) []model.Risk {
+	fingerprints := make(map[string]bool, len(what.Risks))
+	for _, entry := range what.Risks {
+		fingerprints[entry.Fingerprint] = true
+	}
+	result := make([]model.Risk, 0)
+	for _, risk := range risks {
+		if !fingerprints[Fingerprint(risk)] {
+			result = append(result, risk)
+		}
+	}
+	return result
+}
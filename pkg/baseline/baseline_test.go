@@ -0,0 +1,43 @@
+package baseline
+
+import (
+	"testing"
+
+	"github.com/otyg/threagile/model"
+)
+
+func TestNewRisks(t *testing.T) {
+	known := []model.Risk{
+		{CategoryId: "missing-waf", SyntheticId: "missing-waf@web", Severity: model.LowSeverity},
+		{CategoryId: "sql-nosql-injection", SyntheticId: "sql-nosql-injection@web@db@web>db", Severity: model.HighSeverity},
+	}
+	filename := t.TempDir() + "/baseline.json"
+	if err := New(known).Write(filename); err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(baseline.Risks) != 2 || baseline.Risks[0].SyntheticId != "missing-waf@web" || baseline.Risks[0].Fingerprint != Fingerprint(known[0]) {
+		t.Errorf("baseline = %+v, want both risks", baseline)
+	}
+
+	risks := []model.Risk{
+		known[1],
+		{CategoryId: "missing-waf", SyntheticId: "missing-waf@web", Severity: model.MediumSeverity},
+		{CategoryId: "missing-waf", SyntheticId: "missing-waf@api", Severity: model.LowSeverity},
+	}
+	newRisks := baseline.NewRisks(risks)
+	if len(newRisks) != 2 || newRisks[0].Severity != model.MediumSeverity || newRisks[1].SyntheticId != "missing-waf@api" {
+		t.Errorf("new risks = %+v, want the risk with changed severity and the one of the new asset", newRisks)
+	}
+
+	renamed := []model.Risk{
+		{CategoryId: "missing-waf", SyntheticId: "missing-waf@frontend", Severity: model.LowSeverity},
+		{CategoryId: "sql-nosql-injection", SyntheticId: "sql-nosql-injection@frontend@db@frontend>db", Severity: model.HighSeverity},
+	}
+	if newRisks := baseline.Migrate(map[string]string{"web": "frontend", "web>db": "frontend>db"}).NewRisks(renamed); len(newRisks) != 0 {
+		t.Errorf("new risks after renaming = %+v, want none", newRisks)
+	}
+}
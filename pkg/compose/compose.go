@@ -0,0 +1,116 @@
+// Package compose generates model skeletons out of docker-compose files.
+package compose
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Compose is the (relevant part of the) compose file
+type Compose struct {
+	Name     string              `yaml:"name"`
+	Services map[string]Service  `yaml:"services"`
+	Networks map[string]*Network `yaml:"networks"`
+}
+
+type Service struct {
+	Image     string    `yaml:"image"`
+	Build     yaml.Node `yaml:"build"`
+	Ports     []Port    `yaml:"ports"`
+	Expose    []string  `yaml:"expose"`
+	Networks  names     `yaml:"networks"`
+	DependsOn names     `yaml:"depends_on"`
+	Links     []string  `yaml:"links"`
+}
+
+type Network struct {
+	Internal bool `yaml:"internal"`
+}
+
+// Port is the port of the container, published on the host unless bound to localhost
+type Port struct {
+	Target    int
+	Published bool
+}
+
+// names are given either as list or as keys of a mapping (both in declaration order)
+type names []string
+
+// Parse reads the YAML of a compose file
+func Parse(data []byte) (result Compose, err error) {
+	err = yaml.Unmarshal(data, &result)
+	return result, err
+}
+
+// ServiceNames are the names of the services in (stable) alphabetical order
+func (what Compose) ServiceNames() []string {
+	var result []string
+	for name := range what.Services {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ContainerPorts are the container ports of the service, including the exposed (not published) ones
+func (what Service) ContainerPorts() []int {
+	var result []int
+	for _, port := range what.Ports {
+		result = append(result, port.Target)
+	}
+	for _, expose := range what.Expose {
+		if port, err := strconv.Atoi(strings.SplitN(strings.SplitN(expose, "/", 2)[0], "-", 2)[0]); err == nil {
+			result = append(result, port)
+		}
+	}
+	return result
+}
+
+func (what *names) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		var list []string
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*what = list
+	case yaml.MappingNode:
+		for i := 0; i < len(value.Content); i += 2 {
+			*what = append(*what, value.Content[i].Value)
+		}
+	default:
+		return errors.New("expected list or mapping in line " + strconv.Itoa(value.Line))
+	}
+	return nil
+}
+
+func (what *Port) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var long struct {
+			Target int    `yaml:"target"`
+			HostIP string `yaml:"host_ip"`
+		}
+		if err := value.Decode(&long); err != nil {
+			return err
+		}
+		what.Target, what.Published = long.Target, !isLocalhost(long.HostIP)
+		return nil
+	}
+	// short syntax: [HOST_IP:][HOST_PORT:]CONTAINER_PORT[/PROTOCOL] with ranges like 8000-8010
+	parts := strings.Split(strings.SplitN(value.Value, "/", 2)[0], ":")
+	target, err := strconv.Atoi(strings.SplitN(parts[len(parts)-1], "-", 2)[0])
+	if err != nil {
+		return errors.New("invalid port " + value.Value + " in line " + strconv.Itoa(value.Line))
+	}
+	what.Target, what.Published = target, len(parts) < 3 || !isLocalhost(strings.Join(parts[:len(parts)-2], ":"))
+	return nil
+}
+
+func isLocalhost(hostIP string) bool {
+	hostIP = strings.Trim(hostIP, "[]")
+	return hostIP == "localhost" || hostIP == "::1" || strings.HasPrefix(hostIP, "127.")
+}
@@ -0,0 +1,99 @@
+package compose
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/threagile"
+	"gopkg.in/yaml.v3"
+)
+
+func importCompose(t *testing.T, data []byte) model.ParsedModel {
+	compose, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modelInput := ToModelInput(compose)
+	modelYaml, err := yaml.Marshal(&modelInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.ThreagileVersion = "test"
+	result, err := threagile.Analyze(context.Background(), modelYaml, threagile.Options{
+		RAA:       func() string { return "" },
+		RiskRules: map[string]model.RiskRule{},
+	})
+	if err != nil {
+		t.Fatalf("generated model is not ok: %v\n%s", err, modelYaml)
+	}
+	return result.ParsedModel()
+}
+
+func TestImport(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/docker-compose.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedModel := importCompose(t, data)
+
+	if parsedModel.Title != "shop" {
+		t.Errorf("title = %v, want shop", parsedModel.Title)
+	}
+	for id, want := range map[string]model.TechnicalAssetTechnology{"proxy": model.ReverseProxy, "web": model.UnknownTechnology,
+		"db": model.Database, "cache": model.Database, "worker": model.UnknownTechnology} {
+		if got := parsedModel.TechnicalAssets[id]; got.Technology != want || got.Machine != model.Container || !model.Contains(got.Tags, ProvenanceTag) {
+			t.Errorf("technical asset %v = %+v, want technology %v and tag %v", id, got, want, ProvenanceTag)
+		}
+	}
+	if !parsedModel.TechnicalAssets["web"].CustomDevelopedParts || !parsedModel.TechnicalAssets["worker"].CustomDevelopedParts {
+		t.Errorf("services built from source are not custom developed")
+	}
+	for id, want := range map[string]bool{"proxy": true, "web": false, "db": false} {
+		if got := parsedModel.TechnicalAssets[id].Internet; got != want {
+			t.Errorf("internet of %v = %v, want %v", id, got, want)
+		}
+	}
+
+	for id, want := range map[string][]string{"network-front": {"proxy", "web"}, "network-back": {"cache", "db"}, "network-default": {"worker"}} {
+		got := parsedModel.TrustBoundaries[id].TechnicalAssetsInside
+		if len(got) != len(want) || got[0] != want[0] || got[len(got)-1] != want[len(want)-1] {
+			t.Errorf("assets inside %v = %v, want %v", id, got, want)
+		}
+	}
+
+	links := make(map[string]model.Protocol)
+	for _, technicalAsset := range parsedModel.TechnicalAssets {
+		for _, communicationLink := range technicalAsset.CommunicationLinks {
+			links[communicationLink.SourceId+">"+communicationLink.TargetId] = communicationLink.Protocol
+		}
+	}
+	want := map[string]model.Protocol{
+		"internet>proxy": model.HTTPS,
+		"proxy>web":      model.HTTP,
+		"web>db":         model.SQL_access_protocol,
+		"web>cache":      model.NoSQL_access_protocol,
+		"worker>db":      model.SQL_access_protocol,
+	}
+	if len(links) != len(want) {
+		t.Errorf("communication links = %v, want %v", links, want)
+	}
+	for link, protocol := range want {
+		if got, ok := links[link]; !ok || got != protocol {
+			t.Errorf("protocol of communication link %v = %v, want %v", link, got, protocol)
+		}
+	}
+}
+
+func TestImportOfServiceNamedInternet(t *testing.T) {
+	parsedModel := importCompose(t, []byte("services:\n  Internet:\n    image: nginx\n    ports: [ \"443:443\" ]\n"))
+
+	if got := parsedModel.TechnicalAssets["internet-2"]; got.Title != "Internet" || !got.Internet {
+		t.Errorf("technical asset of the service = %+v, want it titled Internet and exposed to the internet", got)
+	}
+	internet := parsedModel.TechnicalAssets["internet"]
+	if internet.Title != "Internet (internet)" || len(internet.CommunicationLinks) != 1 || internet.CommunicationLinks[0].TargetId != "internet-2" {
+		t.Errorf("internet = %+v, want it titled uniquely and linked to the service", internet)
+	}
+}
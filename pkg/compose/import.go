@@ -0,0 +1,132 @@
+package compose
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/skeleton"
+)
+
+// ProvenanceTag marks the elements generated from compose files
+const ProvenanceTag = "docker-compose"
+
+// ToModelInput generates the skeleton of a model: the services become technical assets (running as container) with
+// technologies guessed from their images, inside trust boundaries of their (first) networks. The dependencies (depends_on
+// and links) become communication links, published ports become communication links from the internet. All generated elements
+// carry the provenance tag. All values which can not be derived from the compose file (like data assets and CIA ratings) are
+// set to defaults to be completed afterwards.
+func ToModelInput(compose Compose) model.ModelInput {
+	title := compose.Name
+	if len(title) == 0 {
+		title = "Generated from docker-compose"
+	}
+	result := skeleton.NewModel(title)
+	usedIds := map[string]bool{skeleton.Internet().ID: true}
+	ids := make(map[string]string) // of the services
+
+	var networkNames []string
+	inside := make(map[string][]string)
+	for _, name := range compose.ServiceNames() {
+		service := compose.Services[name]
+		id := skeleton.UniqueId(name, usedIds)
+		ids[name] = id
+
+		description := "Service " + name
+		if len(service.Image) > 0 {
+			description += " running " + service.Image
+		} else {
+			description += " built from source"
+		}
+		networks := service.Networks
+		if len(networks) == 0 {
+			networks = names{"default"}
+		}
+		if len(networks) > 1 {
+			description += ", also attached to the networks " + strings.Join(networks[1:], ", ")
+		}
+		if _, exists := inside[networks[0]]; !exists {
+			networkNames = append(networkNames, networks[0])
+		}
+		inside[networks[0]] = append(inside[networks[0]], id)
+
+		technicalAsset := skeleton.NewTechnicalAsset(id, description, model.Container)
+		technology, datastore := skeleton.TechnologyOfImage(service.Image)
+		technicalAsset.Technology = technology.String()
+		if datastore {
+			technicalAsset.Type = model.Datastore.String()
+		}
+		technicalAsset.Custom_developed_parts = service.Build.Kind != 0
+		result.Technical_assets[name] = technicalAsset
+	}
+
+	for _, network := range networkNames {
+		description := "Docker network " + network
+		if compose.Networks[network] != nil && compose.Networks[network].Internal {
+			description += " (internal)"
+		}
+		result.Trust_boundaries["Network "+network] = model.InputTrustBoundary{
+			ID:                      model.MakeID("network " + network),
+			Description:             description,
+			Type:                    model.NetworkVirtualLAN.String(),
+			Technical_assets_inside: inside[network],
+		}
+	}
+
+	// the title of the internet, unless a service is named like it
+	internetTitle := skeleton.UniqueTitle("Internet", skeleton.Internet().ID, func(title string) bool { _, exists := result.Technical_assets[title]; return exists })
+	for _, name := range compose.ServiceNames() {
+		service := compose.Services[name]
+		source := result.Technical_assets[name]
+		var published []string
+		for _, port := range service.Ports {
+			if port.Published {
+				published = append(published, strconv.Itoa(port.Target))
+			}
+		}
+		if len(published) > 0 {
+			internet, exists := result.Technical_assets[internetTitle]
+			if !exists {
+				internet = skeleton.Internet()
+			}
+			port, _ := strconv.Atoi(published[0])
+			internet.Communication_links["Published ports to "+name] = skeleton.NewCommunicationLink(ids[name],
+				"Container ports "+strings.Join(published, ", ")+" published on the host", skeleton.ProtocolOfPort(port, ""))
+			result.Technical_assets[internetTitle] = internet
+			source.Internet = true
+		}
+
+		dependencies := make(map[string]string) // the names of the services to how they depend
+		for _, dependency := range service.DependsOn {
+			dependencies[dependency] = "depends_on"
+		}
+		for _, link := range service.Links {
+			dependencies[strings.SplitN(link, ":", 2)[0]] = "links"
+		}
+		for _, dependency := range sortedKeys(dependencies) {
+			target, exists := compose.Services[dependency]
+			if !exists || dependency == name {
+				continue
+			}
+			port := skeleton.PortOfImage(target.Image)
+			if ports := target.ContainerPorts(); len(ports) > 0 {
+				port = ports[0]
+			}
+			source.Communication_links[dependency+" Traffic"] = skeleton.NewCommunicationLink(ids[dependency], "Dependency declared by "+dependencies[dependency],
+				skeleton.ProtocolOfPort(port, ""))
+		}
+		result.Technical_assets[name] = source
+	}
+	skeleton.TagGenerated(&result, ProvenanceTag)
+	return result
+}
+
+func sortedKeys(values map[string]string) []string {
+	var result []string
+	for key := range values {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
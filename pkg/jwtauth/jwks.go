@@ -0,0 +1,236 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refetchInterval limits how often the JWKS of an issuer is fetched again due to unknown key IDs
+const refetchInterval = time.Minute
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// keySet holds the public keys by their key ID, those of an issuer are fetched again when an unknown key ID shows up
+// (outside of the lock, so that tokens of known keys are verified meanwhile, and only once for all tokens waiting for it)
+type keySet struct {
+	config      Config
+	client      *http.Client
+	lock        sync.RWMutex
+	keys        map[string]interface{}
+	lastFetched time.Time
+	fetching    chan struct{} // closed when the fetch in progress is done
+}
+
+func newKeySet(config Config) (*keySet, error) {
+	result := &keySet{config: config, client: &http.Client{Timeout: 10 * time.Second}, lastFetched: time.Now()}
+	var err error
+	result.keys, err = result.read()
+	return result, err
+}
+
+func (what *keySet) key(kid string) (interface{}, error) {
+	if key, ok := what.lookup(kid); ok {
+		return key, nil
+	}
+	if len(what.config.JWKSFile) == 0 {
+		if err := what.refetch(); err != nil {
+			return nil, err
+		}
+		if key, ok := what.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, errors.New("unknown key id: " + kid)
+}
+
+// lookup also accepts tokens without key ID as long as there is only a single key
+func (what *keySet) lookup(kid string) (interface{}, bool) {
+	what.lock.RLock()
+	defer what.lock.RUnlock()
+	if len(kid) == 0 && len(what.keys) == 1 {
+		for _, key := range what.keys {
+			return key, true
+		}
+	}
+	key, ok := what.keys[kid]
+	return key, ok
+}
+
+// refetch fetches the keys of the issuer again, unless they were fetched within the refetch interval, or waits for the fetch in progress
+func (what *keySet) refetch() error {
+	what.lock.Lock()
+	if fetching := what.fetching; fetching != nil {
+		what.lock.Unlock()
+		<-fetching
+		return nil
+	}
+	if time.Since(what.lastFetched) <= refetchInterval {
+		what.lock.Unlock()
+		return nil
+	}
+	what.lastFetched = time.Now()
+	fetching := make(chan struct{})
+	what.fetching = fetching
+	what.lock.Unlock()
+
+	keys, err := what.read()
+	what.lock.Lock()
+	if err == nil {
+		what.keys = keys
+	}
+	what.fetching = nil
+	what.lock.Unlock()
+	close(fetching)
+	return err
+}
+
+func (what *keySet) read() (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	if len(what.config.JWKSFile) > 0 {
+		data, err = ioutil.ReadFile(what.config.JWKSFile)
+	} else {
+		data, err = what.fetchOfIssuer()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
+
+// fetchOfIssuer discovers the JWKS of the issuer via its OpenID configuration
+func (what *keySet) fetchOfIssuer() ([]byte, error) {
+	discovery, err := what.get(strings.TrimSuffix(what.config.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	configuration := struct {
+		JwksURI string `json:"jwks_uri"`
+	}{}
+	if err := json.Unmarshal(discovery, &configuration); err != nil {
+		return nil, err
+	}
+	if len(configuration.JwksURI) == 0 {
+		return nil, errors.New("openid configuration of the issuer without jwks_uri: " + what.config.Issuer)
+	}
+	return what.get(configuration.JwksURI)
+}
+
+func (what *keySet) get(url string) ([]byte, error) {
+	response, err := what.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("unable to fetch " + url + ": " + response.Status)
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+// parseJWKS reads the RSA and EC signing keys of the set, others are ignored
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	set := jsonWebKeySet{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var key interface{}
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaPublicKey()
+		case "EC":
+			key, err = jwk.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errors.New("invalid key " + jwk.Kid + " in jwks: " + err.Error())
+		}
+		result[jwk.Kid] = key
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no usable signing key in jwks")
+	}
+	return result, nil
+}
+
+func (what jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(what.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(what.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, errors.New("rsa exponent too large")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (what jsonWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch what.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, errors.New("unsupported curve: " + what.Crv)
+	}
+	x, err := decodeBigInt(what.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(what.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point not on curve " + what.Crv)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("missing key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
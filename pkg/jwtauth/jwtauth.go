@@ -0,0 +1,157 @@
+// Package jwtauth authenticates the users of the server via bearer JWTs (as issued by an OIDC provider) and maps their claims onto roles.
+package jwtauth
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/otyg/threagile/model"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	Viewer       = "viewer"        // may read the models
+	Editor       = "editor"        // may create and change the models (except accepting risks or marking them as false positives)
+	RiskApprover = "risk-approver" // may (additionally to editing the risk tracking) accept risks and mark them as false positives
+	Admin        = "admin"         // may do everything including the deletion of models and the management of webhooks
+)
+
+func Roles() []string {
+	return []string{Viewer, Editor, RiskApprover, Admin}
+}
+
+// Config is read from a YAML file, either a JWKS file or an issuer (whose JWKS is discovered) has to be given
+type Config struct {
+	JWKSFile    string            `yaml:"jwks_file"`
+	Issuer      string            `yaml:"issuer"`
+	Audience    string            `yaml:"audience"`
+	RolesClaim  string            `yaml:"roles_claim"`  // dot-separated path of the claim holding the roles (or groups), default: roles
+	RoleMapping map[string]string `yaml:"role_mapping"` // claim value -> role, role names themselves are always mapped onto their role
+	KeyFile     string            `yaml:"key_file"`     // file holding the key (as created via /auth/keys) used to encrypt the models
+}
+
+func LoadConfig(filename string) (Config, error) {
+	config := Config{}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return config, err
+	}
+	if len(config.JWKSFile) == 0 && len(config.Issuer) == 0 {
+		return config, errors.New("jwt config requires a jwks_file or an issuer: " + filename)
+	}
+	if len(config.RolesClaim) == 0 {
+		config.RolesClaim = "roles"
+	}
+	for claimValue, role := range config.RoleMapping {
+		if !model.Contains(Roles(), role) {
+			return config, errors.New("unknown role " + role + " mapped for " + claimValue + " (use one of " + strings.Join(Roles(), ", ") + ")")
+		}
+	}
+	return config, nil
+}
+
+// Principal is the authenticated user
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+// HasRole also considers the implied roles: admin implies all roles, each role implies viewer
+func (what Principal) HasRole(role string) bool {
+	for _, granted := range what.Roles {
+		if granted == role || granted == Admin || role == Viewer {
+			return true
+		}
+	}
+	return false
+}
+
+func (what Principal) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if what.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator is shared by all requests and safe for concurrent use
+type Authenticator struct {
+	config  Config
+	keys    *keySet
+	methods []string
+}
+
+func NewAuthenticator(config Config) (*Authenticator, error) {
+	keys, err := newKeySet(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Authenticator{
+		config:  config,
+		keys:    keys,
+		methods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"},
+	}, nil
+}
+
+// Authenticate verifies the signature, the expiry, and (when configured) the issuer and audience of the token
+func (what *Authenticator) Authenticate(tokenString string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return what.keys.key(kid)
+	}, jwt.WithValidMethods(what.methods))
+	if err != nil {
+		return Principal{}, err
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return Principal{}, errors.New("token without valid expiry")
+	}
+	if len(what.config.Issuer) > 0 && !claims.VerifyIssuer(what.config.Issuer, true) {
+		return Principal{}, errors.New("token of another issuer")
+	}
+	if len(what.config.Audience) > 0 && !claims.VerifyAudience(what.config.Audience, true) {
+		return Principal{}, errors.New("token for another audience")
+	}
+	subject, _ := claims["sub"].(string)
+	return Principal{Subject: subject, Roles: what.roles(claims)}, nil
+}
+
+func (what *Authenticator) roles(claims jwt.MapClaims) []string {
+	var value interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(what.config.RolesClaim, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{}
+		}
+		value = object[name]
+	}
+	claimValues := make([]string, 0)
+	switch typed := value.(type) {
+	case string:
+		claimValues = strings.Fields(typed)
+	case []interface{}:
+		for _, item := range typed {
+			if text, ok := item.(string); ok {
+				claimValues = append(claimValues, text)
+			}
+		}
+	}
+	result := make([]string, 0)
+	for _, claimValue := range claimValues {
+		role, mapped := what.config.RoleMapping[claimValue]
+		if !mapped && model.Contains(Roles(), claimValue) {
+			role, mapped = claimValue, true
+		}
+		if mapped && !model.Contains(result, role) {
+			result = append(result, role)
+		}
+	}
+	return result
+}
@@ -0,0 +1,230 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestAuthenticateWithJWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := t.TempDir() + "/jwks.json"
+	if err := ioutil.WriteFile(jwksFile, jwks(t, map[string]interface{}{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := NewAuthenticator(Config{
+		JWKSFile:    jwksFile,
+		Audience:    "threagile",
+		RolesClaim:  "realm_access.roles",
+		RoleMapping: map[string]string{"security-team": RiskApprover},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := jwt.MapClaims{
+		"sub":          "alice",
+		"aud":          []string{"threagile", "other"},
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"realm_access": map[string]interface{}{"roles": []string{"editor", "security-team", "offline_access"}},
+	}
+	principal, err := authenticator.Authenticate(sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Subject != "alice" || len(principal.Roles) != 2 || !principal.HasRole(Editor) || !principal.HasRole(RiskApprover) || !principal.HasRole(Viewer) || principal.HasRole(Admin) {
+		t.Errorf("principal = %+v, want alice being editor and risk-approver", principal)
+	}
+	if _, err := authenticator.Authenticate(sign(t, jwt.SigningMethodES256, "ec", ecKey, valid)); err != nil {
+		t.Errorf("token signed with the ec key: %v", err)
+	}
+
+	invalid := map[string]string{
+		"expired":            sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(valid, "exp", time.Now().Add(-time.Minute).Unix())),
+		"without expiry":     sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(valid, "exp", nil)),
+		"for other audience": sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(valid, "aud", "other")),
+		"of unknown key":     sign(t, jwt.SigningMethodRS256, "unknown", rsaKey, valid),
+		"signed with hmac":   sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), valid),
+	}
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	invalid["with wrong signature"] = sign(t, jwt.SigningMethodRS256, "rsa", otherKey, valid)
+	for name, token := range invalid {
+		if principal, err := authenticator.Authenticate(token); err == nil {
+			t.Errorf("token %v accepted as %+v", name, principal)
+		}
+	}
+}
+
+func TestAuthenticateWithIssuer(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]interface{}{"first": &key.PublicKey}
+	jwksFetches := 0
+	var issuer *httptest.Server
+	issuer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_, _ = w.Write([]byte(`{"issuer":"` + issuer.URL + `","jwks_uri":"` + issuer.URL + `/keys"}`))
+		case "/keys":
+			jwksFetches++
+			_, _ = w.Write(jwks(t, keys))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer issuer.Close()
+
+	authenticator, err := NewAuthenticator(Config{Issuer: issuer.URL, RolesClaim: "groups", RoleMapping: map[string]string{"threagile-admins": Admin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.MapClaims{"sub": "bob", "iss": issuer.URL, "exp": time.Now().Add(time.Hour).Unix(), "groups": []string{"threagile-admins"}}
+	principal, err := authenticator.Authenticate(sign(t, jwt.SigningMethodRS256, "first", key, claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !principal.HasRole(RiskApprover) || !principal.HasAnyRole(Editor) {
+		t.Errorf("admin %+v lacks implied roles", principal)
+	}
+	if _, err := authenticator.Authenticate(sign(t, jwt.SigningMethodRS256, "first", key, with(claims, "iss", "https://other.example.com"))); err == nil {
+		t.Errorf("token of another issuer accepted")
+	}
+
+	// a rotated key is only fetched again after the refetch interval
+	keys["second"] = &key.PublicKey
+	if _, err := authenticator.Authenticate(sign(t, jwt.SigningMethodRS256, "second", key, claims)); err == nil || jwksFetches != 1 {
+		t.Errorf("token of a rotated key = %v after %v fetches, want rejection without fetching again", err, jwksFetches)
+	}
+	authenticator.keys.lastFetched = time.Now().Add(-2 * refetchInterval)
+	if _, err := authenticator.Authenticate(sign(t, jwt.SigningMethodRS256, "second", key, claims)); err != nil || jwksFetches != 2 {
+		t.Errorf("token of a rotated key = %v after %v fetches, want acceptance after fetching again", err, jwksFetches)
+	}
+}
+
+func TestKnownKeysWhileFetching(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]interface{}{"first": &key.PublicKey}
+	var jwksFetches int32
+	fetched := make(chan struct{}) // closed to let fetching the rotated keys finish
+	var issuer *httptest.Server
+	issuer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_, _ = w.Write([]byte(`{"issuer":"` + issuer.URL + `","jwks_uri":"` + issuer.URL + `/keys"}`))
+		case "/keys":
+			if atomic.AddInt32(&jwksFetches, 1) > 1 {
+				<-fetched
+			}
+			_, _ = w.Write(jwks(t, keys))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer issuer.Close()
+	authenticator, err := NewAuthenticator(Config{Issuer: issuer.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys["second"] = &key.PublicKey
+	authenticator.keys.lastFetched = time.Now().Add(-2 * refetchInterval)
+	rotated := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := authenticator.keys.key("second")
+			rotated <- err
+		}()
+	}
+	for atomic.LoadInt32(&jwksFetches) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	known := make(chan error, 1)
+	go func() {
+		_, err := authenticator.keys.key("first")
+		known <- err
+	}()
+	select {
+	case err := <-known:
+		if err != nil {
+			t.Errorf("known key while fetching = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("known key blocked by fetching the rotated keys")
+	}
+	close(fetched)
+	for i := 0; i < 2; i++ {
+		if err := <-rotated; err != nil {
+			t.Errorf("rotated key = %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&jwksFetches); got != 2 {
+		t.Errorf("jwks fetched %v times, want once more for both tokens of the rotated key", got)
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	result, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func with(claims jwt.MapClaims, name string, value interface{}) jwt.MapClaims {
+	result := jwt.MapClaims{}
+	for k, v := range claims {
+		result[k] = v
+	}
+	if value == nil {
+		delete(result, name)
+	} else {
+		result[name] = value
+	}
+	return result
+}
+
+func jwks(t *testing.T, keys map[string]interface{}) []byte {
+	encode := func(value *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(value.Bytes())
+	}
+	set := jsonWebKeySet{}
+	for kid, key := range keys {
+		switch typed := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jsonWebKey{Kid: kid, Kty: "RSA", Use: "sig", N: encode(typed.N), E: encode(big.NewInt(int64(typed.E)))})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jsonWebKey{Kid: kid, Kty: "EC", Crv: "P-256", X: encode(typed.X), Y: encode(typed.Y)})
+		}
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
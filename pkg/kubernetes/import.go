@@ -0,0 +1,388 @@
+package kubernetes
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/model/confidentiality"
+	"github.com/otyg/threagile/model/criticality"
+	"github.com/otyg/threagile/pkg/skeleton"
+)
+
+// ProvenanceTag marks the elements generated from Kubernetes manifests
+const ProvenanceTag = "kubernetes"
+
+// secret types without counterpart in the model
+var ignoredSecretTypes = []string{"helm.sh/release.v1", "kubernetes.io/service-account-token"}
+
+// ToModelInput generates the skeleton of a model: the workloads become technical assets (running as container) inside
+// trust boundaries of their namespaces, the secrets become data assets processed by the workloads using them. Ingresses
+// and services of type LoadBalancer or NodePort become communication links from the internet, network policies become
+// communication links between the workloads they allow to talk to each other. All generated elements carry the provenance
+// tag. All values which can not be derived from the manifests (like the CIA ratings) are set to defaults to be refined afterwards.
+func ToModelInput(manifests Manifests) model.ModelInput {
+	importer := kubernetesImporter{
+		manifests: manifests,
+		result:    skeleton.NewModel("Generated from Kubernetes Manifests"),
+		usedIds:   map[string]map[string]bool{"data_assets": {}, "technical_assets": {skeleton.Internet().ID: true}, "trust_boundaries": {}},
+		titles:    make(map[string]string),
+		secrets:   make(map[string]string),
+		linked:    make(map[string]bool),
+	}
+	importer.importSecrets()
+	importer.importWorkloads()
+	importer.importNamespaces()
+	importer.importIngresses()
+	importer.importExposedServices()
+	importer.importNetworkPolicies()
+	skeleton.TagGenerated(&importer.result, ProvenanceTag)
+	return importer.result
+}
+
+type kubernetesImporter struct {
+	manifests Manifests
+	result    model.ModelInput
+	usedIds   map[string]map[string]bool // per kind of element
+	titles    map[string]string          // ids of the technical assets to their titles
+	secrets   map[string]string          // namespace/name of the secrets to the ids of their data assets
+	workloads []workload
+	linked    map[string]bool // source and target ids of the communication links already there
+}
+
+type workload struct {
+	id, namespace string
+	labels        map[string]string
+	pod           podSpec
+}
+
+// name qualified by the namespace unless it is the default one
+func qualified(metadata Metadata) string {
+	if metadata.Namespace == "default" {
+		return metadata.Name
+	}
+	return metadata.Namespace + " " + metadata.Name
+}
+
+func (what *kubernetesImporter) importSecrets() {
+	for _, secret := range what.manifests.Secrets {
+		if model.Contains(ignoredSecretTypes, secret.Type) {
+			continue
+		}
+		id := skeleton.UniqueId(qualified(secret.Metadata), what.usedIds["data_assets"])
+		what.secrets[secret.Metadata.Namespace+"/"+secret.Metadata.Name] = id
+		description := "Kubernetes secret " + secret.Metadata.Name + " in namespace " + secret.Metadata.Namespace
+		if len(secret.Type) > 0 {
+			description += " of type " + secret.Type
+		}
+		what.result.Data_assets["Secret "+qualified(secret.Metadata)] = model.InputDataAsset{
+			ID:              id,
+			Description:     description,
+			Usage:           model.DevOps.String(),
+			Quantity:        model.VeryFew.String(),
+			Confidentiality: confidentiality.StrictlyConfidential.String(),
+			Integrity:       criticality.Critical.String(),
+			Availability:    criticality.Important.String(),
+		}
+	}
+}
+
+func (what *kubernetesImporter) importWorkloads() {
+	for _, object := range what.manifests.Workloads {
+		var spec workloadSpec
+		if err := object.Spec.Decode(&spec); err != nil {
+			continue
+		}
+		current := workload{namespace: object.Metadata.Namespace, labels: spec.Template.Metadata.Labels, pod: spec.Template.Spec}
+		switch object.Kind {
+		case "CronJob":
+			current.labels, current.pod = spec.JobTemplate.Spec.Template.Metadata.Labels, spec.JobTemplate.Spec.Template.Spec
+		case "Pod":
+			current.labels, current.pod = object.Metadata.Labels, spec.podSpec
+		}
+		current.id = skeleton.UniqueId(qualified(object.Metadata), what.usedIds["technical_assets"])
+
+		var images []string
+		for _, container := range current.pod.Containers {
+			images = append(images, container.Image)
+		}
+		technicalAsset := skeleton.NewTechnicalAsset(current.id, object.Kind+" "+object.Metadata.Name+" in namespace "+object.Metadata.Namespace+
+			" running "+strings.Join(images, ", "), model.Container)
+		technology, datastore := model.UnknownTechnology, len(spec.VolumeClaimTemplates) > 0
+		if len(images) > 0 {
+			technology, datastore = skeleton.TechnologyOfImage(images[0])
+			datastore = datastore || len(spec.VolumeClaimTemplates) > 0
+		}
+		if technology == model.UnknownTechnology && (object.Kind == "Job" || object.Kind == "CronJob") {
+			technology = model.Task
+		}
+		technicalAsset.Technology = technology.String()
+		if datastore {
+			technicalAsset.Type = model.Datastore.String()
+		}
+		technicalAsset.Data_assets_processed = what.secretsOf(current)
+
+		title := skeleton.UniqueTitle(qualified(object.Metadata), current.id, func(title string) bool { _, exists := what.result.Technical_assets[title]; return exists })
+		what.result.Technical_assets[title] = technicalAsset
+		what.titles[current.id] = title
+		what.workloads = append(what.workloads, current)
+	}
+}
+
+func (what *kubernetesImporter) secretsOf(workload workload) []string {
+	var names []string
+	for _, container := range append(append([]container{}, workload.pod.InitContainers...), workload.pod.Containers...) {
+		for _, env := range container.Env {
+			names = append(names, env.ValueFrom.SecretKeyRef.Name)
+		}
+		for _, envFrom := range container.EnvFrom {
+			names = append(names, envFrom.SecretRef.Name)
+		}
+	}
+	for _, volume := range workload.pod.Volumes {
+		names = append(names, volume.Secret.SecretName)
+		for _, source := range volume.Projected.Sources {
+			names = append(names, source.Secret.Name)
+		}
+	}
+	for _, imagePullSecret := range workload.pod.ImagePullSecrets {
+		names = append(names, imagePullSecret.Name)
+	}
+	var result []string
+	for _, name := range names {
+		if id, ok := what.secrets[workload.namespace+"/"+name]; ok && !model.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// importNamespaces creates a trust boundary per namespace with workloads, isolated by network policies if there are any
+func (what *kubernetesImporter) importNamespaces() {
+	var namespaces []string
+	inside := make(map[string][]string)
+	for _, workload := range what.workloads {
+		if _, exists := inside[workload.namespace]; !exists {
+			namespaces = append(namespaces, workload.namespace)
+		}
+		inside[workload.namespace] = append(inside[workload.namespace], workload.id)
+	}
+	for _, namespace := range namespaces {
+		trustBoundaryType := model.ExecutionEnvironment
+		for _, networkPolicy := range what.manifests.NetworkPolicies {
+			if networkPolicy.Metadata.Namespace == namespace {
+				trustBoundaryType = model.NetworkPolicyNamespaceIsolation
+			}
+		}
+		what.result.Trust_boundaries["Namespace "+namespace] = model.InputTrustBoundary{
+			ID:                      skeleton.UniqueId("namespace "+namespace, what.usedIds["trust_boundaries"]),
+			Description:             "Kubernetes namespace " + namespace,
+			Type:                    trustBoundaryType.String(),
+			Technical_assets_inside: inside[namespace],
+		}
+	}
+}
+
+func (what *kubernetesImporter) importIngresses() {
+	for _, ingress := range what.manifests.Ingresses {
+		var spec ingressSpec
+		if err := ingress.Spec.Decode(&spec); err != nil {
+			continue
+		}
+		var tlsHosts []string
+		for _, tls := range spec.TLS {
+			tlsHosts = append(tlsHosts, tls.Hosts...)
+		}
+		route := func(host, path string, backend *ingressBackend) {
+			if backend == nil {
+				return
+			}
+			serviceName := backend.Service.Name
+			if len(serviceName) == 0 {
+				serviceName = backend.ServiceName
+			}
+			protocol := model.HTTP
+			if len(spec.TLS) > 0 && (len(host) == 0 || len(tlsHosts) == 0 || model.Contains(tlsHosts, host)) {
+				protocol = model.HTTPS
+			}
+			if len(host) == 0 {
+				host = "*"
+			}
+			for _, target := range what.workloadsOfService(ingress.Metadata.Namespace, serviceName) {
+				what.addInternetLink(target, "Ingress "+ingress.Metadata.Name, "Ingress "+ingress.Metadata.Name+" routing "+host+path+" to service "+serviceName, protocol)
+			}
+		}
+		route("", "", spec.DefaultBackend)
+		route("", "", spec.Backend)
+		for _, rule := range spec.Rules {
+			for _, path := range rule.HTTP.Paths {
+				backend := path.Backend
+				route(rule.Host, path.Path, &backend)
+			}
+		}
+	}
+}
+
+func (what *kubernetesImporter) importExposedServices() {
+	for _, service := range what.manifests.Services {
+		var spec serviceSpec
+		if err := service.Spec.Decode(&spec); err != nil || (spec.Type != "LoadBalancer" && spec.Type != "NodePort") {
+			continue
+		}
+		protocol := model.UnknownProtocol
+		if len(spec.Ports) > 0 {
+			name := spec.Ports[0].AppProtocol
+			if len(name) == 0 {
+				name = spec.Ports[0].Name
+			}
+			protocol = skeleton.ProtocolOfPort(spec.Ports[0].Port, name)
+		}
+		for _, target := range what.workloadsMatching(service.Metadata.Namespace, spec.Selector) {
+			what.addInternetLink(target, "Service "+service.Metadata.Name, "Service "+service.Metadata.Name+" of type "+spec.Type, protocol)
+		}
+	}
+}
+
+func (what *kubernetesImporter) importNetworkPolicies() {
+	for _, networkPolicy := range what.manifests.NetworkPolicies {
+		var spec networkPolicySpec
+		if err := networkPolicy.Spec.Decode(&spec); err != nil {
+			continue
+		}
+		namespace := networkPolicy.Metadata.Namespace
+		selected := what.workloadsSelected(namespace, spec.PodSelector)
+		description := "Allowed by network policy " + networkPolicy.Metadata.Name
+		for _, rule := range spec.Ingress {
+			for _, source := range what.peers(namespace, rule.From) {
+				for _, target := range selected {
+					what.addLink(source, target, description, what.protocolOf(target, rule.Ports))
+				}
+			}
+		}
+		for _, rule := range spec.Egress {
+			for _, target := range what.peers(namespace, rule.To) {
+				for _, source := range selected {
+					what.addLink(source, target, description, what.protocolOf(target, rule.Ports))
+				}
+			}
+		}
+	}
+}
+
+func (what *kubernetesImporter) addInternetLink(target workload, title, description string, protocol model.Protocol) {
+	internet := skeleton.Internet()
+	if _, exists := what.titles[internet.ID]; !exists {
+		what.titles[internet.ID] = skeleton.UniqueTitle("Internet", internet.ID, func(title string) bool { _, exists := what.result.Technical_assets[title]; return exists })
+		what.result.Technical_assets[what.titles[internet.ID]] = internet
+	}
+	technicalAsset := what.result.Technical_assets[what.titles[target.id]]
+	technicalAsset.Internet = true
+	what.result.Technical_assets[what.titles[target.id]] = technicalAsset
+	what.addCommunicationLink(internet.ID, target.id, title+" to "+what.titles[target.id], description, protocol)
+}
+
+func (what *kubernetesImporter) addLink(source, target workload, description string, protocol model.Protocol) {
+	if source.id != target.id {
+		what.addCommunicationLink(source.id, target.id, what.titles[target.id]+" Traffic", description, protocol)
+	}
+}
+
+func (what *kubernetesImporter) addCommunicationLink(sourceId, targetId, title, description string, protocol model.Protocol) {
+	if what.linked[sourceId+">"+targetId] {
+		return
+	}
+	what.linked[sourceId+">"+targetId] = true
+	source := what.result.Technical_assets[what.titles[sourceId]]
+	title = skeleton.UniqueTitle(title, targetId, func(title string) bool { _, exists := source.Communication_links[title]; return exists })
+	source.Communication_links[title] = skeleton.NewCommunicationLink(targetId, description, protocol)
+	what.result.Technical_assets[what.titles[sourceId]] = source
+}
+
+// protocolOf guesses the protocol by the ports of the network policy rule, or else by the ports of the containers
+func (what *kubernetesImporter) protocolOf(target workload, ports []networkPolicyPort) model.Protocol {
+	if len(ports) > 0 {
+		number, name := port(ports[0].Port)
+		if protocol := skeleton.ProtocolOfPort(number, name); protocol != model.UnknownProtocol || len(name) == 0 {
+			return protocol
+		}
+		for _, container := range target.pod.Containers {
+			for _, containerPort := range container.Ports {
+				if containerPort.Name == name {
+					return skeleton.ProtocolOfPort(containerPort.ContainerPort, name)
+				}
+			}
+		}
+		return model.UnknownProtocol
+	}
+	for _, container := range target.pod.Containers {
+		for _, containerPort := range container.Ports {
+			return skeleton.ProtocolOfPort(containerPort.ContainerPort, containerPort.Name)
+		}
+	}
+	return model.UnknownProtocol
+}
+
+func (what *kubernetesImporter) workloadsOfService(namespace, name string) []workload {
+	for _, service := range what.manifests.Services {
+		var spec serviceSpec
+		if service.Metadata.Namespace == namespace && service.Metadata.Name == name && service.Spec.Decode(&spec) == nil {
+			return what.workloadsMatching(namespace, spec.Selector)
+		}
+	}
+	return nil
+}
+
+// workloadsMatching are the workloads of the namespace with all the labels (of a service selector)
+func (what *kubernetesImporter) workloadsMatching(namespace string, labels map[string]string) []workload {
+	if len(labels) == 0 {
+		return nil
+	}
+	return what.workloadsSelected(namespace, labelSelector{MatchLabels: labels})
+}
+
+func (what *kubernetesImporter) workloadsSelected(namespace string, selector labelSelector) []workload {
+	var result []workload
+	for _, workload := range what.workloads {
+		if workload.namespace == namespace && selector.matches(workload.labels) {
+			result = append(result, workload)
+		}
+	}
+	return result
+}
+
+// peers are the workloads selected by the peers of a network policy rule (ip blocks are not resolved)
+func (what *kubernetesImporter) peers(namespace string, peers []networkPolicyPeer) []workload {
+	var result []workload
+	for _, peer := range peers {
+		if peer.PodSelector == nil && peer.NamespaceSelector == nil {
+			continue
+		}
+		podSelector := labelSelector{}
+		if peer.PodSelector != nil {
+			podSelector = *peer.PodSelector
+		}
+		for _, workload := range what.workloads {
+			if !podSelector.matches(workload.labels) {
+				continue
+			}
+			if peer.NamespaceSelector == nil && workload.namespace == namespace ||
+				peer.NamespaceSelector != nil && peer.NamespaceSelector.matches(what.namespaceLabels(workload.namespace)) {
+				result = append(result, workload)
+			}
+		}
+	}
+	return result
+}
+
+func (what *kubernetesImporter) namespaceLabels(namespace string) map[string]string {
+	labels := map[string]string{"kubernetes.io/metadata.name": namespace}
+	for _, object := range what.manifests.Namespaces {
+		if object.Metadata.Name == namespace {
+			for key, value := range object.Metadata.Labels {
+				labels[key] = value
+			}
+		}
+	}
+	return labels
+}
@@ -0,0 +1,293 @@
+// Package kubernetes generates model skeletons out of Kubernetes manifests (like the output of helm template).
+package kubernetes
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifests are the (relevant) objects of all documents read, grouped by kind
+type Manifests struct {
+	Namespaces      []Object
+	Workloads       []Object // Deployments, StatefulSets, DaemonSets, Jobs, CronJobs, and Pods
+	Services        []Object
+	Ingresses       []Object
+	NetworkPolicies []Object
+	Secrets         []Object
+}
+
+type Object struct {
+	Kind     string    `yaml:"kind"`
+	Metadata Metadata  `yaml:"metadata"`
+	Spec     yaml.Node `yaml:"spec"`
+	Type     string    `yaml:"type"` // of secrets
+}
+
+type Metadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace"`
+	Labels    map[string]string `yaml:"labels"`
+}
+
+type list struct {
+	Items []yaml.Node `yaml:"items"`
+}
+
+type workloadSpec struct {
+	Template             podTemplate `yaml:"template"`
+	VolumeClaimTemplates []yaml.Node `yaml:"volumeClaimTemplates"`
+	JobTemplate          struct {
+		Spec struct {
+			Template podTemplate `yaml:"template"`
+		} `yaml:"spec"`
+	} `yaml:"jobTemplate"`
+	podSpec `yaml:",inline"` // of pods
+}
+
+type podTemplate struct {
+	Metadata Metadata `yaml:"metadata"`
+	Spec     podSpec  `yaml:"spec"`
+}
+
+type podSpec struct {
+	Containers       []container `yaml:"containers"`
+	InitContainers   []container `yaml:"initContainers"`
+	Volumes          []volume    `yaml:"volumes"`
+	ImagePullSecrets []reference `yaml:"imagePullSecrets"`
+}
+
+type container struct {
+	Name  string `yaml:"name"`
+	Image string `yaml:"image"`
+	Ports []struct {
+		Name          string `yaml:"name"`
+		ContainerPort int    `yaml:"containerPort"`
+	} `yaml:"ports"`
+	Env []struct {
+		ValueFrom struct {
+			SecretKeyRef reference `yaml:"secretKeyRef"`
+		} `yaml:"valueFrom"`
+	} `yaml:"env"`
+	EnvFrom []struct {
+		SecretRef reference `yaml:"secretRef"`
+	} `yaml:"envFrom"`
+}
+
+type volume struct {
+	Secret struct {
+		SecretName string `yaml:"secretName"`
+	} `yaml:"secret"`
+	Projected struct {
+		Sources []struct {
+			Secret reference `yaml:"secret"`
+		} `yaml:"sources"`
+	} `yaml:"projected"`
+}
+
+type reference struct {
+	Name string `yaml:"name"`
+}
+
+type serviceSpec struct {
+	Type     string            `yaml:"type"`
+	Selector map[string]string `yaml:"selector"`
+	Ports    []struct {
+		Name        string `yaml:"name"`
+		Port        int    `yaml:"port"`
+		AppProtocol string `yaml:"appProtocol"`
+	} `yaml:"ports"`
+}
+
+type ingressSpec struct {
+	TLS []struct {
+		Hosts []string `yaml:"hosts"`
+	} `yaml:"tls"`
+	DefaultBackend *ingressBackend `yaml:"defaultBackend"`
+	Backend        *ingressBackend `yaml:"backend"` // of extensions/v1beta1
+	Rules          []struct {
+		Host string `yaml:"host"`
+		HTTP struct {
+			Paths []struct {
+				Path    string         `yaml:"path"`
+				Backend ingressBackend `yaml:"backend"`
+			} `yaml:"paths"`
+		} `yaml:"http"`
+	} `yaml:"rules"`
+}
+
+type ingressBackend struct {
+	Service struct {
+		Name string `yaml:"name"`
+	} `yaml:"service"`
+	ServiceName string `yaml:"serviceName"` // of extensions/v1beta1
+}
+
+type networkPolicySpec struct {
+	PodSelector labelSelector `yaml:"podSelector"`
+	Ingress     []struct {
+		From  []networkPolicyPeer `yaml:"from"`
+		Ports []networkPolicyPort `yaml:"ports"`
+	} `yaml:"ingress"`
+	Egress []struct {
+		To    []networkPolicyPeer `yaml:"to"`
+		Ports []networkPolicyPort `yaml:"ports"`
+	} `yaml:"egress"`
+}
+
+type networkPolicyPeer struct {
+	PodSelector       *labelSelector `yaml:"podSelector"`
+	NamespaceSelector *labelSelector `yaml:"namespaceSelector"`
+}
+
+type networkPolicyPort struct {
+	Port string `yaml:"port"`
+}
+
+type labelSelector struct {
+	MatchLabels      map[string]string `yaml:"matchLabels"`
+	MatchExpressions []struct {
+		Key      string   `yaml:"key"`
+		Operator string   `yaml:"operator"`
+		Values   []string `yaml:"values"`
+	} `yaml:"matchExpressions"`
+}
+
+// Read parses the manifests of the file, of all .yaml and .yml files within the directory (recursively), or of stdin for "-"
+func Read(path string) (Manifests, error) {
+	result := Manifests{}
+	if path == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return result, err
+		}
+		return result, result.Parse(data)
+	}
+	var filenames []string
+	err := filepath.Walk(path, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		extension := strings.ToLower(filepath.Ext(filename))
+		if !info.IsDir() && (filename == path || extension == ".yaml" || extension == ".yml") {
+			filenames = append(filenames, filename)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return result, err
+		}
+		if err := result.Parse(data); err != nil {
+			return result, errors.New(filename + ": " + err.Error())
+		}
+	}
+	return result, nil
+}
+
+// Parse adds the objects of all (multi-document) YAML documents, ignoring the ones of irrelevant kinds
+func (what *Manifests) Parse(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := what.add(&document); err != nil {
+			return err
+		}
+	}
+}
+
+func (what *Manifests) add(document *yaml.Node) error {
+	var object Object
+	if err := document.Decode(&object); err != nil {
+		return err
+	}
+	if len(object.Metadata.Namespace) == 0 {
+		object.Metadata.Namespace = "default"
+	}
+	switch object.Kind {
+	case "List":
+		var items list
+		if err := document.Decode(&items); err != nil {
+			return err
+		}
+		for i := range items.Items {
+			if err := what.add(&items.Items[i]); err != nil {
+				return err
+			}
+		}
+	case "Namespace":
+		object.Metadata.Namespace = object.Metadata.Name
+		what.Namespaces = append(what.Namespaces, object)
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job", "CronJob", "Pod":
+		what.Workloads = append(what.Workloads, object)
+	case "Service":
+		what.Services = append(what.Services, object)
+	case "Ingress":
+		what.Ingresses = append(what.Ingresses, object)
+	case "NetworkPolicy":
+		what.NetworkPolicies = append(what.NetworkPolicies, object)
+	case "Secret":
+		what.Secrets = append(what.Secrets, object)
+	}
+	return nil
+}
+
+func (what labelSelector) matches(labels map[string]string) bool {
+	for key, value := range what.MatchLabels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	for _, expression := range what.MatchExpressions {
+		value, exists := labels[expression.Key]
+		in := false
+		for _, candidate := range expression.Values {
+			in = in || exists && candidate == value
+		}
+		switch expression.Operator {
+		case "In":
+			if !in {
+				return false
+			}
+		case "NotIn":
+			if in {
+				return false
+			}
+		case "Exists":
+			if !exists {
+				return false
+			}
+		case "DoesNotExist":
+			if exists {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// port is either a number or the name of a port
+func port(value string) (number int, name string) {
+	if number, err := strconv.Atoi(value); err == nil {
+		return number, ""
+	}
+	return 0, value
+}
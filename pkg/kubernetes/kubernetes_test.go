@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/threagile"
+	"gopkg.in/yaml.v3"
+)
+
+func TestImport(t *testing.T) {
+	manifests, err := Read("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modelInput := ToModelInput(manifests)
+	modelYaml, err := yaml.Marshal(&modelInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.ThreagileVersion = "test"
+	result, err := threagile.Analyze(context.Background(), modelYaml, threagile.Options{
+		RAA:       func() string { return "" },
+		RiskRules: map[string]model.RiskRule{},
+	})
+	if err != nil {
+		t.Fatalf("generated model is not ok: %v\n%s", err, modelYaml)
+	}
+	parsedModel := result.ParsedModel()
+
+	for id, want := range map[string]model.TechnicalAssetTechnology{"shop-frontend": model.ReverseProxy, "shop-api": model.UnknownTechnology,
+		"shop-database": model.Database, "shop-cleanup": model.Task, "node-exporter": model.UnknownTechnology} {
+		technicalAsset, ok := parsedModel.TechnicalAssets[id]
+		if !ok || technicalAsset.Technology != want || technicalAsset.Machine != model.Container || !model.Contains(technicalAsset.Tags, ProvenanceTag) {
+			t.Errorf("technical asset %v = %+v, want technology %v and tag %v", id, technicalAsset, want, ProvenanceTag)
+		}
+	}
+	if got := parsedModel.TechnicalAssets["shop-database"].Type; got != model.Datastore {
+		t.Errorf("type of shop-database = %v, want %v", got, model.Datastore)
+	}
+	for id, want := range map[string]bool{"shop-frontend": true, "shop-api": true, "shop-database": false} {
+		if got := parsedModel.TechnicalAssets[id].Internet; got != want {
+			t.Errorf("internet of %v = %v, want %v", id, got, want)
+		}
+	}
+
+	if got := parsedModel.TrustBoundaries["namespace-shop"]; got.Type != model.NetworkPolicyNamespaceIsolation || len(got.TechnicalAssetsInside) != 4 {
+		t.Errorf("namespace-shop = %+v", got)
+	}
+	if got := parsedModel.TrustBoundaries["namespace-default"]; got.Type != model.ExecutionEnvironment || len(got.TechnicalAssetsInside) != 1 {
+		t.Errorf("namespace-default = %+v", got)
+	}
+
+	if len(parsedModel.DataAssets) != 1 {
+		t.Errorf("data assets = %+v, want only the database credentials", parsedModel.DataAssets)
+	}
+	for _, id := range []string{"shop-api", "shop-database"} {
+		if got := parsedModel.TechnicalAssets[id].DataAssetsProcessed; len(got) != 1 || got[0] != "shop-database-credentials" {
+			t.Errorf("data assets processed by %v = %v", id, got)
+		}
+	}
+
+	links := make(map[string]model.Protocol)
+	for _, technicalAsset := range parsedModel.TechnicalAssets {
+		for _, communicationLink := range technicalAsset.CommunicationLinks {
+			links[communicationLink.SourceId+">"+communicationLink.TargetId] = communicationLink.Protocol
+		}
+	}
+	want := map[string]model.Protocol{
+		"internet>shop-frontend": model.HTTPS,
+		"internet>shop-api":      model.HTTPS,
+		"shop-frontend>shop-api": model.HTTP,
+		"shop-api>shop-database": model.SQL_access_protocol,
+	}
+	if len(links) != len(want) {
+		t.Errorf("communication links = %v, want %v", links, want)
+	}
+	for link, protocol := range want {
+		if got, ok := links[link]; !ok || got != protocol {
+			t.Errorf("protocol of communication link %v = %v, want %v", link, got, protocol)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/model/confidentiality"
+	"github.com/otyg/threagile/model/criticality"
+)
+
+// DataFormats are the data formats accepted by the request bodies
+func (what Document) DataFormats() []model.DataFormat {
+	var result []model.DataFormat
+	for _, contentType := range what.RequestContentTypes() {
+		dataFormat, ok := dataFormatOf(contentType)
+		if !ok {
+			continue
+		}
+		found := false
+		for _, existing := range result {
+			found = found || existing == dataFormat
+		}
+		if !found {
+			result = append(result, dataFormat)
+		}
+	}
+	sort.Sort(model.ByDataFormatAcceptedSort(result))
+	return result
+}
+
+func dataFormatOf(contentType string) (model.DataFormat, bool) {
+	switch {
+	case contentType == "application/json" || strings.HasSuffix(contentType, "+json"):
+		return model.JSON, true
+	case strings.HasSuffix(contentType, "/xml") || strings.HasSuffix(contentType, "+xml"):
+		return model.XML, true
+	case contentType == "text/csv":
+		return model.CSV, true
+	case contentType == "application/x-java-serialized-object" || strings.Contains(contentType, "protobuf") ||
+		strings.Contains(contentType, "msgpack") || strings.Contains(contentType, "cbor"):
+		return model.Serialization, true
+	case contentType == "multipart/form-data" || contentType == "application/octet-stream" || contentType == "application/pdf" ||
+		strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "video/"):
+		return model.File, true
+	}
+	return model.JSON, false
+}
+
+// Authentication is the weakest authentication accepted by any operation, thus none when any operation is public,
+// false when the document has no operations at all
+func (what Document) Authentication() (model.Authentication, bool) {
+	operations := what.operations()
+	if len(operations) == 0 {
+		return model.NoneAuthentication, false
+	}
+	result := model.Externalized
+	for _, operation := range operations {
+		if authentication := what.authenticationOfOperation(operation); authentication < result {
+			result = authentication
+		}
+	}
+	return result, true
+}
+
+// authenticationOfOperation is the weakest of the alternative security requirements, each requiring all of its schemes
+// (thus accepting the strongest of them)
+func (what Document) authenticationOfOperation(operation *Operation) model.Authentication {
+	requirements := what.securityRequirements(operation)
+	if len(requirements) == 0 {
+		return model.NoneAuthentication
+	}
+	result := model.Externalized
+	for _, requirement := range requirements {
+		strongest := model.NoneAuthentication
+		for name := range requirement {
+			if scheme, ok := what.Components.SecuritySchemes[name]; ok && authenticationOf(scheme) > strongest {
+				strongest = authenticationOf(scheme)
+			}
+		}
+		if strongest < result {
+			result = strongest
+		}
+	}
+	return result
+}
+
+func authenticationOf(scheme SecurityScheme) model.Authentication {
+	switch strings.ToLower(scheme.Type) {
+	case "http":
+		if strings.ToLower(scheme.Scheme) == "bearer" {
+			return model.Token
+		}
+		return model.Credentials
+	case "apikey":
+		if strings.ToLower(scheme.In) == "cookie" {
+			return model.SessionId
+		}
+		return model.Token
+	case "mutualtls":
+		return model.ClientCertificate
+	}
+	return model.Token // oauth2 and openIdConnect
+}
+
+// confidentialFields are parts of field names hinting at the confidentiality of the data, strongest first
+var confidentialFields = []struct {
+	confidentiality confidentiality.Confidentiality
+	names           []string
+}{
+	{confidentiality.StrictlyConfidential, []string{"password", "passwd", "secret", "token", "apikey", "privatekey", "creditcard", "cardnumber", "cvv", "cvc"}},
+	{confidentiality.Confidential, []string{"iban", "accountnumber", "socialsecurity", "taxid", "passport", "birth", "salary", "diagnosis"}},
+	{confidentiality.Restricted, []string{"email", "phone", "mobile", "address", "street", "zip", "postcode", "postalcode", "firstname", "lastname", "surname"}},
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]`)
+
+// GuessConfidentiality rates the confidentiality of data by the names of its fields (internal if none matches)
+func GuessConfidentiality(fieldNames []string) (result confidentiality.Confidentiality, matching []string) {
+	for _, level := range confidentialFields {
+		for _, fieldName := range fieldNames {
+			normalized := nonAlphanumeric.ReplaceAllString(strings.ToLower(fieldName), "")
+			for _, name := range level.names {
+				if strings.Contains(normalized, name) && !model.Contains(matching, fieldName) {
+					matching = append(matching, fieldName)
+				}
+			}
+		}
+		if len(matching) > 0 {
+			return level.confidentiality, matching
+		}
+	}
+	return confidentiality.Internal, nil
+}
+
+// DataAssets proposes a data asset (by title) for each schema with fields, rated by GuessConfidentiality
+func (what Document) DataAssets() map[string]model.InputDataAsset {
+	result := make(map[string]model.InputDataAsset)
+	for name, schema := range what.Components.Schemas {
+		fieldNames := what.FieldNames(schema)
+		if len(fieldNames) == 0 {
+			continue
+		}
+		rating, matching := GuessConfidentiality(fieldNames)
+		justification := "No fields hinting at a higher confidentiality found in the OpenAPI schema " + name + "."
+		if len(matching) > 0 {
+			justification = "Rated as '" + rating.String() + "' due to the fields " + strings.Join(matching, ", ") + " of the OpenAPI schema " + name + "."
+		}
+		result[name] = model.InputDataAsset{
+			ID:                       model.MakeID(name),
+			Description:              "Data of the OpenAPI schema " + name,
+			Usage:                    model.Business.String(),
+			Tags:                     []string{},
+			Quantity:                 model.Many.String(),
+			Confidentiality:          rating.String(),
+			Integrity:                criticality.Operational.String(),
+			Availability:             criticality.Operational.String(),
+			Justification_cia_rating: justification,
+		}
+	}
+	return result
+}
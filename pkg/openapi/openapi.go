@@ -0,0 +1,183 @@
+// Package openapi derives data formats, authentication, and data assets of a technical asset from its OpenAPI 3 document.
+package openapi
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is the (relevant part of an) OpenAPI 3 document
+type Document struct {
+	OpenAPI    string                `yaml:"openapi"`
+	Paths      map[string]PathItem   `yaml:"paths"`
+	Components Components            `yaml:"components"`
+	Security   []map[string][]string `yaml:"security"`
+}
+
+type PathItem struct {
+	Get     *Operation `yaml:"get"`
+	Put     *Operation `yaml:"put"`
+	Post    *Operation `yaml:"post"`
+	Delete  *Operation `yaml:"delete"`
+	Options *Operation `yaml:"options"`
+	Head    *Operation `yaml:"head"`
+	Patch   *Operation `yaml:"patch"`
+	Trace   *Operation `yaml:"trace"`
+}
+
+type Operation struct {
+	RequestBody *RequestBody           `yaml:"requestBody"`
+	Security    *[]map[string][]string `yaml:"security"` // nil when the security of the document applies
+}
+
+type RequestBody struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]MediaType `yaml:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]Schema         `yaml:"schemas"`
+	RequestBodies   map[string]RequestBody    `yaml:"requestBodies"`
+	SecuritySchemes map[string]SecurityScheme `yaml:"securitySchemes"`
+}
+
+type Schema struct {
+	Ref        string            `yaml:"$ref"`
+	Type       string            `yaml:"type"`
+	Properties map[string]Schema `yaml:"properties"`
+	Items      *Schema           `yaml:"items"`
+	AllOf      []Schema          `yaml:"allOf"`
+	OneOf      []Schema          `yaml:"oneOf"`
+	AnyOf      []Schema          `yaml:"anyOf"`
+}
+
+type SecurityScheme struct {
+	Type   string `yaml:"type"`
+	Scheme string `yaml:"scheme"`
+	In     string `yaml:"in"`
+}
+
+// Parse reads an OpenAPI 3 document in YAML or JSON
+func Parse(data []byte) (result Document, err error) {
+	err = yaml.Unmarshal(data, &result)
+	return result, err
+}
+
+func (what Document) operations() []*Operation {
+	paths := make([]string, 0, len(what.Paths))
+	for path := range what.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var result []*Operation
+	for _, path := range paths {
+		item := what.Paths[path]
+		for _, operation := range []*Operation{item.Get, item.Put, item.Post, item.Delete, item.Options, item.Head, item.Patch, item.Trace} {
+			if operation != nil {
+				result = append(result, operation)
+			}
+		}
+	}
+	return result
+}
+
+// RequestContentTypes are the media types of all request bodies
+func (what Document) RequestContentTypes() []string {
+	var result []string
+	for _, operation := range what.operations() {
+		requestBody := operation.RequestBody
+		if requestBody == nil {
+			continue
+		}
+		if len(requestBody.Ref) > 0 {
+			referenced := what.Components.RequestBodies[componentName(requestBody.Ref)]
+			requestBody = &referenced
+		}
+		for contentType := range requestBody.Content {
+			contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+			if !model.Contains(result, contentType) {
+				result = append(result, contentType)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// SecuritySchemesUsed are the security schemes required by any operation (declared ones not required by any operation are not enforced)
+func (what Document) SecuritySchemesUsed() []SecurityScheme {
+	var names []string
+	for _, operation := range what.operations() {
+		for _, requirement := range what.securityRequirements(operation) {
+			for name := range requirement {
+				if !model.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	var result []SecurityScheme
+	for _, name := range names {
+		if scheme, ok := what.Components.SecuritySchemes[name]; ok {
+			result = append(result, scheme)
+		}
+	}
+	return result
+}
+
+// securityRequirements are the alternative security requirements of the operation (its own, otherwise those of the document),
+// where none at all (like security: []) or an empty one (like {}) allows anonymous access
+func (what Document) securityRequirements(operation *Operation) []map[string][]string {
+	if operation.Security != nil {
+		return *operation.Security
+	}
+	return what.Security
+}
+
+// FieldNames are the names of all properties of a schema, including those of nested and referenced schemas
+func (what Document) FieldNames(schema Schema) []string {
+	var result []string
+	visited := make(map[string]bool)
+	var collect func(schema Schema)
+	collect = func(schema Schema) {
+		if len(schema.Ref) > 0 {
+			name := componentName(schema.Ref)
+			if visited[name] {
+				return
+			}
+			visited[name] = true
+			collect(what.Components.Schemas[name])
+			return
+		}
+		for name, property := range schema.Properties {
+			if !model.Contains(result, name) {
+				result = append(result, name)
+			}
+			collect(property)
+		}
+		if schema.Items != nil {
+			collect(*schema.Items)
+		}
+		for _, parts := range [][]Schema{schema.AllOf, schema.OneOf, schema.AnyOf} {
+			for _, part := range parts {
+				collect(part)
+			}
+		}
+	}
+	collect(schema)
+	sort.Strings(result)
+	return result
+}
+
+// componentName is the last part of a local reference like #/components/schemas/Customer
+func componentName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
@@ -0,0 +1,64 @@
+package openapi
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/otyg/threagile/model"
+)
+
+func TestEnrichment(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := document.DataFormats(); len(got) != 3 || got[0] != model.File || got[1] != model.JSON || got[2] != model.XML {
+		t.Errorf("data formats = %v, want file, json, and xml", got)
+	}
+	if got, ok := document.Authentication(); !ok || got != model.NoneAuthentication {
+		t.Errorf("authentication = %v, want none of the public health check (security: [])", got)
+	}
+	if got := document.SecuritySchemesUsed(); len(got) != 2 || got[0].Scheme != "basic" || got[1].Scheme != "bearer" {
+		t.Errorf("security schemes used = %+v, want basic and bearer (but not the declared mutual TLS)", got)
+	}
+
+	dataAssets := document.DataAssets()
+	if _, exists := dataAssets["Status"]; exists || len(dataAssets) != 4 {
+		t.Errorf("data assets = %+v, want one per schema with fields", dataAssets)
+	}
+	for title, want := range map[string]string{"Customer": "confidential", "Contact": "restricted", "Credentials": "strictly-confidential", "Product": "internal"} {
+		if got := dataAssets[title]; got.ID != model.MakeID(title) || got.Confidentiality != want {
+			t.Errorf("data asset %v = %+v, want confidentiality %v", title, got, want)
+		}
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	const schemes = "components:\n  securitySchemes:\n    bearerAuth:\n      type: http\n      scheme: bearer\n    basicAuth:\n      type: http\n      scheme: basic\n    mtls:\n      type: mutualTLS\n"
+	for _, test := range []struct {
+		name, yaml string
+		want       model.Authentication
+		wantOk     bool
+	}{
+		{"no operations", "security:\n  - bearerAuth: []\n" + schemes, model.NoneAuthentication, false},
+		{"declared schemes only", "paths:\n  /a:\n    get: {}\n" + schemes, model.NoneAuthentication, true},
+		{"document security", "security:\n  - bearerAuth: []\npaths:\n  /a:\n    get: {}\n" + schemes, model.Token, true},
+		{"weaker operation security", "security:\n  - bearerAuth: []\npaths:\n  /a:\n    get: {}\n    post:\n      security:\n        - basicAuth: []\n" + schemes, model.Credentials, true},
+		{"public operation", "security:\n  - bearerAuth: []\npaths:\n  /a:\n    get:\n      security: []\n" + schemes, model.NoneAuthentication, true},
+		{"optional security", "paths:\n  /a:\n    get:\n      security:\n        - {}\n        - bearerAuth: []\n" + schemes, model.NoneAuthentication, true},
+		{"all of a requirement", "paths:\n  /a:\n    get:\n      security:\n        - basicAuth: []\n          mtls: []\n" + schemes, model.ClientCertificate, true},
+	} {
+		document, err := Parse([]byte(test.yaml))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := document.Authentication(); got != test.want || ok != test.wantOk {
+			t.Errorf("authentication of %v = %v (%v), want %v (%v)", test.name, got, ok, test.want, test.wantOk)
+		}
+	}
+}
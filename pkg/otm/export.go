@@ -0,0 +1,283 @@
+package otm
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/model/confidentiality"
+	"github.com/otyg/threagile/model/core"
+	"github.com/otyg/threagile/model/criticality"
+)
+
+// neutralTrustRating is used for all trust zones, as Threagile does not rate its trust boundaries
+const neutralTrustRating = 50
+
+// projectAttributes are the parts of the model without OTM counterpart
+type projectAttributes struct {
+	Date                       string                              `json:"date"`
+	Business_criticality       string                              `json:"business_criticality"`
+	Management_summary_comment string                              `json:"management_summary_comment"`
+	Technical_overview         string                              `json:"technical_overview"`
+	Questions                  map[string]string                   `json:"questions"`
+	Abuse_cases                map[string]string                   `json:"abuse_cases"`
+	Security_requirements      map[string]string                   `json:"security_requirements"`
+	Shared_runtimes            map[string]model.InputSharedRuntime `json:"shared_runtimes"`
+}
+
+// FromModelInput converts the model (merged with its includes) into OTM. The risks of an analysis (if given)
+// become threats with mitigations, their state is taken from the risk tracking.
+func FromModelInput(input model.ModelInput, risks []model.Risk, riskTracking map[string]model.RiskTracking) OTM {
+	result := OTM{
+		OtmVersion: Version,
+		Project: Project{
+			Name:         input.Title,
+			Id:           model.MakeID(input.Title),
+			Description:  input.Business_overview.Description,
+			Owner:        input.Author.Name,
+			OwnerContact: input.Author.Homepage,
+			Tags:         input.Tags_available,
+			Attributes: attributesOf(projectAttributes{input.Date, input.Business_criticality, input.Management_summary_comment, input.Technical_overview.Description,
+				input.Questions, input.Abuse_cases, input.Security_requirements, input.Shared_runtimes}),
+		},
+	}
+
+	for _, title := range sortedKeys(input.Data_assets) {
+		dataAsset := input.Data_assets[title]
+		result.Assets = append(result.Assets, Asset{
+			Id:          dataAsset.ID,
+			Name:        title,
+			Description: dataAsset.Description,
+			Risk: AssetRisk{
+				Confidentiality: ratingOf(dataAsset.Confidentiality, confidentiality.ConfidentialityValues()),
+				Integrity:       ratingOf(dataAsset.Integrity, criticality.CriticalityValues()),
+				Availability:    ratingOf(dataAsset.Availability, criticality.CriticalityValues()),
+				Comment:         dataAsset.Justification_cia_rating,
+			},
+			Attributes: attributesOf(dataAsset, "id", "description", "confidentiality", "integrity", "availability", "justification_cia_rating"),
+		})
+	}
+
+	trustZoneOfTechnicalAsset, parentOfTrustBoundary := make(map[string]string), make(map[string]string)
+	for _, trustBoundary := range input.Trust_boundaries {
+		for _, id := range trustBoundary.Technical_assets_inside {
+			trustZoneOfTechnicalAsset[id] = trustBoundary.ID
+		}
+		for _, id := range trustBoundary.Trust_boundaries_nested {
+			parentOfTrustBoundary[id] = trustBoundary.ID
+		}
+	}
+	for _, title := range sortedKeys(input.Trust_boundaries) {
+		trustBoundary := input.Trust_boundaries[title]
+		trustZone := TrustZone{
+			Id:          trustBoundary.ID,
+			Name:        title,
+			Type:        trustBoundary.Type,
+			Description: trustBoundary.Description,
+			Risk:        TrustZoneRisk{TrustRating: neutralTrustRating},
+			Attributes:  attributesOf(trustBoundary, "id", "description", "type", "technical_assets_inside", "trust_boundaries_nested"),
+		}
+		if parent, ok := parentOfTrustBoundary[trustBoundary.ID]; ok {
+			trustZone.Parent = &Parent{TrustZone: parent}
+		}
+		result.TrustZones = append(result.TrustZones, trustZone)
+	}
+
+	componentIndex, dataflowIndex := make(map[string]int), make(map[string]int)
+	for _, title := range sortedKeys(input.Technical_assets) {
+		technicalAsset := input.Technical_assets[title]
+		component := Component{
+			Id:          technicalAsset.ID,
+			Name:        title,
+			Type:        technicalAsset.Technology,
+			Description: technicalAsset.Description,
+			Tags:        technicalAsset.Tags,
+			Attributes: attributesOf(technicalAsset, "id", "description", "technology", "tags",
+				"data_assets_processed", "data_assets_stored", "communication_links"),
+		}
+		if trustZone, ok := trustZoneOfTechnicalAsset[technicalAsset.ID]; ok {
+			component.Parent = &Parent{TrustZone: trustZone}
+		}
+		if len(technicalAsset.Data_assets_processed) > 0 || len(technicalAsset.Data_assets_stored) > 0 {
+			component.Assets = &ComponentAssets{Processed: technicalAsset.Data_assets_processed, Stored: technicalAsset.Data_assets_stored}
+		}
+		componentIndex[technicalAsset.ID] = len(result.Components)
+		result.Components = append(result.Components, component)
+	}
+	for _, component := range result.Components {
+		technicalAsset := input.Technical_assets[component.Name]
+		for _, title := range sortedKeys(technicalAsset.Communication_links) {
+			communicationLink := technicalAsset.Communication_links[title]
+			id := technicalAsset.ID + ">" + model.MakeID(title) // same as the id of the parsed communication link
+			dataflowIndex[id] = len(result.Dataflows)
+			result.Dataflows = append(result.Dataflows, Dataflow{
+				Id:            id,
+				Name:          title,
+				Description:   communicationLink.Description,
+				Bidirectional: len(communicationLink.Data_assets_received) > 0,
+				Source:        technicalAsset.ID,
+				Destination:   communicationLink.Target,
+				Tags:          communicationLink.Tags,
+				Assets:        union(communicationLink.Data_assets_sent, communicationLink.Data_assets_received),
+				Attributes:    attributesOf(communicationLink, "target", "description", "tags"),
+			})
+		}
+	}
+
+	mitigations := make(map[string]bool)
+	for _, risk := range risks {
+		tracking, tracked := riskTracking[risk.SyntheticId]
+		status := model.Unchecked
+		if tracked {
+			status = tracking.Status
+		}
+		threat := Threat{
+			Id:          risk.SyntheticId,
+			Name:        stripMarkup(risk.Title),
+			Description: stripMarkup(risk.Category.Description),
+			Categories:  []string{risk.Category.STRIDE.Title()},
+			Risk: ThreatRisk{
+				Likelihood: ratingOf(risk.ExploitationLikelihood.String(), model.RiskExploitationLikelihoodValues()),
+				Impact:     ratingOf(risk.ExploitationImpact.String(), model.RiskExploitationImpactValues()),
+			},
+			Attributes: attributesOf(struct {
+				Category                string   `json:"category"`
+				Category_title          string   `json:"category_title"`
+				Individual              bool     `json:"individual"`
+				Severity                string   `json:"severity"`
+				Data_breach_probability string   `json:"data_breach_probability"`
+				Data_breach_assets      []string `json:"data_breach_technical_assets"`
+				Status                  string   `json:"status"`
+				Justification           string   `json:"justification"`
+				Ticket                  string   `json:"ticket"`
+				Checked_by              string   `json:"checked_by"`
+				Date                    string   `json:"date"`
+				Review_by               string   `json:"review_by,omitempty"`
+				Expires                 string   `json:"expires,omitempty"`
+			}{risk.Category.Id, risk.Category.Title, isIndividualRiskCategory(input, risk.Category.Id), risk.Severity.String(), risk.DataBreachProbability.String(),
+				risk.DataBreachTechnicalAssetIDs, trackedStatus(tracking, tracked), tracking.Justification, tracking.Ticket, tracking.CheckedBy, formatDate(tracking.Date),
+				formatDate(tracking.ReviewBy), formatDate(tracking.Expires)}),
+		}
+		if risk.Category.CWE > 0 {
+			threat.Cwes = []string{"CWE-" + strconv.Itoa(risk.Category.CWE)}
+		}
+		result.Threats = append(result.Threats, threat)
+
+		mitigationId := risk.Category.Id + "-mitigation"
+		if !mitigations[mitigationId] {
+			mitigations[mitigationId] = true
+			result.Mitigations = append(result.Mitigations, Mitigation{
+				Id:            mitigationId,
+				Name:          stripMarkup(risk.Category.Action),
+				Description:   stripMarkup(risk.Category.Mitigation),
+				RiskReduction: 100,
+			})
+		}
+		instance := ThreatInstance{Threat: threat.Id, State: stateOf(status), Mitigations: []MitigationInstance{{Mitigation: mitigationId, State: StateRequired}}}
+		if status == model.Mitigated {
+			instance.Mitigations[0].State = StateImplemented
+		}
+		if index, ok := dataflowIndex[risk.MostRelevantCommunicationLinkId]; ok {
+			result.Dataflows[index].Threats = append(result.Dataflows[index].Threats, instance)
+		} else if index, ok := componentIndex[risk.MostRelevantTechnicalAssetId]; ok {
+			result.Components[index].Threats = append(result.Components[index].Threats, instance)
+		}
+	}
+	return result
+}
+
+func stateOf(status model.RiskStatus) string {
+	switch status {
+	case model.Accepted:
+		return StateAccepted
+	case model.InProgress:
+		return StateInProgress
+	case model.Mitigated:
+		return StateMitigated
+	case model.FalsePositive:
+		return StateNotApplicable
+	default:
+		return StateExposed
+	}
+}
+
+// ratingOf maps the enum value onto the OTM rating scale of 0 to 100
+func ratingOf(value string, values []core.TypeEnum) int {
+	for i, candidate := range values {
+		if candidate.String() == value {
+			return i * 100 / (len(values) - 1)
+		}
+	}
+	return 0
+}
+
+func isIndividualRiskCategory(input model.ModelInput, id string) bool {
+	for _, category := range input.Individual_risk_categories {
+		if category.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func trackedStatus(tracking model.RiskTracking, tracked bool) string {
+	if !tracked {
+		return ""
+	}
+	if tracking.Expired { // as the expiry is exported as well
+		return tracking.ExpiredStatus.String()
+	}
+	return tracking.Status.String()
+}
+
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+var markup = regexp.MustCompile(`</?[a-z]+>`)
+
+// stripMarkup removes the basic markup used within titles and descriptions of risks (like <b>)
+func stripMarkup(text string) string {
+	return markup.ReplaceAllString(text, "")
+}
+
+func union(lists ...[]string) []string {
+	var result []string
+	for _, list := range lists {
+		for _, value := range list {
+			if !contains(result, value) {
+				result = append(result, value)
+			}
+		}
+	}
+	return result
+}
+
+func sortedKeys(values interface{}) []string {
+	var keys []string
+	switch typed := values.(type) {
+	case map[string]model.InputDataAsset:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case map[string]model.InputTrustBoundary:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case map[string]model.InputTechnicalAsset:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case map[string]model.InputCommunicationLink:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,456 @@
+package otm
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/model/confidentiality"
+	"github.com/otyg/threagile/model/core"
+	"github.com/otyg/threagile/model/criticality"
+	"github.com/otyg/threagile/support"
+)
+
+// ToModelInput converts the OTM document into a model. Elements lacking the Threagile specific attributes (as written by
+// FromModelInput) get defaults, so that the result is a valid starting point to be refined. Threats which are not generated
+// by Threagile risk rules become individual risk categories, the state of all threat instances becomes risk tracking.
+func ToModelInput(document OTM) (model.ModelInput, error) {
+	importer := otmImporter{ids: make(map[string]string), usedIds: make(map[string]bool), tags: make(map[string]bool)}
+	project := projectAttributes{}
+	if err := applyAttributes(document.Project.Attributes, &project); err != nil {
+		return model.ModelInput{}, err
+	}
+	result := model.ModelInput{
+		Threagile_version:          model.ThreagileVersion,
+		Title:                      document.Project.Name,
+		Author:                     model.Author{Name: document.Project.Owner, Homepage: document.Project.OwnerContact},
+		Date:                       project.Date,
+		Business_overview:          model.Overview{Description: document.Project.Description},
+		Technical_overview:         model.Overview{Description: project.Technical_overview},
+		Business_criticality:       project.Business_criticality,
+		Management_summary_comment: project.Management_summary_comment,
+		Questions:                  project.Questions,
+		Abuse_cases:                project.Abuse_cases,
+		Security_requirements:      project.Security_requirements,
+		Data_assets:                make(map[string]model.InputDataAsset),
+		Technical_assets:           make(map[string]model.InputTechnicalAsset),
+		Trust_boundaries:           make(map[string]model.InputTrustBoundary),
+		Individual_risk_categories: make(map[string]model.InputIndividualRiskCategory),
+		Risk_tracking:              make(map[string]model.InputRiskTracking),
+	}
+	importer.tagsOf(&result, document.Project.Tags)
+	if len(result.Date) == 0 {
+		result.Date = time.Now().Format("2006-01-02")
+	}
+	if len(result.Business_criticality) == 0 {
+		result.Business_criticality = criticality.Important.String()
+	}
+
+	for _, asset := range document.Assets {
+		dataAsset := model.InputDataAsset{
+			Usage:    model.Business.String(),
+			Quantity: model.Few.String(),
+		}
+		if err := applyAttributes(asset.Attributes, &dataAsset); err != nil {
+			return model.ModelInput{}, err
+		}
+		dataAsset.ID = importer.idOf("asset", asset.Id)
+		dataAsset.Description = asset.Description
+		dataAsset.Confidentiality = valueOf(asset.Risk.Confidentiality, confidentiality.ConfidentialityValues())
+		dataAsset.Integrity = valueOf(asset.Risk.Integrity, criticality.CriticalityValues())
+		dataAsset.Availability = valueOf(asset.Risk.Availability, criticality.CriticalityValues())
+		dataAsset.Justification_cia_rating = asset.Risk.Comment
+		result.Data_assets[uniqueTitle(asset.Name, dataAsset.ID, result.Data_assets)] = dataAsset
+	}
+
+	trustBoundaryTitles := make(map[string]string)
+	for _, trustZone := range document.TrustZones {
+		trustBoundary := model.InputTrustBoundary{Type: model.NetworkOnPrem.String()}
+		if _, err := model.ParseTrustBoundaryType(trustZone.Type); err == nil {
+			trustBoundary.Type = trustZone.Type
+		}
+		if err := applyAttributes(trustZone.Attributes, &trustBoundary); err != nil {
+			return model.ModelInput{}, err
+		}
+		trustBoundary.ID = importer.idOf("trustZone", trustZone.Id)
+		trustBoundary.Description = trustZone.Description
+		trustBoundary.Technical_assets_inside, trustBoundary.Trust_boundaries_nested = nil, nil
+		title := uniqueTitle(trustZone.Name, trustBoundary.ID, result.Trust_boundaries)
+		trustBoundaryTitles[trustZone.Id] = title
+		result.Trust_boundaries[title] = trustBoundary
+	}
+	for _, trustZone := range document.TrustZones {
+		if trustZone.Parent != nil && len(trustZone.Parent.TrustZone) > 0 {
+			parentTitle, ok := trustBoundaryTitles[trustZone.Parent.TrustZone]
+			if !ok {
+				return model.ModelInput{}, errors.New("unknown parent trust zone of trust zone " + trustZone.Id + ": " + trustZone.Parent.TrustZone)
+			}
+			parent := result.Trust_boundaries[parentTitle]
+			parent.Trust_boundaries_nested = append(parent.Trust_boundaries_nested, importer.ids["trustZone:"+trustZone.Id])
+			result.Trust_boundaries[parentTitle] = parent
+		}
+	}
+
+	componentsById := make(map[string]Component)
+	for _, component := range document.Components {
+		componentsById[component.Id] = component
+	}
+	technicalAssetTitles := make(map[string]string)
+	for _, component := range document.Components {
+		technicalAsset := model.InputTechnicalAsset{
+			Type:            model.Process.String(),
+			Usage:           model.Business.String(),
+			Size:            model.Service.String(),
+			Technology:      model.UnknownTechnology.String(),
+			Machine:         model.Virtual.String(),
+			Encryption:      model.NoneEncryption.String(),
+			Confidentiality: confidentiality.Internal.String(),
+			Integrity:       criticality.Operational.String(),
+			Availability:    criticality.Operational.String(),
+		}
+		if _, err := model.ParseTechnicalAssetTechnology(component.Type); err == nil {
+			technicalAsset.Technology = component.Type
+		}
+		if err := applyAttributes(component.Attributes, &technicalAsset); err != nil {
+			return model.ModelInput{}, err
+		}
+		technicalAsset.ID = importer.idOf("component", component.Id)
+		technicalAsset.Description = component.Description
+		technicalAsset.Tags = importer.tagsOf(&result, component.Tags)
+		technicalAsset.Communication_links = make(map[string]model.InputCommunicationLink)
+		if component.Assets != nil {
+			var err error
+			if technicalAsset.Data_assets_processed, err = importer.idsOf("asset", component.Assets.Processed); err != nil {
+				return model.ModelInput{}, err
+			}
+			if technicalAsset.Data_assets_stored, err = importer.idsOf("asset", component.Assets.Stored); err != nil {
+				return model.ModelInput{}, err
+			}
+		}
+		title := uniqueTitle(component.Name, technicalAsset.ID, result.Technical_assets)
+		technicalAssetTitles[component.Id] = title
+		result.Technical_assets[title] = technicalAsset
+
+		if trustZone := trustZoneOf(component, componentsById); len(trustZone) > 0 {
+			trustBoundaryTitle, ok := trustBoundaryTitles[trustZone]
+			if !ok {
+				return model.ModelInput{}, errors.New("unknown trust zone of component " + component.Id + ": " + trustZone)
+			}
+			trustBoundary := result.Trust_boundaries[trustBoundaryTitle]
+			trustBoundary.Technical_assets_inside = append(trustBoundary.Technical_assets_inside, technicalAsset.ID)
+			result.Trust_boundaries[trustBoundaryTitle] = trustBoundary
+		}
+	}
+
+	if len(project.Shared_runtimes) > 0 {
+		result.Shared_runtimes = make(map[string]model.InputSharedRuntime)
+		for title, sharedRuntime := range project.Shared_runtimes {
+			for i, id := range sharedRuntime.Technical_assets_running {
+				if mapped, ok := importer.ids["component:"+id]; ok {
+					sharedRuntime.Technical_assets_running[i] = mapped
+				}
+			}
+			result.Shared_runtimes[title] = sharedRuntime
+		}
+	}
+
+	communicationLinkIds := make(map[string]string)
+	for _, dataflow := range document.Dataflows {
+		sourceTitle, ok := technicalAssetTitles[dataflow.Source]
+		if !ok {
+			return model.ModelInput{}, errors.New("unknown source component of dataflow " + dataflow.Id + ": " + dataflow.Source)
+		}
+		if _, ok := technicalAssetTitles[dataflow.Destination]; !ok {
+			return model.ModelInput{}, errors.New("unknown destination component of dataflow " + dataflow.Id + ": " + dataflow.Destination)
+		}
+		communicationLink := model.InputCommunicationLink{
+			Protocol:       model.UnknownProtocol.String(),
+			Authentication: model.NoneAuthentication.String(),
+			Authorization:  model.NoneAuthorization.String(),
+			Usage:          model.Business.String(),
+		}
+		if err := applyAttributes(dataflow.Attributes, &communicationLink); err != nil {
+			return model.ModelInput{}, err
+		}
+		communicationLink.Target = importer.ids["component:"+dataflow.Destination]
+		communicationLink.Description = dataflow.Description
+		communicationLink.Tags = importer.tagsOf(&result, dataflow.Tags)
+		var err error
+		if !dataflow.Attributes.hasAny() {
+			if communicationLink.Data_assets_sent, err = importer.idsOf("asset", dataflow.Assets); err != nil {
+				return model.ModelInput{}, err
+			}
+			if dataflow.Bidirectional {
+				communicationLink.Data_assets_received = communicationLink.Data_assets_sent
+			}
+		} else {
+			if communicationLink.Data_assets_sent, err = importer.idsOf("asset", communicationLink.Data_assets_sent); err != nil {
+				return model.ModelInput{}, err
+			}
+			if communicationLink.Data_assets_received, err = importer.idsOf("asset", communicationLink.Data_assets_received); err != nil {
+				return model.ModelInput{}, err
+			}
+		}
+		source := result.Technical_assets[sourceTitle]
+		title := dataflow.Name
+		for _, exists := source.Communication_links[title]; exists; _, exists = source.Communication_links[title] {
+			title += " (" + dataflow.Id + ")"
+		}
+		source.Communication_links[title] = communicationLink
+		communicationLinkIds[dataflow.Id] = source.ID + ">" + model.MakeID(title)
+	}
+
+	threats := make(map[string]Threat)
+	for _, threat := range document.Threats {
+		threats[threat.Id] = threat
+	}
+	mitigations := make(map[string]Mitigation)
+	for _, mitigation := range document.Mitigations {
+		mitigations[mitigation.Id] = mitigation
+	}
+	for _, component := range document.Components {
+		for _, instance := range component.Threats {
+			if err := importer.importThreat(&result, threats, mitigations, instance, importer.ids["component:"+component.Id], ""); err != nil {
+				return model.ModelInput{}, err
+			}
+		}
+	}
+	for _, dataflow := range document.Dataflows {
+		for _, instance := range dataflow.Threats {
+			if err := importer.importThreat(&result, threats, mitigations, instance, importer.ids["component:"+dataflow.Source], communicationLinkIds[dataflow.Id]); err != nil {
+				return model.ModelInput{}, err
+			}
+		}
+	}
+	return result, nil
+}
+
+type otmImporter struct {
+	ids     map[string]string // OTM ids (prefixed by their kind) to Threagile ids
+	usedIds map[string]bool
+	tags    map[string]bool // normalized tags already added to the tags available
+}
+
+// tagsOf normalizes the tags (as other tools tag their elements freely) and adds them to the tags available of the model
+func (what *otmImporter) tagsOf(result *model.ModelInput, tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = model.NormalizeTag(tag)
+		if len(tag) == 0 || model.Contains(normalized, tag) {
+			continue
+		}
+		normalized = append(normalized, tag)
+		if !what.tags[tag] {
+			what.tags[tag] = true
+			result.Tags_available = append(result.Tags_available, tag)
+		}
+	}
+	return normalized
+}
+
+// idOf turns the OTM id into a unique and syntactically valid Threagile id
+func (what *otmImporter) idOf(kind, otmId string) string {
+	id := otmId
+	if !support.IsValidIdSyntax(id) {
+		id = model.MakeID(otmId)
+	}
+	if len(id) == 0 {
+		id = kind
+	}
+	unique := id
+	for i := 2; what.usedIds[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	what.usedIds[unique] = true
+	what.ids[kind+":"+otmId] = unique
+	return unique
+}
+
+func (what *otmImporter) idsOf(kind string, otmIds []string) ([]string, error) {
+	var result []string
+	for _, otmId := range otmIds {
+		id, ok := what.ids[kind+":"+otmId]
+		if !ok {
+			return nil, errors.New("unknown " + kind + " referenced: " + otmId)
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
+
+func (what *otmImporter) importThreat(result *model.ModelInput, threats map[string]Threat, mitigations map[string]Mitigation,
+	instance ThreatInstance, technicalAssetId, communicationLinkId string) error {
+	threat, ok := threats[instance.Threat]
+	if !ok {
+		return errors.New("unknown threat referenced: " + instance.Threat)
+	}
+	categoryId := threat.Attributes.string("category")
+	if len(categoryId) > 0 && threat.Attributes[attributePrefix+"individual"] != true {
+		// generated by a risk rule, so only the risk tracking is relevant
+		what.importRiskTracking(result, threat.Id, threat, instance)
+		return nil
+	}
+
+	if len(categoryId) == 0 {
+		categoryId = model.MakeID(threat.Id)
+	}
+	categoryTitle := threat.Attributes.string("category_title")
+	if len(categoryTitle) == 0 {
+		categoryTitle = threat.Name
+	}
+	category, exists := result.Individual_risk_categories[categoryTitle]
+	if !exists {
+		category = model.InputIndividualRiskCategory{
+			ID:               categoryId,
+			Description:      threat.Description,
+			Impact:           threat.Risk.ImpactComment,
+			Function:         model.Architecture.String(),
+			STRIDE:           strideOf(threat.Categories),
+			Risks_identified: make(map[string]model.InputRiskIdentified),
+		}
+		for _, cwe := range threat.Cwes {
+			if number, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(cwe), "CWE-")); err == nil {
+				category.CWE = number
+				break
+			}
+		}
+		var actions, descriptions []string
+		for _, mitigationInstance := range instance.Mitigations {
+			if mitigation, ok := mitigations[mitigationInstance.Mitigation]; ok {
+				actions, descriptions = append(actions, mitigation.Name), append(descriptions, mitigation.Description)
+			}
+		}
+		category.Action, category.Mitigation = strings.Join(actions, ", "), strings.Join(descriptions, " ")
+	}
+
+	likelihood := valueOf(threat.Risk.Likelihood, model.RiskExploitationLikelihoodValues())
+	impact := valueOf(threat.Risk.Impact, model.RiskExploitationImpactValues())
+	parsedLikelihood, _ := model.ParseRiskExploitationLikelihood(likelihood)
+	parsedImpact, _ := model.ParseRiskExploitationImpact(impact)
+	risk := model.InputRiskIdentified{
+		Severity:                         threat.Attributes.string("severity"),
+		Exploitation_likelihood:          likelihood,
+		Exploitation_impact:              impact,
+		Data_breach_probability:          threat.Attributes.string("data_breach_probability"),
+		Data_breach_technical_assets:     threat.Attributes.strings("data_breach_technical_assets"),
+		Most_relevant_technical_asset:    technicalAssetId,
+		Most_relevant_communication_link: communicationLinkId,
+	}
+	if len(risk.Severity) == 0 {
+		risk.Severity = model.CalculateSeverity(parsedLikelihood, parsedImpact).String()
+	}
+	if len(risk.Data_breach_probability) == 0 {
+		risk.Data_breach_probability = model.Possible.String()
+	}
+	title := threat.Name
+	for _, exists := category.Risks_identified[title]; exists; _, exists = category.Risks_identified[title] {
+		title += " (" + technicalAssetId + ")"
+	}
+	category.Risks_identified[title] = risk
+	result.Individual_risk_categories[categoryTitle] = category
+
+	syntheticId := categoryId + "@" + technicalAssetId // as created when parsing individual risks
+	if len(communicationLinkId) > 0 {
+		syntheticId += "@" + communicationLinkId
+	}
+	what.importRiskTracking(result, syntheticId, threat, instance)
+	return nil
+}
+
+func (what *otmImporter) importRiskTracking(result *model.ModelInput, syntheticId string, threat Threat, instance ThreatInstance) {
+	status := threat.Attributes.string("status")
+	if len(status) == 0 {
+		status = statusOf(instance.State).String()
+		if status == model.Unchecked.String() {
+			return
+		}
+	}
+	result.Risk_tracking[syntheticId] = model.InputRiskTracking{
+		Status:        status,
+		Justification: threat.Attributes.string("justification"),
+		Ticket:        threat.Attributes.string("ticket"),
+		Checked_by:    threat.Attributes.string("checked_by"),
+		Date:          threat.Attributes.string("date"),
+		Expires:       threat.Attributes.string("expires"),
+		Review_by:     threat.Attributes.string("review_by"),
+	}
+}
+
+func statusOf(state string) model.RiskStatus {
+	switch strings.ToLower(strings.TrimSpace(state)) {
+	case StateAccepted:
+		return model.Accepted
+	case StateInProgress:
+		return model.InProgress
+	case StateMitigated:
+		return model.Mitigated
+	case StateNotApplicable:
+		return model.FalsePositive
+	default:
+		return model.Unchecked
+	}
+}
+
+// strideOf matches the threat categories against the STRIDE values and titles (defaulting to tampering)
+func strideOf(categories []string) string {
+	for _, category := range categories {
+		for _, stride := range model.STRIDEValues() {
+			if strings.EqualFold(category, stride.String()) || strings.EqualFold(category, stride.(model.STRIDE).Title()) {
+				return stride.String()
+			}
+		}
+	}
+	return model.Tampering.String()
+}
+
+// valueOf maps the OTM rating of 0 to 100 onto the enum values
+func valueOf(rating int, values []core.TypeEnum) string {
+	if rating < 0 {
+		rating = 0
+	} else if rating > 100 {
+		rating = 100
+	}
+	return values[(rating*(len(values)-1)+50)/100].String()
+}
+
+// trustZoneOf is the trust zone of the component itself or of the component it is nested in
+func trustZoneOf(component Component, componentsById map[string]Component) string {
+	for visited := make(map[string]bool); component.Parent != nil && !visited[component.Id]; {
+		visited[component.Id] = true
+		if len(component.Parent.TrustZone) > 0 {
+			return component.Parent.TrustZone
+		}
+		component = componentsById[component.Parent.Component]
+	}
+	return ""
+}
+
+func uniqueTitle(title, id string, existing interface{}) string {
+	if len(title) == 0 {
+		title = id
+	}
+	titles := make(map[string]bool)
+	switch typed := existing.(type) {
+	case map[string]model.InputDataAsset:
+		for key := range typed {
+			titles[key] = true
+		}
+	case map[string]model.InputTrustBoundary:
+		for key := range typed {
+			titles[key] = true
+		}
+	case map[string]model.InputTechnicalAsset:
+		for key := range typed {
+			titles[key] = true
+		}
+	}
+	if titles[title] {
+		title += " (" + id + ")"
+	}
+	return title
+}
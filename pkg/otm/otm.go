@@ -0,0 +1,286 @@
+// Package otm converts between Threagile models and the Open Threat Model (OTM) format
+// (see https://github.com/iriusrisk/OpenThreatModel) to exchange threat models with other tools.
+package otm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const Version = "0.2.0"
+
+// attributePrefix marks the attributes holding the Threagile specific values (which have no OTM counterpart),
+// so that a model survives the roundtrip through OTM
+const attributePrefix = "threagile_"
+
+type OTM struct {
+	OtmVersion      string           `json:"otmVersion" yaml:"otmVersion"`
+	Project         Project          `json:"project" yaml:"project"`
+	Representations []Representation `json:"representations,omitempty" yaml:"representations,omitempty"`
+	Assets          []Asset          `json:"assets,omitempty" yaml:"assets,omitempty"`
+	TrustZones      []TrustZone      `json:"trustZones,omitempty" yaml:"trustZones,omitempty"`
+	Components      []Component      `json:"components,omitempty" yaml:"components,omitempty"`
+	Dataflows       []Dataflow       `json:"dataflows,omitempty" yaml:"dataflows,omitempty"`
+	Threats         []Threat         `json:"threats,omitempty" yaml:"threats,omitempty"`
+	Mitigations     []Mitigation     `json:"mitigations,omitempty" yaml:"mitigations,omitempty"`
+}
+
+type Attributes map[string]interface{}
+
+type Project struct {
+	Name         string     `json:"name" yaml:"name"`
+	Id           string     `json:"id" yaml:"id"`
+	Description  string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Owner        string     `json:"owner,omitempty" yaml:"owner,omitempty"`
+	OwnerContact string     `json:"ownerContact,omitempty" yaml:"ownerContact,omitempty"`
+	Tags         []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Attributes   Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+type Representation struct {
+	Name        string     `json:"name" yaml:"name"`
+	Id          string     `json:"id" yaml:"id"`
+	Type        string     `json:"type" yaml:"type"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Attributes  Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+type Asset struct {
+	Name        string     `json:"name" yaml:"name"`
+	Id          string     `json:"id" yaml:"id"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Risk        AssetRisk  `json:"risk" yaml:"risk"`
+	Attributes  Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// AssetRisk ratings range from 0 to 100
+type AssetRisk struct {
+	Confidentiality int    `json:"confidentiality" yaml:"confidentiality"`
+	Integrity       int    `json:"integrity" yaml:"integrity"`
+	Availability    int    `json:"availability" yaml:"availability"`
+	Comment         string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+type TrustZone struct {
+	Id          string        `json:"id" yaml:"id"`
+	Name        string        `json:"name" yaml:"name"`
+	Type        string        `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string        `json:"description,omitempty" yaml:"description,omitempty"`
+	Risk        TrustZoneRisk `json:"risk" yaml:"risk"`
+	Parent      *Parent       `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Attributes  Attributes    `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+type TrustZoneRisk struct {
+	TrustRating int `json:"trustRating" yaml:"trustRating"`
+}
+
+type Parent struct {
+	TrustZone string `json:"trustZone,omitempty" yaml:"trustZone,omitempty"`
+	Component string `json:"component,omitempty" yaml:"component,omitempty"`
+}
+
+type Component struct {
+	Id          string           `json:"id" yaml:"id"`
+	Name        string           `json:"name" yaml:"name"`
+	Type        string           `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Parent      *Parent          `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Tags        []string         `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Assets      *ComponentAssets `json:"assets,omitempty" yaml:"assets,omitempty"`
+	Threats     []ThreatInstance `json:"threats,omitempty" yaml:"threats,omitempty"`
+	Attributes  Attributes       `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+type ComponentAssets struct {
+	Processed []string `json:"processed,omitempty" yaml:"processed,omitempty"`
+	Stored    []string `json:"stored,omitempty" yaml:"stored,omitempty"`
+}
+
+type Dataflow struct {
+	Id            string           `json:"id" yaml:"id"`
+	Name          string           `json:"name" yaml:"name"`
+	Description   string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Bidirectional bool             `json:"bidirectional,omitempty" yaml:"bidirectional,omitempty"`
+	Source        string           `json:"source" yaml:"source"`
+	Destination   string           `json:"destination" yaml:"destination"`
+	Tags          []string         `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Assets        []string         `json:"assets,omitempty" yaml:"assets,omitempty"`
+	Threats       []ThreatInstance `json:"threats,omitempty" yaml:"threats,omitempty"`
+	Attributes    Attributes       `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+type Threat struct {
+	Id          string     `json:"id" yaml:"id"`
+	Name        string     `json:"name" yaml:"name"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Categories  []string   `json:"categories,omitempty" yaml:"categories,omitempty"`
+	Cwes        []string   `json:"cwes,omitempty" yaml:"cwes,omitempty"`
+	Risk        ThreatRisk `json:"risk" yaml:"risk"`
+	Tags        []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Attributes  Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// ThreatRisk ratings range from 0 to 100
+type ThreatRisk struct {
+	Likelihood        int    `json:"likelihood" yaml:"likelihood"`
+	LikelihoodComment string `json:"likelihoodComment,omitempty" yaml:"likelihoodComment,omitempty"`
+	Impact            int    `json:"impact" yaml:"impact"`
+	ImpactComment     string `json:"impactComment,omitempty" yaml:"impactComment,omitempty"`
+}
+
+type ThreatInstance struct {
+	Threat      string               `json:"threat" yaml:"threat"`
+	State       string               `json:"state" yaml:"state"`
+	Mitigations []MitigationInstance `json:"mitigations,omitempty" yaml:"mitigations,omitempty"`
+}
+
+type MitigationInstance struct {
+	Mitigation string `json:"mitigation" yaml:"mitigation"`
+	State      string `json:"state" yaml:"state"`
+}
+
+type Mitigation struct {
+	Id            string     `json:"id" yaml:"id"`
+	Name          string     `json:"name" yaml:"name"`
+	Description   string     `json:"description,omitempty" yaml:"description,omitempty"`
+	RiskReduction int        `json:"riskReduction" yaml:"riskReduction"`
+	Attributes    Attributes `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// threat instance and mitigation instance states
+const (
+	StateExposed       = "exposed"
+	StateInProgress    = "in-progress"
+	StateAccepted      = "accepted"
+	StateMitigated     = "mitigated"
+	StateNotApplicable = "not-applicable"
+	StateRequired      = "required"
+	StateImplemented   = "implemented"
+)
+
+// Parse reads an OTM document in JSON or YAML format
+func Parse(data []byte) (result OTM, err error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = json.Unmarshal(data, &result)
+	} else {
+		err = yaml.Unmarshal(data, &result)
+	}
+	return result, err
+}
+
+// Marshal writes the OTM document as YAML when asked for, otherwise as (indented) JSON
+func Marshal(document OTM, asYAML bool) ([]byte, error) {
+	if asYAML {
+		return yaml.Marshal(document)
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(document)
+	return buffer.Bytes(), err
+}
+
+// IsYAMLFilename tells if the file extension asks for YAML instead of JSON
+func IsYAMLFilename(filename string) bool {
+	filename = strings.ToLower(filename)
+	return strings.HasSuffix(filename, ".yaml") || strings.HasSuffix(filename, ".yml")
+}
+
+// attributesOf holds all non-empty values of the (json tagged) model input struct except the ones mapped to OTM fields
+func attributesOf(value interface{}, mapped ...string) Attributes {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(data, &values); err != nil {
+		panic(err)
+	}
+	result := make(Attributes)
+	for key, attribute := range values {
+		if contains(mapped, key) || isEmpty(attribute) {
+			continue
+		}
+		result[attributePrefix+key] = attribute
+	}
+	return result
+}
+
+// applyAttributes sets the values of the (json tagged) model input struct from the Threagile specific attributes
+func applyAttributes(attributes Attributes, target interface{}) error {
+	values := make(map[string]interface{})
+	for key, attribute := range attributes {
+		if strings.HasPrefix(key, attributePrefix) {
+			values[strings.TrimPrefix(key, attributePrefix)] = attribute
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// hasAny tells if there are Threagile specific attributes, i.e. the element was exported by Threagile
+func (what Attributes) hasAny() bool {
+	for key := range what {
+		if strings.HasPrefix(key, attributePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (what Attributes) string(key string) string {
+	if value, ok := what[attributePrefix+key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+func (what Attributes) strings(key string) []string {
+	var result []string
+	if values, ok := what[attributePrefix+key].([]interface{}); ok {
+		for _, value := range values {
+			if text, ok := value.(string); ok {
+				result = append(result, text)
+			}
+		}
+	}
+	return result
+}
+
+func isEmpty(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case string:
+		return len(typed) == 0
+	case bool:
+		return !typed
+	case float64:
+		return typed == 0
+	case []interface{}:
+		return len(typed) == 0
+	case map[string]interface{}:
+		return len(typed) == 0
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
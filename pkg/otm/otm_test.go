@@ -0,0 +1,171 @@
+package otm
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/threagile"
+	"gopkg.in/yaml.v2"
+)
+
+// threatPerComponent generates a risk per technical asset, whose category carries what becomes the threat and mitigation in OTM
+type threatPerComponent struct{}
+
+func (r threatPerComponent) Category() model.RiskCategory {
+	return model.RiskCategory{Id: "test-rule", Title: "Test Rule", STRIDE: model.Spoofing, Action: "Test", Mitigation: "Test"}
+}
+
+func (r threatPerComponent) SupportedTags() []string {
+	return []string{}
+}
+
+func (r threatPerComponent) GenerateRisks() []model.Risk {
+	risks := make([]model.Risk, 0)
+	for _, id := range model.SortedTechnicalAssetIDs() {
+		risks = append(risks, model.Risk{
+			Category:                     r.Category(),
+			Severity:                     model.MediumSeverity,
+			ExploitationLikelihood:       model.Likely,
+			ExploitationImpact:           model.MediumImpact,
+			DataBreachProbability:        model.Possible,
+			MostRelevantTechnicalAssetId: id,
+			SyntheticId:                  r.Category().Id + "@" + id,
+		})
+	}
+	return risks
+}
+
+func analyze(t *testing.T, modelYaml []byte) *threagile.Result {
+	result, err := threagile.Analyze(context.Background(), modelYaml, threagile.Options{
+		RAA:                        func() string { return "" },
+		RiskRules:                  map[string]model.RiskRule{"test-rule": threatPerComponent{}},
+		IgnoreOrphanedRiskTracking: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func export(t *testing.T, result *threagile.Result, asYAML bool) []byte {
+	data, err := Marshal(FromModelInput(result.MergedModelInput(), result.Risks(), result.RiskTracking()), asYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func reimport(t *testing.T, data []byte) []byte {
+	document, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modelInput, err := ToModelInput(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modelYaml, err := yaml.Marshal(&modelInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return modelYaml
+}
+
+func TestRoundtrip(t *testing.T) {
+	model.ThreagileVersion = "test"
+	modelYaml, err := ioutil.ReadFile("../../demo/example/threagile.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := analyze(t, modelYaml)
+	for _, asYAML := range []bool{false, true} {
+		exported := export(t, original, asYAML)
+		roundtrip := analyze(t, reimport(t, exported))
+		if got := export(t, roundtrip, asYAML); !bytes.Equal(got, exported) {
+			t.Errorf("export of imported model (yaml: %v) differs:\n%s\nwant:\n%s", asYAML, got, exported)
+		}
+		if got, want := len(roundtrip.Risks()), len(original.Risks()); got != want {
+			t.Errorf("len(roundtrip.Risks()) = %v, want %v", got, want)
+		}
+		for _, risk := range original.Risks() {
+			if got, want := roundtrip.RiskTracking()[risk.SyntheticId].Status, original.RiskTracking()[risk.SyntheticId].Status; got != want {
+				t.Errorf("risk tracking status of %v = %v, want %v", risk.SyntheticId, got, want)
+			}
+		}
+	}
+}
+
+func TestImportOfForeignModel(t *testing.T) {
+	model.ThreagileVersion = "test"
+	result := analyze(t, reimport(t, []byte(`{
+  "otmVersion": "0.2.0",
+  "project": {"name": "Foreign", "id": "foreign", "tags": ["Internet-Facing"]},
+  "assets": [{"id": "credentials", "name": "Credentials", "risk": {"confidentiality": 100, "integrity": 75, "availability": 0}}],
+  "trustZones": [{"id": "internet", "name": "Internet", "risk": {"trustRating": 1}},
+    {"id": "dmz zone", "name": "DMZ", "risk": {"trustRating": 50}, "parent": {"trustZone": "internet"}}],
+  "components": [{"id": "browser", "name": "Browser", "type": "browser", "parent": {"trustZone": "internet"}, "tags": ["internet-facing", "External"]},
+    {"id": "web", "name": "Web Server", "type": "web-server", "parent": {"trustZone": "dmz zone"}, "assets": {"processed": ["credentials"]}},
+    {"id": "db", "name": "Database", "type": "CD-V2-SQL-DATABASE", "parent": {"component": "web"}, "assets": {"stored": ["credentials"]},
+      "threats": [{"threat": "sqli", "state": "mitigated"}]}],
+  "dataflows": [{"id": "login", "name": "Login", "source": "browser", "destination": "web", "bidirectional": true, "assets": ["credentials"], "tags": [" TLS", "tls"],
+    "threats": [{"threat": "sniffing", "state": "exposed", "mitigations": [{"mitigation": "tls", "state": "required"}]}]}],
+  "threats": [{"id": "sqli", "name": "SQL Injection", "categories": ["Tampering"], "cwes": ["CWE-89"], "risk": {"likelihood": 50, "impact": 100}},
+    {"id": "sniffing", "name": "Sniffing", "categories": ["Information Disclosure"], "risk": {"likelihood": 25, "impact": 50}}],
+  "mitigations": [{"id": "tls", "name": "Use TLS", "description": "Encrypt the traffic.", "riskReduction": 100}]
+}`)))
+
+	parsedModel := result.ParsedModel()
+	if got, want := parsedModel.TechnicalAssets["db"].Technology, model.UnknownTechnology; got != want {
+		t.Errorf("technology of db = %v, want %v", got, want)
+	}
+	if got, want := parsedModel.TrustBoundaries["dmz-zone"].TechnicalAssetsInside, []string{"web", "db"}; len(got) != len(want) {
+		t.Errorf("assets inside dmz-zone = %v, want %v", got, want)
+	}
+	if got, want := parsedModel.DataAssets["credentials"].Confidentiality.String(), "strictly-confidential"; got != want {
+		t.Errorf("confidentiality of credentials = %v, want %v", got, want)
+	}
+	sniffing, ok := result.RiskBySyntheticId("sniffing@browser@browser>login")
+	if !ok {
+		t.Fatalf("risk of threat sniffing not found in %v", result.Risks())
+	}
+	if got, want := sniffing.Category.STRIDE, model.InformationDisclosure; got != want {
+		t.Errorf("STRIDE of sniffing = %v, want %v", got, want)
+	}
+	if got, want := sniffing.Category.Action, "Use TLS"; got != want {
+		t.Errorf("action of sniffing = %v, want %v", got, want)
+	}
+	if got, want := result.RiskTracking()["sqli@db"].Status, model.Mitigated; got != want {
+		t.Errorf("risk tracking status of sqli@db = %v, want %v", got, want)
+	}
+	if got, want := parsedModel.TagsAvailable, []string{"internet-facing", "external", "tls"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("tags available = %v, want %v", got, want)
+	}
+	if got := parsedModel.TechnicalAssets["browser"].CommunicationLinks[0].Tags; len(got) != 1 || got[0] != "tls" {
+		t.Errorf("tags of browser>login = %v, want [tls]", got)
+	}
+}
+
+func TestImportOfInvalidReferences(t *testing.T) {
+	for _, test := range []struct {
+		name, document, wantErr string
+	}{
+		{"parent trust zone", `{"trustZones": [{"id": "dmz", "parent": {"trustZone": "internet"}}]}`, "unknown parent trust zone of trust zone dmz: internet"},
+		{"trust zone of component", `{"components": [{"id": "web", "parent": {"trustZone": "dmz"}}]}`, "unknown trust zone of component web: dmz"},
+		{"asset of component", `{"components": [{"id": "web", "assets": {"stored": ["credentials"]}}]}`, "unknown asset referenced: credentials"},
+		{"source of dataflow", `{"dataflows": [{"id": "login", "source": "browser", "destination": "web"}]}`, "unknown source component of dataflow login: browser"},
+		{"threat of component", `{"components": [{"id": "web", "threats": [{"threat": "sqli"}]}]}`, "unknown threat referenced: sqli"},
+		{"attributes", `{"components": [{"id": "web", "attributes": {"threagile_size": 1}}]}`, "json: cannot unmarshal"},
+	} {
+		document, err := Parse([]byte(test.document))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ToModelInput(document); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("importing an invalid reference to the %v = %v, want %v", test.name, err, test.wantErr)
+		}
+	}
+}
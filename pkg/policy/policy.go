@@ -0,0 +1,191 @@
+// Package policy evaluates the risks of a model against rules (like "critical,high:unchecked"), so that pipelines can be failed like by a linter.
+package policy
+
+import (
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/model/core"
+	"gopkg.in/yaml.v2"
+)
+
+// Rule is violated by each risk matching all of its (non-empty) criteria
+type Rule struct {
+	Name       string   `yaml:"name"`
+	Severities []string `yaml:"severities"`
+	Statuses   []string `yaml:"statuses"`   // when empty: the statuses of risks not yet handled (unchecked, in-discussion, in-progress)
+	Categories []string `yaml:"categories"` // risk category IDs
+	Tags       []string `yaml:"tags"`       // any tag of the elements most relevant for the risk
+}
+
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Violation is a risk violating a rule
+type Violation struct {
+	Rule string
+	Risk model.Risk
+}
+
+func LoadPolicy(filename string) (Policy, error) {
+	policy := Policy{}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return policy, err
+	}
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return policy, err
+	}
+	for i := range policy.Rules {
+		if len(policy.Rules[i].Name) == 0 {
+			policy.Rules[i].Name = "rule " + strconv.Itoa(i+1)
+		}
+		if err := policy.Rules[i].Check(); err != nil {
+			return policy, errors.New(filename + ": " + err.Error())
+		}
+	}
+	return policy, nil
+}
+
+// ParseFailOn parses comma-separated rules of a severity (or * for all) optionally followed by a status,
+// like "critical,high:unchecked" for unhandled critical risks and unchecked high risks
+func ParseFailOn(value string) (Policy, error) {
+	policy := Policy{}
+	for _, term := range strings.Split(value, ",") {
+		term = strings.TrimSpace(term)
+		if len(term) == 0 {
+			continue
+		}
+		rule := Rule{Name: term}
+		severity, status := term, ""
+		if index := strings.Index(term, ":"); index >= 0 {
+			severity, status = term[:index], term[index+1:]
+		}
+		if severity != "*" {
+			rule.Severities = []string{severity}
+		}
+		if len(status) > 0 && status != "*" {
+			rule.Statuses = []string{status}
+		} else if status == "*" {
+			rule.Statuses = arrayOfStringValues(model.RiskStatusValues())
+		}
+		if err := rule.Check(); err != nil {
+			return policy, err
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy, nil
+}
+
+// Check validates the severities and statuses of the rule
+func (what Rule) Check() error {
+	for _, severity := range what.Severities {
+		if _, err := model.ParseRiskSeverity(severity); err != nil {
+			return errors.New("unknown severity in rule " + what.Name + ": " + severity + " (use one of " + strings.Join(arrayOfStringValues(model.RiskSeverityValues()), ", ") + ")")
+		}
+	}
+	for _, status := range what.Statuses {
+		if _, err := model.ParseRiskStatus(status); err != nil {
+			return errors.New("unknown status in rule " + what.Name + ": " + status + " (use one of " + strings.Join(arrayOfStringValues(model.RiskStatusValues()), ", ") + ")")
+		}
+	}
+	return nil
+}
+
+// Matches tells whether the risk violates the rule (the parsed model is required to look up the tags)
+func (what Rule) Matches(risk model.Risk, parsedModel model.ParsedModel) bool {
+	if len(what.Severities) > 0 && !model.Contains(what.Severities, risk.Severity.String()) {
+		return false
+	}
+	if len(what.Statuses) > 0 {
+		if !model.Contains(what.Statuses, risk.RiskStatus.String()) {
+			return false
+		}
+	} else if risk.RiskStatus != model.Unchecked && risk.RiskStatus != model.InDiscussion && risk.RiskStatus != model.InProgress {
+		return false
+	}
+	if len(what.Categories) > 0 && !model.Contains(what.Categories, risk.CategoryId) {
+		return false
+	}
+	if len(what.Tags) > 0 && !isTaggedWithAny(risk, parsedModel, what.Tags...) {
+		return false
+	}
+	return true
+}
+
+// Evaluate returns the violations of all rules, the most severe first
+func (what Policy) Evaluate(risks []model.Risk, parsedModel model.ParsedModel) []Violation {
+	result := make([]Violation, 0)
+	for _, risk := range risks {
+		for _, rule := range what.Rules {
+			if rule.Matches(risk, parsedModel) {
+				result = append(result, Violation{Rule: rule.Name, Risk: risk})
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Risk.Severity != result[j].Risk.Severity {
+			return result[i].Risk.Severity > result[j].Risk.Severity
+		}
+		return result[i].Risk.SyntheticId < result[j].Risk.SyntheticId
+	})
+	return result
+}
+
+// RulesBySyntheticId lists the violated rules per risk
+func RulesBySyntheticId(violations []Violation) map[string][]string {
+	result := make(map[string][]string)
+	for _, violation := range violations {
+		result[violation.Risk.SyntheticId] = append(result[violation.Risk.SyntheticId], violation.Rule)
+	}
+	return result
+}
+
+// Summary lists the violations one per line
+func Summary(violations []Violation) string {
+	var text strings.Builder
+	text.WriteString("policy violations: " + strconv.Itoa(len(violations)) + "\n")
+	for _, violation := range violations {
+		text.WriteString("  " + violation.Risk.Severity.String() + " " + violation.Risk.RiskStatus.String() + " " +
+			violation.Risk.SyntheticId + " (violating " + violation.Rule + ")\n")
+	}
+	return text.String()
+}
+
+func isTaggedWithAny(risk model.Risk, parsedModel model.ParsedModel, tags ...string) bool {
+	if technicalAsset, ok := parsedModel.TechnicalAssets[risk.MostRelevantTechnicalAssetId]; ok && technicalAsset.IsTaggedWithAny(tags...) {
+		return true
+	}
+	if dataAsset, ok := parsedModel.DataAssets[risk.MostRelevantDataAssetId]; ok && dataAsset.IsTaggedWithAny(tags...) {
+		return true
+	}
+	if trustBoundary, ok := parsedModel.TrustBoundaries[risk.MostRelevantTrustBoundaryId]; ok && trustBoundary.IsTaggedWithAny(tags...) {
+		return true
+	}
+	if sharedRuntime, ok := parsedModel.SharedRuntimes[risk.MostRelevantSharedRuntimeId]; ok && sharedRuntime.IsTaggedWithAny(tags...) {
+		return true
+	}
+	if len(risk.MostRelevantCommunicationLinkId) > 0 {
+		for _, technicalAsset := range parsedModel.TechnicalAssets {
+			for _, communicationLink := range technicalAsset.CommunicationLinks {
+				if communicationLink.Id == risk.MostRelevantCommunicationLinkId && communicationLink.IsTaggedWithAny(tags...) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func arrayOfStringValues(values []core.TypeEnum) []string {
+	result := make([]string, 0)
+	for _, value := range values {
+		result = append(result, value.String())
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/otyg/threagile/model"
+)
+
+func TestParseFailOn(t *testing.T) {
+	policy, err := ParseFailOn("critical, high:unchecked,*:accepted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	risks := []model.Risk{
+		{SyntheticId: "critical-unchecked", Severity: model.CriticalSeverity, RiskStatus: model.Unchecked},
+		{SyntheticId: "critical-in-progress", Severity: model.CriticalSeverity, RiskStatus: model.InProgress},
+		{SyntheticId: "critical-mitigated", Severity: model.CriticalSeverity, RiskStatus: model.Mitigated},
+		{SyntheticId: "high-unchecked", Severity: model.HighSeverity, RiskStatus: model.Unchecked},
+		{SyntheticId: "high-in-discussion", Severity: model.HighSeverity, RiskStatus: model.InDiscussion},
+		{SyntheticId: "low-accepted", Severity: model.LowSeverity, RiskStatus: model.Accepted},
+	}
+	got := RulesBySyntheticId(policy.Evaluate(risks, model.ParsedModel{}))
+	want := map[string]string{"critical-unchecked": "critical", "critical-in-progress": "critical", "high-unchecked": "high:unchecked", "low-accepted": "*:accepted"}
+	if len(got) != len(want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+	for syntheticId, rule := range want {
+		if rules := got[syntheticId]; len(rules) != 1 || rules[0] != rule {
+			t.Errorf("violations of %v = %v, want %v", syntheticId, rules, rule)
+		}
+	}
+
+	for _, invalid := range []string{"severe", "high:open"} {
+		if _, err := ParseFailOn(invalid); err == nil {
+			t.Errorf("ParseFailOn(%q) accepted", invalid)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	filename := t.TempDir() + "/policy.yaml"
+	content := `
+rules:
+  - name: no unhandled risks of internet-facing assets
+    severities: [high, critical]
+    tags: [internet-facing]
+  - categories: [sql-nosql-injection]
+    statuses: [unchecked, accepted]
+`
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedModel := model.ParsedModel{
+		TechnicalAssets: map[string]model.TechnicalAsset{
+			"web":      {Id: "web", Tags: []string{"internet-facing"}},
+			"database": {Id: "database", CommunicationLinks: []model.CommunicationLink{{Id: "web>sql", Tags: []string{"internet-facing"}}}},
+		},
+	}
+	risks := []model.Risk{
+		{SyntheticId: "web", Severity: model.HighSeverity, MostRelevantTechnicalAssetId: "web"},
+		{SyntheticId: "link", Severity: model.CriticalSeverity, MostRelevantCommunicationLinkId: "web>sql"},
+		{SyntheticId: "database", Severity: model.CriticalSeverity, MostRelevantTechnicalAssetId: "database"},
+		{SyntheticId: "injection", Severity: model.MediumSeverity, RiskStatus: model.Accepted, CategoryId: "sql-nosql-injection"},
+	}
+	violations := policy.Evaluate(risks, parsedModel)
+	if len(violations) != 3 || violations[0].Risk.SyntheticId != "link" || violations[1].Risk.SyntheticId != "web" || violations[2].Rule != "rule 2" {
+		t.Errorf("violations = %+v, want link, web and injection (violating rule 2)", violations)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("rules:\n  - severities: [severe]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(filename); err == nil {
+		t.Errorf("policy with unknown severity accepted")
+	}
+}
@@ -0,0 +1,192 @@
+package skeleton
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/otyg/threagile/model"
+)
+
+// Merge adds the generated skeleton to the existing (hand-written) model. Nothing of the existing model is lost:
+// elements already there (matched by id) keep their values like CIA ratings and justifications, only their lists of
+// data assets, tags, and communication links (matched by title) are extended. Technical assets and trust boundaries
+// already placed into another trust boundary stay there. Risk tracking, individual risk categories etc. stay untouched.
+// Elements carrying the provenance tag (if given) were generated before: their values derived from the source (like the
+// technology) are updated and their generated communication links (carrying the tag as well) are replaced.
+func Merge(existing, generated model.ModelInput, provenance string) model.ModelInput {
+	result := existing
+	if result.Data_assets == nil {
+		result.Data_assets = make(map[string]model.InputDataAsset)
+	}
+	if result.Technical_assets == nil {
+		result.Technical_assets = make(map[string]model.InputTechnicalAsset)
+	}
+	if result.Trust_boundaries == nil {
+		result.Trust_boundaries = make(map[string]model.InputTrustBoundary)
+	}
+	result.Tags_available = union(result.Tags_available, generated.Tags_available)
+
+	dataAssetIds := make(map[string]bool)
+	for _, dataAsset := range result.Data_assets {
+		dataAssetIds[dataAsset.ID] = true
+	}
+	for title, dataAsset := range generated.Data_assets {
+		if !dataAssetIds[dataAsset.ID] {
+			title = UniqueTitle(title, dataAsset.ID, func(title string) bool { _, exists := result.Data_assets[title]; return exists })
+			result.Data_assets[title] = dataAsset
+		}
+	}
+
+	technicalAssetTitles := make(map[string]string)
+	for title, technicalAsset := range result.Technical_assets {
+		technicalAssetTitles[technicalAsset.ID] = title
+	}
+	for title, technicalAsset := range generated.Technical_assets {
+		existingTitle, found := technicalAssetTitles[technicalAsset.ID]
+		if !found {
+			title = UniqueTitle(title, technicalAsset.ID, func(title string) bool { _, exists := result.Technical_assets[title]; return exists })
+			result.Technical_assets[title] = technicalAsset
+			continue
+		}
+		merged := result.Technical_assets[existingTitle]
+		merged.Internet = merged.Internet || technicalAsset.Internet
+		if generatedBefore(merged.Tags, provenance) {
+			merged.Type, merged.Size, merged.Technology = technicalAsset.Type, technicalAsset.Size, technicalAsset.Technology
+			merged.Machine, merged.Encryption, merged.Internet = technicalAsset.Machine, technicalAsset.Encryption, technicalAsset.Internet
+			for linkTitle, communicationLink := range merged.Communication_links {
+				if generatedBefore(communicationLink.Tags, provenance) {
+					delete(merged.Communication_links, linkTitle)
+				}
+			}
+		}
+		merged.Tags = union(merged.Tags, technicalAsset.Tags)
+		merged.Data_assets_processed = union(merged.Data_assets_processed, technicalAsset.Data_assets_processed)
+		merged.Data_assets_stored = union(merged.Data_assets_stored, technicalAsset.Data_assets_stored)
+		if merged.Communication_links == nil {
+			merged.Communication_links = make(map[string]model.InputCommunicationLink)
+		}
+		for linkTitle, communicationLink := range technicalAsset.Communication_links {
+			if _, exists := merged.Communication_links[linkTitle]; !exists {
+				merged.Communication_links[linkTitle] = communicationLink
+			}
+		}
+		result.Technical_assets[existingTitle] = merged
+	}
+
+	// technical assets and trust boundaries may only be inside one trust boundary
+	placedBy, trustBoundaryTitles := make(map[string]string), make(map[string]string)
+	for title, trustBoundary := range result.Trust_boundaries {
+		trustBoundaryTitles[trustBoundary.ID] = title
+		for _, id := range append(append([]string{}, trustBoundary.Technical_assets_inside...), trustBoundary.Trust_boundaries_nested...) {
+			placedBy[id] = trustBoundary.ID
+		}
+	}
+	unplaced := func(ids []string, trustBoundaryId string) []string {
+		var result []string
+		for _, id := range ids {
+			if placer, placed := placedBy[id]; !placed || placer == trustBoundaryId {
+				result = append(result, id)
+			}
+		}
+		return result
+	}
+	for title, trustBoundary := range generated.Trust_boundaries {
+		existingTitle, found := trustBoundaryTitles[trustBoundary.ID]
+		if !found {
+			trustBoundary.Technical_assets_inside = unplaced(trustBoundary.Technical_assets_inside, trustBoundary.ID)
+			trustBoundary.Trust_boundaries_nested = unplaced(trustBoundary.Trust_boundaries_nested, trustBoundary.ID)
+			title = UniqueTitle(title, trustBoundary.ID, func(title string) bool { _, exists := result.Trust_boundaries[title]; return exists })
+			result.Trust_boundaries[title] = trustBoundary
+			continue
+		}
+		merged := result.Trust_boundaries[existingTitle]
+		if generatedBefore(merged.Tags, provenance) {
+			merged.Type = trustBoundary.Type
+		}
+		merged.Tags = union(merged.Tags, trustBoundary.Tags)
+		merged.Technical_assets_inside = union(merged.Technical_assets_inside, unplaced(trustBoundary.Technical_assets_inside, trustBoundary.ID))
+		merged.Trust_boundaries_nested = union(merged.Trust_boundaries_nested, unplaced(trustBoundary.Trust_boundaries_nested, trustBoundary.ID))
+		result.Trust_boundaries[existingTitle] = merged
+	}
+	return result
+}
+
+// MergeWithIncludes merges the generated skeleton like Merge, but matches its elements against the resolved model (the existing
+// one with its includes merged), so that elements of the included files are not added again. Only the existing model (along
+// with the new elements) is returned to be written back: changes of elements of the included files can not be written back,
+// so the paths of those elements (like technical_assets.API) are returned instead. The resolved model must not share its
+// maps with the existing one.
+func MergeWithIncludes(existing, resolved, generated model.ModelInput, provenance string) (result model.ModelInput, notWrittenBack []string) {
+	included := make(map[string]string) // section and title of the included elements to their JSON before the merge
+	snapshot := func(section, title string, element interface{}, existingElement bool) {
+		if !existingElement {
+			data, _ := json.Marshal(element)
+			included[section+"\x00"+title] = string(data)
+		}
+	}
+	for title, dataAsset := range resolved.Data_assets {
+		_, exists := existing.Data_assets[title]
+		snapshot("data_assets", title, dataAsset, exists)
+	}
+	for title, technicalAsset := range resolved.Technical_assets {
+		_, exists := existing.Technical_assets[title]
+		snapshot("technical_assets", title, technicalAsset, exists)
+	}
+	for title, trustBoundary := range resolved.Trust_boundaries {
+		_, exists := existing.Trust_boundaries[title]
+		snapshot("trust_boundaries", title, trustBoundary, exists)
+	}
+	resolvedTags := append([]string{}, resolved.Tags_available...)
+
+	merged := Merge(resolved, generated, provenance)
+	result = existing
+	keep := func(section, title string, element interface{}) bool {
+		before, isIncluded := included[section+"\x00"+title]
+		if !isIncluded {
+			return true
+		}
+		if data, _ := json.Marshal(element); string(data) != before {
+			notWrittenBack = append(notWrittenBack, section+"."+title)
+		}
+		return false
+	}
+	result.Data_assets = make(map[string]model.InputDataAsset)
+	for title, dataAsset := range merged.Data_assets {
+		if keep("data_assets", title, dataAsset) {
+			result.Data_assets[title] = dataAsset
+		}
+	}
+	result.Technical_assets = make(map[string]model.InputTechnicalAsset)
+	for title, technicalAsset := range merged.Technical_assets {
+		if keep("technical_assets", title, technicalAsset) {
+			result.Technical_assets[title] = technicalAsset
+		}
+	}
+	result.Trust_boundaries = make(map[string]model.InputTrustBoundary)
+	for title, trustBoundary := range merged.Trust_boundaries {
+		if keep("trust_boundaries", title, trustBoundary) {
+			result.Trust_boundaries[title] = trustBoundary
+		}
+	}
+	result.Tags_available = append([]string{}, existing.Tags_available...)
+	for _, tag := range merged.Tags_available {
+		if !model.Contains(resolvedTags, tag) {
+			result.Tags_available = append(result.Tags_available, tag)
+		}
+	}
+	sort.Strings(notWrittenBack)
+	return result, notWrittenBack
+}
+
+func union(values, others []string) []string {
+	for _, other := range others {
+		if !model.Contains(values, other) {
+			values = append(values, other)
+		}
+	}
+	return values
+}
+
+func generatedBefore(tags []string, provenance string) bool {
+	return len(provenance) > 0 && model.Contains(tags, provenance)
+}
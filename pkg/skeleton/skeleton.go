@@ -0,0 +1,207 @@
+// Package skeleton holds what the importers generating model skeletons from deployment descriptions
+// (like Kubernetes manifests) have in common: defaults, heuristics, and the merge into hand-written models.
+package skeleton
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/model/confidentiality"
+	"github.com/otyg/threagile/model/criticality"
+)
+
+// NewModel is an empty model with the mandatory values set
+func NewModel(title string) model.ModelInput {
+	return model.ModelInput{
+		Threagile_version:    model.ThreagileVersion,
+		Title:                title,
+		Date:                 time.Now().Format("2006-01-02"),
+		Business_criticality: criticality.Important.String(),
+		Data_assets:          make(map[string]model.InputDataAsset),
+		Technical_assets:     make(map[string]model.InputTechnicalAsset),
+		Trust_boundaries:     make(map[string]model.InputTrustBoundary),
+	}
+}
+
+// NewTechnicalAsset has defaults for all values which can not be derived from deployment descriptions
+func NewTechnicalAsset(id, description string, machine model.TechnicalAssetMachine) model.InputTechnicalAsset {
+	return model.InputTechnicalAsset{
+		ID:                  id,
+		Description:         description,
+		Type:                model.Process.String(),
+		Usage:               model.Business.String(),
+		Size:                model.Service.String(),
+		Technology:          model.UnknownTechnology.String(),
+		Machine:             machine.String(),
+		Encryption:          model.NoneEncryption.String(),
+		Confidentiality:     confidentiality.Internal.String(),
+		Integrity:           criticality.Operational.String(),
+		Availability:        criticality.Operational.String(),
+		Communication_links: make(map[string]model.InputCommunicationLink),
+	}
+}
+
+// NewCommunicationLink has defaults for all values which can not be derived from deployment descriptions
+func NewCommunicationLink(targetId, description string, protocol model.Protocol) model.InputCommunicationLink {
+	return model.InputCommunicationLink{
+		Target:         targetId,
+		Description:    description,
+		Protocol:       protocol.String(),
+		Authentication: model.NoneAuthentication.String(),
+		Authorization:  model.NoneAuthorization.String(),
+		Usage:          model.Business.String(),
+	}
+}
+
+// Internet is the external entity representing the clients on the internet
+func Internet() model.InputTechnicalAsset {
+	internet := NewTechnicalAsset("internet", "Clients on the internet", model.Physical)
+	internet.Type = model.ExternalEntity.String()
+	internet.Size = model.System.String()
+	internet.Technology = model.ClientSystem.String()
+	internet.Internet = true
+	internet.Out_of_scope = true
+	internet.Justification_out_of_scope = "Represents the clients on the internet"
+	return internet
+}
+
+// TagGenerated tags all elements and communication links of the skeleton with the provenance tag of the importer (like
+// "kubernetes"). Merge recognizes elements generated before by that tag: re-imports merged into the model update them,
+// while hand-written elements are only extended. Importers tagging their elements themselves have to use the same tag.
+func TagGenerated(generated *model.ModelInput, provenance string) {
+	generated.Tags_available = union(generated.Tags_available, []string{provenance})
+	for title, dataAsset := range generated.Data_assets {
+		dataAsset.Tags = union(dataAsset.Tags, []string{provenance})
+		generated.Data_assets[title] = dataAsset
+	}
+	for title, technicalAsset := range generated.Technical_assets {
+		technicalAsset.Tags = union(technicalAsset.Tags, []string{provenance})
+		for linkTitle, communicationLink := range technicalAsset.Communication_links {
+			communicationLink.Tags = union(communicationLink.Tags, []string{provenance})
+			technicalAsset.Communication_links[linkTitle] = communicationLink
+		}
+		generated.Technical_assets[title] = technicalAsset
+	}
+	for title, trustBoundary := range generated.Trust_boundaries {
+		trustBoundary.Tags = union(trustBoundary.Tags, []string{provenance})
+		generated.Trust_boundaries[title] = trustBoundary
+	}
+}
+
+// UniqueTitle appends the id to the title if it is already taken
+func UniqueTitle(title, id string, exists func(title string) bool) string {
+	if exists(title) {
+		title += " (" + id + ")"
+	}
+	return title
+}
+
+// UniqueId appends a number to the id (made of the name) if it is already taken
+func UniqueId(name string, used map[string]bool) string {
+	id := model.MakeID(name)
+	unique := id
+	for i := 2; used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+type imageTechnology struct {
+	names      []string
+	technology model.TechnicalAssetTechnology
+	datastore  bool
+}
+
+var imageTechnologies = []imageTechnology{
+	{[]string{"postgres", "mysql", "mariadb", "mssql", "oracle", "cockroach", "mongo", "cassandra", "couchdb", "couchbase", "neo4j", "redis", "memcached", "valkey", "influxdb"}, model.Database, true},
+	{[]string{"elasticsearch", "opensearch", "solr"}, model.SearchIndex, true},
+	{[]string{"minio"}, model.BlockStorage, true},
+	{[]string{"openldap", "ldap"}, model.IdentityStoreLDAP, true},
+	{[]string{"rabbitmq", "activemq", "artemis", "nats", "kafka", "pulsar", "mosquitto"}, model.MessageQueue, false},
+	// nginx and caddy containers mostly proxy to the application containers (rather than serving static content)
+	{[]string{"nginx", "traefik", "envoy", "haproxy", "caddy", "oauth2-proxy"}, model.ReverseProxy, false},
+	{[]string{"httpd", "apache", "tomcat"}, model.WebServer, false},
+	{[]string{"keycloak", "dex", "hydra"}, model.IdentityProvider, false},
+	{[]string{"vault"}, model.Vault, false},
+	{[]string{"prometheus", "grafana", "alertmanager", "loki", "jaeger", "zipkin"}, model.Monitoring, false},
+	{[]string{"jenkins", "gitlab-runner", "tekton", "argocd"}, model.BuildPipeline, false},
+	{[]string{"gitlab", "gitea", "gogs"}, model.SourcecodeRepository, false},
+	{[]string{"nexus", "artifactory", "harbor", "registry"}, model.ArtifactRegistry, false},
+	{[]string{"sonarqube"}, model.CodeInspectionPlatform, false},
+	{[]string{"mailhog", "postfix"}, model.MailServer, false},
+	{[]string{"flink", "spark"}, model.StreamProcessing, false},
+}
+
+// default ports of the images, for guessing the protocol when no ports are given
+var imagePorts = map[string]int{
+	"postgres": 5432, "mysql": 3306, "mariadb": 3306, "mssql": 1433, "cockroach": 26257,
+	"mongo": 27017, "cassandra": 9042, "couchdb": 5984, "redis": 6379, "memcached": 11211, "valkey": 6379,
+	"elasticsearch": 9200, "opensearch": 9200, "rabbitmq": 5672, "activemq": 61616, "nats": 4222, "kafka": 9092,
+	"mosquitto": 1883, "openldap": 389,
+}
+
+// imageName is the name of the container image without registry and tag
+func imageName(image string) string {
+	name := strings.ToLower(image)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.IndexAny(name, ":@"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// TechnologyOfImage guesses the technology by the name of the container image
+func TechnologyOfImage(image string) (technology model.TechnicalAssetTechnology, datastore bool) {
+	name := imageName(image)
+	for _, candidate := range imageTechnologies {
+		for _, prefix := range candidate.names {
+			if strings.HasPrefix(name, prefix) {
+				return candidate.technology, candidate.datastore
+			}
+		}
+	}
+	return model.UnknownTechnology, false
+}
+
+// PortOfImage is the default port of well-known container images like databases, zero if unknown
+func PortOfImage(image string) int {
+	name := imageName(image)
+	for prefix, port := range imagePorts {
+		if strings.HasPrefix(name, prefix) {
+			return port
+		}
+	}
+	return 0
+}
+
+var portProtocols = map[int]model.Protocol{
+	80: model.HTTP, 8000: model.HTTP, 8080: model.HTTP, 3000: model.HTTP, 5000: model.HTTP, 9200: model.HTTP,
+	443: model.HTTPS, 8443: model.HTTPS,
+	5432: model.SQL_access_protocol, 3306: model.SQL_access_protocol, 1433: model.SQL_access_protocol, 1521: model.SQL_access_protocol, 26257: model.SQL_access_protocol,
+	27017: model.NoSQL_access_protocol, 6379: model.NoSQL_access_protocol, 9042: model.NoSQL_access_protocol, 11211: model.NoSQL_access_protocol, 5984: model.NoSQL_access_protocol,
+	5672: model.BINARY, 61616: model.BINARY, 4222: model.BINARY, 9092: model.BINARY,
+	1883: model.MQTT, 389: model.LDAP, 636: model.LDAPS, 22: model.SSH,
+	25: model.SMTP, 587: model.SMTP, 465: model.SMTP_encrypted, 21: model.FTP, 2049: model.NFS, 445: model.SMB,
+}
+
+// ProtocolOfPort guesses the protocol by the (well-known) port number or its name (like https)
+func ProtocolOfPort(port int, name string) model.Protocol {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(name, "https"):
+		return model.HTTPS
+	case strings.HasPrefix(name, "http"):
+		return model.HTTP
+	case strings.HasPrefix(name, "grpc"), strings.HasPrefix(name, "tcp"):
+		return model.BINARY
+	}
+	if protocol, ok := portProtocols[port]; ok {
+		return protocol
+	}
+	return model.UnknownProtocol
+}
@@ -0,0 +1,103 @@
+package skeleton
+
+import (
+	"testing"
+
+	"github.com/otyg/threagile/model"
+)
+
+func TestMerge(t *testing.T) {
+	generated := NewModel("Generated")
+	api := NewTechnicalAsset("api", "Generated API", model.Container)
+	api.Data_assets_processed = []string{"credentials"}
+	api.Communication_links["Database Traffic"] = NewCommunicationLink("database", "", model.SQL_access_protocol)
+	generated.Technical_assets["api"] = api
+	generated.Technical_assets["database"] = NewTechnicalAsset("database", "", model.Container)
+	generated.Data_assets["Credentials"] = model.InputDataAsset{ID: "credentials"}
+	generated.Trust_boundaries["Namespace"] = model.InputTrustBoundary{ID: "namespace", Technical_assets_inside: []string{"api", "database"}}
+
+	existing := NewModel("Hand-written")
+	existing.Technical_assets["API Service"] = model.InputTechnicalAsset{ID: "api", Description: "Hand-written API", Confidentiality: "confidential",
+		Justification_cia_rating: "Handles customer data", Communication_links: map[string]model.InputCommunicationLink{
+			"Database Traffic": {Target: "database", Protocol: model.SQL_access_protocol_encrypted.String()}}}
+	existing.Trust_boundaries["Protected Zone"] = model.InputTrustBoundary{ID: "protected", Technical_assets_inside: []string{"database"}}
+	existing.Risk_tracking = map[string]model.InputRiskTracking{"some-risk@api": {Status: "mitigated"}}
+
+	merged := Merge(existing, generated, "")
+
+	if merged.Title != "Hand-written" || len(merged.Risk_tracking) != 1 {
+		t.Errorf("merged model lost hand-written values: %+v", merged)
+	}
+	got := merged.Technical_assets["API Service"]
+	if got.Description != "Hand-written API" || got.Confidentiality != "confidential" || got.Justification_cia_rating != "Handles customer data" {
+		t.Errorf("merged api = %+v", got)
+	}
+	if len(got.Data_assets_processed) != 1 || got.Communication_links["Database Traffic"].Protocol != model.SQL_access_protocol_encrypted.String() {
+		t.Errorf("data assets or communication links of merged api = %+v", got)
+	}
+	if _, exists := merged.Technical_assets["api"]; exists || len(merged.Technical_assets) != 2 {
+		t.Errorf("merged technical assets = %+v", merged.Technical_assets)
+	}
+	if got := merged.Trust_boundaries["Namespace"].Technical_assets_inside; len(got) != 1 || got[0] != "api" {
+		t.Errorf("assets inside namespace = %v, want database to stay inside the protected zone", got)
+	}
+}
+
+func TestMergeWithProvenance(t *testing.T) {
+	generated := NewModel("Generated")
+	server := NewTechnicalAsset("server", "", model.Virtual)
+	server.Technology, server.Tags = model.Database.String(), []string{"aws:rds", "generator"}
+	link := NewCommunicationLink("other", "", model.HTTPS)
+	link.Tags = []string{"generator"}
+	server.Communication_links["Current Traffic"] = link
+	generated.Technical_assets["server"] = server
+
+	existing := NewModel("Hand-written")
+	existing.Technical_assets["Orders Database"] = model.InputTechnicalAsset{ID: "server", Technology: model.UnknownTechnology.String(), Confidentiality: "confidential",
+		Tags: []string{"aws:rds", "generator"}, Communication_links: map[string]model.InputCommunicationLink{
+			"Stale Traffic":       {Target: "other", Tags: []string{"generator"}},
+			"Hand-written Backup": {Target: "backup"}}}
+
+	got := Merge(existing, generated, "generator").Technical_assets["Orders Database"]
+	if got.Technology != model.Database.String() || got.Confidentiality != "confidential" {
+		t.Errorf("merged server = %+v, want technology updated and CIA rating kept", got)
+	}
+	if _, exists := got.Communication_links["Stale Traffic"]; exists || len(got.Communication_links) != 2 {
+		t.Errorf("communication links of merged server = %+v, want the generated one replaced and the hand-written one kept", got.Communication_links)
+	}
+}
+
+func TestMergeWithIncludes(t *testing.T) {
+	generated := NewModel("Generated")
+	generated.Tags_available = []string{"generator"}
+	api := NewTechnicalAsset("api", "", model.Container)
+	api.Communication_links["Database Traffic"] = NewCommunicationLink("database", "", model.SQL_access_protocol)
+	generated.Technical_assets["api"] = api
+	database := NewTechnicalAsset("database", "", model.Container)
+	database.Tags = []string{"generator"}
+	generated.Technical_assets["database"] = database
+	generated.Technical_assets["worker"] = NewTechnicalAsset("worker", "", model.Container)
+
+	existing := NewModel("Hand-written")
+	existing.Includes, existing.Tags_available = []string{"database.yaml"}, []string{"hand-written"}
+	existing.Technical_assets["API"] = model.InputTechnicalAsset{ID: "api", Confidentiality: "confidential"}
+	resolved := NewModel("Hand-written")
+	resolved.Includes, resolved.Tags_available = []string{"database.yaml"}, []string{"hand-written", "shared"}
+	resolved.Technical_assets["API"] = model.InputTechnicalAsset{ID: "api", Confidentiality: "confidential"}
+	resolved.Technical_assets["Database"] = model.InputTechnicalAsset{ID: "database", Tags: []string{"shared"}}
+
+	merged, notWrittenBack := MergeWithIncludes(existing, resolved, generated, "")
+
+	if _, exists := merged.Technical_assets["Database"]; exists || len(merged.Technical_assets) != 2 || len(merged.Includes) != 1 {
+		t.Errorf("merged technical assets = %+v, want the included database neither duplicated nor written back", merged.Technical_assets)
+	}
+	if got := merged.Technical_assets["API"]; got.Confidentiality != "confidential" || got.Communication_links["Database Traffic"].Target != "database" {
+		t.Errorf("merged api = %+v, want the communication link to the included database added", got)
+	}
+	if len(notWrittenBack) != 1 || notWrittenBack[0] != "technical_assets.Database" {
+		t.Errorf("not written back = %v, want the tagged included database", notWrittenBack)
+	}
+	if got := merged.Tags_available; len(got) != 2 || got[0] != "hand-written" || got[1] != "generator" {
+		t.Errorf("available tags = %v, want the tags of the included files not written back", got)
+	}
+}
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var modelsBucket, dataBucket, historyBucket = []byte("models"), []byte("data"), []byte("history")
+var modelKey, createdKey, modifiedKey = []byte("threagile.yaml"), []byte("created"), []byte("modified")
+
+// Bolt keeps everything in a single bbolt database file: a bucket per key holding a bucket per model
+// (with a nested history bucket) as well as a bucket for further data of the key
+type Bolt struct {
+	db *bolt.DB
+}
+
+// OpenBolt locks the database file for this process (waiting for another one to release it at most 5 seconds),
+// so a single server process can use it only
+func OpenBolt(filename string) (*Bolt, error) {
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &Bolt{db: db}, nil
+}
+
+func (what *Bolt) CreateKey(key string) error {
+	return what.db.Update(func(tx *bolt.Tx) error {
+		keyBucket, err := tx.CreateBucket([]byte(key))
+		if err != nil {
+			return err
+		}
+		if _, err = keyBucket.CreateBucket(modelsBucket); err != nil {
+			return err
+		}
+		_, err = keyBucket.CreateBucket(dataBucket)
+		return err
+	})
+}
+
+func (what *Bolt) KeyExists(key string) (result bool, err error) {
+	err = what.db.View(func(tx *bolt.Tx) error {
+		result = tx.Bucket([]byte(key)) != nil
+		return nil
+	})
+	return result, err
+}
+
+func (what *Bolt) DeleteKey(key string) error {
+	return what.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(key))
+		if err == bolt.ErrBucketNotFound {
+			return ErrNotFound
+		}
+		return err
+	})
+}
+
+func (what *Bolt) Keys() (result []string, err error) {
+	result = make([]string, 0)
+	err = what.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			result = append(result, string(name))
+			return nil
+		})
+	})
+	return result, err
+}
+
+func (what *Bolt) Models(key string) (result []ModelInfo, err error) {
+	result = make([]ModelInfo, 0)
+	err = what.db.View(func(tx *bolt.Tx) error {
+		models, err := modelsOf(tx, key)
+		if err != nil {
+			return err
+		}
+		return models.ForEach(func(modelID, _ []byte) error {
+			model := models.Bucket(modelID)
+			info := ModelInfo{ID: string(modelID)}
+			if err := info.Created.UnmarshalText(model.Get(createdKey)); err != nil {
+				return err
+			}
+			if err := info.Modified.UnmarshalText(model.Get(modifiedKey)); err != nil {
+				return err
+			}
+			result = append(result, info)
+			return nil
+		})
+	})
+	return result, err
+}
+
+func (what *Bolt) ModelExists(key, modelID string) (result bool, err error) {
+	err = what.db.View(func(tx *bolt.Tx) error {
+		models, err := modelsOf(tx, key)
+		if err != nil {
+			return err
+		}
+		result = models.Bucket([]byte(modelID)) != nil
+		return nil
+	})
+	return result, err
+}
+
+func (what *Bolt) CreateModel(key, modelID string, data []byte) (version string, err error) {
+	err = what.db.Update(func(tx *bolt.Tx) error {
+		models, err := modelsOf(tx, key)
+		if err != nil {
+			return err
+		}
+		model, err := models.CreateBucket([]byte(modelID))
+		if err != nil {
+			return err
+		}
+		if _, err = model.CreateBucket(historyBucket); err != nil {
+			return err
+		}
+		now, _ := time.Now().MarshalText()
+		if err = model.Put(createdKey, now); err != nil {
+			return err
+		}
+		return putModel(model, data)
+	})
+	return Version(data), err
+}
+
+func (what *Bolt) ReadModel(key, modelID string) (data []byte, version string, err error) {
+	err = what.db.View(func(tx *bolt.Tx) error {
+		model, err := modelOf(tx, key, modelID)
+		if err != nil {
+			return err
+		}
+		data = copyBytes(model.Get(modelKey))
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return data, Version(data), nil
+}
+
+func (what *Bolt) WriteModel(key, modelID string, data []byte, expectedVersion string, replaced HistoryEntry) (version string, err error) {
+	err = what.db.Update(func(tx *bolt.Tx) error {
+		model, err := modelOf(tx, key, modelID)
+		if err != nil {
+			return err
+		}
+		current := model.Get(modelKey)
+		if err = checkVersion(current, expectedVersion); err != nil {
+			return err
+		}
+		history := model.Bucket(historyBucket)
+		replaced = uniqueHistoryEntry(replaced, func(name string) bool {
+			return history.Get([]byte(name)) != nil
+		})
+		if err = history.Put([]byte(replaced.Name), current); err != nil {
+			return err
+		}
+		return putModel(model, data)
+	})
+	return Version(data), err
+}
+
+func (what *Bolt) DeleteModel(key, modelID string) error {
+	return what.db.Update(func(tx *bolt.Tx) error {
+		models, err := modelsOf(tx, key)
+		if err != nil {
+			return err
+		}
+		err = models.DeleteBucket([]byte(modelID))
+		if err == bolt.ErrBucketNotFound {
+			return ErrNotFound
+		}
+		return err
+	})
+}
+
+func (what *Bolt) History(key, modelID string) (result []HistoryEntry, err error) {
+	result = make([]HistoryEntry, 0)
+	err = what.db.View(func(tx *bolt.Tx) error {
+		model, err := modelOf(tx, key, modelID)
+		if err != nil {
+			return err
+		}
+		return model.Bucket(historyBucket).ForEach(func(name, _ []byte) error {
+			if entry, ok := parseHistoryEntry(string(name)); ok {
+				result = append(result, entry)
+			}
+			return nil
+		})
+	})
+	sortHistory(result)
+	return result, err
+}
+
+func (what *Bolt) ReadHistory(key, modelID, name string) (data []byte, err error) {
+	err = what.db.View(func(tx *bolt.Tx) error {
+		model, err := modelOf(tx, key, modelID)
+		if err != nil {
+			return err
+		}
+		data = copyBytes(model.Bucket(historyBucket).Get([]byte(name)))
+		if data == nil {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return data, err
+}
+
+func (what *Bolt) DeleteHistory(key, modelID, name string) error {
+	return what.db.Update(func(tx *bolt.Tx) error {
+		model, err := modelOf(tx, key, modelID)
+		if err != nil {
+			return err
+		}
+		return model.Bucket(historyBucket).Delete([]byte(name))
+	})
+}
+
+func (what *Bolt) ReadKeyData(key, name string) (data []byte, err error) {
+	err = what.db.View(func(tx *bolt.Tx) error {
+		keyBucket := tx.Bucket([]byte(key))
+		if keyBucket == nil {
+			return ErrNotFound
+		}
+		data = copyBytes(keyBucket.Bucket(dataBucket).Get([]byte(name)))
+		return nil
+	})
+	return data, err
+}
+
+func (what *Bolt) WriteKeyData(key, name string, data []byte) error {
+	return what.db.Update(func(tx *bolt.Tx) error {
+		keyBucket := tx.Bucket([]byte(key))
+		if keyBucket == nil {
+			return ErrNotFound
+		}
+		return keyBucket.Bucket(dataBucket).Put([]byte(name), data)
+	})
+}
+
+func (what *Bolt) Close() error {
+	return what.db.Close()
+}
+
+func modelsOf(tx *bolt.Tx, key string) (*bolt.Bucket, error) {
+	keyBucket := tx.Bucket([]byte(key))
+	if keyBucket == nil {
+		return nil, ErrNotFound
+	}
+	return keyBucket.Bucket(modelsBucket), nil
+}
+
+func modelOf(tx *bolt.Tx, key, modelID string) (*bolt.Bucket, error) {
+	models, err := modelsOf(tx, key)
+	if err != nil {
+		return nil, err
+	}
+	model := models.Bucket([]byte(modelID))
+	if model == nil {
+		return nil, ErrNotFound
+	}
+	return model, nil
+}
+
+func putModel(model *bolt.Bucket, data []byte) error {
+	now, _ := time.Now().MarshalText()
+	if err := model.Put(modifiedKey, now); err != nil {
+		return err
+	}
+	return model.Put(modelKey, data)
+}
+
+// copyBytes is required as values returned by bbolt are only valid within their transaction
+func copyBytes(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append([]byte{}, data...)
+}
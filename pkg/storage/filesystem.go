@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileSystem keeps each key in a folder with a sub-folder per model (holding its threagile.yaml and a history folder)
+type FileSystem struct {
+	baseFolder string
+	lock       sync.Mutex // serializes the version check and the write of a model (within this process only)
+}
+
+func NewFileSystem(baseFolder string) *FileSystem {
+	return &FileSystem{baseFolder: baseFolder}
+}
+
+func (what *FileSystem) keyFolder(key string) string {
+	return what.baseFolder + "/" + key
+}
+
+func (what *FileSystem) modelFolder(key, modelID string) string {
+	return what.keyFolder(key) + "/" + modelID
+}
+
+func (what *FileSystem) CreateKey(key string) error {
+	return os.Mkdir(what.keyFolder(key), 0700)
+}
+
+func (what *FileSystem) KeyExists(key string) (bool, error) {
+	return exists(what.keyFolder(key))
+}
+
+func (what *FileSystem) DeleteKey(key string) error {
+	return os.RemoveAll(what.keyFolder(key))
+}
+
+func (what *FileSystem) Keys() ([]string, error) {
+	folders, err := ioutil.ReadDir(what.baseFolder)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0)
+	for _, folder := range folders {
+		if folder.IsDir() && len(folder.Name()) == 128 { // it's a sha512 key hash probably
+			result = append(result, folder.Name())
+		}
+	}
+	return result, nil
+}
+
+func (what *FileSystem) Models(key string) ([]ModelInfo, error) {
+	folders, err := ioutil.ReadDir(what.keyFolder(key))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ModelInfo, 0)
+	for _, folder := range folders {
+		if !folder.IsDir() {
+			continue
+		}
+		modelStat, err := os.Stat(what.modelFolder(key, folder.Name()) + "/threagile.yaml")
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ModelInfo{
+			ID:       folder.Name(),
+			Created:  folder.ModTime(),
+			Modified: modelStat.ModTime(),
+		})
+	}
+	return result, nil
+}
+
+func (what *FileSystem) ModelExists(key, modelID string) (bool, error) {
+	return exists(what.modelFolder(key, modelID))
+}
+
+func (what *FileSystem) CreateModel(key, modelID string, data []byte) (version string, err error) {
+	err = os.Mkdir(what.modelFolder(key, modelID), 0700)
+	if err != nil {
+		return "", err
+	}
+	err = ioutil.WriteFile(what.modelFolder(key, modelID)+"/threagile.yaml", data, 0600)
+	return Version(data), err
+}
+
+func (what *FileSystem) ReadModel(key, modelID string) (data []byte, version string, err error) {
+	data, err = ioutil.ReadFile(what.modelFolder(key, modelID) + "/threagile.yaml")
+	if os.IsNotExist(err) {
+		return nil, "", ErrNotFound
+	} else if err != nil {
+		return nil, "", err
+	}
+	return data, Version(data), nil
+}
+
+func (what *FileSystem) WriteModel(key, modelID string, data []byte, expectedVersion string, replaced HistoryEntry) (version string, err error) {
+	what.lock.Lock()
+	defer what.lock.Unlock()
+	current, _, err := what.ReadModel(key, modelID)
+	if err != nil {
+		return "", err
+	}
+	if err = checkVersion(current, expectedVersion); err != nil {
+		return "", err
+	}
+	// the modification time of the model folder is its creation time, so it is restored after changing its entries
+	modelFolder, err := os.Stat(what.modelFolder(key, modelID))
+	if err != nil {
+		return "", err
+	}
+	historyFolder := what.modelFolder(key, modelID) + "/history"
+	if _, err := os.Stat(historyFolder); os.IsNotExist(err) {
+		err = os.Mkdir(historyFolder, 0700)
+		if err != nil {
+			return "", err
+		}
+	}
+	replaced = uniqueHistoryEntry(replaced, func(name string) bool {
+		_, err := os.Lstat(historyFolder + "/" + name + ".backup")
+		return !os.IsNotExist(err)
+	})
+	historyFile, err := os.OpenFile(historyFolder+"/"+replaced.Name+".backup", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0400)
+	if err != nil {
+		return "", err
+	}
+	_, err = historyFile.Write(current)
+	if closeErr := historyFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err = writeFileAtomically(what.modelFolder(key, modelID)+"/threagile.yaml", data); err != nil {
+		return "", err
+	}
+	return Version(data), os.Chtimes(what.modelFolder(key, modelID), modelFolder.ModTime(), modelFolder.ModTime())
+}
+
+func (what *FileSystem) DeleteModel(key, modelID string) error {
+	if ok, err := what.ModelExists(key, modelID); err != nil || !ok {
+		return notFoundUnless(err)
+	}
+	return os.RemoveAll(what.modelFolder(key, modelID))
+}
+
+func (what *FileSystem) History(key, modelID string) ([]HistoryEntry, error) {
+	result := make([]HistoryEntry, 0)
+	files, err := ioutil.ReadDir(what.modelFolder(key, modelID) + "/history")
+	if os.IsNotExist(err) {
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".backup")
+		if name == file.Name() {
+			continue
+		}
+		if entry, ok := parseHistoryEntry(name); ok {
+			result = append(result, entry)
+		}
+	}
+	sortHistory(result)
+	return result, nil
+}
+
+func (what *FileSystem) ReadHistory(key, modelID, name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(what.modelFolder(key, modelID) + "/history/" + filepath.Base(name) + ".backup")
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (what *FileSystem) DeleteHistory(key, modelID, name string) error {
+	return os.Remove(what.modelFolder(key, modelID) + "/history/" + filepath.Base(name) + ".backup")
+}
+
+func (what *FileSystem) ReadKeyData(key, name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(what.keyFolder(key) + "/" + filepath.Base(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (what *FileSystem) WriteKeyData(key, name string, data []byte) error {
+	return writeFileAtomically(what.keyFolder(key)+"/"+filepath.Base(name), data)
+}
+
+func (what *FileSystem) Close() error {
+	return nil
+}
+
+func exists(filename string) (bool, error) {
+	_, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func notFoundUnless(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrNotFound
+}
+
+// writeFileAtomically writes into a temporary file first, so that readers never see a partially written file
+func writeFileAtomically(filename string, data []byte) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filename), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filename)
+}
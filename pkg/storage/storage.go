@@ -0,0 +1,131 @@
+// Package storage keeps the (already encrypted) models of the server along with their history, either in the filesystem or in an embedded database.
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// ErrVersionMismatch signals that a model was changed in the meantime (optimistic concurrency)
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// historyTimestampFormat names the history entries, with nanoseconds to keep the names unique (and without colons, as they become filenames),
+// where entries named by the former formats are still read
+const historyTimestampFormat = "2006-01-02 15.04.05.000000000"
+
+var formerHistoryTimestampFormats = []string{"2006-01-02 15:04:05.000000000", "2006-01-02 15:04:05"}
+
+// maxChangeReasonLength limits the length (in bytes) of the change reason within the name of a history entry
+const maxChangeReasonLength = 200
+
+// unsafeInHistoryNames are the characters replaced within the change reason of a history entry, as its name becomes a filename
+var unsafeInHistoryNames = regexp.MustCompile(`[^\p{L}\p{N} @.,;_()+=#-]`)
+
+// Storage is shared by all requests, so implementations have to be safe for concurrent use.
+// Keys are the (hashed) identifiers of the keys, whose models are identified by UUIDs.
+type Storage interface {
+	CreateKey(key string) error
+	KeyExists(key string) (bool, error)
+	DeleteKey(key string) error // including all of its models and data
+	Keys() ([]string, error)
+
+	Models(key string) ([]ModelInfo, error)
+	ModelExists(key, modelID string) (bool, error)
+	CreateModel(key, modelID string, data []byte) (version string, err error)
+	ReadModel(key, modelID string) (data []byte, version string, err error)
+	// WriteModel replaces a model (keeping the replaced one in its history as the given entry), when an expected version
+	// is given only if the model is still of that version
+	WriteModel(key, modelID string, data []byte, expectedVersion string, replaced HistoryEntry) (version string, err error)
+	DeleteModel(key, modelID string) error
+
+	History(key, modelID string) ([]HistoryEntry, error) // newest first
+	ReadHistory(key, modelID, name string) ([]byte, error)
+	DeleteHistory(key, modelID, name string) error
+
+	// ReadKeyData reads further data of a key (like its webhooks), nil if there is none
+	ReadKeyData(key, name string) ([]byte, error)
+	WriteKeyData(key, name string, data []byte) error
+
+	Close() error
+}
+
+type ModelInfo struct {
+	ID       string
+	Created  time.Time
+	Modified time.Time
+}
+
+// HistoryEntry is a previous version of a model, named by the timestamp and the reason of the change which replaced it
+type HistoryEntry struct {
+	Name         string
+	Timestamp    time.Time
+	ChangeReason string
+}
+
+func NewHistoryEntry(timestamp time.Time, changeReason string) HistoryEntry {
+	// the name becomes a filename, so keep it short and free of path separators (and other characters special to filesystems)
+	changeReason = unsafeInHistoryNames.ReplaceAllString(changeReason, "_")
+	if len(changeReason) > maxChangeReasonLength {
+		changeReason = strings.ToValidUTF8(changeReason[:maxChangeReasonLength], "") // without the rune cut in half
+	}
+	return HistoryEntry{
+		Name:         timestamp.Format(historyTimestampFormat) + " " + changeReason,
+		Timestamp:    timestamp,
+		ChangeReason: changeReason,
+	}
+}
+
+// uniqueHistoryEntry moves the timestamp of the entry forward until its name is not taken by an existing entry
+func uniqueHistoryEntry(entry HistoryEntry, exists func(name string) bool) HistoryEntry {
+	for exists(entry.Name) {
+		entry = NewHistoryEntry(entry.Timestamp.Add(time.Nanosecond), entry.ChangeReason)
+	}
+	return entry
+}
+
+func parseHistoryEntry(name string) (HistoryEntry, bool) {
+	for _, format := range append([]string{historyTimestampFormat}, formerHistoryTimestampFormats...) {
+		if len(name) < len(format)+1 || name[len(format)] != ' ' {
+			continue
+		}
+		timestamp, err := time.ParseInLocation(format, name[:len(format)], time.Local)
+		if err != nil {
+			continue
+		}
+		return HistoryEntry{
+			Name:         name,
+			Timestamp:    timestamp,
+			ChangeReason: name[len(format)+1:],
+		}, true
+	}
+	return HistoryEntry{}, false
+}
+
+func sortHistory(entries []HistoryEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Timestamp.Equal(entries[j].Timestamp) {
+			return entries[i].Timestamp.After(entries[j].Timestamp) // the formats of the names don't sort alike
+		}
+		return entries[i].Name > entries[j].Name
+	})
+}
+
+// Version identifies the content of a model, used as its ETag
+func Version(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:16])
+}
+
+func checkVersion(current []byte, expectedVersion string) error {
+	if len(expectedVersion) > 0 && expectedVersion != Version(current) {
+		return ErrVersionMismatch
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStorage(t *testing.T) {
+	bolt, err := OpenBolt(t.TempDir() + "/threagile.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+	for name, storage := range map[string]Storage{"filesystem": NewFileSystem(t.TempDir()), "bolt": bolt} {
+		t.Run(name, func(t *testing.T) {
+			testStorage(t, storage)
+		})
+	}
+}
+
+func testStorage(t *testing.T, storage Storage) {
+	key := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	modelID := "9d3d1f5c-0f6f-4b5e-8d4a-1a2b3c4d5e6f"
+	if err := storage.CreateKey(key); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := storage.Keys(); err != nil || len(keys) != 1 || keys[0] != key {
+		t.Errorf("keys = %v (%v), want [%v]", keys, err, key)
+	}
+	if _, _, err := storage.ReadModel(key, modelID); err != ErrNotFound {
+		t.Errorf("reading a missing model = %v, want %v", err, ErrNotFound)
+	}
+
+	version1, err := storage.CreateModel(key, modelID, []byte("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, version, err := storage.ReadModel(key, modelID)
+	if err != nil || string(data) != "v1" || version != version1 {
+		t.Errorf("model = %s with version %v (%v), want v1 with version %v", data, version, err, version1)
+	}
+
+	older := NewHistoryEntry(time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local), "Cover Update")
+	version2, err := storage.WriteModel(key, modelID, []byte("v2"), version1, older)
+	if err != nil || version2 == version1 {
+		t.Fatalf("write of v2 = %v with version %v", err, version2)
+	}
+	if _, err := storage.WriteModel(key, modelID, []byte("v3"), version1, NewHistoryEntry(time.Now(), "Lost Update")); err != ErrVersionMismatch {
+		t.Errorf("write based on an outdated version = %v, want %v", err, ErrVersionMismatch)
+	}
+	newer := NewHistoryEntry(time.Date(2024, 1, 2, 3, 4, 6, 0, time.Local), "Tags/Update")
+	if _, err := storage.WriteModel(key, modelID, []byte("v3"), "", newer); err != nil {
+		t.Errorf("write without expected version = %v", err)
+	}
+
+	history, err := storage.History(key, modelID)
+	if err != nil || len(history) != 2 || history[0].Name != newer.Name || history[1].ChangeReason != "Cover Update" || !history[1].Timestamp.Equal(older.Timestamp) {
+		t.Fatalf("history = %+v (%v), want %+v and %+v", history, err, newer, older)
+	}
+	if history[0].ChangeReason != "Tags_Update" {
+		t.Errorf("change reason = %v, want it without path separator", history[0].ChangeReason)
+	}
+	if data, err := storage.ReadHistory(key, modelID, older.Name); err != nil || string(data) != "v1" {
+		t.Errorf("history entry %v = %s (%v), want v1", older.Name, data, err)
+	}
+	if err := storage.DeleteHistory(key, modelID, older.Name); err != nil {
+		t.Error(err)
+	}
+	if _, err := storage.ReadHistory(key, modelID, older.Name); err != ErrNotFound {
+		t.Errorf("reading a deleted history entry = %v, want %v", err, ErrNotFound)
+	}
+
+	// a model replaced twice at the same time keeps both previous versions
+	if _, err := storage.WriteModel(key, modelID, []byte("v4"), "", newer); err != nil {
+		t.Fatal(err)
+	}
+	history, err = storage.History(key, modelID)
+	if err != nil || len(history) != 2 || history[0].Name == history[1].Name || history[0].ChangeReason != "Tags_Update" {
+		t.Fatalf("history = %+v (%v), want two distinct entries of the same change", history, err)
+	}
+	for i, want := range []string{"v3", "v2"} {
+		if data, err := storage.ReadHistory(key, modelID, history[i].Name); err != nil || string(data) != want {
+			t.Errorf("history entry %v = %s (%v), want %v", history[i].Name, data, err, want)
+		}
+	}
+
+	if data, err := storage.ReadKeyData(key, "webhooks.json"); err != nil || data != nil {
+		t.Errorf("missing key data = %s (%v), want none", data, err)
+	}
+	if err := storage.WriteKeyData(key, "webhooks.json", []byte("[]")); err != nil {
+		t.Error(err)
+	}
+	if data, err := storage.ReadKeyData(key, "webhooks.json"); err != nil || string(data) != "[]" {
+		t.Errorf("key data = %s (%v), want []", data, err)
+	}
+
+	models, err := storage.Models(key)
+	if err != nil || len(models) != 1 || models[0].ID != modelID || models[0].Modified.Before(models[0].Created) {
+		t.Errorf("models = %+v (%v), want %v", models, err, modelID)
+	}
+	if err := storage.DeleteModel(key, modelID); err != nil {
+		t.Error(err)
+	}
+	if exists, err := storage.ModelExists(key, modelID); err != nil || exists {
+		t.Errorf("deleted model exists = %v (%v)", exists, err)
+	}
+	if err := storage.DeleteKey(key); err != nil {
+		t.Error(err)
+	}
+	if exists, err := storage.KeyExists(key); err != nil || exists {
+		t.Errorf("deleted key exists = %v (%v)", exists, err)
+	}
+}
+
+func TestParseHistoryEntry(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		wantOk           bool
+		wantTimestamp    time.Time
+		wantChangeReason string
+	}{
+		{"2024-01-02 03.04.05.000000007 Model Import", true, time.Date(2024, 1, 2, 3, 4, 5, 7, time.Local), "Model Import"},
+		{"2024-01-02 03:04:05.000000007 Model Import", true, time.Date(2024, 1, 2, 3, 4, 5, 7, time.Local), "Model Import"},
+		{"2024-01-02 03:04:05 Model Import", true, time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local), "Model Import"},
+		{"2024-01-02 03:04 Model Import", false, time.Time{}, ""},
+		{".tmp-123", false, time.Time{}, ""},
+	} {
+		entry, ok := parseHistoryEntry(test.name)
+		if ok != test.wantOk || !entry.Timestamp.Equal(test.wantTimestamp) || entry.ChangeReason != test.wantChangeReason {
+			t.Errorf("history entry %v = %+v (%v), want %v of %v", test.name, entry, ok, test.wantChangeReason, test.wantTimestamp)
+		}
+	}
+}
+
+func TestNewHistoryEntry(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 7, time.Local)
+	for _, test := range []struct {
+		changeReason, want string
+	}{
+		{"Model Import", "Model Import"},
+		{"Tags/Update", "Tags_Update"},
+		{"Risk Tracking of some-rule@web>database-traffic set to accepted", "Risk Tracking of some-rule@web_database-traffic set to accepted"},
+		{`Risk Tracking of some-rule@* deleted: C:\models`, "Risk Tracking of some-rule@_ deleted_ C__models"},
+		{"Übersicht der Änderungen", "Übersicht der Änderungen"},
+		{strings.Repeat("a", 199) + "äb", strings.Repeat("a", 199)},
+	} {
+		entry := NewHistoryEntry(timestamp, test.changeReason)
+		if entry.ChangeReason != test.want || entry.Name != "2024-01-02 03.04.05.000000007 "+test.want {
+			t.Errorf("history entry of %q = %+v, want the change reason %q", test.changeReason, entry, test.want)
+		}
+		if parsed, ok := parseHistoryEntry(entry.Name); !ok || parsed != entry {
+			t.Errorf("parsed history entry %v = %+v (%v), want %+v", entry.Name, parsed, ok, entry)
+		}
+	}
+}
+
+func TestSortHistoryOfFormerNames(t *testing.T) {
+	entries := make([]HistoryEntry, 0)
+	for _, name := range []string{"2024-01-02 03:04:06 Older", "2024-01-02 03.04.07.000000000 Newest", "2024-01-02 03:04:05.000000001 Oldest"} {
+		entry, ok := parseHistoryEntry(name)
+		if !ok {
+			t.Fatalf("history entry %v not parsed", name)
+		}
+		entries = append(entries, entry)
+	}
+	sortHistory(entries)
+	for i, want := range []string{"Newest", "Older", "Oldest"} {
+		if entries[i].ChangeReason != want {
+			t.Errorf("history entry %v = %v, want %v", i, entries[i].Name, want)
+		}
+	}
+}
@@ -0,0 +1,540 @@
+package terraform
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/skeleton"
+)
+
+// ProvenanceTag is added to each generated element along with the tag of its resource type (as skeleton.TagGenerated would)
+const ProvenanceTag = "terraform"
+
+type assetMapping struct {
+	tag        string
+	assetType  model.TechnicalAssetType
+	technology model.TechnicalAssetTechnology
+	machine    model.TechnicalAssetMachine
+}
+
+// the resource types of compute, storage, and database resources becoming technical assets
+var assetMappings = map[string]assetMapping{
+	"aws_instance":                       {"aws:ec2", model.Process, model.UnknownTechnology, model.Virtual},
+	"aws_autoscaling_group":              {"aws:ec2", model.Process, model.UnknownTechnology, model.Virtual},
+	"aws_lambda_function":                {"aws:lambda", model.Process, model.Function, model.Serverless},
+	"aws_ecs_service":                    {"aws", model.Process, model.UnknownTechnology, model.Container},
+	"aws_eks_cluster":                    {"aws", model.Process, model.ContainerPlatform, model.Virtual},
+	"aws_api_gateway_rest_api":           {"aws:apigateway", model.Process, model.Gateway, model.Serverless},
+	"aws_apigatewayv2_api":               {"aws:apigateway", model.Process, model.Gateway, model.Serverless},
+	"aws_lb":                             {"aws", model.Process, model.LoadBalancer, model.Virtual},
+	"aws_alb":                            {"aws", model.Process, model.LoadBalancer, model.Virtual},
+	"aws_elb":                            {"aws", model.Process, model.LoadBalancer, model.Virtual},
+	"aws_sqs_queue":                      {"aws:sqs", model.Process, model.MessageQueue, model.Serverless},
+	"aws_s3_bucket":                      {"aws:s3", model.Datastore, model.FileServer, model.Virtual},
+	"aws_ebs_volume":                     {"aws:ebs", model.Datastore, model.BlockStorage, model.Virtual},
+	"aws_efs_file_system":                {"aws", model.Datastore, model.FileServer, model.Virtual},
+	"aws_db_instance":                    {"aws:rds", model.Datastore, model.Database, model.Virtual},
+	"aws_rds_cluster":                    {"aws:rds", model.Datastore, model.Database, model.Virtual},
+	"aws_dynamodb_table":                 {"aws:dynamodb", model.Datastore, model.Database, model.Serverless},
+	"aws_elasticache_cluster":            {"aws", model.Datastore, model.Database, model.Virtual},
+	"aws_elasticache_replication_group":  {"aws", model.Datastore, model.Database, model.Virtual},
+	"azurerm_linux_virtual_machine":      {"azure", model.Process, model.UnknownTechnology, model.Virtual},
+	"azurerm_windows_virtual_machine":    {"azure", model.Process, model.UnknownTechnology, model.Virtual},
+	"azurerm_virtual_machine":            {"azure", model.Process, model.UnknownTechnology, model.Virtual},
+	"azurerm_linux_function_app":         {"azure", model.Process, model.Function, model.Serverless},
+	"azurerm_windows_function_app":       {"azure", model.Process, model.Function, model.Serverless},
+	"azurerm_storage_account":            {"azure", model.Datastore, model.FileServer, model.Virtual},
+	"azurerm_mssql_server":               {"azure", model.Datastore, model.Database, model.Virtual},
+	"azurerm_postgresql_server":          {"azure", model.Datastore, model.Database, model.Virtual},
+	"azurerm_postgresql_flexible_server": {"azure", model.Datastore, model.Database, model.Virtual},
+	"azurerm_mysql_flexible_server":      {"azure", model.Datastore, model.Database, model.Virtual},
+	"azurerm_cosmosdb_account":           {"azure", model.Datastore, model.Database, model.Serverless},
+	"google_compute_instance":            {"gcp", model.Process, model.UnknownTechnology, model.Virtual},
+	"google_cloudfunctions_function":     {"gcp", model.Process, model.Function, model.Serverless},
+	"google_cloudfunctions2_function":    {"gcp", model.Process, model.Function, model.Serverless},
+	"google_cloud_run_service":           {"gcp", model.Process, model.UnknownTechnology, model.Container},
+	"google_cloud_run_v2_service":        {"gcp", model.Process, model.UnknownTechnology, model.Container},
+	"google_storage_bucket":              {"gcp", model.Datastore, model.FileServer, model.Virtual},
+	"google_sql_database_instance":       {"gcp", model.Datastore, model.Database, model.Virtual},
+}
+
+type boundaryMapping struct {
+	tag          string
+	boundaryType model.TrustBoundaryType
+}
+
+// the resource types of networks (containing the subnets) and subnets becoming trust boundaries
+var boundaryMappings = map[string]boundaryMapping{
+	"aws_vpc":                   {"aws:vpc", model.NetworkCloudProvider},
+	"aws_subnet":                {"aws:vpc", model.NetworkCloudSecurityGroup},
+	"azurerm_virtual_network":   {"azure", model.NetworkCloudProvider},
+	"azurerm_subnet":            {"azure", model.NetworkCloudSecurityGroup},
+	"google_compute_network":    {"gcp", model.NetworkCloudProvider},
+	"google_compute_subnetwork": {"gcp", model.NetworkCloudSecurityGroup},
+}
+
+// attributes signaling encryption at rest
+var encryptionAttributes = []string{"encrypted", "storage_encrypted", "kms_key_id", "kms_master_key_id"}
+
+// ToModelInput generates the skeleton of a model: compute, storage, and database resources become technical assets tagged
+// with their cloud provider (like aws:ec2), networks and their subnets become (nested) trust boundaries containing them.
+// The ingress rules of security groups (and of Azure network security groups and GCP firewalls) become communication links
+// between the technical assets within the groups, or from the internet for rules open to everyone. All generated elements carry the provenance tag. All values which can not be
+// derived from the resources (like data assets and CIA ratings) are set to defaults to be completed afterwards.
+func ToModelInput(show Show) model.ModelInput {
+	resources := show.resources()
+	importer := terraformImporter{
+		result:    skeleton.NewModel("Generated from Terraform"),
+		resolver:  newResolver(resources),
+		resources: make(map[string]resource),
+		ids:       make(map[string]string),
+		titles:    make(map[string]string),
+		linked:    make(map[string]bool),
+	}
+	for _, current := range resources {
+		importer.resources[current.Address] = current
+	}
+	for _, current := range resources {
+		if mapping, ok := boundaryMappings[current.Type]; ok {
+			importer.importTrustBoundary(current, mapping)
+		}
+	}
+	for _, current := range resources {
+		if mapping, ok := assetMappings[current.Type]; ok {
+			importer.importTechnicalAsset(current, mapping)
+		}
+	}
+	importer.placeIntoTrustBoundaries(resources)
+	importer.importSecurityGroups(resources)
+	sort.Strings(importer.tags)
+	importer.result.Tags_available = importer.tags
+	return importer.result
+}
+
+type terraformImporter struct {
+	result    model.ModelInput
+	resolver  resolver
+	resources map[string]resource // by address
+	ids       map[string]string   // addresses of the resources to the ids of their elements
+	titles    map[string]string   // ids of the elements to their titles
+	tags      []string            // all tags used
+	linked    map[string]bool     // source and target ids of the communication links already there
+}
+
+func (what *terraformImporter) tagsOf(tag string) []string {
+	for _, used := range []string{tag, ProvenanceTag} {
+		if !model.Contains(what.tags, used) {
+			what.tags = append(what.tags, used)
+		}
+	}
+	return []string{tag, ProvenanceTag}
+}
+
+func (what *terraformImporter) importTrustBoundary(current resource, mapping boundaryMapping) {
+	id := model.MakeID(current.Address)
+	title := skeleton.UniqueTitle(titleOf(current), id, func(title string) bool { _, exists := what.result.Trust_boundaries[title]; return exists })
+	what.result.Trust_boundaries[title] = model.InputTrustBoundary{
+		ID:          id,
+		Description: "Terraform resource " + current.Address,
+		Type:        mapping.boundaryType.String(),
+		Tags:        what.tagsOf(mapping.tag),
+	}
+	what.ids[current.Address], what.titles[id] = id, title
+}
+
+func (what *terraformImporter) importTechnicalAsset(current resource, mapping assetMapping) {
+	id := model.MakeID(current.Address)
+	technicalAsset := skeleton.NewTechnicalAsset(id, "Terraform resource "+current.Address, mapping.machine)
+	technicalAsset.Type, technicalAsset.Technology = mapping.assetType.String(), mapping.technology.String()
+	if mapping.assetType == model.Datastore {
+		technicalAsset.Size = model.Component.String()
+	}
+	technicalAsset.Tags = what.tagsOf(mapping.tag)
+	if current.Type == "aws_s3_bucket" { // encrypted by default
+		technicalAsset.Encryption = model.Transparent.String()
+	}
+	for _, attribute := range encryptionAttributes {
+		if value, ok := current.Values[attribute]; ok && value != false && value != "" && value != nil {
+			technicalAsset.Encryption = model.Transparent.String()
+		}
+	}
+	if internal, ok := current.Values["internal"].(bool); ok && !internal && (current.Type == "aws_lb" || current.Type == "aws_alb" || current.Type == "aws_elb") {
+		technicalAsset.Internet = true
+	}
+	title := skeleton.UniqueTitle(titleOf(current), id, func(title string) bool { _, exists := what.result.Technical_assets[title]; return exists })
+	what.result.Technical_assets[title] = technicalAsset
+	what.ids[current.Address], what.titles[id] = id, title
+}
+
+// placeIntoTrustBoundaries puts the technical assets into the subnet (or else the network) they reference (directly or via
+// other resources like network interfaces), and nests the subnets into their networks
+func (what *terraformImporter) placeIntoTrustBoundaries(resources []resource) {
+	for _, current := range resources {
+		_, isAsset := assetMappings[current.Type]
+		boundary, isBoundary := boundaryMappings[current.Type]
+		if !isAsset && !(isBoundary && boundary.boundaryType == model.NetworkCloudSecurityGroup) {
+			continue
+		}
+		var network, subnet string
+		for _, address := range what.referencedVia(current) {
+			if mapping, ok := boundaryMappings[what.resources[address].Type]; ok {
+				if mapping.boundaryType == model.NetworkCloudSecurityGroup && len(subnet) == 0 && !isBoundary {
+					subnet = address
+				} else if mapping.boundaryType == model.NetworkCloudProvider && len(network) == 0 {
+					network = address
+				}
+			}
+		}
+		container := subnet
+		if len(container) == 0 {
+			container = network
+		}
+		if len(container) == 0 {
+			continue
+		}
+		trustBoundary := what.result.Trust_boundaries[what.titles[what.ids[container]]]
+		if isBoundary {
+			trustBoundary.Trust_boundaries_nested = append(trustBoundary.Trust_boundaries_nested, what.ids[current.Address])
+		} else {
+			trustBoundary.Technical_assets_inside = append(trustBoundary.Technical_assets_inside, what.ids[current.Address])
+		}
+		what.result.Trust_boundaries[what.titles[what.ids[container]]] = trustBoundary
+	}
+}
+
+// referencedVia are the resources referenced directly and (one level) via other resources not becoming elements of the model
+func (what *terraformImporter) referencedVia(current resource) []string {
+	direct := what.referenced(current)
+	result := append([]string{}, direct...)
+	for _, address := range direct {
+		via := what.resources[address]
+		if _, isAsset := assetMappings[via.Type]; isAsset {
+			continue
+		}
+		if _, isBoundary := boundaryMappings[via.Type]; isBoundary {
+			continue
+		}
+		for _, indirect := range what.referenced(via) {
+			if !model.Contains(result, indirect) {
+				result = append(result, indirect)
+			}
+		}
+	}
+	return result
+}
+
+func (what *terraformImporter) referenced(current resource) []string {
+	var result []string
+	for _, address := range what.resolver.references(current.module, current.Values, current.expressions) {
+		if address != current.Address {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+// permission allows traffic into a group of technical assets from other groups or the internet, where the groups are
+// security groups (of AWS and Azure) or the instances of a network having a tag (of GCP) identified by their group keys
+type permission struct {
+	allowedBy     string // the kind and address of the resource allowing the traffic
+	securityGroup string
+	sources       []string
+	internet      bool
+	port          int
+}
+
+// importSecurityGroups turns the ingress rules of AWS security groups, Azure network security groups, and GCP firewalls into
+// communication links (those of other providers are not imported)
+func (what *terraformImporter) importSecurityGroups(resources []resource) {
+	members := make(map[string][]string) // group keys to the addresses of the assets within
+	var permissions []permission
+	for _, current := range resources {
+		if _, isAsset := assetMappings[current.Type]; isAsset {
+			for _, group := range what.groupsOf(current, resources) {
+				members[group] = append(members[group], current.Address)
+			}
+		}
+		permissions = append(permissions, what.awsPermissions(current)...)
+		permissions = append(permissions, what.azurePermissions(current)...)
+		permissions = append(permissions, what.gcpPermissions(current)...)
+	}
+
+	for _, allowed := range permissions {
+		description := "Allowed by " + allowed.allowedBy
+		if allowed.port > 0 {
+			description += " on port " + strconv.Itoa(allowed.port)
+		}
+		protocol := skeleton.ProtocolOfPort(allowed.port, "")
+		for _, target := range members[allowed.securityGroup] {
+			if allowed.internet {
+				what.addInternetLink(what.ids[target], description, protocol)
+			}
+			for _, sourceGroup := range allowed.sources {
+				for _, source := range members[sourceGroup] {
+					what.addCommunicationLink(what.ids[source], what.ids[target], description, protocol)
+				}
+			}
+		}
+	}
+}
+
+// groupsOf are the keys of the groups the technical asset of the resource belongs to: the AWS security groups it references,
+// the Azure network security groups associated with its network interfaces or subnets, and of GCP instances their tags
+// within their networks (as well as the networks themselves)
+func (what *terraformImporter) groupsOf(current resource, resources []resource) []string {
+	var result []string
+	for _, address := range what.referenced(current) {
+		if what.resources[address].Type == "aws_security_group" {
+			result = append(result, address)
+		}
+	}
+	referencedVia := what.referencedVia(current)
+	for _, association := range resources {
+		if association.Type != "azurerm_network_interface_security_group_association" && association.Type != "azurerm_subnet_network_security_group_association" {
+			continue
+		}
+		var securityGroup, associated string
+		for _, address := range what.referenced(association) {
+			if what.resources[address].Type == "azurerm_network_security_group" {
+				securityGroup = address
+			} else {
+				associated = address
+			}
+		}
+		if len(securityGroup) > 0 && model.Contains(referencedVia, associated) && !model.Contains(result, securityGroup) {
+			result = append(result, securityGroup)
+		}
+	}
+	if current.Type == "google_compute_instance" {
+		tags := stringsOf(current.Values["tags"])
+		for _, address := range referencedVia {
+			if what.resources[address].Type != "google_compute_network" {
+				continue
+			}
+			result = append(result, address)
+			for _, tag := range tags {
+				result = append(result, address+" tag:"+tag)
+			}
+		}
+	}
+	return result
+}
+
+func (what *terraformImporter) awsPermissions(current resource) []permission {
+	var result []permission
+	switch current.Type {
+	case "aws_security_group":
+		rules, _ := current.Values["ingress"].([]interface{})
+		ruleExpressions, _ := current.expressions["ingress"].([]interface{})
+		for i, rule := range rules {
+			values, _ := rule.(map[string]interface{})
+			var expressions interface{}
+			if len(ruleExpressions) == len(rules) {
+				expressions = ruleExpressions[i]
+			}
+			rulePermission := permission{allowedBy: "security group " + current.Address, securityGroup: current.Address, port: number(values["from_port"]),
+				internet: isOpen(values["cidr_blocks"]) || isOpen(values["ipv6_cidr_blocks"])}
+			rulePermission.sources = what.securityGroups(current.module, values["security_groups"], expressions)
+			if values["self"] == true {
+				rulePermission.sources = append(rulePermission.sources, current.Address)
+			}
+			result = append(result, rulePermission)
+		}
+	case "aws_security_group_rule", "aws_vpc_security_group_ingress_rule":
+		if current.Type == "aws_security_group_rule" && current.Values["type"] != "ingress" {
+			return nil
+		}
+		for _, securityGroup := range what.securityGroups(current.module, current.Values["security_group_id"], current.expressions["security_group_id"]) {
+			rulePermission := permission{allowedBy: "security group " + securityGroup, securityGroup: securityGroup, port: number(current.Values["from_port"]),
+				internet: isOpen(current.Values["cidr_blocks"]) || isOpen(current.Values["ipv6_cidr_blocks"]) ||
+					isOpen(current.Values["cidr_ipv4"]) || isOpen(current.Values["cidr_ipv6"])}
+			for _, attribute := range []string{"source_security_group_id", "referenced_security_group_id"} {
+				rulePermission.sources = append(rulePermission.sources, what.securityGroups(current.module, current.Values[attribute], current.expressions[attribute])...)
+			}
+			if current.Values["self"] == true {
+				rulePermission.sources = append(rulePermission.sources, securityGroup)
+			}
+			result = append(result, rulePermission)
+		}
+	}
+	return result
+}
+
+func (what *terraformImporter) securityGroups(module string, values, expressions interface{}) []string {
+	var result []string
+	for _, address := range what.resolver.references(module, values, expressions) {
+		if what.resources[address].Type == "aws_security_group" {
+			result = append(result, address)
+		}
+	}
+	return result
+}
+
+// azurePermissions are the allowed inbound rules of network security groups, where only those open to the internet
+// become communication links (as other address prefixes are not resolved to technical assets)
+func (what *terraformImporter) azurePermissions(current resource) []permission {
+	var rules []interface{}
+	var securityGroups []string
+	switch current.Type {
+	case "azurerm_network_security_group":
+		rules, _ = current.Values["security_rule"].([]interface{})
+		securityGroups = []string{current.Address}
+	case "azurerm_network_security_rule":
+		rules = []interface{}{current.Values}
+		for _, address := range what.resolver.references(current.module, current.Values["network_security_group_name"], current.expressions["network_security_group_name"]) {
+			if what.resources[address].Type == "azurerm_network_security_group" {
+				securityGroups = append(securityGroups, address)
+			}
+		}
+	}
+	var result []permission
+	for _, rule := range rules {
+		values, _ := rule.(map[string]interface{})
+		if !strings.EqualFold(stringOf(values["direction"]), "Inbound") || !strings.EqualFold(stringOf(values["access"]), "Allow") {
+			continue
+		}
+		internet := false
+		for _, prefix := range append(stringsOf(values["source_address_prefixes"]), stringOf(values["source_address_prefix"])) {
+			internet = internet || prefix == "*" || strings.EqualFold(prefix, "Internet") || isOpen(prefix)
+		}
+		if !internet {
+			continue
+		}
+		ports := append([]string{stringOf(values["destination_port_range"])}, stringsOf(values["destination_port_ranges"])...)
+		for _, securityGroup := range securityGroups {
+			result = append(result, permission{allowedBy: "network security group " + securityGroup, securityGroup: securityGroup,
+				internet: true, port: firstPort(ports)})
+		}
+	}
+	return result
+}
+
+// gcpPermissions are the enabled ingress rules of firewalls, allowing traffic into the instances of the network having one
+// of the target tags (or into all instances of the network without target tags) from those having one of the source tags
+func (what *terraformImporter) gcpPermissions(current resource) []permission {
+	if current.Type != "google_compute_firewall" || current.Values["disabled"] == true ||
+		(len(stringOf(current.Values["direction"])) > 0 && !strings.EqualFold(stringOf(current.Values["direction"]), "INGRESS")) {
+		return nil
+	}
+	allows, _ := current.Values["allow"].([]interface{})
+	if len(allows) == 0 {
+		return nil
+	}
+	var ports []string
+	for _, allow := range allows {
+		values, _ := allow.(map[string]interface{})
+		ports = append(ports, stringsOf(values["ports"])...)
+	}
+	var result []permission
+	for _, network := range what.resolver.references(current.module, current.Values["network"], current.expressions["network"]) {
+		if what.resources[network].Type != "google_compute_network" {
+			continue
+		}
+		var sources []string
+		for _, tag := range stringsOf(current.Values["source_tags"]) {
+			sources = append(sources, network+" tag:"+tag)
+		}
+		targets := []string{network}
+		if targetTags := stringsOf(current.Values["target_tags"]); len(targetTags) > 0 {
+			targets = nil
+			for _, tag := range targetTags {
+				targets = append(targets, network+" tag:"+tag)
+			}
+		}
+		for _, target := range targets {
+			result = append(result, permission{allowedBy: "firewall " + current.Address, securityGroup: target, sources: sources,
+				internet: isOpen(current.Values["source_ranges"]), port: firstPort(ports)})
+		}
+	}
+	return result
+}
+
+func (what *terraformImporter) addInternetLink(targetId, description string, protocol model.Protocol) {
+	internet := skeleton.Internet()
+	if _, exists := what.titles[internet.ID]; !exists {
+		internet.Tags = []string{ProvenanceTag}
+		what.titles[internet.ID] = skeleton.UniqueTitle("Internet", internet.ID, func(title string) bool { _, exists := what.result.Technical_assets[title]; return exists })
+		what.result.Technical_assets[what.titles[internet.ID]] = internet
+	}
+	technicalAsset := what.result.Technical_assets[what.titles[targetId]]
+	technicalAsset.Internet = true
+	what.result.Technical_assets[what.titles[targetId]] = technicalAsset
+	what.addCommunicationLink(internet.ID, targetId, description, protocol)
+}
+
+func (what *terraformImporter) addCommunicationLink(sourceId, targetId, description string, protocol model.Protocol) {
+	if sourceId == targetId || what.linked[sourceId+">"+targetId] {
+		return
+	}
+	what.linked[sourceId+">"+targetId] = true
+	source := what.result.Technical_assets[what.titles[sourceId]]
+	title := skeleton.UniqueTitle(what.titles[targetId]+" Traffic", targetId, func(title string) bool { _, exists := source.Communication_links[title]; return exists })
+	communicationLink := skeleton.NewCommunicationLink(targetId, description, protocol)
+	communicationLink.Tags = []string{ProvenanceTag}
+	source.Communication_links[title] = communicationLink
+	what.result.Technical_assets[what.titles[sourceId]] = source
+}
+
+// titleOf is the name given to the resource (by its tags or attributes), or else the name within the configuration
+func titleOf(current resource) string {
+	if tags, ok := current.Values["tags"].(map[string]interface{}); ok {
+		if name, ok := tags["Name"].(string); ok && len(name) > 0 {
+			return name
+		}
+	}
+	for _, attribute := range []string{"name", "bucket", "identifier", "function_name"} {
+		if name, ok := current.Values[attribute].(string); ok && len(name) > 0 {
+			return name
+		}
+	}
+	return current.Name
+}
+
+func isOpen(cidrs interface{}) bool {
+	switch cidrs := cidrs.(type) {
+	case string:
+		return cidrs == "0.0.0.0/0" || cidrs == "::/0"
+	case []interface{}:
+		for _, cidr := range cidrs {
+			if isOpen(cidr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstPort is the first port of port ranges like 80, 8000-8080, or * (0 for any)
+func firstPort(ranges []string) int {
+	for _, portRange := range ranges {
+		if port, err := strconv.Atoi(strings.Split(portRange, "-")[0]); err == nil {
+			return port
+		}
+	}
+	return 0
+}
+
+func stringOf(value interface{}) string {
+	result, _ := value.(string)
+	return result
+}
+
+func stringsOf(values interface{}) []string {
+	var result []string
+	list, _ := values.([]interface{})
+	for _, value := range list {
+		if value, ok := value.(string); ok && len(value) > 0 {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func number(value interface{}) int {
+	if value, ok := value.(float64); ok {
+		return int(value)
+	}
+	return 0
+}
@@ -0,0 +1,200 @@
+// Package terraform generates model skeletons out of the JSON output of terraform show (of states or plans).
+package terraform
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+)
+
+// Show is the (relevant part of the) output of terraform show -json
+type Show struct {
+	Values        *Values        `json:"values"`         // of states
+	PlannedValues *Values        `json:"planned_values"` // of plans
+	Configuration *Configuration `json:"configuration"`  // of plans, with the references between the resources
+}
+
+type Values struct {
+	RootModule Module `json:"root_module"`
+}
+
+type Module struct {
+	Address      string     `json:"address"`
+	Resources    []Resource `json:"resources"`
+	ChildModules []Module   `json:"child_modules"`
+}
+
+type Resource struct {
+	Address string                 `json:"address"`
+	Mode    string                 `json:"mode"`
+	Type    string                 `json:"type"`
+	Name    string                 `json:"name"`
+	Values  map[string]interface{} `json:"values"`
+}
+
+type Configuration struct {
+	RootModule ConfigurationModule `json:"root_module"`
+}
+
+type ConfigurationModule struct {
+	Resources   []ConfigurationResource `json:"resources"`
+	ModuleCalls map[string]struct {
+		Module ConfigurationModule `json:"module"`
+	} `json:"module_calls"`
+}
+
+type ConfigurationResource struct {
+	Address     string                 `json:"address"`
+	Expressions map[string]interface{} `json:"expressions"`
+}
+
+// Parse reads the output of terraform show -json
+func Parse(data []byte) (result Show, err error) {
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// resource is a managed resource along with its configuration (to resolve references of plans)
+type resource struct {
+	Resource
+	module      string // address of the module containing the resource, empty for the root module
+	expressions map[string]interface{}
+}
+
+var instanceIndex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// resources are the managed resources of all modules (of the planned values if there are any)
+func (what Show) resources() []resource {
+	values := what.Values
+	if what.PlannedValues != nil {
+		values = what.PlannedValues
+	}
+	if values == nil {
+		return nil
+	}
+	expressions := make(map[string]map[string]interface{})
+	if what.Configuration != nil {
+		collectExpressions(what.Configuration.RootModule, "", expressions)
+	}
+	var result []resource
+	var collect func(module Module)
+	collect = func(module Module) {
+		for _, managed := range module.Resources {
+			if managed.Mode == "managed" {
+				result = append(result, resource{Resource: managed, module: module.Address,
+					expressions: expressions[instanceIndex.ReplaceAllString(managed.Address, "")]})
+			}
+		}
+		for _, child := range module.ChildModules {
+			collect(child)
+		}
+	}
+	collect(values.RootModule)
+	sort.Slice(result, func(i, j int) bool { return result[i].Address < result[j].Address })
+	return result
+}
+
+func collectExpressions(module ConfigurationModule, prefix string, result map[string]map[string]interface{}) {
+	for _, configured := range module.Resources {
+		result[prefix+configured.Address] = configured.Expressions
+	}
+	for name, call := range module.ModuleCalls {
+		collectExpressions(call.Module, prefix+"module."+name+".", result)
+	}
+}
+
+// resolver finds the resources referenced by the values (matching their ids) or by the expressions of the configuration
+type resolver struct {
+	byValue   map[string][]string // ids, arns, self links, and (of networks) names to the addresses of the resources
+	byAddress map[string][]string // addresses without instance index to the addresses of the resources
+}
+
+// the resource types referenced by their names (like Azure network security rules referencing their network security group)
+var networkResourceTypes = []string{"aws_vpc", "aws_subnet", "azurerm_virtual_network", "azurerm_subnet", "azurerm_network_security_group",
+	"google_compute_network", "google_compute_subnetwork"}
+
+func newResolver(resources []resource) resolver {
+	result := resolver{byValue: make(map[string][]string), byAddress: make(map[string][]string)}
+	for _, current := range resources {
+		keys := []string{"id", "arn", "self_link"}
+		if model.Contains(networkResourceTypes, current.Type) {
+			keys = append(keys, "name")
+		}
+		for _, key := range keys {
+			if value, ok := current.Values[key].(string); ok && len(value) > 0 {
+				result.byValue[value] = append(result.byValue[value], current.Address)
+			}
+		}
+		base := instanceIndex.ReplaceAllString(current.Address, "")
+		result.byAddress[base] = append(result.byAddress[base], current.Address)
+	}
+	return result
+}
+
+// references are the addresses of all resources referenced within the values and expressions (of the same part of a resource)
+func (what resolver) references(module string, values, expressions interface{}) []string {
+	var result []string
+	add := func(addresses []string) {
+		for _, address := range addresses {
+			if !model.Contains(result, address) {
+				result = append(result, address)
+			}
+		}
+	}
+	var scanValues func(value interface{})
+	scanValues = func(value interface{}) {
+		switch value := value.(type) {
+		case string:
+			add(what.byValue[value])
+		case []interface{}:
+			for _, item := range value {
+				scanValues(item)
+			}
+		case map[string]interface{}:
+			for _, item := range value {
+				scanValues(item)
+			}
+		}
+	}
+	var scanExpressions func(expression interface{})
+	scanExpressions = func(expression interface{}) {
+		switch expression := expression.(type) {
+		case []interface{}:
+			for _, item := range expression {
+				scanExpressions(item)
+			}
+		case map[string]interface{}:
+			if references, ok := expression["references"].([]interface{}); ok {
+				for _, reference := range references {
+					if reference, ok := reference.(string); ok {
+						add(what.resolveReference(module, reference))
+					}
+				}
+			}
+			for _, item := range expression {
+				scanExpressions(item)
+			}
+		}
+	}
+	scanValues(values)
+	scanExpressions(expressions)
+	sort.Strings(result)
+	return result
+}
+
+// resolveReference finds the resources of a reference like aws_vpc.main.id (relative to the module)
+func (what resolver) resolveReference(module, reference string) []string {
+	if len(module) > 0 {
+		reference = module + "." + reference
+	}
+	parts := strings.Split(instanceIndex.ReplaceAllString(reference, ""), ".")
+	for i := len(parts); i > 0; i-- {
+		if addresses, ok := what.byAddress[strings.Join(parts[:i], ".")]; ok {
+			return addresses
+		}
+	}
+	return nil
+}
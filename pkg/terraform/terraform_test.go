@@ -0,0 +1,139 @@
+package terraform
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/threagile"
+	"gopkg.in/yaml.v3"
+)
+
+func importExample(t *testing.T, filename string) model.ParsedModel {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	show, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modelInput := ToModelInput(show)
+	modelYaml, err := yaml.Marshal(&modelInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.ThreagileVersion = "test"
+	result, err := threagile.Analyze(context.Background(), modelYaml, threagile.Options{
+		RAA:       func() string { return "" },
+		RiskRules: map[string]model.RiskRule{},
+	})
+	if err != nil {
+		t.Fatalf("generated model is not ok: %v\n%s", err, modelYaml)
+	}
+	return result.ParsedModel()
+}
+
+func linksOf(parsedModel model.ParsedModel) map[string]model.Protocol {
+	links := make(map[string]model.Protocol)
+	for _, technicalAsset := range parsedModel.TechnicalAssets {
+		for _, communicationLink := range technicalAsset.CommunicationLinks {
+			links[communicationLink.SourceId+">"+communicationLink.TargetId] = communicationLink.Protocol
+		}
+	}
+	return links
+}
+
+func checkLinks(t *testing.T, links, want map[string]model.Protocol) {
+	if len(links) != len(want) {
+		t.Errorf("communication links = %v, want %v", links, want)
+	}
+	for link, protocol := range want {
+		if got, ok := links[link]; !ok || got != protocol {
+			t.Errorf("protocol of communication link %v = %v, want %v", link, got, protocol)
+		}
+	}
+}
+
+func TestImportState(t *testing.T) {
+	parsedModel := importExample(t, "testdata/state.json")
+
+	for id, want := range map[string]string{"aws-instance-web-0": "aws:ec2", "aws-db-instance-orders": "aws:rds", "aws-s3-bucket-assets": "aws:s3",
+		"module-workers-aws-lambda-function-worker": "aws:lambda"} {
+		technicalAsset, ok := parsedModel.TechnicalAssets[id]
+		if !ok || !model.Contains(technicalAsset.Tags, want) || !model.Contains(technicalAsset.Tags, ProvenanceTag) {
+			t.Errorf("technical asset %v = %+v, want tags %v and %v", id, technicalAsset, want, ProvenanceTag)
+		}
+	}
+	if got := parsedModel.TechnicalAssets["aws-instance-web-0"].Title; got != "Web Server" {
+		t.Errorf("title of aws-instance-web-0 = %v, want Web Server", got)
+	}
+	database := parsedModel.TechnicalAssets["aws-db-instance-orders"]
+	if database.Technology != model.Database || database.Type != model.Datastore || database.Encryption != model.Transparent {
+		t.Errorf("aws-db-instance-orders = %+v", database)
+	}
+
+	if got := parsedModel.TrustBoundaries["aws-vpc-main"]; got.Type != model.NetworkCloudProvider || len(got.TrustBoundariesNested) != 2 {
+		t.Errorf("aws-vpc-main = %+v", got)
+	}
+	if got := parsedModel.TrustBoundaries["aws-subnet-public"]; got.Type != model.NetworkCloudSecurityGroup ||
+		len(got.TechnicalAssetsInside) != 1 || got.TechnicalAssetsInside[0] != "aws-instance-web-0" {
+		t.Errorf("aws-subnet-public = %+v", got)
+	}
+	if got := parsedModel.TrustBoundaries["aws-subnet-private"].TechnicalAssetsInside; len(got) != 2 {
+		t.Errorf("assets inside aws-subnet-private = %v, want the database (via its subnet group) and the lambda function", got)
+	}
+
+	checkLinks(t, linksOf(parsedModel), map[string]model.Protocol{
+		"internet>aws-instance-web-0":                                      model.HTTPS,
+		"internet>module-workers-aws-lambda-function-worker":               model.HTTPS,
+		"aws-instance-web-0>aws-db-instance-orders":                        model.SQL_access_protocol,
+		"module-workers-aws-lambda-function-worker>aws-db-instance-orders": model.SQL_access_protocol,
+	})
+	if !parsedModel.TechnicalAssets["aws-instance-web-0"].Internet || parsedModel.TechnicalAssets["aws-db-instance-orders"].Internet {
+		t.Errorf("internet exposure not derived from the security groups")
+	}
+}
+
+func TestImportPlan(t *testing.T) {
+	parsedModel := importExample(t, "testdata/plan.json")
+
+	if got := parsedModel.TrustBoundaries["aws-vpc-main"].TrustBoundariesNested; len(got) != 1 || got[0] != "aws-subnet-app" {
+		t.Errorf("trust boundaries nested in aws-vpc-main = %v", got)
+	}
+	if got := parsedModel.TrustBoundaries["aws-subnet-app"].TechnicalAssetsInside; len(got) != 2 {
+		t.Errorf("assets inside aws-subnet-app = %v, want the instance and the load balancer", got)
+	}
+	if !parsedModel.TechnicalAssets["aws-lb-front"].Internet {
+		t.Errorf("internet-facing load balancer not exposed to the internet")
+	}
+	checkLinks(t, linksOf(parsedModel), map[string]model.Protocol{
+		"internet>aws-lb-front":         model.HTTPS,
+		"aws-lb-front>aws-instance-app": model.HTTP,
+	})
+}
+
+func TestImportAzureNetworkSecurityGroups(t *testing.T) {
+	parsedModel := importExample(t, "testdata/azure.json")
+
+	if got := parsedModel.TrustBoundaries["azurerm-subnet-data"].TechnicalAssetsInside; len(got) != 1 || got[0] != "azurerm-postgresql-flexible-server-orders" {
+		t.Errorf("assets inside azurerm-subnet-data = %v, want the database", got)
+	}
+	checkLinks(t, linksOf(parsedModel), map[string]model.Protocol{
+		"internet>azurerm-linux-virtual-machine-web":         model.HTTPS,
+		"internet>azurerm-postgresql-flexible-server-orders": model.SQL_access_protocol,
+	})
+}
+
+func TestImportGCPFirewalls(t *testing.T) {
+	parsedModel := importExample(t, "testdata/gcp.json")
+
+	checkLinks(t, linksOf(parsedModel), map[string]model.Protocol{
+		"internet>google-compute-instance-web":                    model.HTTPS,
+		"google-compute-instance-web>google-compute-instance-app": model.HTTP,
+	})
+	if !parsedModel.TechnicalAssets["google-compute-instance-web"].Internet || parsedModel.TechnicalAssets["google-compute-instance-app"].Internet {
+		t.Errorf("internet exposure not derived from the firewalls")
+	}
+}
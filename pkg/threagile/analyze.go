@@ -0,0 +1,370 @@
+package threagile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"plugin"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/support"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// the model package (and therefore all RAA and risk rule plugins) works on package level state,
+// so analyses are serialized here and each one leaves an independent snapshot behind in its Result
+var analysisLock sync.Mutex
+
+// Analyze runs the full pipeline (parsing, RAA calculation, risk generation and risk tracking) on the given model.
+// It is safe to be called concurrently and the returned Result is not affected by later calls.
+func Analyze(ctx context.Context, modelYAML []byte, options Options) (result *Result, err error) {
+	analysisLock.Lock()
+	defer analysisLock.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, asError(r)
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(options.SchemaFile) > 0 {
+		if diagnostics := validateSchema(modelYAML, options.SchemaFile, options.Verbose); len(diagnostics) > 0 {
+			diagnostics.Locate(modelYAML)
+			return nil, diagnostics
+		}
+	}
+	modelInput := model.ModelInput{}
+	if err := yaml.Unmarshal(modelYAML, &modelInput); err != nil {
+		return nil, model.DiagnosticsOfYamlError(err)
+	}
+	// the result keeps the model as written (e.g. for model macros), the analysis works on the model merged with its includes
+	mergedInput, fragments := modelInput, []model.Fragment(nil)
+	if len(modelInput.Includes) > 0 {
+		mergedInput = model.ModelInput{}
+		support.CheckErr(yaml.Unmarshal(modelYAML, &mergedInput))
+		var includeDiagnostics model.Diagnostics
+		fragments, includeDiagnostics = model.ResolveIncludes(&mergedInput, options.ModelFilename, options.RestrictIncludesToModelDirectory)
+		if includeDiagnostics.HasErrors() {
+			includeDiagnostics.Locate(modelYAML)
+			return nil, includeDiagnostics
+		}
+	}
+
+	model.Init()
+	deferredRiskTrackingDueToWildcardMatching := make(map[string]model.RiskTracking)
+	parsedModel, diagnostics := model.ParseModelInput(mergedInput, deferredRiskTrackingDueToWildcardMatching)
+	if diagnostics.HasErrors() {
+		diagnostics.Locate(modelYAML, fragments...)
+		return nil, diagnostics
+	}
+	model.ParsedModelRoot = parsedModel
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	introTextRAA := applyRAA(options)
+	riskRules := options.RiskRules
+	if riskRules == nil {
+		riskRules, err = LoadRiskRules(options.RiskRulesPlugins, options.Verbose)
+		support.CheckErr(err)
+	}
+	if len(options.CustomRiskRulesFile) > 0 {
+		if riskRules, err = withCustomRiskRules(riskRules, options.CustomRiskRulesFile, options.Verbose); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	applyRiskGeneration(riskRules, options, &diagnostics)
+	applyWildcardRiskTrackingEvaluation(deferredRiskTrackingDueToWildcardMatching, options, &diagnostics)
+	checkRiskTracking(options, &diagnostics)
+	diagnostics.Locate(modelYAML, fragments...)
+	if diagnostics.HasErrors() {
+		return nil, diagnostics
+	}
+
+	hash := sha256.New()
+	hash.Write(modelYAML)
+	for _, fragment := range fragments {
+		hash.Write(fragment.Yaml)
+	}
+	return &Result{
+		state:        captureState(),
+		modelInput:   modelInput,
+		mergedInput:  mergedInput,
+		riskRules:    riskRules,
+		introTextRAA: introTextRAA,
+		statistics:   model.OverallRiskStatistics(),
+		modelHash:    hex.EncodeToString(hash.Sum(nil)),
+		diagnostics:  diagnostics,
+	}, nil
+}
+
+// LoadRiskRules loads all risk rule plugins (.so shared object) matching the glob pattern keyed by their category id
+func LoadRiskRules(pattern string, verbose bool) (riskRules map[string]model.RiskRule, err error) {
+	riskRules = make(map[string]model.RiskRule)
+	if len(pattern) == 0 {
+		return riskRules, nil
+	}
+	pluginFiles, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	for _, pluginFile := range pluginFiles {
+		if _, err := os.Stat(pluginFile); os.IsNotExist(err) {
+			return nil, errors.New("risk rule implementation file not found: " + pluginFile)
+		}
+		plug, err := plugin.Open(pluginFile)
+		if err != nil {
+			return nil, err
+		}
+		// look up a symbol (an exported function or variable): in this case variable RiskRule
+		symRiskRule, err := plug.Lookup("RiskRule")
+		if err != nil {
+			return nil, err
+		}
+		// register the risk rule plugin for later use: in this case interface type model.RiskRule
+		riskRule, ok := symRiskRule.(model.RiskRule)
+		if !ok {
+			return nil, errors.New("risk rule plugin has no 'RiskRule' variable: " + pluginFile)
+		}
+		// simply add to a map (just convenience) where key is the category id and value the rule's execution function
+		ruleID := riskRule.Category().Id
+		riskRules[ruleID] = riskRule
+		if verbose {
+			fmt.Println("Risk rule loaded:", ruleID)
+		}
+	}
+	return riskRules, nil
+}
+
+// withCustomRiskRules returns the risk rules along with the ones of the custom risk rules file (which must not replace any of them)
+func withCustomRiskRules(riskRules map[string]model.RiskRule, filename string, verbose bool) (map[string]model.RiskRule, error) {
+	customRiskRules, err := LoadCustomRiskRules(filename, verbose)
+	if err != nil {
+		return nil, err
+	}
+	result := copyRiskRuleMap(riskRules)
+	for id, riskRule := range customRiskRules {
+		if _, exists := result[id]; exists {
+			return nil, errors.New("custom risk rule id already used by another risk rule: " + id)
+		}
+		result[id] = riskRule
+	}
+	return result, nil
+}
+
+func validateSchema(modelYAML []byte, schemaFilename string, verbose bool) model.Diagnostics {
+	if verbose {
+		fmt.Println("Validating model against schema:", schemaFilename)
+	}
+	var validatorYaml interface{}
+	err := yamlv3.Unmarshal(modelYAML, &validatorYaml)
+	support.CheckErr(err)
+	validatorYaml, err = support.ToStringKeys(validatorYaml)
+	support.CheckErr(err)
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertContent = true
+	compiler.AssertFormat = true
+	schemaFile, err := ioutil.ReadFile(schemaFilename)
+	support.CheckErr(err)
+	support.CheckErr(compiler.AddResource("schema.json", strings.NewReader(string(schemaFile))))
+	schema, err := compiler.Compile("schema.json")
+	support.CheckErr(err)
+	diagnostics := make(model.Diagnostics, 0)
+	err = schema.Validate(validatorYaml)
+	if validationError, ok := err.(*jsonschema.ValidationError); ok {
+		addSchemaViolations(validationError, &diagnostics)
+	} else {
+		support.CheckErr(err)
+	}
+	return diagnostics
+}
+
+func addSchemaViolations(validationError *jsonschema.ValidationError, diagnostics *model.Diagnostics) {
+	if len(validationError.Causes) > 0 {
+		for _, cause := range validationError.Causes {
+			addSchemaViolations(cause, diagnostics)
+		}
+		return
+	}
+	keys := make([]string, 0)
+	for _, key := range strings.Split(validationError.InstanceLocation, "/") {
+		if len(key) > 0 {
+			if unescaped, err := url.PathUnescape(key); err == nil {
+				key = unescaped
+			}
+			keys = append(keys, strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~"))
+		}
+	}
+	diagnostics.AddError(validationError.Message, "see the schema rule "+validationError.KeywordLocation, keys...)
+}
+
+func applyRAA(options Options) string {
+	if options.RAA != nil {
+		return options.RAA()
+	}
+	if options.Verbose {
+		fmt.Println("Applying RAA calculation:", options.RAAPlugin)
+	}
+	// load plugin: open the ".so" file to load the symbols
+	plug, err := plugin.Open(options.RAAPlugin)
+	support.CheckErr(err)
+	// look up a symbol (an exported function or variable): in this case, function CalculateRAA
+	symCalculateRAA, err := plug.Lookup("CalculateRAA")
+	support.CheckErr(err)
+	raaCalcFunc, ok := symCalculateRAA.(func() string)
+	if !ok {
+		panic(errors.New("RAA plugin has no 'CalculateRAA() string' function"))
+	}
+	return raaCalcFunc()
+}
+
+func applyRiskGeneration(riskRules map[string]model.RiskRule, options Options, diagnostics *model.Diagnostics) {
+	if options.Verbose {
+		fmt.Println("Applying risk generation")
+	}
+	skippedRules := make(map[string]interface{})
+	for _, id := range options.SkipRiskRules {
+		if id = strings.TrimSpace(id); len(id) > 0 {
+			skippedRules[id] = true
+		}
+	}
+
+	for id, riskRule := range riskRules {
+		if _, ok := skippedRules[riskRule.Category().Id]; ok {
+			if options.Verbose {
+				fmt.Println("Skipping risk rule:", id)
+			}
+			delete(skippedRules, riskRule.Category().Id)
+		} else {
+			model.AddToListOfSupportedTags(riskRule.SupportedTags())
+			risks := riskRule.GenerateRisks()
+			if len(risks) > 0 {
+				model.GeneratedRisksByCategory[riskRule.Category()] = risks
+			}
+		}
+	}
+
+	if len(skippedRules) > 0 {
+		keys := make([]string, 0)
+		for k := range skippedRules {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		diagnostics.AddWarning("unknown risk rules to skip: "+strings.Join(keys, ", "), "skip only the ids of risk rules in use")
+	}
+
+	// save also in map keyed by synthetic risk-id
+	for _, category := range model.SortedRiskCategories() {
+		risks := model.SortedRisksOfCategory(category)
+		for _, risk := range risks {
+			model.GeneratedRisksBySyntheticId[strings.ToLower(risk.SyntheticId)] = risk
+		}
+	}
+}
+
+func applyWildcardRiskTrackingEvaluation(deferredRiskTrackingDueToWildcardMatching map[string]model.RiskTracking, options Options, diagnostics *model.Diagnostics) {
+	if options.Verbose {
+		fmt.Println("Executing risk tracking evaluation")
+	}
+	for syntheticRiskIdPattern, riskTracking := range deferredRiskTrackingDueToWildcardMatching {
+		foundSome := false
+		var matchingRiskIdExpression = regexp.MustCompile(strings.ReplaceAll(regexp.QuoteMeta(syntheticRiskIdPattern), `\*`, `[^@]+`))
+		for syntheticRiskId := range model.GeneratedRisksBySyntheticId {
+			if matchingRiskIdExpression.Match([]byte(syntheticRiskId)) && hasNotYetAnyDirectNonWildcardRiskTrackings(syntheticRiskId) {
+				foundSome = true
+				tracking := riskTracking
+				tracking.SyntheticRiskId = strings.TrimSpace(syntheticRiskId)
+				model.ParsedModelRoot.RiskTracking[syntheticRiskId] = tracking
+			}
+		}
+		if !foundSome {
+			model.ParsedModelRoot.OrphanedRiskTracking[syntheticRiskIdPattern] = riskTracking
+			if options.IgnoreOrphanedRiskTracking {
+				diagnostics.AddWarning("wildcard risk tracking does not match any risk id: "+syntheticRiskIdPattern, "", "risk_tracking", syntheticRiskIdPattern)
+			} else {
+				diagnostics.AddError("wildcard risk tracking does not match any risk id: "+syntheticRiskIdPattern, orphanedRiskTrackingFix, "risk_tracking", syntheticRiskIdPattern)
+			}
+		}
+	}
+}
+
+func hasNotYetAnyDirectNonWildcardRiskTrackings(syntheticRiskId string) bool {
+	if _, ok := model.ParsedModelRoot.RiskTracking[syntheticRiskId]; ok {
+		return false
+	}
+	return true
+}
+
+const orphanedRiskTrackingFix = "correct the risk id (as listed in the risks Excel or JSON), remove the entry or use the option -ignore-orphaned-risk-tracking " +
+	"- the model macro \"seed-risk-tracking\" helps in initially seeding the risk tracking based on already identified and not yet handled risks, " +
+	"the model macro \"cleanup-risk-tracking\" in re-mapping or removing orphaned entries"
+
+func checkRiskTracking(options Options, diagnostics *model.Diagnostics) {
+	if options.Verbose {
+		fmt.Println("Checking risk tracking")
+	}
+	for syntheticRiskId, tracking := range model.ParsedModelRoot.RiskTracking {
+		if _, ok := model.GeneratedRisksBySyntheticId[tracking.SyntheticRiskId]; !ok {
+			model.ParsedModelRoot.OrphanedRiskTracking[syntheticRiskId] = tracking
+			if options.IgnoreOrphanedRiskTracking {
+				diagnostics.AddWarning("risk tracking references unknown risk (risk id not found): "+tracking.SyntheticRiskId, "", "risk_tracking", syntheticRiskId)
+			} else {
+				diagnostics.AddError("risk tracking references unknown risk (risk id not found): "+tracking.SyntheticRiskId, orphanedRiskTrackingFix, "risk_tracking", syntheticRiskId)
+			}
+		}
+	}
+
+	// expired risk tracking (like a time-limited acceptance) falls back to the configured status
+	now := time.Now()
+	for syntheticRiskId, tracking := range model.ParsedModelRoot.RiskTracking {
+		if tracking.IsExpired(now) {
+			diagnostics.AddWarning("risk tracking expired on "+tracking.Expires.Format("2006-01-02")+" (falling back from "+tracking.Status.String()+" to "+options.ExpiredRiskTrackingStatus.String()+"): "+syntheticRiskId,
+				"review the risk and set a new expiry", "risk_tracking", syntheticRiskId, "expires")
+			tracking.Expired, tracking.ExpiredStatus = true, tracking.Status
+			tracking.Status = options.ExpiredRiskTrackingStatus
+			model.ParsedModelRoot.RiskTracking[syntheticRiskId] = tracking
+		} else if tracking.IsReviewOverdue(now) {
+			diagnostics.AddWarning("review of risk tracking overdue since "+tracking.ReviewBy.Format("2006-01-02")+": "+syntheticRiskId,
+				"review the risk and set a new review date", "risk_tracking", syntheticRiskId, "review_by")
+		}
+	}
+
+	// save also the risk-category-id and risk-status (as well as the deadlines of the risk tracking) directly in the risk for better JSON marshalling
+	for category := range model.GeneratedRisksByCategory {
+		for i := range model.GeneratedRisksByCategory[category] {
+			risk := &model.GeneratedRisksByCategory[category][i]
+			risk.CategoryId = category.Id
+			risk.RiskStatus = risk.GetRiskTrackingStatusDefaultingUnchecked()
+			tracking := risk.GetRiskTracking()
+			risk.RiskTrackingExpired = tracking.Expired
+			risk.RiskReviewOverdue = !tracking.Expired && tracking.IsReviewOverdue(now)
+			model.GeneratedRisksBySyntheticId[strings.ToLower(risk.SyntheticId)] = *risk
+		}
+	}
+}
+
+func asError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
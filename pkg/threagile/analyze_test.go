@@ -0,0 +1,587 @@
+package threagile
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/otyg/threagile/model"
+)
+
+type riskPerTechnicalAsset struct{}
+
+func (r riskPerTechnicalAsset) Category() model.RiskCategory {
+	return model.RiskCategory{Id: "test-rule", Title: "Test Rule"}
+}
+
+func (r riskPerTechnicalAsset) SupportedTags() []string {
+	return []string{"test"}
+}
+
+func (r riskPerTechnicalAsset) GenerateRisks() []model.Risk {
+	risks := make([]model.Risk, 0)
+	for _, id := range model.SortedTechnicalAssetIDs() {
+		risks = append(risks, model.Risk{
+			Category:                     r.Category(),
+			Severity:                     model.MediumSeverity,
+			MostRelevantTechnicalAssetId: id,
+			DataBreachTechnicalAssetIDs:  []string{id},
+			SyntheticId:                  r.Category().Id + "@" + id,
+		})
+	}
+	return risks
+}
+
+func analyzeFile(t *testing.T, filename string) *Result {
+	modelYaml, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Analyze(context.Background(), modelYaml, Options{
+		RAA:                        func() string { return "" },
+		RiskRules:                  map[string]model.RiskRule{"test-rule": riskPerTechnicalAsset{}},
+		IgnoreOrphanedRiskTracking: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestAnalyzeResultsAreIndependent(t *testing.T) {
+	model.ThreagileVersion = "test"
+	example := analyzeFile(t, "../../demo/example/threagile.yaml")
+	stub := analyzeFile(t, "../../demo/stub/threagile.yaml")
+
+	if got, want := len(example.RisksByCategory()[riskPerTechnicalAsset{}.Category()]), len(example.ParsedModel().TechnicalAssets); got != want {
+		t.Errorf("len(example.RisksByCategory()) = %v, want %v", got, want)
+	}
+	if got, want := len(stub.RisksByCategory()[riskPerTechnicalAsset{}.Category()]), len(stub.ParsedModel().TechnicalAssets); got != want {
+		t.Errorf("len(stub.RisksByCategory()) = %v, want %v", got, want)
+	}
+	if _, ok := example.RiskBySyntheticId("test-rule@sql-database"); !ok {
+		t.Errorf("example.RiskBySyntheticId() did not find risk of example model")
+	}
+	if err := example.WithModel(func() {
+		if got, want := model.TotalRiskCount(), len(example.Risks()); got != want {
+			t.Errorf("model.TotalRiskCount() = %v, want %v", got, want)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResultHandsOutDeepCopies(t *testing.T) {
+	model.ThreagileVersion = "test"
+	example := analyzeFile(t, "../../demo/example/threagile.yaml")
+	asJSON := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	parsedModelBefore, risksBefore := asJSON(example.ParsedModel()), asJSON(example.Risks())
+
+	change := func(values []string) {
+		for i := range values {
+			values[i] = "changed"
+		}
+	}
+	parsedModel := example.ParsedModel()
+	for _, technicalAsset := range parsedModel.TechnicalAssets {
+		change(technicalAsset.Tags)
+		change(technicalAsset.DataAssetsProcessed)
+		for _, communicationLink := range technicalAsset.CommunicationLinks {
+			change(communicationLink.DataAssetsSent)
+		}
+	}
+	for _, trustBoundary := range parsedModel.TrustBoundaries {
+		change(trustBoundary.TechnicalAssetsInside)
+	}
+	for _, risk := range example.Risks() {
+		change(risk.DataBreachTechnicalAssetIDs)
+	}
+
+	if asJSON(example.ParsedModel()) != parsedModelBefore {
+		t.Errorf("changing the parsed model handed out changed the result")
+	}
+	if asJSON(example.Risks()) != risksBefore {
+		t.Errorf("changing the risks handed out changed the result")
+	}
+	if err := example.WithModel(func() {
+		if asJSON(model.ParsedModelRoot) != parsedModelBefore {
+			t.Errorf("changing the parsed model handed out changed the model installed")
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnalyzeReturnsModelErrors(t *testing.T) {
+	model.ThreagileVersion = "test"
+	_, err := Analyze(context.Background(), []byte("data_assets:\n  Foo:\n    id: foo\n    usage: unknown-usage\n"), Options{
+		RAA:       func() string { return "" },
+		RiskRules: map[string]model.RiskRule{},
+	})
+	diagnostics, ok := err.(model.Diagnostics)
+	if !ok || len(diagnostics) != 2 {
+		t.Fatalf("Analyze() error = %v, want two diagnostics", err)
+	}
+	// sorted by position, a missing value is located at its parent
+	if got, want := diagnostics[0].Path, "data_assets.Foo.quantity"; got != want || diagnostics[0].Line != 2 {
+		t.Errorf("diagnostics[0] = %v (line %v), want %v (line 2)", got, diagnostics[0].Line, want)
+	}
+	if got, want := diagnostics[1].Path, "data_assets.Foo.usage"; got != want || diagnostics[1].Line != 4 {
+		t.Errorf("diagnostics[1] = %v (line %v), want %v (line 4)", got, diagnostics[1].Line, want)
+	}
+}
+
+func TestAnalyzeMergesIncludes(t *testing.T) {
+	model.ThreagileVersion = "test"
+	directory, err := ioutil.TempDir("", "threagile-includes-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	fragment := "data_assets:\n  Bar:\n    id: foo\n"
+	if err := ioutil.WriteFile(filepath.Join(directory, "fragment.yaml"), []byte(fragment), 0600); err != nil {
+		t.Fatal(err)
+	}
+	modelYaml := "includes:\n  - '*.yaml'\ndata_assets:\n  Foo:\n    id: foo\n"
+	_, err = Analyze(context.Background(), []byte(modelYaml), Options{
+		RAA:           func() string { return "" },
+		RiskRules:     map[string]model.RiskRule{},
+		ModelFilename: filepath.Join(directory, "threagile.yaml"),
+	})
+	diagnostics, ok := err.(model.Diagnostics)
+	if !ok || len(diagnostics) != 1 {
+		t.Fatalf("Analyze() error = %v, want one diagnostic", err)
+	}
+	if got, want := diagnostics[0].File, filepath.Join(directory, "fragment.yaml"); got != want || diagnostics[0].Line != 3 {
+		t.Errorf("diagnostics[0] located in %v (line %v), want %v (line 3)", got, diagnostics[0].Line, want)
+	}
+}
+
+func TestAnalyzeRestrictsIncludesToModelDirectory(t *testing.T) {
+	model.ThreagileVersion = "test"
+	directory, err := ioutil.TempDir("", "threagile-includes-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	for _, folder := range []string{"model", "outside"} {
+		if err := os.Mkdir(filepath.Join(directory, folder), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fragment := []byte("data_assets:\n  Bar:\n    id: bar\n    usage: business\n    quantity: few\n    confidentiality: internal\n    integrity: operational\n    availability: operational\n")
+	for _, filename := range []string{"model/inside.yaml", "outside/secret.yaml"} {
+		if err := ioutil.WriteFile(filepath.Join(directory, filename), fragment, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(directory, "outside", "secret.yaml"), filepath.Join(directory, "model", "link.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	// the model directory itself reached via a symbolic link
+	if err := os.Symlink(filepath.Join(directory, "model"), filepath.Join(directory, "linked-model")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		include, modelFolder string
+		wantOk               bool
+	}{
+		{"inside.yaml", "model", true},
+		{"inside.yaml", "linked-model", true},
+		{"link.yaml", "model", false},
+		{"link.yaml", "linked-model", false},
+		{"../outside/secret.yaml", "model", false},
+	} {
+		_, err := Analyze(context.Background(), []byte("includes:\n  - "+test.include+"\n"), Options{
+			RAA:                              func() string { return "" },
+			RiskRules:                        map[string]model.RiskRule{},
+			ModelFilename:                    filepath.Join(directory, test.modelFolder, "threagile.yaml"),
+			RestrictIncludesToModelDirectory: true,
+		})
+		diagnostics, _ := err.(model.Diagnostics)
+		outside := false
+		for _, diagnostic := range diagnostics {
+			outside = outside || strings.Contains(diagnostic.Message, "outside of the model directory")
+		}
+		if outside == test.wantOk {
+			t.Errorf("include of %v into %v = %v, want ok %v", test.include, test.modelFolder, err, test.wantOk)
+		}
+	}
+}
+
+func TestLoadCustomRiskRules(t *testing.T) {
+	model.ThreagileVersion = "test"
+	riskRules, err := LoadCustomRiskRules("../../demo/custom-risk-rules/custom-risk-rules.yaml", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(riskRules), 2; got != want {
+		t.Fatalf("len(LoadCustomRiskRules()) = %v, want %v", got, want)
+	}
+	modelYaml, err := ioutil.ReadFile("../../demo/example/threagile.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Analyze(context.Background(), modelYaml, Options{
+		RAA:                        func() string { return "" },
+		RiskRules:                  riskRules,
+		IgnoreOrphanedRiskTracking: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	asset := `
+    usage: business
+    size: component
+    machine: container
+    encryption: none
+    owner: team
+    confidentiality: internal
+    integrity: operational
+    availability: operational`
+	link := `
+        protocol: https
+        authentication: token
+        authorization: technical-user
+        usage: business
+        data_assets_sent: [ secret ]`
+	modelYaml = []byte(`title: Custom Risk Rules
+date: "2024-01-02"
+business_criticality: important
+tags_available: [ datastore-filtering-exception ]
+data_assets:
+  Secret:
+    id: secret
+    usage: business
+    quantity: few
+    confidentiality: strictly-confidential
+    integrity: operational
+    availability: operational
+technical_assets:
+  Gateway:
+    id: gateway
+    type: process
+    technology: gateway
+    internet: true` + asset + `
+    data_assets_processed: [ secret ]
+    communication_links:
+      Database Access:
+        target: database` + link + `
+      Archive Access:
+        target: archive
+        tags: [ datastore-filtering-exception ]` + link + `
+      Queue Access:
+        target: queue` + link + `
+  Database:
+    id: database
+    type: datastore
+    technology: database
+    internet: false` + asset + `
+    data_assets_stored: [ secret ]
+  Archive:
+    id: archive
+    type: datastore
+    technology: file-server
+    internet: false` + asset + `
+    data_assets_stored: [ secret ]
+  Queue:
+    id: queue
+    type: process
+    technology: message-queue
+    internet: false` + asset + `
+    data_assets_processed: [ secret ]
+`)
+	result, err := Analyze(context.Background(), modelYaml, Options{
+		RAA:       func() string { return "" },
+		RiskRules: riskRules,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		category, syntheticId, technicalAsset, communicationLink string
+		severity                                                 model.RiskSeverity
+	}{
+		{"unencrypted-message-queue", "unencrypted-message-queue@queue", "queue", "", model.MediumSeverity},
+		{"unfiltered-datastore-access-from-internet", "unfiltered-datastore-access-from-internet@gateway>database-access@gateway", "gateway", "gateway>database-access", model.ElevatedSeverity},
+	} {
+		var risks []model.Risk
+		for category, risksOfCategory := range result.RisksByCategory() {
+			if category.Id == want.category {
+				risks = risksOfCategory
+			}
+		}
+		if len(risks) != 1 {
+			t.Errorf("risks of %v = %+v, want only %v", want.category, risks, want.syntheticId)
+			continue
+		}
+		if risk := risks[0]; risk.SyntheticId != want.syntheticId || risk.MostRelevantTechnicalAssetId != want.technicalAsset ||
+			risk.MostRelevantCommunicationLinkId != want.communicationLink || risk.Severity != want.severity {
+			t.Errorf("risk of %v = %v at %v (%v) with severity %v, want %v at %v (%v) with severity %v", want.category,
+				risk.SyntheticId, risk.MostRelevantTechnicalAssetId, risk.MostRelevantCommunicationLinkId, risk.Severity,
+				want.syntheticId, want.technicalAsset, want.communicationLink, want.severity)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	model.ThreagileVersion = "test"
+	example := analyzeFile(t, "../../demo/example/threagile.yaml")
+	stub := analyzeFile(t, "../../demo/stub/threagile.yaml")
+
+	if diff := Diff(example, example); !diff.IsEmpty() {
+		t.Errorf("Diff() of same model = %v, want no differences", diff.Text())
+	}
+	diff := Diff(stub, example)
+	if len(diff.TechnicalAssets.Added) == 0 || len(diff.NewRisks) == 0 {
+		t.Fatalf("Diff() = %v, want added technical assets and new risks", diff.Text())
+	}
+	if _, found := example.RiskBySyntheticId(diff.NewRisks[0].SyntheticId); !found {
+		t.Errorf("Diff().NewRisks[0] = %v, want a risk of the new model", diff.NewRisks[0].SyntheticId)
+	}
+}
+
+func TestRiskTrackingExpiry(t *testing.T) {
+	model.ThreagileVersion = "test"
+	modelYaml := `title: Expiry
+date: "2024-01-02"
+business_criticality: important
+technical_assets:
+  First:
+    id: first
+    type: process
+    usage: business
+    size: component
+    technology: web-server
+    internet: false
+    machine: container
+    encryption: none
+    owner: team
+    confidentiality: internal
+    integrity: operational
+    availability: operational
+  Second:
+    id: second
+    type: process
+    usage: business
+    size: component
+    technology: web-server
+    internet: false
+    machine: container
+    encryption: none
+    owner: team
+    confidentiality: internal
+    integrity: operational
+    availability: operational
+risk_tracking:
+  test-rule@first:
+    status: accepted
+    date: "2020-01-02"
+    expires: "2021-01-02"
+  test-rule@second:
+    status: accepted
+    date: "2020-01-02"
+    review_by: "2021-01-02"
+    expires: "2999-01-02"
+`
+	result, err := Analyze(context.Background(), []byte(modelYaml), Options{
+		RAA:                       func() string { return "" },
+		RiskRules:                 map[string]model.RiskRule{"test-rule": riskPerTechnicalAsset{}},
+		ExpiredRiskTrackingStatus: model.InDiscussion,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _ := result.RiskBySyntheticId("test-rule@first")
+	if expired.RiskStatus != model.InDiscussion || !expired.RiskTrackingExpired || expired.RiskReviewOverdue {
+		t.Errorf("expired risk tracking = %v (expired %v, review overdue %v), want fallback to %v", expired.RiskStatus, expired.RiskTrackingExpired, expired.RiskReviewOverdue, model.InDiscussion)
+	}
+	if tracking := result.RiskTracking()["test-rule@first"]; tracking.ExpiredStatus != model.Accepted {
+		t.Errorf("expired status = %v, want %v", tracking.ExpiredStatus, model.Accepted)
+	}
+	overdue, _ := result.RiskBySyntheticId("test-rule@second")
+	if overdue.RiskStatus != model.Accepted || overdue.RiskTrackingExpired || !overdue.RiskReviewOverdue {
+		t.Errorf("risk tracking with overdue review = %v (expired %v, review overdue %v), want still accepted", overdue.RiskStatus, overdue.RiskTrackingExpired, overdue.RiskReviewOverdue)
+	}
+	if got := len(result.Diagnostics()); got != 2 {
+		t.Errorf("diagnostics = %v, want warnings about the expiry and the overdue review", result.Diagnostics())
+	}
+}
+
+func TestRiskTrackingOfPreviousIds(t *testing.T) {
+	model.ThreagileVersion = "test"
+	asset := `
+    type: process
+    usage: business
+    size: component
+    technology: web-server
+    internet: false
+    machine: container
+    encryption: none
+    owner: team
+    confidentiality: internal
+    integrity: operational
+    availability: operational`
+	modelYaml := `title: Renamed
+date: "2024-01-02"
+business_criticality: important
+technical_assets:
+  First:
+    id: first
+    previous_ids: [ old-first ]` + asset + `
+  Second:
+    id: second
+    previous_ids: [ old-second ]` + asset + `
+data_assets:
+  Data:
+    id: data
+    previous_ids: [ old-data ]
+    usage: business
+    quantity: few
+    confidentiality: internal
+    integrity: operational
+    availability: operational
+shared_runtimes:
+  Runtime:
+    id: runtime
+    previous_ids: [ old-runtime ]
+    technical_assets_running: [ first ]
+risk_tracking:
+  test-rule@old-first:
+    status: accepted
+  test-rule@old-second:
+    status: mitigated
+  test-rule@second:
+    status: accepted
+`
+	options := Options{
+		RAA:       func() string { return "" },
+		RiskRules: map[string]model.RiskRule{"test-rule": riskPerTechnicalAsset{}},
+	}
+	result, err := Analyze(context.Background(), []byte(modelYaml), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"test-rule@first", "test-rule@second"} {
+		if risk, _ := result.RiskBySyntheticId(id); risk.RiskStatus != model.Accepted {
+			t.Errorf("status of %v = %v, want %v", id, risk.RiskStatus, model.Accepted)
+		}
+	}
+	if got := len(result.Diagnostics()); got != 2 {
+		t.Errorf("diagnostics = %v, want warnings about the migrated and the ignored risk tracking", result.Diagnostics())
+	}
+	currentIdsByPreviousId := model.CurrentIdsByPreviousId(result.ParsedModel())
+	for previousId, currentId := range map[string]string{"old-first": "first", "old-data": "data", "old-runtime": "runtime"} {
+		if got := currentIdsByPreviousId[previousId]; got != currentId {
+			t.Errorf("current id of %v = %v, want %v", previousId, got, currentId)
+		}
+	}
+	if got := model.CurrentIdsByPreviousIdOfInput(result.MergedModelInput()); !reflect.DeepEqual(got, currentIdsByPreviousId) {
+		t.Errorf("current ids by previous id of the input = %v, want %v", got, currentIdsByPreviousId)
+	}
+
+	if _, err := Analyze(context.Background(), []byte(strings.Replace(modelYaml, "old-second", "first", 1)), options); err == nil {
+		t.Errorf("previous id still in use accepted")
+	}
+}
+
+func TestRiskTrackingOfCollidingPreviousIds(t *testing.T) {
+	model.ThreagileVersion = "test"
+	modelYaml := `title: Renamed
+date: "2024-01-02"
+business_criticality: important
+technical_assets:
+  First:
+    id: first
+    previous_ids: [ old-second, old-first ]
+    type: process
+    usage: business
+    size: component
+    technology: web-server
+    internet: false
+    machine: container
+    encryption: none
+    owner: team
+    confidentiality: internal
+    integrity: operational
+    availability: operational
+risk_tracking:
+  test-rule@old-second:
+    status: mitigated
+  test-rule@old-first:
+    status: accepted
+`
+	options := Options{
+		RAA:       func() string { return "" },
+		RiskRules: map[string]model.RiskRule{"test-rule": riskPerTechnicalAsset{}},
+	}
+	for i := 0; i < 10; i++ { // the order of iterating the risk tracking varies
+		result, err := Analyze(context.Background(), []byte(modelYaml), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if risk, _ := result.RiskBySyntheticId("test-rule@first"); risk.RiskStatus != model.Accepted {
+			t.Fatalf("status of test-rule@first = %v, want %v of the first entry in sorted order", risk.RiskStatus, model.Accepted)
+		}
+		if got := len(result.Diagnostics()); got != 2 {
+			t.Fatalf("diagnostics = %v, want warnings about the migrated and the ignored risk tracking", result.Diagnostics())
+		}
+		migrated := model.MigrateRiskTracking(result.MergedModelInput().Risk_tracking, model.CurrentIdsByPreviousId(result.ParsedModel()))
+		if got := migrated["test-rule@first"].Status; len(migrated) != 1 || got != "accepted" {
+			t.Fatalf("migrated risk tracking = %v, want the entry of test-rule@old-first only", migrated)
+		}
+	}
+}
+
+func TestOrphanedRiskTracking(t *testing.T) {
+	model.ThreagileVersion = "test"
+	modelYaml := `title: Orphaned
+date: "2024-01-02"
+business_criticality: important
+technical_assets:
+  First:
+    id: first
+    type: process
+    usage: business
+    size: component
+    technology: web-server
+    internet: false
+    machine: container
+    encryption: none
+    owner: team
+    confidentiality: internal
+    integrity: operational
+    availability: operational
+risk_tracking:
+  test-rule@first:
+    status: accepted
+  test-rule@renamed:
+    status: accepted
+  other-rule@*:
+    status: mitigated
+`
+	result, err := Analyze(context.Background(), []byte(modelYaml), Options{
+		RAA:                        func() string { return "" },
+		RiskRules:                  map[string]model.RiskRule{"test-rule": riskPerTechnicalAsset{}},
+		IgnoreOrphanedRiskTracking: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphaned := result.ParsedModel().OrphanedRiskTracking
+	if _, ok := orphaned["test-rule@renamed"]; !ok || len(orphaned) != 2 || orphaned["other-rule@*"].Status != model.Mitigated {
+		t.Errorf("orphaned risk tracking = %v, want the exact and the wildcard entry not matching any risk", orphaned)
+	}
+}
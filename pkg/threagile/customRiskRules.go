@@ -0,0 +1,415 @@
+package threagile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/antonmedv/expr"
+	"github.com/antonmedv/expr/vm"
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/model/confidentiality"
+	"github.com/otyg/threagile/model/criticality"
+	"github.com/otyg/threagile/support"
+	"gopkg.in/yaml.v2"
+)
+
+type InputCustomRiskRules struct {
+	Risk_rules map[string]InputCustomRiskRule `json:"risk_rules"`
+}
+
+// InputCustomRiskRule is a risk category (keyed by its title, like the individual risk categories of a model) along with
+// expressions deciding which model elements are at risk and how likely and severe the risk is
+type InputCustomRiskRule struct {
+	model.InputIndividualRiskCategory `yaml:",inline"`
+	Supported_tags                    []string `json:"supported_tags"`
+	Match                             string   `json:"match"`
+	Condition                         string   `json:"condition"`
+	Risk_title                        string   `json:"risk_title"`
+	Exploitation_likelihood           string   `json:"exploitation_likelihood"`
+	Exploitation_impact               string   `json:"exploitation_impact"`
+	Data_breach_probability           string   `json:"data_breach_probability"`
+}
+
+const (
+	matchTechnicalAsset    = "technical_asset"
+	matchCommunicationLink = "communication_link"
+	matchDataAsset         = "data_asset"
+	matchTrustBoundary     = "trust_boundary"
+	matchSharedRuntime     = "shared_runtime"
+)
+
+var matchValues = []string{matchTechnicalAsset, matchCommunicationLink, matchDataAsset, matchTrustBoundary, matchSharedRuntime}
+
+type customRiskRule struct {
+	category              model.RiskCategory
+	supportedTags         []string
+	match                 string
+	condition             *vm.Program
+	riskTitle             *vm.Program
+	likelihood            *vm.Program
+	impact                *vm.Program
+	dataBreachProbability *vm.Program
+}
+
+// LoadCustomRiskRules loads risk rules defined declaratively in a YAML file (instead of as Go plugins) keyed by their category id.
+// Problems found in the file are returned as model.Diagnostics.
+func LoadCustomRiskRules(filename string, verbose bool) (map[string]model.RiskRule, error) {
+	rulesYaml, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	rulesInput := InputCustomRiskRules{}
+	if err := yaml.UnmarshalStrict(rulesYaml, &rulesInput); err != nil {
+		return nil, withFile(model.DiagnosticsOfYamlError(err), filename)
+	}
+	riskRules := make(map[string]model.RiskRule)
+	diagnostics := make(model.Diagnostics, 0)
+	for title, ruleInput := range rulesInput.Risk_rules {
+		riskRule := parseCustomRiskRule(title, ruleInput, &diagnostics)
+		if _, exists := riskRules[riskRule.category.Id]; exists {
+			diagnostics.AddError("duplicate id used: "+riskRule.category.Id, "use a unique id", "risk_rules", title, "id")
+		}
+		riskRules[riskRule.category.Id] = riskRule
+		if verbose {
+			fmt.Println("Custom risk rule loaded:", riskRule.category.Id)
+		}
+	}
+	if len(diagnostics) > 0 {
+		diagnostics.Locate(rulesYaml)
+		return nil, withFile(diagnostics, filename)
+	}
+	return riskRules, nil
+}
+
+func withFile(diagnostics model.Diagnostics, filename string) model.Diagnostics {
+	for i := range diagnostics {
+		diagnostics[i].File = filename
+	}
+	return diagnostics
+}
+
+func parseCustomRiskRule(title string, ruleInput InputCustomRiskRule, diagnostics *model.Diagnostics) customRiskRule {
+	keys := []string{"risk_rules", title}
+	if !support.IsValidIdSyntax(ruleInput.ID) {
+		diagnostics.AddError("invalid id syntax used: "+ruleInput.ID, "only use letters, numbers, and hyphen", append(keys, "id")...)
+	}
+	function, err := model.ParseRiskFunction(ruleInput.Function)
+	if err != nil {
+		diagnostics.AddError(err.Error(), "", append(keys, "function")...)
+	}
+	stride, err := model.ParseStride(ruleInput.STRIDE)
+	if err != nil {
+		diagnostics.AddError(err.Error(), "", append(keys, "stride")...)
+	}
+	description := ruleInput.Description
+	if len(description) == 0 {
+		description = title
+	}
+	riskRule := customRiskRule{
+		category: model.RiskCategory{
+			Id:                         ruleInput.ID,
+			Title:                      title,
+			Description:                description,
+			Impact:                     ruleInput.Impact,
+			CRE:                        ruleInput.CRE,
+			ASVS:                       ruleInput.ASVS,
+			CheatSheet:                 ruleInput.Cheat_sheet,
+			TestingGuide:               ruleInput.Testing_guide,
+			Action:                     ruleInput.Action,
+			Mitigation:                 ruleInput.Mitigation,
+			Check:                      ruleInput.Check,
+			DetectionLogic:             ruleInput.Detection_logic,
+			RiskAssessment:             ruleInput.Risk_assessment,
+			FalsePositives:             ruleInput.False_positives,
+			Function:                   function,
+			STRIDE:                     stride,
+			ModelFailurePossibleReason: ruleInput.Model_failure_possible_reason,
+			CWE:                        ruleInput.CWE,
+		},
+		supportedTags: ruleInput.Supported_tags,
+		match:         ruleInput.Match,
+	}
+	if !model.Contains(matchValues, ruleInput.Match) {
+		diagnostics.AddError("unknown element to match: "+ruleInput.Match, "use one of: "+strings.Join(matchValues, ", "), append(keys, "match")...)
+		return riskRule
+	}
+
+	env := elementEnv(ruleInput.Match, "")
+	compile := func(key, input, defaultInput string, options ...expr.Option) *vm.Program {
+		if len(strings.TrimSpace(input)) == 0 {
+			if len(defaultInput) == 0 {
+				diagnostics.AddError("missing "+key+" expression", "", append(keys, key)...)
+				return nil
+			}
+			input = defaultInput
+		}
+		program, err := expr.Compile(input, append(options, expr.Env(env))...)
+		if err != nil {
+			diagnostics.AddError("invalid "+key+" expression: "+err.Error(), "", append(keys, key)...)
+		}
+		return program
+	}
+	riskRule.condition = compile("condition", ruleInput.Condition, "", expr.AsBool())
+	riskRule.riskTitle = compile("risk_title", ruleInput.Risk_title, strconv.Quote("<b>"+title+"</b> at <b>")+` + title + "</b>"`)
+	riskRule.likelihood = compile("exploitation_likelihood", ruleInput.Exploitation_likelihood, "")
+	riskRule.impact = compile("exploitation_impact", ruleInput.Exploitation_impact, "")
+	riskRule.dataBreachProbability = compile("data_breach_probability", ruleInput.Data_breach_probability, `"`+model.Improbable.String()+`"`)
+	return riskRule
+}
+
+func (r customRiskRule) Category() model.RiskCategory {
+	return r.category
+}
+
+func (r customRiskRule) SupportedTags() []string {
+	return r.supportedTags
+}
+
+func (r customRiskRule) GenerateRisks() []model.Risk {
+	risks := make([]model.Risk, 0)
+	switch r.match {
+	case matchTechnicalAsset:
+		for _, id := range model.SortedTechnicalAssetIDs() {
+			technicalAsset := model.ParsedModelRoot.TechnicalAssets[id]
+			if risk, matches := r.evaluate(elementEnv(r.match, id)); matches {
+				risk.MostRelevantTechnicalAssetId = technicalAsset.Id
+				risk.DataBreachTechnicalAssetIDs = []string{technicalAsset.Id}
+				risk.SyntheticId = risk.Category.Id + "@" + technicalAsset.Id
+				risks = append(risks, risk)
+			}
+		}
+	case matchCommunicationLink:
+		for _, id := range model.SortedTechnicalAssetIDs() {
+			for _, communicationLink := range model.ParsedModelRoot.TechnicalAssets[id].CommunicationLinksSorted() {
+				if risk, matches := r.evaluate(elementEnv(r.match, communicationLink.Id)); matches {
+					risk.MostRelevantTechnicalAssetId = communicationLink.SourceId
+					risk.MostRelevantCommunicationLinkId = communicationLink.Id
+					risk.DataBreachTechnicalAssetIDs = []string{communicationLink.TargetId}
+					risk.SyntheticId = risk.Category.Id + "@" + communicationLink.Id + "@" + communicationLink.SourceId
+					risks = append(risks, risk)
+				}
+			}
+		}
+	case matchDataAsset:
+		for _, id := range model.SortedKeysOfDataAssets() {
+			dataAsset := model.ParsedModelRoot.DataAssets[id]
+			if risk, matches := r.evaluate(elementEnv(r.match, id)); matches {
+				risk.MostRelevantDataAssetId = dataAsset.Id
+				for _, technicalAsset := range dataAsset.StoredByTechnicalAssetsSorted() {
+					risk.DataBreachTechnicalAssetIDs = append(risk.DataBreachTechnicalAssetIDs, technicalAsset.Id)
+				}
+				risk.SyntheticId = risk.Category.Id + "@" + dataAsset.Id
+				risks = append(risks, risk)
+			}
+		}
+	case matchTrustBoundary:
+		for _, id := range model.SortedKeysOfTrustBoundaries() {
+			trustBoundary := model.ParsedModelRoot.TrustBoundaries[id]
+			if risk, matches := r.evaluate(elementEnv(r.match, id)); matches {
+				risk.MostRelevantTrustBoundaryId = trustBoundary.Id
+				risk.SyntheticId = risk.Category.Id + "@" + trustBoundary.Id
+				risks = append(risks, risk)
+			}
+		}
+	case matchSharedRuntime:
+		for _, id := range model.SortedKeysOfSharedRuntime() {
+			sharedRuntime := model.ParsedModelRoot.SharedRuntimes[id]
+			if risk, matches := r.evaluate(elementEnv(r.match, id)); matches {
+				risk.MostRelevantSharedRuntimeId = sharedRuntime.Id
+				risk.SyntheticId = risk.Category.Id + "@" + sharedRuntime.Id
+				risks = append(risks, risk)
+			}
+		}
+	}
+	return risks
+}
+
+// evaluate the expressions of the rule for one element: evaluation errors panic (like all other model errors during risk generation)
+func (r customRiskRule) evaluate(env map[string]interface{}) (model.Risk, bool) {
+	matches, err := expr.Run(r.condition, env)
+	r.checkEvaluation(err, "condition", env)
+	if matches != true {
+		return model.Risk{}, false
+	}
+	title := r.run(r.riskTitle, "risk_title", env)
+	likelihood, err := model.ParseRiskExploitationLikelihood(r.run(r.likelihood, "exploitation_likelihood", env))
+	r.checkEvaluation(err, "exploitation_likelihood", env)
+	impact, err := model.ParseRiskExploitationImpact(r.run(r.impact, "exploitation_impact", env))
+	r.checkEvaluation(err, "exploitation_impact", env)
+	dataBreachProbability, err := model.ParseDataBreachProbability(r.run(r.dataBreachProbability, "data_breach_probability", env))
+	r.checkEvaluation(err, "data_breach_probability", env)
+	return model.Risk{
+		Category:               r.category,
+		Severity:               model.CalculateSeverity(likelihood, impact),
+		ExploitationLikelihood: likelihood,
+		ExploitationImpact:     impact,
+		Title:                  title,
+		DataBreachProbability:  dataBreachProbability,
+	}, true
+}
+
+func (r customRiskRule) run(program *vm.Program, key string, env map[string]interface{}) string {
+	value, err := expr.Run(program, env)
+	r.checkEvaluation(err, key, env)
+	text, ok := value.(string)
+	if !ok {
+		r.checkEvaluation(errors.New("expression does not evaluate to a string"), key, env)
+	}
+	return text
+}
+
+func (r customRiskRule) checkEvaluation(err error, key string, env map[string]interface{}) {
+	if err != nil {
+		panic(fmt.Errorf("custom risk rule %v: unable to evaluate %v for %v %v: %v", r.category.Id, key, r.match, env["id"], err))
+	}
+}
+
+// elementEnv exposes a model element to the expressions using the attribute names and values of the model file.
+// Ordered values (like confidentiality or criticality ratings) can be compared via at_least(value, minimum).
+func elementEnv(match, id string) map[string]interface{} {
+	var env map[string]interface{}
+	switch match {
+	case matchTechnicalAsset:
+		env = technicalAssetEnv(model.ParsedModelRoot.TechnicalAssets[id])
+	case matchCommunicationLink:
+		communicationLink := model.CommunicationLinks[id]
+		env = communicationLinkEnv(communicationLink)
+		env["source"] = technicalAssetEnv(model.ParsedModelRoot.TechnicalAssets[communicationLink.SourceId])
+		env["target"] = technicalAssetEnv(model.ParsedModelRoot.TechnicalAssets[communicationLink.TargetId])
+	case matchDataAsset:
+		env = dataAssetEnv(model.ParsedModelRoot.DataAssets[id])
+	case matchTrustBoundary:
+		env = trustBoundaryEnv(model.ParsedModelRoot.TrustBoundaries[id])
+	case matchSharedRuntime:
+		env = sharedRuntimeEnv(model.ParsedModelRoot.SharedRuntimes[id])
+	}
+	env["at_least"] = atLeast
+	return env
+}
+
+func technicalAssetEnv(technicalAsset model.TechnicalAsset) map[string]interface{} {
+	dataFormatsAccepted := make([]string, 0, len(technicalAsset.DataFormatsAccepted))
+	for _, dataFormat := range technicalAsset.DataFormatsAccepted {
+		dataFormatsAccepted = append(dataFormatsAccepted, dataFormat.String())
+	}
+	return map[string]interface{}{
+		"id":                      technicalAsset.Id,
+		"title":                   technicalAsset.Title,
+		"description":             technicalAsset.Description,
+		"type":                    technicalAsset.Type.String(),
+		"usage":                   technicalAsset.Usage.String(),
+		"used_as_client_by_human": technicalAsset.UsedAsClientByHuman,
+		"out_of_scope":            technicalAsset.OutOfScope,
+		"size":                    technicalAsset.Size.String(),
+		"technology":              technicalAsset.Technology.String(),
+		"tags":                    append([]string{}, technicalAsset.Tags...),
+		"internet":                technicalAsset.Internet,
+		"machine":                 technicalAsset.Machine.String(),
+		"encryption":              technicalAsset.Encryption.String(),
+		"owner":                   technicalAsset.Owner,
+		"confidentiality":         technicalAsset.Confidentiality.String(),
+		"integrity":               technicalAsset.Integrity.String(),
+		"availability":            technicalAsset.Availability.String(),
+		"highest_confidentiality": technicalAsset.HighestConfidentiality().String(),
+		"highest_integrity":       technicalAsset.HighestIntegrity().String(),
+		"highest_availability":    technicalAsset.HighestAvailability().String(),
+		"multi_tenant":            technicalAsset.MultiTenant,
+		"redundant":               technicalAsset.Redundant,
+		"custom_developed_parts":  technicalAsset.CustomDevelopedParts,
+		"data_assets_processed":   append([]string{}, technicalAsset.DataAssetsProcessed...),
+		"data_assets_stored":      append([]string{}, technicalAsset.DataAssetsStored...),
+		"data_formats_accepted":   dataFormatsAccepted,
+		"trust_boundary":          technicalAsset.GetTrustBoundaryId(),
+		"raa":                     technicalAsset.RAA,
+	}
+}
+
+func communicationLinkEnv(communicationLink model.CommunicationLink) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                      communicationLink.Id,
+		"title":                   communicationLink.Title,
+		"description":             communicationLink.Description,
+		"protocol":                communicationLink.Protocol.String(),
+		"authentication":          communicationLink.Authentication.String(),
+		"authorization":           communicationLink.Authorization.String(),
+		"tags":                    append([]string{}, communicationLink.Tags...),
+		"vpn":                     communicationLink.VPN,
+		"ip_filtered":             communicationLink.IpFiltered,
+		"readonly":                communicationLink.Readonly,
+		"usage":                   communicationLink.Usage.String(),
+		"data_assets_sent":        append([]string{}, communicationLink.DataAssetsSent...),
+		"data_assets_received":    append([]string{}, communicationLink.DataAssetsReceived...),
+		"highest_confidentiality": communicationLink.HighestConfidentiality().String(),
+		"highest_integrity":       communicationLink.HighestIntegrity().String(),
+		"highest_availability":    communicationLink.HighestAvailability().String(),
+		"across_trust_boundary":   communicationLink.IsAcrossTrustBoundary(),
+	}
+}
+
+func dataAssetEnv(dataAsset model.DataAsset) map[string]interface{} {
+	processedBy, storedBy := make([]string, 0), make([]string, 0)
+	for _, technicalAsset := range dataAsset.ProcessedByTechnicalAssetsSorted() {
+		processedBy = append(processedBy, technicalAsset.Id)
+	}
+	for _, technicalAsset := range dataAsset.StoredByTechnicalAssetsSorted() {
+		storedBy = append(storedBy, technicalAsset.Id)
+	}
+	return map[string]interface{}{
+		"id":              dataAsset.Id,
+		"title":           dataAsset.Title,
+		"description":     dataAsset.Description,
+		"usage":           dataAsset.Usage.String(),
+		"tags":            append([]string{}, dataAsset.Tags...),
+		"origin":          dataAsset.Origin,
+		"owner":           dataAsset.Owner,
+		"quantity":        dataAsset.Quantity.String(),
+		"confidentiality": dataAsset.Confidentiality.String(),
+		"integrity":       dataAsset.Integrity.String(),
+		"availability":    dataAsset.Availability.String(),
+		"processed_by":    processedBy,
+		"stored_by":       storedBy,
+	}
+}
+
+func trustBoundaryEnv(trustBoundary model.TrustBoundary) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                      trustBoundary.Id,
+		"title":                   trustBoundary.Title,
+		"description":             trustBoundary.Description,
+		"type":                    trustBoundary.Type.String(),
+		"tags":                    append([]string{}, trustBoundary.Tags...),
+		"technical_assets_inside": append([]string{}, trustBoundary.TechnicalAssetsInside...),
+		"trust_boundaries_nested": append([]string{}, trustBoundary.TrustBoundariesNested...),
+		"highest_confidentiality": trustBoundary.HighestConfidentiality().String(),
+		"highest_integrity":       trustBoundary.HighestIntegrity().String(),
+		"highest_availability":    trustBoundary.HighestAvailability().String(),
+	}
+}
+
+func sharedRuntimeEnv(sharedRuntime model.SharedRuntime) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                       sharedRuntime.Id,
+		"title":                    sharedRuntime.Title,
+		"description":              sharedRuntime.Description,
+		"tags":                     append([]string{}, sharedRuntime.Tags...),
+		"technical_assets_running": append([]string{}, sharedRuntime.TechnicalAssetsRunning...),
+	}
+}
+
+// atLeast compares confidentiality or criticality ratings by their rank
+func atLeast(value, minimum string) bool {
+	return ratingRank(value) >= ratingRank(minimum)
+}
+
+func ratingRank(value string) int {
+	if rating, err := confidentiality.ParseConfidentiality(value); err == nil {
+		return int(rating)
+	}
+	if rating, err := criticality.ParseCriticality(value); err == nil {
+		return int(rating)
+	}
+	panic(errors.New("unknown confidentiality or criticality rating: " + value))
+}
@@ -0,0 +1,309 @@
+package threagile
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/otyg/threagile/model"
+)
+
+// ModelDiff are the differences between two analyzed versions of a model
+type ModelDiff struct {
+	TechnicalAssets    ElementsDiff `json:"technical_assets"`
+	CommunicationLinks ElementsDiff `json:"communication_links"`
+	DataAssets         ElementsDiff `json:"data_assets"`
+	TrustBoundaries    ElementsDiff `json:"trust_boundaries"`
+	SharedRuntimes     ElementsDiff `json:"shared_runtimes"`
+	NewRisks           []RiskDiff   `json:"new_risks"`
+	ResolvedRisks      []RiskDiff   `json:"resolved_risks"`
+	SeverityChanges    []RiskDiff   `json:"severity_changes"`
+}
+
+type ElementsDiff struct {
+	Added   []ElementDiff `json:"added"`
+	Removed []ElementDiff `json:"removed"`
+	Changed []ElementDiff `json:"changed"`
+}
+
+type ElementDiff struct {
+	Id            string   `json:"id"`
+	Title         string   `json:"title"`
+	ChangedFields []string `json:"changed_fields,omitempty"` // named like in the model file
+}
+
+type RiskDiff struct {
+	SyntheticId string `json:"synthetic_id"`
+	Category    string `json:"category"`
+	Title       string `json:"title"`
+	Severity    string `json:"severity"`
+	OldSeverity string `json:"old_severity,omitempty"` // only set for severity changes
+	RiskStatus  string `json:"risk_status"`
+}
+
+// Diff compares the elements (by id) and the generated risks (by synthetic id) of two analyzed versions of a model
+func Diff(oldResult, newResult *Result) ModelDiff {
+	oldModel, newModel := oldResult.ParsedModel(), newResult.ParsedModel()
+	result := ModelDiff{
+		TechnicalAssets:    diffElements(technicalAssetElements(oldModel), technicalAssetElements(newModel)),
+		CommunicationLinks: diffElements(communicationLinkElements(oldModel), communicationLinkElements(newModel)),
+		DataAssets:         diffElements(dataAssetElements(oldModel), dataAssetElements(newModel)),
+		TrustBoundaries:    diffElements(trustBoundaryElements(oldModel), trustBoundaryElements(newModel)),
+		SharedRuntimes:     diffElements(sharedRuntimeElements(oldModel), sharedRuntimeElements(newModel)),
+		NewRisks:           make([]RiskDiff, 0),
+		ResolvedRisks:      make([]RiskDiff, 0),
+		SeverityChanges:    make([]RiskDiff, 0),
+	}
+	for _, risk := range newResult.Risks() {
+		oldRisk, existed := oldResult.RiskBySyntheticId(risk.SyntheticId)
+		if !existed {
+			result.NewRisks = append(result.NewRisks, riskDiffOf(risk, newModel))
+		} else if oldRisk.Severity != risk.Severity {
+			riskDiff := riskDiffOf(risk, newModel)
+			riskDiff.OldSeverity = oldRisk.Severity.String()
+			result.SeverityChanges = append(result.SeverityChanges, riskDiff)
+		}
+	}
+	for _, risk := range oldResult.Risks() {
+		if _, exists := newResult.RiskBySyntheticId(risk.SyntheticId); !exists {
+			result.ResolvedRisks = append(result.ResolvedRisks, riskDiffOf(risk, oldModel))
+		}
+	}
+	return result
+}
+
+func (what ModelDiff) IsEmpty() bool {
+	for _, elements := range what.elementsDiffs() {
+		if len(elements.diff.Added) > 0 || len(elements.diff.Removed) > 0 || len(elements.diff.Changed) > 0 {
+			return false
+		}
+	}
+	return len(what.NewRisks) == 0 && len(what.ResolvedRisks) == 0 && len(what.SeverityChanges) == 0
+}
+
+type namedElementsDiff struct {
+	name string
+	diff ElementsDiff
+}
+
+func (what ModelDiff) elementsDiffs() []namedElementsDiff {
+	return []namedElementsDiff{
+		{"Technical Assets", what.TechnicalAssets},
+		{"Communication Links", what.CommunicationLinks},
+		{"Data Assets", what.DataAssets},
+		{"Trust Boundaries", what.TrustBoundaries},
+		{"Shared Runtimes", what.SharedRuntimes},
+	}
+}
+
+// Text renders the differences as plain text
+func (what ModelDiff) Text() string {
+	var result strings.Builder
+	if what.IsEmpty() {
+		result.WriteString("No differences\n")
+		return result.String()
+	}
+	for _, elements := range what.elementsDiffs() {
+		if len(elements.diff.Added)+len(elements.diff.Removed)+len(elements.diff.Changed) == 0 {
+			continue
+		}
+		result.WriteString(elements.name + ":\n")
+		for _, element := range elements.diff.Added {
+			result.WriteString("  + " + element.Title + " (" + element.Id + ")\n")
+		}
+		for _, element := range elements.diff.Removed {
+			result.WriteString("  - " + element.Title + " (" + element.Id + ")\n")
+		}
+		for _, element := range elements.diff.Changed {
+			result.WriteString("  ~ " + element.Title + " (" + element.Id + "): " + strings.Join(element.ChangedFields, ", ") + "\n")
+		}
+	}
+	writeRisks := func(title, prefix string, risks []RiskDiff) {
+		if len(risks) == 0 {
+			return
+		}
+		result.WriteString(fmt.Sprintf("%v (%d):\n", title, len(risks)))
+		for _, risk := range risks {
+			severity := risk.Severity
+			if len(risk.OldSeverity) > 0 {
+				severity = risk.OldSeverity + " -> " + risk.Severity
+			}
+			result.WriteString("  " + prefix + " [" + severity + "] " + withoutMarkup(risk.Title) + " (" + risk.SyntheticId + ")\n")
+		}
+	}
+	writeRisks("New Risks", "+", what.NewRisks)
+	writeRisks("Resolved Risks", "-", what.ResolvedRisks)
+	writeRisks("Severity Changes", "~", what.SeverityChanges)
+	return result.String()
+}
+
+// Markdown renders the differences as Markdown (e.g. to be posted as comment of a pull request)
+func (what ModelDiff) Markdown() string {
+	var result strings.Builder
+	result.WriteString("## Threat Model Changes\n\n")
+	if what.IsEmpty() {
+		result.WriteString("No differences\n")
+		return result.String()
+	}
+	result.WriteString("| Risks | Count |\n|---|---|\n")
+	result.WriteString(fmt.Sprintf("| New | %d |\n| Resolved | %d |\n| Severity changed | %d |\n\n", len(what.NewRisks), len(what.ResolvedRisks), len(what.SeverityChanges)))
+	writeRisks := func(title string, risks []RiskDiff) {
+		if len(risks) == 0 {
+			return
+		}
+		result.WriteString("### " + title + "\n\n| Severity | Risk | ID | Status |\n|---|---|---|---|\n")
+		for _, risk := range risks {
+			severity := risk.Severity
+			if len(risk.OldSeverity) > 0 {
+				severity = risk.OldSeverity + " &rarr; " + risk.Severity
+			}
+			result.WriteString("| " + severity + " | " + markdownCell(strings.ReplaceAll(strings.ReplaceAll(risk.Title, "<b>", "**"), "</b>", "**")) +
+				" | `" + risk.SyntheticId + "` | " + risk.RiskStatus + " |\n")
+		}
+		result.WriteString("\n")
+	}
+	writeRisks("New Risks", what.NewRisks)
+	writeRisks("Resolved Risks", what.ResolvedRisks)
+	writeRisks("Severity Changes", what.SeverityChanges)
+	for _, elements := range what.elementsDiffs() {
+		if len(elements.diff.Added)+len(elements.diff.Removed)+len(elements.diff.Changed) == 0 {
+			continue
+		}
+		result.WriteString("### " + elements.name + "\n\n| Change | Title | ID | Changed Fields |\n|---|---|---|---|\n")
+		for _, element := range elements.diff.Added {
+			result.WriteString("| added | " + markdownCell(element.Title) + " | `" + element.Id + "` | |\n")
+		}
+		for _, element := range elements.diff.Removed {
+			result.WriteString("| removed | " + markdownCell(element.Title) + " | `" + element.Id + "` | |\n")
+		}
+		for _, element := range elements.diff.Changed {
+			result.WriteString("| changed | " + markdownCell(element.Title) + " | `" + element.Id + "` | " + strings.Join(element.ChangedFields, ", ") + " |\n")
+		}
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+func riskDiffOf(risk model.Risk, parsedModel model.ParsedModel) RiskDiff {
+	status := model.Unchecked
+	if riskTracking, tracked := parsedModel.RiskTracking[risk.SyntheticId]; tracked {
+		status = riskTracking.Status
+	}
+	return RiskDiff{
+		SyntheticId: risk.SyntheticId,
+		Category:    risk.Category.Id,
+		Title:       risk.Title,
+		Severity:    risk.Severity.String(),
+		RiskStatus:  status.String(),
+	}
+}
+
+func withoutMarkup(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "<b>", ""), "</b>", "")
+}
+
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+// the elements to compare keyed by id: fields not describing the element itself (but calculated or compared separately) are excluded
+func technicalAssetElements(parsedModel model.ParsedModel) map[string]interface{} {
+	result := make(map[string]interface{})
+	for id, technicalAsset := range parsedModel.TechnicalAssets {
+		technicalAsset.CommunicationLinks = nil
+		technicalAsset.RAA = 0
+		result[id] = technicalAsset
+	}
+	return result
+}
+
+func communicationLinkElements(parsedModel model.ParsedModel) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, technicalAsset := range parsedModel.TechnicalAssets {
+		for _, communicationLink := range technicalAsset.CommunicationLinks {
+			result[communicationLink.Id] = communicationLink
+		}
+	}
+	return result
+}
+
+func dataAssetElements(parsedModel model.ParsedModel) map[string]interface{} {
+	result := make(map[string]interface{})
+	for id, dataAsset := range parsedModel.DataAssets {
+		result[id] = dataAsset
+	}
+	return result
+}
+
+func trustBoundaryElements(parsedModel model.ParsedModel) map[string]interface{} {
+	result := make(map[string]interface{})
+	for id, trustBoundary := range parsedModel.TrustBoundaries {
+		result[id] = trustBoundary
+	}
+	return result
+}
+
+func sharedRuntimeElements(parsedModel model.ParsedModel) map[string]interface{} {
+	result := make(map[string]interface{})
+	for id, sharedRuntime := range parsedModel.SharedRuntimes {
+		result[id] = sharedRuntime
+	}
+	return result
+}
+
+func diffElements(oldElements, newElements map[string]interface{}) ElementsDiff {
+	result := ElementsDiff{Added: make([]ElementDiff, 0), Removed: make([]ElementDiff, 0), Changed: make([]ElementDiff, 0)}
+	for _, id := range sortedKeys(newElements) {
+		oldElement, existed := oldElements[id]
+		if !existed {
+			result.Added = append(result.Added, ElementDiff{Id: id, Title: titleOf(newElements[id])})
+		} else if changedFields := changedFieldsOf(oldElement, newElements[id]); len(changedFields) > 0 {
+			result.Changed = append(result.Changed, ElementDiff{Id: id, Title: titleOf(newElements[id]), ChangedFields: changedFields})
+		}
+	}
+	for _, id := range sortedKeys(oldElements) {
+		if _, exists := newElements[id]; !exists {
+			result.Removed = append(result.Removed, ElementDiff{Id: id, Title: titleOf(oldElements[id])})
+		}
+	}
+	return result
+}
+
+func sortedKeys(elements map[string]interface{}) []string {
+	keys := make([]string, 0, len(elements))
+	for key := range elements {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func titleOf(element interface{}) string {
+	return reflect.ValueOf(element).FieldByName("Title").String()
+}
+
+func changedFieldsOf(oldElement, newElement interface{}) []string {
+	result := make([]string, 0)
+	oldValue, newValue := reflect.ValueOf(oldElement), reflect.ValueOf(newElement)
+	for i := 0; i < oldValue.NumField(); i++ {
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			result = append(result, snakeCase(oldValue.Type().Field(i).Name))
+		}
+	}
+	return result
+}
+
+// snakeCase turns field names like DataAssetsProcessed or IpFiltered into the keys used in the model file
+func snakeCase(name string) string {
+	var result strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			result.WriteRune('_')
+		}
+		result.WriteRune(unicode.ToLower(r))
+	}
+	return result.String()
+}
@@ -0,0 +1,33 @@
+package threagile
+
+import "github.com/otyg/threagile/model"
+
+type Options struct {
+	// RAA calculation: either a function called directly or a plugin (.so shared object) file name to load it from
+	RAA       func() string
+	RAAPlugin string
+	// risk rules: either given directly (keyed by category id) or loaded from all plugins (.so shared object) matching a glob pattern
+	RiskRules        map[string]model.RiskRule
+	RiskRulesPlugins string
+	SkipRiskRules    []string
+	// when set, additional risk rules are loaded from this YAML file (see LoadCustomRiskRules)
+	CustomRiskRulesFile string
+	// when set, the model is validated against this JSON schema file before being parsed
+	SchemaFile string
+	// file name of the model: includes are resolved relative to it (and optionally refused when outside of its directory)
+	ModelFilename                    string
+	RestrictIncludesToModelDirectory bool
+	IgnoreOrphanedRiskTracking       bool
+	Verbose                          bool
+	// status of the risk tracking after its expiry (like an acceptance being unchecked again), zero value being unchecked
+	ExpiredRiskTrackingStatus model.RiskStatus
+}
+
+// DefaultOptions mirrors the defaults of the commandline tool (plugins and schema are looked up relative to the working directory)
+func DefaultOptions() Options {
+	return Options{
+		RAAPlugin:        "raa.so",
+		RiskRulesPlugins: "risk-plugins/*.so",
+		SchemaFile:       "schema.json",
+	}
+}
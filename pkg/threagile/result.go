@@ -0,0 +1,386 @@
+package threagile
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"gopkg.in/yaml.v2"
+)
+
+// Result of an analysis: it is immutable, all accessors hand out (deep) copies
+type Result struct {
+	state        modelState
+	modelInput   model.ModelInput
+	mergedInput  model.ModelInput
+	riskRules    map[string]model.RiskRule
+	introTextRAA string
+	statistics   model.RiskStatistics
+	modelHash    string
+	diagnostics  model.Diagnostics
+}
+
+// the package level state of the model package an analysis leaves behind
+type modelState struct {
+	parsedModel                                           model.ParsedModel
+	communicationLinks                                    map[string]model.CommunicationLink
+	incomingTechnicalCommunicationLinksMappedByTargetId   map[string][]model.CommunicationLink
+	directContainingTrustBoundaryMappedByTechnicalAssetId map[string]model.TrustBoundary
+	directContainingSharedRuntimeMappedByTechnicalAssetId map[string]model.SharedRuntime
+	generatedRisksByCategory                              map[model.RiskCategory][]model.Risk
+	generatedRisksBySyntheticId                           map[string]model.Risk
+	allSupportedTags                                      map[string]bool
+}
+
+func captureState() modelState {
+	return modelState{
+		parsedModel:        copyParsedModel(model.ParsedModelRoot),
+		communicationLinks: copyCommunicationLinkMap(model.CommunicationLinks),
+		incomingTechnicalCommunicationLinksMappedByTargetId:   copyLinksMap(model.IncomingTechnicalCommunicationLinksMappedByTargetId),
+		directContainingTrustBoundaryMappedByTechnicalAssetId: copyTrustBoundaryMap(model.DirectContainingTrustBoundaryMappedByTechnicalAssetId),
+		directContainingSharedRuntimeMappedByTechnicalAssetId: copySharedRuntimeMap(model.DirectContainingSharedRuntimeMappedByTechnicalAssetId),
+		generatedRisksByCategory:                              copyRisksMap(model.GeneratedRisksByCategory),
+		generatedRisksBySyntheticId:                           copyRiskMap(model.GeneratedRisksBySyntheticId),
+		allSupportedTags:                                      copyBoolMap(model.AllSupportedTags),
+	}
+}
+
+func (what modelState) install() {
+	model.ParsedModelRoot = copyParsedModel(what.parsedModel)
+	model.CommunicationLinks = copyCommunicationLinkMap(what.communicationLinks)
+	model.IncomingTechnicalCommunicationLinksMappedByTargetId = copyLinksMap(what.incomingTechnicalCommunicationLinksMappedByTargetId)
+	model.DirectContainingTrustBoundaryMappedByTechnicalAssetId = copyTrustBoundaryMap(what.directContainingTrustBoundaryMappedByTechnicalAssetId)
+	model.DirectContainingSharedRuntimeMappedByTechnicalAssetId = copySharedRuntimeMap(what.directContainingSharedRuntimeMappedByTechnicalAssetId)
+	model.GeneratedRisksByCategory = copyRisksMap(what.generatedRisksByCategory)
+	model.GeneratedRisksBySyntheticId = copyRiskMap(what.generatedRisksBySyntheticId)
+	model.AllSupportedTags = copyBoolMap(what.allSupportedTags)
+}
+
+// WithModel makes the result the current state of the model package while fn runs, so that everything
+// working on that state (report writers, model macros, ...) can be used. Calls are serialized with Analyze.
+func (what *Result) WithModel(fn func()) (err error) {
+	analysisLock.Lock()
+	defer analysisLock.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			err = asError(r)
+		}
+	}()
+	what.state.install()
+	fn()
+	return nil
+}
+
+// ModelInput returns a deep copy of the model as it was read (before parsing), e.g. to be modified by model macros
+func (what *Result) ModelInput() model.ModelInput {
+	return copyModelInput(what.modelInput)
+}
+
+// MergedModelInput returns a deep copy of the model merged with its includes, i.e. the model which was analyzed
+func (what *Result) MergedModelInput() model.ModelInput {
+	return copyModelInput(what.mergedInput)
+}
+
+func copyModelInput(input model.ModelInput) model.ModelInput {
+	result := model.ModelInput{}
+	data, err := yaml.Marshal(&input)
+	if err == nil {
+		err = yaml.Unmarshal(data, &result)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func (what *Result) ParsedModel() model.ParsedModel {
+	return copyParsedModel(what.state.parsedModel)
+}
+
+// Risks returns all generated risks sorted by their synthetic id
+func (what *Result) Risks() []model.Risk {
+	result := make([]model.Risk, 0, len(what.state.generatedRisksBySyntheticId))
+	for _, risks := range what.state.generatedRisksByCategory {
+		result = append(result, copyRisks(risks)...)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SyntheticId < result[j].SyntheticId
+	})
+	return result
+}
+
+func (what *Result) RisksByCategory() map[model.RiskCategory][]model.Risk {
+	return copyRisksMap(what.state.generatedRisksByCategory)
+}
+
+// RiskBySyntheticId looks up a risk by its (case-insensitive) synthetic id
+func (what *Result) RiskBySyntheticId(syntheticId string) (model.Risk, bool) {
+	risk, ok := what.state.generatedRisksBySyntheticId[strings.ToLower(strings.TrimSpace(syntheticId))]
+	return copyRisk(risk), ok
+}
+
+// RiskTracking returns the effective risk tracking (including the entries expanded from wildcards)
+func (what *Result) RiskTracking() map[string]model.RiskTracking {
+	return copyRiskTrackingMap(what.state.parsedModel.RiskTracking)
+}
+
+func (what *Result) Statistics() model.RiskStatistics {
+	result := model.RiskStatistics{Risks: make(map[string]map[string]int)}
+	for severity, counts := range what.statistics.Risks {
+		result.Risks[severity] = copyIntMap(counts)
+	}
+	return result
+}
+
+func (what *Result) SupportedTags() []string {
+	result := make([]string, 0, len(what.state.allSupportedTags))
+	for tag := range what.state.allSupportedTags {
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (what *Result) RiskRules() map[string]model.RiskRule {
+	return copyRiskRuleMap(what.riskRules)
+}
+
+func (what *Result) IntroTextRAA() string {
+	return what.introTextRAA
+}
+
+// Diagnostics returns the warnings found while analyzing the model (errors make Analyze fail instead)
+func (what *Result) Diagnostics() model.Diagnostics {
+	return append(model.Diagnostics(nil), what.diagnostics...)
+}
+
+// ModelHash is the hex encoded SHA-256 of the analyzed model YAML (including the YAML of its included fragments)
+func (what *Result) ModelHash() string {
+	return what.modelHash
+}
+
+func copyParsedModel(parsedModel model.ParsedModel) model.ParsedModel {
+	result := parsedModel
+	result.BusinessOverview = copyOverview(parsedModel.BusinessOverview)
+	result.TechnicalOverview = copyOverview(parsedModel.TechnicalOverview)
+	result.SecurityRequirements = copyStringMap(parsedModel.SecurityRequirements)
+	result.Questions = copyStringMap(parsedModel.Questions)
+	result.AbuseCases = copyStringMap(parsedModel.AbuseCases)
+	result.TagsAvailable = copyStrings(parsedModel.TagsAvailable)
+	result.DefaultValues = copyStringMap(parsedModel.DefaultValues)
+	result.DataAssets = copyDataAssetMap(parsedModel.DataAssets)
+	result.TechnicalAssets = copyTechnicalAssetMap(parsedModel.TechnicalAssets)
+	result.TrustBoundaries = copyTrustBoundaryMap(parsedModel.TrustBoundaries)
+	result.SharedRuntimes = copySharedRuntimeMap(parsedModel.SharedRuntimes)
+	result.IndividualRiskCategories = copyRiskCategoryMap(parsedModel.IndividualRiskCategories)
+	result.RiskTracking = copyRiskTrackingMap(parsedModel.RiskTracking)
+	result.OrphanedRiskTracking = copyRiskTrackingMap(parsedModel.OrphanedRiskTracking)
+	result.DiagramTweakInvisibleConnectionsBetweenAssets = copyStrings(parsedModel.DiagramTweakInvisibleConnectionsBetweenAssets)
+	result.DiagramTweakSameRankAssets = copyStrings(parsedModel.DiagramTweakSameRankAssets)
+	return result
+}
+
+func copyOverview(overview model.Overview) model.Overview {
+	result := overview
+	if overview.Images != nil {
+		result.Images = make([]map[string]string, len(overview.Images))
+		for i, image := range overview.Images {
+			result.Images[i] = copyStringMap(image)
+		}
+	}
+	return result
+}
+
+func copyDataAsset(dataAsset model.DataAsset) model.DataAsset {
+	result := dataAsset
+	result.PreviousIds = copyStrings(dataAsset.PreviousIds)
+	result.Tags = copyStrings(dataAsset.Tags)
+	return result
+}
+
+func copyTechnicalAsset(technicalAsset model.TechnicalAsset) model.TechnicalAsset {
+	result := technicalAsset
+	result.PreviousIds = copyStrings(technicalAsset.PreviousIds)
+	result.Tags = copyStrings(technicalAsset.Tags)
+	result.DataAssetsProcessed = copyStrings(technicalAsset.DataAssetsProcessed)
+	result.DataAssetsStored = copyStrings(technicalAsset.DataAssetsStored)
+	if technicalAsset.DataFormatsAccepted != nil {
+		result.DataFormatsAccepted = append(make([]model.DataFormat, 0, len(technicalAsset.DataFormatsAccepted)), technicalAsset.DataFormatsAccepted...)
+	}
+	result.CommunicationLinks = copyCommunicationLinks(technicalAsset.CommunicationLinks)
+	return result
+}
+
+func copyCommunicationLink(communicationLink model.CommunicationLink) model.CommunicationLink {
+	result := communicationLink
+	result.PreviousIds = copyStrings(communicationLink.PreviousIds)
+	result.Tags = copyStrings(communicationLink.Tags)
+	result.DataAssetsSent = copyStrings(communicationLink.DataAssetsSent)
+	result.DataAssetsReceived = copyStrings(communicationLink.DataAssetsReceived)
+	return result
+}
+
+func copyCommunicationLinks(source []model.CommunicationLink) []model.CommunicationLink {
+	if source == nil {
+		return nil
+	}
+	result := make([]model.CommunicationLink, len(source))
+	for i, communicationLink := range source {
+		result[i] = copyCommunicationLink(communicationLink)
+	}
+	return result
+}
+
+func copyTrustBoundary(trustBoundary model.TrustBoundary) model.TrustBoundary {
+	result := trustBoundary
+	result.PreviousIds = copyStrings(trustBoundary.PreviousIds)
+	result.Tags = copyStrings(trustBoundary.Tags)
+	result.TechnicalAssetsInside = copyStrings(trustBoundary.TechnicalAssetsInside)
+	result.TrustBoundariesNested = copyStrings(trustBoundary.TrustBoundariesNested)
+	return result
+}
+
+func copySharedRuntime(sharedRuntime model.SharedRuntime) model.SharedRuntime {
+	result := sharedRuntime
+	result.PreviousIds = copyStrings(sharedRuntime.PreviousIds)
+	result.Tags = copyStrings(sharedRuntime.Tags)
+	result.TechnicalAssetsRunning = copyStrings(sharedRuntime.TechnicalAssetsRunning)
+	return result
+}
+
+func copyRisk(risk model.Risk) model.Risk {
+	result := risk
+	result.DataBreachTechnicalAssetIDs = copyStrings(risk.DataBreachTechnicalAssetIDs)
+	return result
+}
+
+func copyRisks(source []model.Risk) []model.Risk {
+	if source == nil {
+		return nil
+	}
+	result := make([]model.Risk, len(source))
+	for i, risk := range source {
+		result[i] = copyRisk(risk)
+	}
+	return result
+}
+
+// copyStrings keeps nil slices nil (as those are rendered differently than empty ones, e.g. in JSON)
+func copyStrings(source []string) []string {
+	if source == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(source)), source...)
+}
+
+func copyStringMap(source map[string]string) map[string]string {
+	result := make(map[string]string, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	return result
+}
+
+func copyBoolMap(source map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	return result
+}
+
+func copyIntMap(source map[string]int) map[string]int {
+	result := make(map[string]int, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	return result
+}
+
+func copyDataAssetMap(source map[string]model.DataAsset) map[string]model.DataAsset {
+	result := make(map[string]model.DataAsset, len(source))
+	for key, value := range source {
+		result[key] = copyDataAsset(value)
+	}
+	return result
+}
+
+func copyTechnicalAssetMap(source map[string]model.TechnicalAsset) map[string]model.TechnicalAsset {
+	result := make(map[string]model.TechnicalAsset, len(source))
+	for key, value := range source {
+		result[key] = copyTechnicalAsset(value)
+	}
+	return result
+}
+
+func copyTrustBoundaryMap(source map[string]model.TrustBoundary) map[string]model.TrustBoundary {
+	result := make(map[string]model.TrustBoundary, len(source))
+	for key, value := range source {
+		result[key] = copyTrustBoundary(value)
+	}
+	return result
+}
+
+func copySharedRuntimeMap(source map[string]model.SharedRuntime) map[string]model.SharedRuntime {
+	result := make(map[string]model.SharedRuntime, len(source))
+	for key, value := range source {
+		result[key] = copySharedRuntime(value)
+	}
+	return result
+}
+
+func copyRiskCategoryMap(source map[string]model.RiskCategory) map[string]model.RiskCategory {
+	result := make(map[string]model.RiskCategory, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	return result
+}
+
+func copyRiskTrackingMap(source map[string]model.RiskTracking) map[string]model.RiskTracking {
+	result := make(map[string]model.RiskTracking, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	return result
+}
+
+func copyCommunicationLinkMap(source map[string]model.CommunicationLink) map[string]model.CommunicationLink {
+	result := make(map[string]model.CommunicationLink, len(source))
+	for key, value := range source {
+		result[key] = copyCommunicationLink(value)
+	}
+	return result
+}
+
+func copyRiskMap(source map[string]model.Risk) map[string]model.Risk {
+	result := make(map[string]model.Risk, len(source))
+	for key, value := range source {
+		result[key] = copyRisk(value)
+	}
+	return result
+}
+
+func copyRiskRuleMap(source map[string]model.RiskRule) map[string]model.RiskRule {
+	result := make(map[string]model.RiskRule, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	return result
+}
+
+func copyLinksMap(source map[string][]model.CommunicationLink) map[string][]model.CommunicationLink {
+	result := make(map[string][]model.CommunicationLink, len(source))
+	for key, links := range source {
+		result[key] = copyCommunicationLinks(links)
+	}
+	return result
+}
+
+func copyRisksMap(source map[model.RiskCategory][]model.Risk) map[model.RiskCategory][]model.Risk {
+	result := make(map[model.RiskCategory][]model.Risk, len(source))
+	for category, risks := range source {
+		result[category] = copyRisks(risks)
+	}
+	return result
+}
@@ -0,0 +1,299 @@
+package tm7
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/model/confidentiality"
+	"github.com/otyg/threagile/model/criticality"
+)
+
+// Unmapped is something of the .tm7 file without counterpart in the generated model
+type Unmapped struct {
+	Diagram  string
+	Element  string
+	TypeId   string
+	Property string
+	Value    string
+}
+
+// Report lists everything which could not be mapped, to be transferred manually into the model
+type Report []Unmapped
+
+func (what Report) String() string {
+	var result strings.Builder
+	for _, unmapped := range what {
+		result.WriteString(unmapped.Diagram)
+		if len(unmapped.Element) > 0 {
+			result.WriteString(" / " + unmapped.Element)
+		}
+		if len(unmapped.TypeId) > 0 {
+			result.WriteString(" (" + unmapped.TypeId + ")")
+		}
+		result.WriteString(": " + unmapped.Property)
+		if len(unmapped.Value) > 0 {
+			result.WriteString(" = " + strings.ReplaceAll(unmapped.Value, "\n", " "))
+		}
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// ToModelInput converts the diagrams of the threat model into a model: the processes, external interactors, and data stores
+// become technical assets (inside the smallest trust boundary drawn as border around them), the data flows become
+// communication links. Technologies, protocols, and trust boundary types are taken from the mapping, all other values
+// are set to defaults to be refined afterwards. The report holds the properties and elements which could not be mapped.
+func ToModelInput(threatModel ThreatModel, mapping Mapping) (model.ModelInput, Report) {
+	importer := tm7Importer{
+		mapping: mapping,
+		ids:     make(map[string]string),
+		usedIds: make(map[string]bool),
+	}
+	result := model.ModelInput{
+		Threagile_version:    model.ThreagileVersion,
+		Title:                threatModel.MetaInformation.ThreatModelName,
+		Author:               model.Author{Name: threatModel.MetaInformation.Owner},
+		Date:                 time.Now().Format("2006-01-02"),
+		Business_overview:    model.Overview{Description: threatModel.MetaInformation.HighLevelSystemDescription},
+		Business_criticality: criticality.Important.String(),
+		Technical_assets:     make(map[string]model.InputTechnicalAsset),
+		Trust_boundaries:     make(map[string]model.InputTrustBoundary),
+	}
+	if len(result.Title) == 0 {
+		result.Title = "Imported Threat Model"
+	}
+	meta := threatModel.MetaInformation
+	for _, property := range [][2]string{{"Reviewer", meta.Reviewer}, {"Contributors", meta.Contributors},
+		{"Assumptions", meta.Assumptions}, {"External Dependencies", meta.ExternalDependencies}} {
+		if value := strings.TrimSpace(property[1]); len(value) > 0 {
+			importer.report = append(importer.report, Unmapped{Diagram: "Threat Model", Property: property[0], Value: value})
+		}
+	}
+
+	for _, surface := range threatModel.DrawingSurfaces {
+		importer.importDrawingSurface(&result, surface)
+	}
+	return result, importer.report
+}
+
+type tm7Importer struct {
+	mapping Mapping
+	ids     map[string]string // guids of the elements to their ids
+	usedIds map[string]bool
+	report  Report
+}
+
+func (what *tm7Importer) importDrawingSurface(result *model.ModelInput, surface DrawingSurface) {
+	var shapes, boundaries []Element
+	technicalAssetTitles, trustBoundaryTitles := make(map[string]string), make(map[string]string)
+	for _, border := range surface.Borders {
+		element := border.Value
+		switch element.GenericTypeId {
+		case genericProcess, genericExternalInteractor, genericDataStore:
+			shapes = append(shapes, element)
+			technicalAssetTitles[element.Guid] = what.importTechnicalAsset(result, surface, element)
+		case genericBorderBoundary:
+			boundaries = append(boundaries, element)
+			trustBoundaryTitles[element.Guid] = what.importTrustBoundary(result, surface, element)
+		case genericAnnotation:
+			what.report = append(what.report, Unmapped{Diagram: surface.Header, TypeId: element.TypeId, Property: "annotation", Value: element.Name()})
+		default:
+			what.report = append(what.report, Unmapped{Diagram: surface.Header, Element: element.Name(), TypeId: element.TypeId, Property: "unsupported stencil"})
+		}
+	}
+
+	// the smallest trust boundary around the shape (or trust boundary) contains it
+	innermost := func(element Element) (Element, bool) {
+		var found Element
+		ok := false
+		for _, boundary := range boundaries {
+			if boundary.Guid == element.Guid || !boundary.contains(element) || boundary.Width*boundary.Height <= element.Width*element.Height {
+				continue
+			}
+			if !ok || boundary.Width*boundary.Height < found.Width*found.Height {
+				found, ok = boundary, true
+			}
+		}
+		return found, ok
+	}
+	for _, shape := range shapes {
+		if boundary, ok := innermost(shape); ok {
+			title := trustBoundaryTitles[boundary.Guid]
+			trustBoundary := result.Trust_boundaries[title]
+			trustBoundary.Technical_assets_inside = append(trustBoundary.Technical_assets_inside, what.ids[shape.Guid])
+			result.Trust_boundaries[title] = trustBoundary
+		}
+	}
+	for _, nested := range boundaries {
+		if boundary, ok := innermost(nested); ok {
+			title := trustBoundaryTitles[boundary.Guid]
+			trustBoundary := result.Trust_boundaries[title]
+			trustBoundary.Trust_boundaries_nested = append(trustBoundary.Trust_boundaries_nested, what.ids[nested.Guid])
+			result.Trust_boundaries[title] = trustBoundary
+		}
+	}
+
+	for _, line := range surface.Lines {
+		element := line.Value
+		switch element.GenericTypeId {
+		case genericDataFlow:
+			sourceTitle, sourceFound := technicalAssetTitles[element.SourceGuid]
+			_, targetFound := technicalAssetTitles[element.TargetGuid]
+			if !sourceFound || !targetFound {
+				what.report = append(what.report, Unmapped{Diagram: surface.Header, Element: element.Name(), TypeId: element.TypeId, Property: "data flow not connected to both source and target"})
+				continue
+			}
+			what.importCommunicationLink(result, surface, element, sourceTitle)
+		case genericLineBoundary:
+			what.report = append(what.report, Unmapped{Diagram: surface.Header, Element: element.Name(), TypeId: element.TypeId,
+				Property: "trust boundary drawn as line (only the ones drawn as border contain elements)"})
+		default:
+			what.report = append(what.report, Unmapped{Diagram: surface.Header, Element: element.Name(), TypeId: element.TypeId, Property: "unsupported stencil"})
+		}
+	}
+}
+
+func (what *tm7Importer) importTechnicalAsset(result *model.ModelInput, surface DrawingSurface, element Element) string {
+	technicalAsset := model.InputTechnicalAsset{
+		ID:              what.idOf(element),
+		Usage:           model.Business.String(),
+		Machine:         model.Virtual.String(),
+		Encryption:      model.NoneEncryption.String(),
+		Confidentiality: confidentiality.Internal.String(),
+		Integrity:       criticality.Operational.String(),
+		Availability:    criticality.Operational.String(),
+	}
+	switch element.GenericTypeId {
+	case genericExternalInteractor:
+		technicalAsset.Type, technicalAsset.Size = model.ExternalEntity.String(), model.System.String()
+	case genericDataStore:
+		technicalAsset.Type, technicalAsset.Size = model.Datastore.String(), model.Component.String()
+	default:
+		technicalAsset.Type, technicalAsset.Size = model.Process.String(), model.Service.String()
+	}
+	technology, mapped := lookup(what.mapping.Technologies, element)
+	if !mapped {
+		what.reportUnmappedType(surface, element, technology)
+	}
+	if len(technology) == 0 {
+		technology = model.UnknownTechnology.String()
+	}
+	technicalAsset.Technology = technology
+	for _, property := range propertiesOf(element) {
+		switch strings.ToLower(property.DisplayName) {
+		case "out of scope":
+			technicalAsset.Out_of_scope = isTrue(property.Value())
+		case "reason for out of scope":
+			technicalAsset.Justification_out_of_scope = property.Value()
+		default:
+			what.reportProperty(surface, element, property)
+		}
+	}
+	title := uniqueTitle(titleOf(element), technicalAsset.ID, func(title string) bool { _, exists := result.Technical_assets[title]; return exists })
+	result.Technical_assets[title] = technicalAsset
+	return title
+}
+
+func (what *tm7Importer) importTrustBoundary(result *model.ModelInput, surface DrawingSurface, element Element) string {
+	trustBoundary := model.InputTrustBoundary{ID: what.idOf(element)}
+	trustBoundaryType, mapped := lookup(what.mapping.TrustBoundaries, element)
+	if !mapped {
+		what.reportUnmappedType(surface, element, trustBoundaryType)
+	}
+	if len(trustBoundaryType) == 0 {
+		trustBoundaryType = model.NetworkOnPrem.String()
+	}
+	trustBoundary.Type = trustBoundaryType
+	for _, property := range propertiesOf(element) {
+		what.reportProperty(surface, element, property)
+	}
+	title := uniqueTitle(titleOf(element), trustBoundary.ID, func(title string) bool { _, exists := result.Trust_boundaries[title]; return exists })
+	result.Trust_boundaries[title] = trustBoundary
+	return title
+}
+
+func (what *tm7Importer) importCommunicationLink(result *model.ModelInput, surface DrawingSurface, element Element, sourceTitle string) {
+	communicationLink := model.InputCommunicationLink{
+		Target:         what.ids[element.TargetGuid],
+		Authentication: model.NoneAuthentication.String(),
+		Authorization:  model.NoneAuthorization.String(),
+		Usage:          model.Business.String(),
+	}
+	protocol, mapped := lookup(what.mapping.Protocols, element)
+	if !mapped {
+		what.reportUnmappedType(surface, element, protocol)
+	}
+	if len(protocol) == 0 {
+		protocol = model.UnknownProtocol.String()
+	}
+	communicationLink.Protocol = protocol
+	for _, property := range propertiesOf(element) {
+		what.reportProperty(surface, element, property)
+	}
+	source := result.Technical_assets[sourceTitle]
+	if source.Communication_links == nil {
+		source.Communication_links = make(map[string]model.InputCommunicationLink)
+	}
+	title := uniqueTitle(titleOf(element), communicationLink.Target, func(title string) bool { _, exists := source.Communication_links[title]; return exists })
+	source.Communication_links[title] = communicationLink
+	result.Technical_assets[sourceTitle] = source
+}
+
+// propertiesOf are all properties with values except the name (which is always mapped)
+func propertiesOf(element Element) []Property {
+	var result []Property
+	for _, property := range element.Properties {
+		if property.DisplayName != "Name" && len(property.Value()) > 0 {
+			result = append(result, property)
+		}
+	}
+	return result
+}
+
+func (what *tm7Importer) reportProperty(surface DrawingSurface, element Element, property Property) {
+	what.report = append(what.report, Unmapped{Diagram: surface.Header, Element: element.Name(), TypeId: element.TypeId, Property: property.DisplayName, Value: property.Value()})
+}
+
+func (what *tm7Importer) reportUnmappedType(surface DrawingSurface, element Element, fallback string) {
+	if len(fallback) == 0 {
+		fallback = "default"
+	}
+	what.report = append(what.report, Unmapped{Diagram: surface.Header, Element: element.Name(), TypeId: element.TypeId,
+		Property: "stencil type not in mapping", Value: "used " + fallback})
+}
+
+func (what *tm7Importer) idOf(element Element) string {
+	id := model.MakeID(titleOf(element))
+	if len(id) == 0 {
+		id = "element"
+	}
+	unique := id
+	for i := 2; what.usedIds[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	what.usedIds[unique] = true
+	what.ids[element.Guid] = unique
+	return unique
+}
+
+func titleOf(element Element) string {
+	if name := element.Name(); len(name) > 0 {
+		return name
+	}
+	typeIds := strings.Split(element.TypeId, ".")
+	return typeIds[len(typeIds)-1]
+}
+
+func uniqueTitle(title, id string, exists func(string) bool) string {
+	if exists(title) {
+		title += " (" + id + ")"
+	}
+	return title
+}
+
+func isTrue(value string) bool {
+	value = strings.ToLower(value)
+	return value == "true" || value == "yes"
+}
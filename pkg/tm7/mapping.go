@@ -0,0 +1,69 @@
+package tm7
+
+import (
+	_ "embed"
+	"errors"
+	"io/ioutil"
+
+	"github.com/otyg/threagile/model"
+	"gopkg.in/yaml.v2"
+)
+
+//go:embed default-mapping.yaml
+var defaultMappingYAML []byte
+
+// Mapping from the (generic) type ids of the stencils onto Threagile values
+type Mapping struct {
+	Technologies    map[string]string `yaml:"technologies"`
+	Protocols       map[string]string `yaml:"protocols"`
+	TrustBoundaries map[string]string `yaml:"trust_boundaries"`
+}
+
+// DefaultMapping covers the stencils of the built-in (TMCore) template
+func DefaultMapping() Mapping {
+	mapping := Mapping{}
+	if err := yaml.Unmarshal(defaultMappingYAML, &mapping); err != nil {
+		panic(err)
+	}
+	return mapping
+}
+
+// LoadMapping reads the YAML mapping file, its entries extend and override the default mapping
+func LoadMapping(filename string) (Mapping, error) {
+	mapping := DefaultMapping()
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return mapping, err
+	}
+	custom := Mapping{}
+	if err := yaml.UnmarshalStrict(data, &custom); err != nil {
+		return mapping, err
+	}
+	for typeId, value := range custom.Technologies {
+		if _, err := model.ParseTechnicalAssetTechnology(value); err != nil {
+			return mapping, errors.New("invalid technology of " + typeId + ": " + value)
+		}
+		mapping.Technologies[typeId] = value
+	}
+	for typeId, value := range custom.Protocols {
+		if _, err := model.ParseProtocol(value); err != nil {
+			return mapping, errors.New("invalid protocol of " + typeId + ": " + value)
+		}
+		mapping.Protocols[typeId] = value
+	}
+	for typeId, value := range custom.TrustBoundaries {
+		if _, err := model.ParseTrustBoundaryType(value); err != nil {
+			return mapping, errors.New("invalid trust boundary type of " + typeId + ": " + value)
+		}
+		mapping.TrustBoundaries[typeId] = value
+	}
+	return mapping, nil
+}
+
+// lookup finds the value of the type id, falling back to the generic type id
+func lookup(values map[string]string, element Element) (value string, mapped bool) {
+	if value, ok := values[element.TypeId]; ok {
+		return value, true
+	}
+	return values[element.GenericTypeId], false
+}
@@ -0,0 +1,117 @@
+// Package tm7 imports threat models of the Microsoft Threat Modeling Tool (.tm7 files) as Threagile models.
+package tm7
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// generic type ids of the stencils
+const (
+	genericProcess            = "GE.P"
+	genericExternalInteractor = "GE.EI"
+	genericDataStore          = "GE.DS"
+	genericDataFlow           = "GE.DF"
+	genericBorderBoundary     = "GE.TB.B"
+	genericLineBoundary       = "GE.TB.L"
+	genericAnnotation         = "GE.A"
+)
+
+// ThreatModel is the (relevant part of the) data contract serialized as .tm7 file
+type ThreatModel struct {
+	XMLName         xml.Name         `xml:"ThreatModel"`
+	DrawingSurfaces []DrawingSurface `xml:"DrawingSurfaceList>DrawingSurfaceModel"`
+	MetaInformation MetaInformation  `xml:"MetaInformation"`
+}
+
+type MetaInformation struct {
+	ThreatModelName            string `xml:"ThreatModelName"`
+	Owner                      string `xml:"Owner"`
+	Reviewer                   string `xml:"Reviewer"`
+	Contributors               string `xml:"Contributors"`
+	HighLevelSystemDescription string `xml:"HighLevelSystemDescription"`
+	Assumptions                string `xml:"Assumptions"`
+	ExternalDependencies       string `xml:"ExternalDependencies"`
+}
+
+// DrawingSurface is a diagram: the borders hold the shapes (including the trust boundaries drawn as border),
+// the lines hold the data flows and the trust boundaries drawn as line
+type DrawingSurface struct {
+	Header  string     `xml:"Header"`
+	Borders []KeyValue `xml:"Borders>KeyValueOfguidanyType"`
+	Lines   []KeyValue `xml:"Lines>KeyValueOfguidanyType"`
+}
+
+type KeyValue struct {
+	Key   string  `xml:"Key"`
+	Value Element `xml:"Value"`
+}
+
+type Element struct {
+	GenericTypeId string     `xml:"GenericTypeId"`
+	Guid          string     `xml:"Guid"`
+	TypeId        string     `xml:"TypeId"`
+	Properties    []Property `xml:"Properties>anyType"`
+	Left          float64    `xml:"Left"`
+	Top           float64    `xml:"Top"`
+	Width         float64    `xml:"Width"`
+	Height        float64    `xml:"Height"`
+	SourceGuid    string     `xml:"SourceGuid"`
+	TargetGuid    string     `xml:"TargetGuid"`
+}
+
+type Property struct {
+	Type          string        `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+	DisplayName   string        `xml:"DisplayName"`
+	Name          string        `xml:"Name"`
+	Content       PropertyValue `xml:"Value"`
+	SelectedIndex int           `xml:"SelectedIndex"`
+}
+
+// PropertyValue is either a simple value or the list of choices (of which the one at SelectedIndex is selected)
+type PropertyValue struct {
+	Nil     bool     `xml:"http://www.w3.org/2001/XMLSchema-instance nil,attr"`
+	Text    string   `xml:",chardata"`
+	Choices []string `xml:"string"`
+}
+
+// Parse reads the XML of a .tm7 file
+func Parse(data []byte) (result ThreatModel, err error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	err = xml.Unmarshal(data, &result)
+	return result, err
+}
+
+// Value is the (selected) value of the property, empty for headers and unset values
+func (what Property) Value() string {
+	if what.Content.Nil || strings.HasSuffix(what.Type, "HeaderDisplayAttribute") {
+		return ""
+	}
+	if len(what.Content.Choices) > 0 {
+		if what.SelectedIndex < 0 || what.SelectedIndex >= len(what.Content.Choices) {
+			return ""
+		}
+		choice := strings.TrimSpace(what.Content.Choices[what.SelectedIndex])
+		if strings.EqualFold(choice, "Select") || strings.EqualFold(choice, "Not Selected") {
+			return ""
+		}
+		return choice
+	}
+	return strings.TrimSpace(what.Content.Text)
+}
+
+// Name is the name given to the element within the diagram
+func (what Element) Name() string {
+	for _, property := range what.Properties {
+		if property.DisplayName == "Name" {
+			return property.Value()
+		}
+	}
+	return ""
+}
+
+func (what Element) contains(other Element) bool {
+	centerX, centerY := other.Left+other.Width/2, other.Top+other.Height/2
+	return what.Left <= centerX && centerX <= what.Left+what.Width && what.Top <= centerY && centerY <= what.Top+what.Height
+}
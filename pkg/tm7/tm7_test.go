@@ -0,0 +1,111 @@
+package tm7
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/pkg/threagile"
+	"gopkg.in/yaml.v2"
+)
+
+func importExample(t *testing.T, mapping Mapping) (model.ParsedModel, Report) {
+	data, err := ioutil.ReadFile("testdata/example.tm7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	threatModel, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modelInput, report := ToModelInput(threatModel, mapping)
+	modelYaml, err := yaml.Marshal(&modelInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.ThreagileVersion = "test"
+	result, err := threagile.Analyze(context.Background(), modelYaml, threagile.Options{
+		RAA:       func() string { return "" },
+		RiskRules: map[string]model.RiskRule{},
+	})
+	if err != nil {
+		t.Fatalf("imported model is not ok: %v\n%s", err, modelYaml)
+	}
+	return result.ParsedModel(), report
+}
+
+func TestImport(t *testing.T) {
+	parsedModel, report := importExample(t, DefaultMapping())
+
+	if got, want := parsedModel.Title, "Customer Portal"; got != want {
+		t.Errorf("title = %v, want %v", got, want)
+	}
+	browser := parsedModel.TechnicalAssets["customer-browser"]
+	if browser.Technology != model.Browser || browser.Type != model.ExternalEntity || !browser.OutOfScope || browser.JustificationOutOfScope != "Managed by the customer" {
+		t.Errorf("customer-browser = %+v", browser)
+	}
+	if got, want := parsedModel.TechnicalAssets["customer-database"].Technology, model.Database; got != want {
+		t.Errorf("technology of customer-database = %v, want %v", got, want)
+	}
+	if got := parsedModel.TrustBoundaries["corporate-network"]; len(got.TechnicalAssetsInside) != 1 || got.TechnicalAssetsInside[0] != "web-application" ||
+		len(got.TrustBoundariesNested) != 1 || got.TrustBoundariesNested[0] != "database-zone" {
+		t.Errorf("corporate-network = %+v", got)
+	}
+	if got := parsedModel.TrustBoundaries["database-zone"].TechnicalAssetsInside; len(got) != 1 || got[0] != "customer-database" {
+		t.Errorf("assets inside database-zone = %v", got)
+	}
+	links := parsedModel.TechnicalAssets["web-application"].CommunicationLinks
+	if len(links) != 1 || links[0].Protocol != model.UnknownProtocol || links[0].TargetId != "customer-database" {
+		t.Errorf("communication links of web-application = %+v", links)
+	}
+	if links := parsedModel.TechnicalAssets["customer-browser"].CommunicationLinks; len(links) != 1 || links[0].Protocol != model.HTTPS {
+		t.Errorf("communication links of customer-browser = %+v", links)
+	}
+
+	text := report.String()
+	for _, want := range []string{
+		"Threat Model: Assumptions = The cloud provider is trusted.",
+		"Customer Portal / Web Application (SE.P.TMCore.WebApp): Code Type = Managed",
+		"Customer Portal / Customer Database (SE.DS.TMCore.SQL): Stores Credentials = true",
+		"Customer Portal / Database Zone (SE.TB.B.Custom.DbZone): stencil type not in mapping = used network-on-prem",
+		"Customer Portal / Queries (SE.DF.Custom.TDS): stencil type not in mapping = used unknown-protocol",
+		"Customer Portal / Queries (SE.DF.Custom.TDS): Provides Confidentiality = Yes",
+		"Customer Portal / Internet Boundary (SE.TB.L.TMCore.Internet): trust boundary drawn as line",
+		"Customer Portal (GE.A): annotation = Backups are not modelled",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report does not contain %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Browser Type") {
+		t.Errorf("report contains unset property:\n%s", text)
+	}
+}
+
+func TestImportWithCustomMapping(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "mapping.yaml")
+	if err := ioutil.WriteFile(filename, []byte("protocols:\n  SE.DF.Custom.TDS: sql-access-protocol-encrypted\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := LoadMapping(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedModel, report := importExample(t, mapping)
+	if links := parsedModel.TechnicalAssets["web-application"].CommunicationLinks; len(links) != 1 || links[0].Protocol != model.SQL_access_protocol_encrypted {
+		t.Errorf("communication links of web-application = %+v", links)
+	}
+	if strings.Contains(report.String(), "SE.DF.Custom.TDS): stencil type not in mapping") {
+		t.Errorf("report contains mapped stencil type:\n%s", report)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("technologies:\n  SE.P.Custom: no-such-technology\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMapping(filename); err == nil {
+		t.Errorf("LoadMapping() accepted invalid technology")
+	}
+}
@@ -0,0 +1,266 @@
+// Package webhook delivers signed notifications about changes of the models stored by the server.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/otyg/threagile/model"
+)
+
+const (
+	ModelCreated = "model-created"
+	ModelUpdated = "model-updated"
+	ModelDeleted = "model-deleted"
+	NewRisks     = "new-risks" // new unchecked risks at or above the minimum severity of the webhook
+)
+
+const SignatureHeader, EventHeader, DeliveryHeader = "X-Threagile-Signature", "X-Threagile-Event", "X-Threagile-Delivery"
+
+func Events() []string {
+	return []string{ModelCreated, ModelUpdated, ModelDeleted, NewRisks}
+}
+
+// Webhook is a registration of a receiver for (some of) the events
+type Webhook struct {
+	ID              string   `json:"id"`
+	URL             string   `json:"url"`
+	Secret          string   `json:"secret,omitempty"`
+	Events          []string `json:"events"` // all events when empty
+	MinimumSeverity string   `json:"minimum_severity"`
+}
+
+// Check validates the URL, the events, and the minimum severity of the webhook
+func (what Webhook) Check() error {
+	parsed, err := url.Parse(what.URL)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return errors.New("webhook url must be an absolute http or https url: " + what.URL)
+	}
+	addresses, err := lookupIPAddr(context.Background(), parsed.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		if err := checkAddress(address.IP); err != nil {
+			return err
+		}
+	}
+	for _, event := range what.Events {
+		if !model.Contains(Events(), event) {
+			return errors.New("unknown webhook event: " + event)
+		}
+	}
+	_, err = model.ParseRiskSeverity(what.MinimumSeverity)
+	return err
+}
+
+// lookupIPAddr resolves the host of a webhook url (replaced by tests)
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// internalNetworks are the private (RFC 1918 and RFC 4193) and shared (RFC 6598) address ranges
+var internalNetworks = parseNetworks("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7")
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	result := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, network)
+	}
+	return result
+}
+
+// checkAddress rejects loopback, link-local, private, and unspecified addresses, so that webhooks can't reach
+// the server itself, the cloud metadata endpoints, or other internal services
+func checkAddress(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return errors.New("webhook must not target a loopback, link-local, or unspecified address: " + ip.String())
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return errors.New("webhook must not target a private address: " + ip.String())
+		}
+	}
+	return nil
+}
+
+func (what Webhook) Subscribes(event string) bool {
+	return len(what.Events) == 0 || model.Contains(what.Events, event)
+}
+
+type Risk struct {
+	SyntheticId string `json:"synthetic_id"`
+	Category    string `json:"category"`
+	Title       string `json:"title"`
+	Severity    string `json:"severity"`
+}
+
+// Payload is the JSON body posted to the webhooks
+type Payload struct {
+	Event        string    `json:"event"`
+	Delivery     string    `json:"delivery"`
+	Timestamp    time.Time `json:"timestamp"`
+	ModelId      string    `json:"model_id"`
+	ChangeReason string    `json:"change_reason,omitempty"`
+	Risks        []Risk    `json:"risks,omitempty"`
+}
+
+// forWebhook reduces the risks of the payload to those at or above the minimum severity of the webhook,
+// false when the webhook should not be notified at all
+func (what Payload) forWebhook(webhook Webhook) (Payload, bool) {
+	if !webhook.Subscribes(what.Event) {
+		return what, false
+	}
+	if what.Event != NewRisks {
+		return what, true
+	}
+	minimum, err := model.ParseRiskSeverity(webhook.MinimumSeverity)
+	if err != nil {
+		return what, false
+	}
+	risks := make([]Risk, 0)
+	for _, risk := range what.Risks {
+		if severity, err := model.ParseRiskSeverity(risk.Severity); err == nil && severity >= minimum {
+			risks = append(risks, risk)
+		}
+	}
+	what.Risks = risks
+	return what, len(risks) > 0
+}
+
+// Sign is the hex encoded HMAC-SHA256 of the body (prefixed with "sha256=") sent in the signature header
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a received body
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+type Sender struct {
+	Client   *http.Client
+	Attempts int
+	Backoff  time.Duration // doubled after each failed attempt
+}
+
+// DefaultSender checks the addresses again when dialing (as the host might resolve differently than when the webhook
+// was registered) and refuses redirects (which might point anywhere)
+func DefaultSender() Sender {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return errors.New("webhook must target an ip address: " + address)
+			}
+			return checkAddress(ip)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // the proxy would be dialed instead of the webhook
+	transport.DialContext = dialer.DialContext
+	return Sender{
+		Client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Attempts: 5,
+		Backoff:  2 * time.Second,
+	}
+}
+
+// Deliver posts the signed payload to the webhook, retrying failed deliveries (but not rejected ones) with exponential backoff
+func (what Sender) Deliver(webhook Webhook, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	backoff := what.Backoff
+	for attempt := 1; ; attempt++ {
+		retry := false
+		err = what.post(webhook, payload, body)
+		if statusErr, ok := err.(statusError); ok {
+			retry = statusErr.retryable()
+		} else if err != nil {
+			retry = true
+		}
+		if !retry || attempt >= what.Attempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (what Sender) post(webhook Webhook, payload Payload, body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, payload.Event)
+	request.Header.Set(DeliveryHeader, payload.Delivery)
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	response, err := what.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return statusError(response.StatusCode)
+	}
+	return nil
+}
+
+// Notify delivers the payload in the background to all webhooks interested in it
+func (what Sender) Notify(webhooks []Webhook, payload Payload) {
+	for _, webhook := range webhooks {
+		payloadOfWebhook, ok := payload.forWebhook(webhook)
+		if !ok {
+			continue
+		}
+		go func(webhook Webhook, payload Payload) {
+			if err := what.Deliver(webhook, payload); err != nil {
+				log.Println("unable to deliver " + payload.Event + " of model " + payload.ModelId + " to webhook " + webhook.ID + ": " + err.Error())
+			}
+		}(webhook, payloadOfWebhook)
+	}
+}
+
+type statusError int
+
+func (what statusError) Error() string {
+	return "webhook responded with status " + strconv.Itoa(int(what))
+}
+
+// retryable are server errors as well as timeouts and throttling, other client errors are final
+func (what statusError) retryable() bool {
+	return what >= 500 || what == http.StatusRequestTimeout || what == http.StatusTooManyRequests
+}
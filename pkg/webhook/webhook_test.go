@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeliver(t *testing.T) {
+	webhook := Webhook{ID: "chat", Secret: "s3cr3t", MinimumSeverity: "elevated"}
+	attempts := 0
+	var received Payload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if !Verify(webhook.Secret, body, r.Header.Get(SignatureHeader)) {
+			t.Errorf("signature %v does not match the body %s", r.Header.Get(SignatureHeader), body)
+		}
+		if r.Header.Get(EventHeader) != ModelUpdated {
+			t.Errorf("event header = %v, want %v", r.Header.Get(EventHeader), ModelUpdated)
+		}
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer receiver.Close()
+	webhook.URL = receiver.URL
+
+	sender := Sender{Client: receiver.Client(), Attempts: 3, Backoff: time.Millisecond}
+	payload := Payload{Event: ModelUpdated, Delivery: "1", ModelId: "model", ChangeReason: "Cover Update"}
+	if err := sender.Deliver(webhook, payload); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || received.ModelId != "model" || received.ChangeReason != "Cover Update" {
+		t.Errorf("received %+v after %v attempts, want %+v after 3 attempts", received, attempts, payload)
+	}
+
+	attempts = 0
+	sender.Attempts = 2
+	if err := sender.Deliver(webhook, payload); err == nil || attempts != 2 {
+		t.Errorf("delivery failing %v times = %v, want an error after 2 attempts", attempts, err)
+	}
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer rejecting.Close()
+	attempts = 0
+	webhook.URL = rejecting.URL
+	if err := sender.Deliver(webhook, payload); err == nil || attempts != 1 {
+		t.Errorf("rejected delivery after %v attempts = %v, want an error without retry", attempts, err)
+	}
+}
+
+func TestPayloadForWebhook(t *testing.T) {
+	payload := Payload{Event: NewRisks, Risks: []Risk{
+		{SyntheticId: "a@x", Severity: "medium"},
+		{SyntheticId: "b@x", Severity: "high"},
+		{SyntheticId: "c@x", Severity: "critical"},
+	}}
+	forHigh, ok := payload.forWebhook(Webhook{MinimumSeverity: "high"})
+	if !ok || len(forHigh.Risks) != 2 || forHigh.Risks[0].SyntheticId != "b@x" {
+		t.Errorf("risks for minimum severity high = %+v", forHigh.Risks)
+	}
+	if len(payload.Risks) != 3 {
+		t.Errorf("risks of the payload were modified: %+v", payload.Risks)
+	}
+	if _, ok := payload.forWebhook(Webhook{MinimumSeverity: "high", Events: []string{ModelDeleted}}); ok {
+		t.Errorf("webhook not subscribed to new risks is notified")
+	}
+	if _, ok := (Payload{Event: NewRisks, Risks: payload.Risks[:1]}).forWebhook(Webhook{MinimumSeverity: "elevated"}); ok {
+		t.Errorf("webhook is notified without any risk at or above its minimum severity")
+	}
+	if _, ok := (Payload{Event: ModelDeleted}).forWebhook(Webhook{MinimumSeverity: "critical"}); !ok {
+		t.Errorf("webhook subscribed to all events is not notified of a deletion")
+	}
+
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+	}
+	defer func() { lookupIPAddr = net.DefaultResolver.LookupIPAddr }()
+	if err := (Webhook{URL: "ftp://example.com", MinimumSeverity: "high"}).Check(); err == nil {
+		t.Errorf("non-http webhook url accepted")
+	}
+	if err := (Webhook{URL: "https://example.com/hook", MinimumSeverity: "high", Events: []string{"model-renamed"}}).Check(); err == nil {
+		t.Errorf("unknown webhook event accepted")
+	}
+	if err := (Webhook{URL: "https://example.com/hook", MinimumSeverity: "high", Events: []string{NewRisks}}).Check(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckAddresses(t *testing.T) {
+	for _, test := range []struct {
+		url       string
+		addresses []string
+		wantOk    bool
+	}{
+		{"https://example.com/hook", []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"}, true},
+		{"http://127.0.0.1:8080/hook", nil, false},
+		{"http://[::1]/hook", nil, false},
+		{"http://169.254.169.254/latest/meta-data", nil, false},
+		{"http://0.0.0.0/hook", nil, false},
+		{"http://10.1.2.3/hook", nil, false},
+		{"http://172.20.0.1/hook", nil, false},
+		{"http://192.168.1.1/hook", nil, false},
+		{"http://[fd00::1]/hook", nil, false},
+		{"https://internal.example.com/hook", []string{"93.184.216.34", "10.0.0.1"}, false},
+		{"https://localhost/hook", []string{"127.0.0.1"}, false},
+	} {
+		lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+			if ip := net.ParseIP(host); ip != nil {
+				return []net.IPAddr{{IP: ip}}, nil
+			}
+			var result []net.IPAddr
+			for _, address := range test.addresses {
+				result = append(result, net.IPAddr{IP: net.ParseIP(address)})
+			}
+			return result, nil
+		}
+		if err := (Webhook{URL: test.url, MinimumSeverity: "high"}).Check(); (err == nil) != test.wantOk {
+			t.Errorf("check of %v resolving to %v = %v, want ok %v", test.url, test.addresses, err, test.wantOk)
+		}
+	}
+	lookupIPAddr = net.DefaultResolver.LookupIPAddr
+}
+
+func TestDefaultSender(t *testing.T) {
+	attempts := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+	}))
+	defer receiver.Close()
+	sender := DefaultSender()
+	sender.Attempts = 1
+	if err := sender.Deliver(Webhook{URL: receiver.URL}, Payload{Event: ModelUpdated}); err == nil || attempts != 0 {
+		t.Errorf("delivery to the loopback address after %v attempts = %v, want an error when dialing", attempts, err)
+	}
+
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Redirect(w, r, receiver.URL, http.StatusTemporaryRedirect)
+	}))
+	defer redirecting.Close()
+	sender.Client.Transport = redirecting.Client().Transport // not checking the loopback address of the test server
+	sender.Attempts = 3
+	if err := sender.Deliver(Webhook{URL: redirecting.URL}, Payload{Event: ModelUpdated}); err == nil || attempts != 1 {
+		t.Errorf("redirected delivery after %v attempts = %v, want an error without following the redirect", attempts, err)
+	}
+}
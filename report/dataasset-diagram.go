@@ -16,9 +16,11 @@ import (
 )
 
 const graphvizDataAssetDiagramConversionCall = "render-data-asset-diagram.sh"
+const graphvizDataAssetDiagramSVGConversionCall = "render-data-asset-diagram-svg.sh"
 const dataAssetDiagramFilenamePNG = "data-asset-diagram.png"
+const dataAssetDiagramFilenameSVG = "data-asset-diagram.svg"
 
-func RenderDataAssetDiagram(outputDirectory string, dataAssetDiagramFilenameDOT string, keepDiagramSourceFiles bool, diagramDPI *int, verbose *bool) {
+func RenderDataAssetDiagram(outputDirectory string, dataAssetDiagramFilenameDOT string, keepDiagramSourceFiles bool, diagramDPI *int, riskOverlay bool, verbose *bool) {
 	gvFile := outputDirectory + "/" + dataAssetDiagramFilenameDOT
 	if !keepDiagramSourceFiles {
 		tmpFile, err := ioutil.TempFile(model.TempFolder, dataAssetDiagramFilenameDOT)
@@ -26,10 +28,28 @@ func RenderDataAssetDiagram(outputDirectory string, dataAssetDiagramFilenameDOT
 		gvFile = tmpFile.Name()
 		defer os.Remove(gvFile)
 	}
-	dotFile := WriteDataAssetDiagramGraphvizDOT(gvFile, *diagramDPI, verbose)
+	dotFile := writeDataAssetDiagramGraphvizDOT(gvFile, *diagramDPI, diagramOptions{riskOverlay: riskOverlay}, verbose)
 	RenderDataAssetDiagramGraphvizImage(dotFile, outputDirectory, verbose)
 }
+
+// RenderDataAssetDiagramSVG writes the data asset diagram as SVG with its elements linking into the HTML report
+func RenderDataAssetDiagramSVG(outputDirectory string, riskOverlay bool, verbose *bool) {
+	if *verbose {
+		fmt.Println("Rendering data asset diagram as SVG")
+	}
+	tmpFileDOT, err := ioutil.TempFile(model.TempFolder, "diagram-*-.gv")
+	support.CheckErr(err)
+	defer os.Remove(tmpFileDOT.Name())
+	writeDataAssetDiagramGraphvizDOT(tmpFileDOT.Name(), 72, diagramOptions{interactive: true, linkDocument: reportFilenameHTML, riskOverlay: riskOverlay}, verbose)
+	svg := renderGraphvizSVG(tmpFileDOT.Name(), graphvizDataAssetDiagramSVGConversionCall)
+	support.CheckErr(ioutil.WriteFile(outputDirectory+"/"+dataAssetDiagramFilenameSVG, svg, 0644))
+}
+
 func WriteDataAssetDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbose *bool) *os.File {
+	return writeDataAssetDiagramGraphvizDOT(diagramFilenameDOT, dpi, diagramOptions{}, verbose)
+}
+
+func writeDataAssetDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, options diagramOptions, verbose *bool) *os.File {
 	if *verbose {
 		fmt.Println("Writing data asset diagram input")
 	}
@@ -68,7 +88,7 @@ func WriteDataAssetDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbos
 	sort.Sort(model.ByOrderAndIdSort(techAssets))
 	for _, technicalAsset := range techAssets {
 		if len(technicalAsset.DataAssetsStored) > 0 || len(technicalAsset.DataAssetsProcessed) > 0 {
-			dotContent.WriteString(MakeTechAssetNode(technicalAsset, true))
+			dotContent.WriteString(makeTechAssetNode(technicalAsset, true, options))
 			dotContent.WriteString("\n")
 		}
 	}
@@ -80,7 +100,7 @@ func WriteDataAssetDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbos
 	}
 	sort.Sort(model.ByDataAssetDataBreachProbabilityAndTitleSort(dataAssets))
 	for _, dataAsset := range dataAssets {
-		dotContent.WriteString(makeDataAssetNode(dataAsset))
+		dotContent.WriteString(makeDataAssetNode(dataAsset, options))
 		dotContent.WriteString("\n")
 	}
 
@@ -115,7 +135,7 @@ func WriteDataAssetDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbos
 	return file
 }
 
-func makeDataAssetNode(dataAsset model.DataAsset) string {
+func makeDataAssetNode(dataAsset model.DataAsset, options diagramOptions) string {
 	var color string
 	switch dataAsset.IdentifiedDataBreachProbabilityStillAtRisk() {
 	case model.Probable:
@@ -130,7 +150,10 @@ func makeDataAssetNode(dataAsset model.DataAsset) string {
 	if !dataAsset.IsDataBreachPotentialStillAtRisk() {
 		color = "#444444" // since black is too dark here as fill color
 	}
-	return "  " + support.Hash(dataAsset.Id) + ` [ label=<<b>` + support.Encode(dataAsset.Title) + `</b>> penwidth="3.0" style="filled" fillcolor="` + color + `" color="` + color + "\"\n  ]; "
+	tooltip := dataAsset.Title + " (" + dataAsset.Confidentiality.String() + ", " + dataAsset.Integrity.String() + ", " + dataAsset.Availability.String() + ")\n" +
+		"data breach probability: " + dataAsset.IdentifiedDataBreachProbabilityStillAtRisk().Title()
+	return "  " + support.Hash(dataAsset.Id) + ` [ label=<<b>` + support.Encode(dataAsset.Title) + `</b>> penwidth="3.0" style="filled" fillcolor="` + color + `" color="` + color + `"` +
+		options.elementAttributes("data-asset", dataAsset.Id, tooltip) + "\n  ]; "
 }
 
 func RenderDataAssetDiagramGraphvizImage(dotFile *os.File, targetDir string, verbose *bool) { // TODO dedupe with other render...() method here
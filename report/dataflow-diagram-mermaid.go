@@ -0,0 +1,85 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/support"
+)
+
+const dataFlowDiagramFilenameMermaid = "data-flow-diagram.mmd"
+
+// WriteDataFlowDiagramMermaid writes the data-flow diagram as Mermaid flowchart (renderable in Markdown without graphviz)
+func WriteDataFlowDiagramMermaid(outputDirectory string, verbose *bool) {
+	if *verbose {
+		fmt.Println("Writing data flow diagram as mermaid")
+	}
+	direction := "TB"
+	if model.ParsedModelRoot.DiagramTweakLayoutLeftToRight {
+		direction = "LR"
+	}
+	writer := &mermaidWriter{}
+	writer.content.WriteString("flowchart " + direction + "\n")
+	walkDataFlowDiagram(writer)
+	writer.content.WriteString("  classDef outOfScope stroke-dasharray: 5 5\n")
+
+	file, err := os.Create(outputDirectory + "/" + dataFlowDiagramFilenameMermaid)
+	support.CheckErr(err)
+	defer file.Close()
+	_, err = file.WriteString(writer.content.String())
+	support.CheckErr(err)
+}
+
+type mermaidWriter struct {
+	content strings.Builder
+}
+
+func (what *mermaidWriter) beginTrustBoundary(trustBoundary model.TrustBoundary, depth int) {
+	what.content.WriteString(mermaidIndent(depth) + "subgraph tb_" + support.Hash(trustBoundary.Id) + " [" +
+		mermaidText(trustBoundary.Title+" ("+trustBoundary.Type.String()+")") + "]\n")
+}
+
+func (what *mermaidWriter) endTrustBoundary(trustBoundary model.TrustBoundary, depth int) {
+	what.content.WriteString(mermaidIndent(depth) + "end\n")
+}
+
+func (what *mermaidWriter) technicalAsset(technicalAsset model.TechnicalAsset, depth int) {
+	title := mermaidText(technicalAsset.Title)
+	var node string
+	switch technicalAsset.Type {
+	case model.ExternalEntity:
+		node = "[" + title + "]"
+	case model.Process:
+		node = "([" + title + "])"
+	case model.Datastore:
+		node = "[(" + title + ")]"
+	}
+	if technicalAsset.UsedAsClientByHuman {
+		node = "{{" + title + "}}"
+	}
+	class := ""
+	if technicalAsset.OutOfScope {
+		class = ":::outOfScope"
+	}
+	what.content.WriteString(mermaidIndent(depth) + "ta_" + support.Hash(technicalAsset.Id) + node + class + "\n")
+}
+
+func (what *mermaidWriter) communicationLink(source model.TechnicalAsset, communicationLink model.CommunicationLink) {
+	arrow := "-->"
+	if communicationLink.DetermineArrowLineStyle() != "solid" {
+		arrow = "-.->"
+	}
+	what.content.WriteString("  ta_" + support.Hash(source.Id) + " " + arrow + "|" +
+		mermaidText(communicationLink.Protocol.String()+", "+communicationLink.Authentication.String()) + "| ta_" + support.Hash(communicationLink.TargetId) + "\n")
+}
+
+func mermaidIndent(depth int) string {
+	return strings.Repeat("  ", depth+1)
+}
+
+// mermaidText quotes the text (using mermaid's entity codes for the characters not allowed within quotes)
+func mermaidText(text string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(text) + `"`
+}
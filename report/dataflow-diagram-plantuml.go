@@ -0,0 +1,93 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/support"
+)
+
+const dataFlowDiagramFilenamePlantUML = "data-flow-diagram.puml"
+
+// WriteDataFlowDiagramPlantUML writes the data-flow diagram as PlantUML deployment diagram (renderable in Markdown without graphviz)
+func WriteDataFlowDiagramPlantUML(outputDirectory string, verbose *bool) {
+	if *verbose {
+		fmt.Println("Writing data flow diagram as plantuml")
+	}
+	writer := &plantUMLWriter{}
+	writer.content.WriteString("@startuml\n")
+	if model.ParsedModelRoot.DiagramTweakLayoutLeftToRight {
+		writer.content.WriteString("left to right direction\n")
+	}
+	writer.content.WriteString("title " + plantUMLText(model.ParsedModelRoot.Title) + "\n")
+	walkDataFlowDiagram(writer)
+	writer.content.WriteString("@enduml\n")
+
+	file, err := os.Create(outputDirectory + "/" + dataFlowDiagramFilenamePlantUML)
+	support.CheckErr(err)
+	defer file.Close()
+	_, err = file.WriteString(writer.content.String())
+	support.CheckErr(err)
+}
+
+type plantUMLWriter struct {
+	content strings.Builder
+}
+
+func (what *plantUMLWriter) beginTrustBoundary(trustBoundary model.TrustBoundary, depth int) {
+	var element string
+	switch trustBoundary.Type {
+	case model.NetworkCloudProvider, model.NetworkCloudSecurityGroup:
+		element = "cloud"
+	case model.ExecutionEnvironment:
+		element = "frame"
+	default:
+		element = "rectangle"
+	}
+	what.content.WriteString(plantUMLIndent(depth) + element + ` "` + plantUMLText(trustBoundary.Title) + `" <<` + trustBoundary.Type.String() +
+		`>> as tb_` + support.Hash(trustBoundary.Id) + " #line.dashed {\n")
+}
+
+func (what *plantUMLWriter) endTrustBoundary(trustBoundary model.TrustBoundary, depth int) {
+	what.content.WriteString(plantUMLIndent(depth) + "}\n")
+}
+
+func (what *plantUMLWriter) technicalAsset(technicalAsset model.TechnicalAsset, depth int) {
+	var element string
+	switch technicalAsset.Type {
+	case model.ExternalEntity:
+		element = "rectangle"
+	case model.Process:
+		element = "usecase"
+	case model.Datastore:
+		element = "database"
+	}
+	if technicalAsset.UsedAsClientByHuman {
+		element = "actor"
+	}
+	style := ""
+	if technicalAsset.OutOfScope {
+		style = " #line.dashed"
+	}
+	what.content.WriteString(plantUMLIndent(depth) + element + ` "` + plantUMLText(technicalAsset.Title) + `" <<` + technicalAsset.Technology.String() +
+		`>> as ta_` + support.Hash(technicalAsset.Id) + style + "\n")
+}
+
+func (what *plantUMLWriter) communicationLink(source model.TechnicalAsset, communicationLink model.CommunicationLink) {
+	arrow := "-->"
+	if communicationLink.DetermineArrowLineStyle() != "solid" {
+		arrow = "..>"
+	}
+	what.content.WriteString("ta_" + support.Hash(source.Id) + " " + arrow + " ta_" + support.Hash(communicationLink.TargetId) + " : " +
+		plantUMLText(communicationLink.Protocol.String()+`\n`+communicationLink.Authentication.String()) + "\n")
+}
+
+func plantUMLIndent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+func plantUMLText(text string) string {
+	return strings.NewReplacer(`"`, "'", "\n", " ").Replace(text)
+}
@@ -19,8 +19,19 @@ import (
 const dataFlowDiagramFilenameDOT = "data-flow-diagram.gv"
 const dataFlowDiagramFilenamePNG = "data-flow-diagram.png"
 const graphvizDataFlowDiagramConversionCall = "render-data-flow-diagram.sh"
+const graphvizDataFlowDiagramSVGConversionCall = "render-data-flow-diagram-svg.sh"
 
-func RenderDataFlowDiagram(outputDirectory string, keepDiagramSourceFiles bool, diagramDPI *int, verbose *bool) {
+const dataFlowDiagramFilenameSVG = "data-flow-diagram.svg"
+const reportFilenameHTML = "report.html"
+
+// diagramOptions control the optional parts of the diagrams
+type diagramOptions struct {
+	interactive  bool   // ids, tooltips and links of the elements (only used when rendered as SVG)
+	linkDocument string // document the elements link into (empty when the diagram gets embedded into that document)
+	riskOverlay  bool   // color technical assets by their highest severity still at risk and badge them with their open risk count
+}
+
+func RenderDataFlowDiagram(outputDirectory string, keepDiagramSourceFiles bool, diagramDPI *int, riskOverlay bool, verbose *bool) {
 	gvFile := outputDirectory + "/" + dataFlowDiagramFilenameDOT
 	if !keepDiagramSourceFiles {
 		tmpFileGV, err := ioutil.TempFile(model.TempFolder, dataFlowDiagramFilenameDOT)
@@ -28,11 +39,94 @@ func RenderDataFlowDiagram(outputDirectory string, keepDiagramSourceFiles bool,
 		gvFile = tmpFileGV.Name()
 		defer os.Remove(gvFile)
 	}
-	dotFile := writeDataFlowDiagramGraphvizDOT(gvFile, *diagramDPI, verbose)
+	dotFile := writeDataFlowDiagramGraphvizDOT(gvFile, *diagramDPI, diagramOptions{riskOverlay: riskOverlay}, verbose)
 	renderDataFlowDiagramGraphvizImage(dotFile, outputDirectory, verbose)
 }
 
-func writeDataFlowDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbose *bool) *os.File {
+// RenderDataFlowDiagramSVG writes the data-flow diagram as SVG with its elements linking into the HTML report
+func RenderDataFlowDiagramSVG(outputDirectory string, riskOverlay bool, verbose *bool) {
+	svg := renderDataFlowDiagramSVG(diagramOptions{interactive: true, linkDocument: reportFilenameHTML, riskOverlay: riskOverlay}, verbose)
+	support.CheckErr(ioutil.WriteFile(outputDirectory+"/"+dataFlowDiagramFilenameSVG, svg, 0644))
+}
+
+// DataFlowDiagramSVG renders the data-flow diagram as SVG to be embedded into a document having anchors
+// for all of its elements (like #technical-asset-<id>)
+func DataFlowDiagramSVG(riskOverlay bool, verbose *bool) []byte {
+	return renderDataFlowDiagramSVG(diagramOptions{interactive: true, riskOverlay: riskOverlay}, verbose)
+}
+
+func renderDataFlowDiagramSVG(options diagramOptions, verbose *bool) []byte {
+	if *verbose {
+		fmt.Println("Rendering data flow diagram as SVG")
+	}
+	tmpFileDOT, err := ioutil.TempFile(model.TempFolder, "diagram-*-.gv")
+	support.CheckErr(err)
+	defer os.Remove(tmpFileDOT.Name())
+	writeDataFlowDiagramGraphvizDOT(tmpFileDOT.Name(), 72, options, verbose)
+	return renderGraphvizSVG(tmpFileDOT.Name(), graphvizDataFlowDiagramSVGConversionCall)
+}
+
+func renderGraphvizSVG(dotFilename string, conversionCall string) []byte {
+	tmpFileSVG, err := ioutil.TempFile(model.TempFolder, "diagram-*-.svg")
+	support.CheckErr(err)
+	defer os.Remove(tmpFileSVG.Name())
+	cmd := exec.Command(conversionCall, dotFilename, tmpFileSVG.Name())
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		panic(errors.New("graph rendering call failed with error:" + err.Error()))
+	}
+	svg, err := ioutil.ReadFile(tmpFileSVG.Name())
+	support.CheckErr(err)
+	return svg
+}
+
+// elementAttributes are the id, link and tooltip of a diagram element, where kind and id form the anchor within the linked document
+func (what diagramOptions) elementAttributes(kind, id, tooltip string) string {
+	if !what.interactive {
+		return ""
+	}
+	return ` id="` + dotEscape("diagram-"+kind+"-"+id) + `" href="` + dotEscape(what.linkDocument+"#"+kind+"-"+id) + `" target="_top" tooltip="` + dotEscape(tooltip) + `" `
+}
+
+func dotEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "").Replace(text)
+}
+
+func technicalAssetTooltip(technicalAsset model.TechnicalAsset) string {
+	tooltip := technicalAsset.Title + " (" + technicalAsset.Type.String() + ", " + technicalAsset.Technology.String() + ")"
+	if risks := model.ReduceToOnlyStillAtRisk(technicalAsset.GeneratedRisks()); len(risks) > 0 {
+		tooltip += "\n" + openRisksText(len(risks)) + ", highest severity: " + model.HighestSeverityStillAtRisk(risks).Title()
+	}
+	if len(technicalAsset.Description) > 0 {
+		tooltip += "\n" + technicalAsset.Description
+	}
+	return tooltip
+}
+
+// riskOverlayOf is the color of the highest severity still at risk and the number of open risks of a technical asset
+func riskOverlayOf(technicalAsset model.TechnicalAsset) (color string, openRisks int) {
+	risks := model.ReduceToOnlyStillAtRisk(technicalAsset.GeneratedRisks())
+	if technicalAsset.OutOfScope || len(risks) == 0 {
+		return "", 0
+	}
+	return severityColor(model.HighestSeverityStillAtRisk(risks)), len(risks)
+}
+
+func riskOverlayBadge(color string, openRisks int) string {
+	return `<table border="0" cellborder="0" cellpadding="2" bgcolor="` + color + `"><tr><td><font point-size="15" color="white"><b>` +
+		openRisksText(openRisks) + `</b></font></td></tr></table>`
+}
+
+func openRisksText(openRisks int) string {
+	if openRisks == 1 {
+		return "1 open risk"
+	}
+	return strconv.Itoa(openRisks) + " open risks"
+}
+
+func writeDataFlowDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, options diagramOptions, verbose *bool) *os.File {
 	if *verbose {
 		fmt.Println("Writing data flow diagram input")
 	}
@@ -160,6 +254,7 @@ func writeDataFlowDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbose
       forcelabels=true
       outputorder="nodesfirst"
 	  margin="50.0"
+      ` + options.elementAttributes("trust-boundary", trustBoundary.Id, trustBoundary.Title+" ("+trustBoundary.Type.String()+")\n"+trustBoundary.Description) + `
     ];`)
 			snippet.WriteString("\n")
 			keys := trustBoundary.TechnicalAssetsInside
@@ -220,7 +315,7 @@ func writeDataFlowDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbose
 	}
 	sort.Sort(model.ByOrderAndIdSort(techAssets))
 	for _, technicalAsset := range techAssets {
-		dotContent.WriteString(MakeTechAssetNode(technicalAsset, false))
+		dotContent.WriteString(makeTechAssetNode(technicalAsset, false, options))
 		dotContent.WriteString("\n")
 	}
 
@@ -257,6 +352,9 @@ func writeDataFlowDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbose
 			if !model.ParsedModelRoot.DiagramTweakSuppressEdgeLabels {
 				dotContent.WriteString(` xlabel="` + support.Encode(dataFlow.Protocol.String()) + `" fontcolor="` + dataFlow.DetermineLabelColor() + `" `)
 			}
+			dotContent.WriteString(options.elementAttributes("communication-link", dataFlow.Id, dataFlow.Title+": "+technicalAsset.Title+" -> "+
+				model.ParsedModelRoot.TechnicalAssets[targetId].Title+"\n"+dataFlow.Protocol.String()+", "+dataFlow.Authentication.String()+" authentication, "+
+				dataFlow.Authorization.String()+" authorization"))
 			dotContent.WriteString(" ];\n")
 		}
 	}
@@ -277,7 +375,72 @@ func writeDataFlowDiagramGraphvizDOT(diagramFilenameDOT string, dpi int, verbose
 	return file
 }
 
+// dataFlowDiagramWalker gets called for the same structures as written into the graphviz DOT of the data-flow diagram,
+// where the technical assets (and nested trust boundaries) inside a trust boundary are enclosed by its begin and end calls
+type dataFlowDiagramWalker interface {
+	beginTrustBoundary(trustBoundary model.TrustBoundary, depth int)
+	endTrustBoundary(trustBoundary model.TrustBoundary, depth int)
+	technicalAsset(technicalAsset model.TechnicalAsset, depth int)
+	communicationLink(source model.TechnicalAsset, communicationLink model.CommunicationLink)
+}
+
+func walkDataFlowDiagram(walker dataFlowDiagramWalker) {
+	techAssets := make([]model.TechnicalAsset, 0)
+	for _, techAsset := range model.ParsedModelRoot.TechnicalAssets {
+		techAssets = append(techAssets, techAsset)
+	}
+	sort.Sort(model.ByOrderAndIdSort(techAssets))
+
+	var walkTrustBoundary func(trustBoundary model.TrustBoundary, depth int)
+	walkTrustBoundary = func(trustBoundary model.TrustBoundary, depth int) {
+		if len(trustBoundary.TechnicalAssetsInside) == 0 && len(trustBoundary.TrustBoundariesNested) == 0 {
+			return
+		}
+		walker.beginTrustBoundary(trustBoundary, depth)
+		for _, technicalAsset := range techAssets {
+			if model.Contains(trustBoundary.TechnicalAssetsInside, technicalAsset.Id) {
+				walker.technicalAsset(technicalAsset, depth+1)
+			}
+		}
+		nested := append([]string(nil), trustBoundary.TrustBoundariesNested...)
+		sort.Strings(nested)
+		for _, id := range nested {
+			walkTrustBoundary(model.ParsedModelRoot.TrustBoundaries[id], depth+1)
+		}
+		walker.endTrustBoundary(trustBoundary, depth)
+	}
+	keys := make([]string, 0)
+	for k, trustBoundary := range model.ParsedModelRoot.TrustBoundaries {
+		if len(trustBoundary.ParentTrustBoundaryID()) == 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		walkTrustBoundary(model.ParsedModelRoot.TrustBoundaries[key], 0)
+	}
+	for _, technicalAsset := range techAssets {
+		if len(technicalAsset.GetTrustBoundaryId()) == 0 {
+			walker.technicalAsset(technicalAsset, 0)
+		}
+	}
+	for _, technicalAsset := range techAssets {
+		for _, communicationLink := range technicalAsset.CommunicationLinksSorted() {
+			walker.communicationLink(technicalAsset, communicationLink)
+		}
+	}
+}
+
 func MakeTechAssetNode(technicalAsset model.TechnicalAsset, simplified bool) string {
+	return makeTechAssetNode(technicalAsset, simplified, diagramOptions{})
+}
+
+func makeTechAssetNode(technicalAsset model.TechnicalAsset, simplified bool, options diagramOptions) string {
+	attributes := options.elementAttributes("technical-asset", technicalAsset.Id, technicalAssetTooltip(technicalAsset))
+	overlayColor, openRisks := "", 0
+	if options.riskOverlay {
+		overlayColor, openRisks = riskOverlayOf(technicalAsset)
+	}
 	if simplified {
 		color := colors.RgbHexColorOutOfScope()
 		if !technicalAsset.OutOfScope {
@@ -300,8 +463,12 @@ func MakeTechAssetNode(technicalAsset model.TechnicalAsset, simplified bool) str
 				color = "#444444" // since black is too dark here as fill color
 			}
 		}
+		badge := ""
+		if openRisks > 0 { // the node itself is already colored by its highest severity
+			badge = `<br/><font point-size="15">` + openRisksText(openRisks) + `</font>`
+		}
 		return "  " + support.Hash(technicalAsset.Id) + ` [ shape="box" style="filled" fillcolor="` + color + `" 
-				label=<<b>` + support.Encode(technicalAsset.Title) + `</b>> penwidth="3.0" color="` + color + `" ];
+				label=<<b>` + support.Encode(technicalAsset.Title) + `</b>` + badge + `> penwidth="3.0" color="` + color + `" ` + attributes + `];
 				`
 	} else {
 		var shape, title string
@@ -339,11 +506,17 @@ func MakeTechAssetNode(technicalAsset model.TechnicalAsset, simplified bool) str
 			compartmentBorder = "1"
 		}
 
+		fillColor, badge := technicalAsset.DetermineShapeFillColor(), ""
+		if openRisks > 0 {
+			fillColor = colors.LightenHexColor(overlayColor, 80)
+			badge = `<tr><td>` + riskOverlayBadge(overlayColor, openRisks) + `</td></tr>`
+		}
+
 		return "  " + support.Hash(technicalAsset.Id) + ` [
-	label=<<table border="0" cellborder="` + compartmentBorder + `" cellpadding="2" cellspacing="0"><tr><td><font point-size="15" color="` + colors.DarkBlue + `">` + lineBreak + technicalAsset.Technology.String() + `</font><br/><font point-size="15" color="` + colors.LightGray + `">` + technicalAsset.Size.String() + `</font></td></tr><tr><td><b><font color="` + technicalAsset.DetermineLabelColor() + `">` + support.Encode(title) + `</font></b><br/></td></tr><tr><td>` + attackerAttractivenessLabel + `</td></tr></table>>
-	shape=` + shape + ` style="` + technicalAsset.DetermineShapeBorderLineStyle() + `,` + technicalAsset.DetermineShapeStyle() + `" penwidth="` + technicalAsset.DetermineShapeBorderPenWidth() + `" fillcolor="` + technicalAsset.DetermineShapeFillColor() + `" 
+	label=<<table border="0" cellborder="` + compartmentBorder + `" cellpadding="2" cellspacing="0"><tr><td><font point-size="15" color="` + colors.DarkBlue + `">` + lineBreak + technicalAsset.Technology.String() + `</font><br/><font point-size="15" color="` + colors.LightGray + `">` + technicalAsset.Size.String() + `</font></td></tr><tr><td><b><font color="` + technicalAsset.DetermineLabelColor() + `">` + support.Encode(title) + `</font></b><br/></td></tr><tr><td>` + attackerAttractivenessLabel + `</td></tr>` + badge + `</table>>
+	shape=` + shape + ` style="` + technicalAsset.DetermineShapeBorderLineStyle() + `,` + technicalAsset.DetermineShapeStyle() + `" penwidth="` + technicalAsset.DetermineShapeBorderPenWidth() + `" fillcolor="` + fillColor + `" 
 	peripheries=` + strconv.Itoa(technicalAsset.DetermineShapePeripheries()) + `
-	color="` + technicalAsset.DetermineShapeBorderColor() + "\"\n  ]; "
+	color="` + technicalAsset.DetermineShapeBorderColor() + `"` + attributes + "\n  ]; "
 	}
 }
 
@@ -354,8 +527,8 @@ func makeDiagramInvisibleConnectionsTweaks() string {
 		for _, invisibleConnections := range model.ParsedModelRoot.DiagramTweakInvisibleConnectionsBetweenAssets {
 			assetIDs := strings.Split(invisibleConnections, ":")
 			if len(assetIDs) == 2 {
-				model.CheckTechnicalAssetExists(assetIDs[0], "diagram tweak connections", true)
-				model.CheckTechnicalAssetExists(assetIDs[1], "diagram tweak connections", true)
+				support.CheckErr(model.CheckTechnicalAssetExists(assetIDs[0], "diagram tweak connections", true))
+				support.CheckErr(model.CheckTechnicalAssetExists(assetIDs[1], "diagram tweak connections", true))
 				tweak += "\n" + support.Hash(assetIDs[0]) + " -> " + support.Hash(assetIDs[1]) + " [style=invis]; \n"
 			}
 		}
@@ -372,7 +545,7 @@ func makeDiagramSameRankNodeTweaks() string {
 			if len(assetIDs) > 0 {
 				tweak += "{ rank=same; "
 				for _, id := range assetIDs {
-					model.CheckTechnicalAssetExists(id, "diagram tweak same-rank", true)
+					support.CheckErr(model.CheckTechnicalAssetExists(id, "diagram tweak same-rank", true))
 					if len(model.ParsedModelRoot.TechnicalAssets[id].GetTrustBoundaryId()) > 0 {
 						panic(errors.New("technical assets (referenced in same rank diagram tweak) are inside trust boundaries: " +
 							fmt.Sprintf("%v", model.ParsedModelRoot.DiagramTweakSameRankAssets)))
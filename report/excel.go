@@ -72,6 +72,8 @@ func WriteRisksExcelToFile(filename string) {
 	err = excel.SetCellValue(sheetName, "R1", "Date")
 	err = excel.SetCellValue(sheetName, "S1", "Checked by")
 	err = excel.SetCellValue(sheetName, "T1", "Ticket")
+	err = excel.SetCellValue(sheetName, "U1", "Review by")
+	err = excel.SetCellValue(sheetName, "V1", "Expires")
 
 	err = excel.SetColWidth(sheetName, "A", "A", 12)
 	err = excel.SetColWidth(sheetName, "B", "B", 15)
@@ -93,6 +95,8 @@ func WriteRisksExcelToFile(filename string) {
 	err = excel.SetColWidth(sheetName, "R", "R", 18)
 	err = excel.SetColWidth(sheetName, "S", "S", 20)
 	err = excel.SetColWidth(sheetName, "T", "T", 20)
+	err = excel.SetColWidth(sheetName, "U", "U", 18)
+	err = excel.SetColWidth(sheetName, "V", "V", 18)
 	support.CheckErr(err)
 
 	styleSeverityCriticalBold, err := excel.NewStyle(`{"font":{"color":"` + colors.RgbHexColorCriticalRisk() + `","size":12,"bold":true}}`)
@@ -145,8 +149,8 @@ func WriteRisksExcelToFile(filename string) {
 			err = excel.SetCellValue(sheetName, "N"+strconv.Itoa(excelRow), risk.Category.Check)
 			err = excel.SetCellValue(sheetName, "O"+strconv.Itoa(excelRow), risk.SyntheticId)
 			err = excel.SetCellValue(sheetName, "P"+strconv.Itoa(excelRow), riskTrackingStatus.Title())
+			riskTracking := risk.GetRiskTracking()
 			if riskTrackingStatus != model.Unchecked {
-				riskTracking := risk.GetRiskTracking()
 				err = excel.SetCellValue(sheetName, "Q"+strconv.Itoa(excelRow), riskTracking.Justification)
 				if !riskTracking.Date.IsZero() {
 					err = excel.SetCellValue(sheetName, "R"+strconv.Itoa(excelRow), riskTracking.Date.Format("2006-01-02"))
@@ -154,6 +158,12 @@ func WriteRisksExcelToFile(filename string) {
 				err = excel.SetCellValue(sheetName, "S"+strconv.Itoa(excelRow), riskTracking.CheckedBy)
 				err = excel.SetCellValue(sheetName, "T"+strconv.Itoa(excelRow), riskTracking.Ticket)
 			}
+			if !riskTracking.ReviewBy.IsZero() {
+				err = excel.SetCellValue(sheetName, "U"+strconv.Itoa(excelRow), riskTracking.ReviewBy.Format("2006-01-02"))
+			}
+			if !riskTracking.Expires.IsZero() {
+				err = excel.SetCellValue(sheetName, "V"+strconv.Itoa(excelRow), riskTracking.Expires.Format("2006-01-02"))
+			}
 			// styles
 			if riskTrackingStatus.IsStillAtRisk() {
 				switch risk.Severity {
@@ -205,13 +215,22 @@ func WriteRisksExcelToFile(filename string) {
 			err = excel.SetCellStyle(sheetName, "R"+strconv.Itoa(excelRow), "R"+strconv.Itoa(excelRow), styleBlackCenter)
 			err = excel.SetCellStyle(sheetName, "S"+strconv.Itoa(excelRow), "S"+strconv.Itoa(excelRow), styleBlackCenter)
 			err = excel.SetCellStyle(sheetName, "T"+strconv.Itoa(excelRow), "T"+strconv.Itoa(excelRow), styleBlackLeft)
+			styleFromReviewBy, styleFromExpires := styleBlackCenter, styleBlackCenter
+			if risk.RiskReviewOverdue {
+				styleFromReviewBy = styleRedCenter
+			}
+			if risk.RiskTrackingExpired {
+				styleFromExpires = styleRedCenter
+			}
+			err = excel.SetCellStyle(sheetName, "U"+strconv.Itoa(excelRow), "U"+strconv.Itoa(excelRow), styleFromReviewBy)
+			err = excel.SetCellStyle(sheetName, "V"+strconv.Itoa(excelRow), "V"+strconv.Itoa(excelRow), styleFromExpires)
 			support.CheckErr(err)
 		}
 	}
 
 	//styleHead, err := excel.NewStyle(`{"font":{"bold":true,"italic":false,"size":14,"color":"#000000"},"fill":{"type":"pattern","color":["#eeeeee"],"pattern":1}}`)
 	styleHeadCenter, err := excel.NewStyle(`{"font":{"bold":true,"italic":false,"size":14,"color":"#000000"},"fill":{"type":"pattern","color":["#eeeeee"],"pattern":1},"alignment":{"horizontal":"center","shrink_to_fit":true,"wrap_text":false}}`)
-	err = excel.SetCellStyle(sheetName, "A1", "T1", styleHeadCenter)
+	err = excel.SetCellStyle(sheetName, "A1", "V1", styleHeadCenter)
 	support.CheckErr(err)
 
 	excel.SetActiveSheet(sheetIndex)
@@ -0,0 +1,419 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otyg/threagile/colors"
+	"github.com/otyg/threagile/model"
+	"github.com/otyg/threagile/support"
+)
+
+//go:embed template/report.html
+var htmlReportTemplate string
+
+type htmlReport struct {
+	Title, Author, AuthorHomepage, Date        string
+	ManagementSummaryComment                   string
+	BusinessOverview, TechnicalOverview        string
+	TotalRisks, TotalCategories, StillAtRisk   int
+	Severities, Statuses, Functions, Strides   []htmlCount
+	DataFlowDiagram                            template.HTML
+	RiskCategories                             []htmlRiskCategory
+	TechnicalAssets                            []htmlElement
+	DataAssets, TrustBoundaries, SharedRuntime []htmlElement
+	RiskRules                                  []htmlRiskRule
+	ThreagileVersion, BuildTimestamp           string
+	ExecutionTimestamp, ModelFilename          string
+	ModelHash                                  string
+}
+
+// htmlCount is the number of risks (and the ones still at risk) having a certain value, used for the summaries and the filters
+type htmlCount struct {
+	Value, Title, Color string
+	Count, StillAtRisk  int
+}
+
+type htmlRiskCategory struct {
+	Category                        model.RiskCategory
+	ASVS, CheatSheet, TestingGuide  htmlLink
+	HighestSeverity, SeverityColor  string
+	Risks                           []htmlRisk
+	CountStillAtRisk                int
+	IsIndividual, IsModelFailure    bool
+	FunctionTitle, STRIDETitle, CWE string
+}
+
+type htmlLink struct {
+	Text, Url string
+}
+
+type htmlRisk struct {
+	SyntheticId                                                    string
+	Title                                                          template.HTML
+	Severity, SeverityColor, Status, StatusColor, Function, STRIDE string
+	Likelihood, Impact, DataBreachProbability                      string
+	MostRelevantTechnicalAsset, MostRelevantTechnicalAssetTitle    string
+	Justification, Ticket, CheckedBy, Date                         string
+	StillAtRisk                                                    bool
+}
+
+type htmlElement struct {
+	Id, Title, Description string
+	Attributes             []htmlAttribute
+	Risks                  []htmlRisk
+	OutOfScope             bool
+}
+
+type htmlAttribute struct {
+	Id, Name, Value string     // the id is the anchor of the attribute, if it represents an element itself (like communication links)
+	Links           []htmlLink // links to other elements of the report (instead of the value)
+}
+
+type htmlRiskRule struct {
+	Id, Title, STRIDE, Description, Detection, Rating string
+	Individual, Skipped                               bool
+}
+
+// WriteReportHTML writes the report as a single self-contained HTML file (including the data-flow diagram as SVG)
+func WriteReportHTML(reportFilename string, dataFlowDiagramSVG []byte, modelFilename string, skipRiskRules string,
+	buildTimestamp string, modelHash string, pluginRiskRules map[string]model.RiskRule) {
+	report := htmlReport{
+		Title:                    model.ParsedModelRoot.Title,
+		Author:                   model.ParsedModelRoot.Author.Name,
+		AuthorHomepage:           model.ParsedModelRoot.Author.Homepage,
+		Date:                     model.ParsedModelRoot.Date.Format("2006-01-02"),
+		ManagementSummaryComment: model.ParsedModelRoot.ManagementSummaryComment,
+		BusinessOverview:         model.ParsedModelRoot.BusinessOverview.Description,
+		TechnicalOverview:        model.ParsedModelRoot.TechnicalOverview.Description,
+		TotalRisks:               model.TotalRiskCount(),
+		TotalCategories:          len(model.GeneratedRisksByCategory),
+		StillAtRisk:              len(model.FilteredByStillAtRisk()),
+		ThreagileVersion:         model.ThreagileVersion,
+		BuildTimestamp:           buildTimestamp,
+		ExecutionTimestamp:       time.Now().Format("20060102150405"),
+		ModelFilename:            modelFilename,
+		ModelHash:                modelHash,
+	}
+	if start := bytes.Index(dataFlowDiagramSVG, []byte("<svg")); start >= 0 { // inline without the XML prolog and doctype
+		report.DataFlowDiagram = template.HTML(dataFlowDiagramSVG[start:]) // as rendered by graphviz (with the model titles escaped)
+	}
+	allRisks := model.AllRisks()
+	for _, severity := range model.RiskSeverityValues() {
+		report.Severities = append(report.Severities, countRisks(allRisks, severity.String(), severity.(model.RiskSeverity).Title(), severityColor(severity.(model.RiskSeverity)),
+			func(risk model.Risk) bool { return risk.Severity == severity }))
+	}
+	reverseCounts(report.Severities) // most severe first
+	for _, status := range model.RiskStatusValues() {
+		report.Statuses = append(report.Statuses, countRisks(allRisks, status.String(), status.(model.RiskStatus).Title(), statusColor(status.(model.RiskStatus)),
+			func(risk model.Risk) bool { return risk.GetRiskTrackingStatusDefaultingUnchecked() == status }))
+	}
+	for _, function := range model.RiskFunctionValues() {
+		report.Functions = append(report.Functions, countRisks(allRisks, function.String(), function.(model.RiskFunction).Title(), "",
+			func(risk model.Risk) bool { return risk.Category.Function == function }))
+	}
+	for _, stride := range model.STRIDEValues() {
+		report.Strides = append(report.Strides, countRisks(allRisks, stride.String(), stride.(model.STRIDE).Title(), "",
+			func(risk model.Risk) bool { return risk.Category.STRIDE == stride }))
+	}
+
+	for _, category := range model.SortedRiskCategories() {
+		risks := model.SortedRisksOfCategory(category)
+		htmlCategory := htmlRiskCategory{
+			Category:         category,
+			ASVS:             linkOf(category.ASVS),
+			CheatSheet:       linkOf(category.CheatSheet),
+			TestingGuide:     linkOf(category.TestingGuide),
+			HighestSeverity:  model.HighestSeverityStillAtRisk(risks).String(),
+			SeverityColor:    severityColor(model.HighestSeverityStillAtRisk(risks)),
+			CountStillAtRisk: len(model.ReduceToOnlyStillAtRisk(risks)),
+			FunctionTitle:    category.Function.Title(),
+			STRIDETitle:      category.STRIDE.Title(),
+			IsModelFailure:   category.ModelFailurePossibleReason,
+		}
+		_, htmlCategory.IsIndividual = model.ParsedModelRoot.IndividualRiskCategories[category.Id]
+		if category.CWE > 0 {
+			htmlCategory.CWE = "CWE-" + strconv.Itoa(category.CWE)
+		}
+		for _, risk := range risks {
+			htmlCategory.Risks = append(htmlCategory.Risks, htmlRiskOf(risk))
+		}
+		report.RiskCategories = append(report.RiskCategories, htmlCategory)
+	}
+
+	for _, technicalAsset := range model.SortedTechnicalAssetsByTitle() {
+		report.TechnicalAssets = append(report.TechnicalAssets, htmlTechnicalAssetOf(technicalAsset))
+	}
+	for _, dataAsset := range model.SortedDataAssetsByTitle() {
+		report.DataAssets = append(report.DataAssets, htmlDataAssetOf(dataAsset))
+	}
+	for _, trustBoundary := range model.SortedTrustBoundariesByTitle() {
+		report.TrustBoundaries = append(report.TrustBoundaries, htmlTrustBoundaryOf(trustBoundary))
+	}
+	for _, sharedRuntime := range model.SortedSharedRuntimesByTitle() {
+		report.SharedRuntime = append(report.SharedRuntime, htmlSharedRuntimeOf(sharedRuntime))
+	}
+	report.RiskRules = htmlRiskRulesOf(skipRiskRules, pluginRiskRules)
+
+	reportTemplate := template.Must(template.New("report").Funcs(template.FuncMap{"basicHTML": basicHTML}).Parse(htmlReportTemplate))
+	file, err := os.Create(reportFilename)
+	support.CheckErr(err)
+	defer file.Close()
+	support.CheckErr(reportTemplate.Execute(file, report))
+}
+
+func countRisks(risks []model.Risk, value, title, color string, matches func(model.Risk) bool) htmlCount {
+	result := htmlCount{Value: value, Title: title, Color: color}
+	for _, risk := range risks {
+		if matches(risk) {
+			result.Count++
+			if risk.GetRiskTrackingStatusDefaultingUnchecked().IsStillAtRisk() {
+				result.StillAtRisk++
+			}
+		}
+	}
+	return result
+}
+
+func reverseCounts(counts []htmlCount) {
+	for i, j := 0, len(counts)-1; i < j; i, j = i+1, j-1 {
+		counts[i], counts[j] = counts[j], counts[i]
+	}
+}
+
+func htmlRiskOf(risk model.Risk) htmlRisk {
+	status := risk.GetRiskTrackingStatusDefaultingUnchecked()
+	tracking := risk.GetRiskTracking()
+	result := htmlRisk{
+		SyntheticId:                risk.SyntheticId,
+		Title:                      basicHTML(risk.Title),
+		Severity:                   risk.Severity.String(),
+		SeverityColor:              severityColor(risk.Severity),
+		Status:                     status.String(),
+		StatusColor:                statusColor(status),
+		Function:                   risk.Category.Function.String(),
+		STRIDE:                     risk.Category.STRIDE.String(),
+		Likelihood:                 risk.ExploitationLikelihood.Title(),
+		Impact:                     risk.ExploitationImpact.Title(),
+		DataBreachProbability:      risk.DataBreachProbability.Title(),
+		MostRelevantTechnicalAsset: risk.MostRelevantTechnicalAssetId,
+		Justification:              tracking.Justification,
+		Ticket:                     tracking.Ticket,
+		CheckedBy:                  tracking.CheckedBy,
+		StillAtRisk:                status.IsStillAtRisk(),
+	}
+	if technicalAsset, exists := model.ParsedModelRoot.TechnicalAssets[risk.MostRelevantTechnicalAssetId]; exists {
+		result.MostRelevantTechnicalAssetTitle = technicalAsset.Title
+	}
+	if !tracking.Date.IsZero() {
+		result.Date = tracking.Date.Format("2006-01-02")
+	}
+	return result
+}
+
+// risk titles and category texts contain some basic markup (as rendered in the PDF too), everything else gets escaped
+func basicHTML(text string) template.HTML {
+	escaped := template.HTMLEscapeString(text)
+	for _, tag := range []string{"b", "/b", "i", "/i", "u", "/u", "br"} {
+		escaped = strings.ReplaceAll(escaped, "&lt;"+tag+"&gt;", "<"+tag+">")
+	}
+	return template.HTML(escaped)
+}
+
+func linkOf(link string) htmlLink {
+	if len(link) == 0 {
+		return htmlLink{}
+	}
+	return htmlLink{Text: support.GetLinkText(link), Url: support.GetLinkUrl(link)}
+}
+
+func linksTo(prefix string, ids []string, titleOf func(id string) string) []htmlLink {
+	result := make([]htmlLink, 0, len(ids))
+	sortedIds := append([]string(nil), ids...)
+	sort.Strings(sortedIds)
+	for _, id := range sortedIds {
+		result = append(result, htmlLink{Text: titleOf(id), Url: "#" + prefix + id})
+	}
+	return result
+}
+
+func technicalAssetTitle(id string) string {
+	return model.ParsedModelRoot.TechnicalAssets[id].Title
+}
+
+func dataAssetTitle(id string) string {
+	return model.ParsedModelRoot.DataAssets[id].Title
+}
+
+func trustBoundaryTitle(id string) string {
+	return model.ParsedModelRoot.TrustBoundaries[id].Title
+}
+
+func htmlTechnicalAssetOf(technicalAsset model.TechnicalAsset) htmlElement {
+	attributes := []htmlAttribute{
+		{Name: "ID", Value: technicalAsset.Id},
+		{Name: "Type", Value: technicalAsset.Type.String()},
+		{Name: "Usage", Value: technicalAsset.Usage.String()},
+		{Name: "RAA", Value: fmt.Sprintf("%.0f %%", technicalAsset.RAA)},
+		{Name: "Size", Value: technicalAsset.Size.String()},
+		{Name: "Technology", Value: technicalAsset.Technology.String()},
+		{Name: "Tags", Value: strings.Join(technicalAsset.Tags, ", ")},
+		{Name: "Internet", Value: yesNo(technicalAsset.Internet)},
+		{Name: "Machine", Value: technicalAsset.Machine.String()},
+		{Name: "Encryption", Value: technicalAsset.Encryption.String()},
+		{Name: "Multi-Tenant", Value: yesNo(technicalAsset.MultiTenant)},
+		{Name: "Redundant", Value: yesNo(technicalAsset.Redundant)},
+		{Name: "Custom-Developed", Value: yesNo(technicalAsset.CustomDevelopedParts)},
+		{Name: "Client by Human", Value: yesNo(technicalAsset.UsedAsClientByHuman)},
+		{Name: "Owner", Value: technicalAsset.Owner},
+		{Name: "Confidentiality", Value: technicalAsset.Confidentiality.String() + " (highest processed: " + technicalAsset.HighestConfidentiality().String() + ")"},
+		{Name: "Integrity", Value: technicalAsset.Integrity.String() + " (highest processed: " + technicalAsset.HighestIntegrity().String() + ")"},
+		{Name: "Availability", Value: technicalAsset.Availability.String() + " (highest processed: " + technicalAsset.HighestAvailability().String() + ")"},
+		{Name: "CIA Justification", Value: technicalAsset.JustificationCiaRating},
+		{Name: "Data Processed", Links: linksTo("data-asset-", technicalAsset.DataAssetsProcessed, dataAssetTitle)},
+		{Name: "Data Stored", Links: linksTo("data-asset-", technicalAsset.DataAssetsStored, dataAssetTitle)},
+	}
+	if trustBoundaryId := technicalAsset.GetTrustBoundaryId(); len(trustBoundaryId) > 0 {
+		attributes = append(attributes, htmlAttribute{Name: "Trust Boundary", Links: linksTo("trust-boundary-", []string{trustBoundaryId}, trustBoundaryTitle)})
+	}
+	for _, communicationLink := range technicalAsset.CommunicationLinksSorted() {
+		attributes = append(attributes, htmlAttribute{Id: "communication-link-" + communicationLink.Id, Name: "Outgoing Link", Value: communicationLink.Title + " (" + communicationLink.Protocol.String() + ", " +
+			communicationLink.Authentication.String() + " authentication) to",
+			Links: linksTo("technical-asset-", []string{communicationLink.TargetId}, technicalAssetTitle)})
+	}
+	for _, communicationLink := range model.IncomingTechnicalCommunicationLinksMappedByTargetId[technicalAsset.Id] {
+		attributes = append(attributes, htmlAttribute{Name: "Incoming Link", Value: communicationLink.Title + " (" + communicationLink.Protocol.String() + ") from",
+			Links: linksTo("technical-asset-", []string{communicationLink.SourceId}, technicalAssetTitle)})
+	}
+	if technicalAsset.OutOfScope {
+		attributes = append(attributes, htmlAttribute{Name: "Out of Scope", Value: technicalAsset.JustificationOutOfScope})
+	}
+	result := htmlElement{Id: "technical-asset-" + technicalAsset.Id, Title: technicalAsset.Title, Description: technicalAsset.Description,
+		Attributes: attributes, OutOfScope: technicalAsset.OutOfScope}
+	risks := technicalAsset.GeneratedRisks()
+	sort.Sort(model.ByRiskSeveritySort(risks))
+	for _, risk := range risks {
+		result.Risks = append(result.Risks, htmlRiskOf(risk))
+	}
+	return result
+}
+
+func htmlDataAssetOf(dataAsset model.DataAsset) htmlElement {
+	processedBy, storedBy := make([]string, 0), make([]string, 0)
+	for _, technicalAsset := range dataAsset.ProcessedByTechnicalAssetsSorted() {
+		processedBy = append(processedBy, technicalAsset.Id)
+	}
+	for _, technicalAsset := range dataAsset.StoredByTechnicalAssetsSorted() {
+		storedBy = append(storedBy, technicalAsset.Id)
+	}
+	return htmlElement{Id: "data-asset-" + dataAsset.Id, Title: dataAsset.Title, Description: dataAsset.Description,
+		Attributes: []htmlAttribute{
+			{Name: "ID", Value: dataAsset.Id},
+			{Name: "Usage", Value: dataAsset.Usage.String()},
+			{Name: "Quantity", Value: dataAsset.Quantity.String()},
+			{Name: "Tags", Value: strings.Join(dataAsset.Tags, ", ")},
+			{Name: "Origin", Value: dataAsset.Origin},
+			{Name: "Owner", Value: dataAsset.Owner},
+			{Name: "Confidentiality", Value: dataAsset.Confidentiality.String()},
+			{Name: "Integrity", Value: dataAsset.Integrity.String()},
+			{Name: "Availability", Value: dataAsset.Availability.String()},
+			{Name: "CIA Justification", Value: dataAsset.JustificationCiaRating},
+			{Name: "Data Breach", Value: dataAsset.IdentifiedDataBreachProbabilityStillAtRisk().Title()},
+			{Name: "Processed by", Links: linksTo("technical-asset-", processedBy, technicalAssetTitle)},
+			{Name: "Stored by", Links: linksTo("technical-asset-", storedBy, technicalAssetTitle)},
+		}}
+}
+
+func htmlTrustBoundaryOf(trustBoundary model.TrustBoundary) htmlElement {
+	attributes := []htmlAttribute{
+		{Name: "ID", Value: trustBoundary.Id},
+		{Name: "Type", Value: trustBoundary.Type.String()},
+		{Name: "Tags", Value: strings.Join(trustBoundary.Tags, ", ")},
+		{Name: "Assets inside", Links: linksTo("technical-asset-", trustBoundary.TechnicalAssetsInside, technicalAssetTitle)},
+		{Name: "Boundaries nested", Links: linksTo("trust-boundary-", trustBoundary.TrustBoundariesNested, trustBoundaryTitle)},
+	}
+	if parentId := trustBoundary.ParentTrustBoundaryID(); len(parentId) > 0 {
+		attributes = append(attributes, htmlAttribute{Name: "Parent Boundary", Links: linksTo("trust-boundary-", []string{parentId}, trustBoundaryTitle)})
+	}
+	return htmlElement{Id: "trust-boundary-" + trustBoundary.Id, Title: trustBoundary.Title, Description: trustBoundary.Description, Attributes: attributes}
+}
+
+func htmlSharedRuntimeOf(sharedRuntime model.SharedRuntime) htmlElement {
+	return htmlElement{Id: "shared-runtime-" + sharedRuntime.Id, Title: sharedRuntime.Title, Description: sharedRuntime.Description,
+		Attributes: []htmlAttribute{
+			{Name: "ID", Value: sharedRuntime.Id},
+			{Name: "Tags", Value: strings.Join(sharedRuntime.Tags, ", ")},
+			{Name: "Assets running", Links: linksTo("technical-asset-", sharedRuntime.TechnicalAssetsRunning, technicalAssetTitle)},
+		}}
+}
+
+func htmlRiskRulesOf(skipRiskRules string, pluginRiskRules map[string]model.RiskRule) []htmlRiskRule {
+	result := make([]htmlRiskRule, 0)
+	for _, key := range model.SortedKeysOfIndividualRiskCategories() {
+		category := model.ParsedModelRoot.IndividualRiskCategories[key]
+		result = append(result, htmlRiskRule{Id: category.Id, Title: category.Title, STRIDE: category.STRIDE.Title(), Description: firstParagraph(category.Description),
+			Detection: category.DetectionLogic, Rating: category.RiskAssessment, Individual: true})
+	}
+	skippedRules := strings.Split(skipRiskRules, ",")
+	ids := make([]string, 0, len(pluginRiskRules))
+	for id := range pluginRiskRules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		category := pluginRiskRules[id].Category()
+		result = append(result, htmlRiskRule{Id: category.Id, Title: category.Title, STRIDE: category.STRIDE.Title(), Description: firstParagraph(category.Description),
+			Detection: category.DetectionLogic, Rating: category.RiskAssessment, Skipped: model.Contains(skippedRules, category.Id)})
+	}
+	return result
+}
+
+func severityColor(severity model.RiskSeverity) string {
+	switch severity {
+	case model.CriticalSeverity:
+		return colors.RgbHexColorCriticalRisk()
+	case model.HighSeverity:
+		return colors.RgbHexColorHighRisk()
+	case model.ElevatedSeverity:
+		return colors.RgbHexColorElevatedRisk()
+	case model.MediumSeverity:
+		return colors.RgbHexColorMediumRisk()
+	case model.LowSeverity:
+		return colors.RgbHexColorLowRisk()
+	default:
+		return colors.Black
+	}
+}
+
+func statusColor(status model.RiskStatus) string {
+	switch status {
+	case model.Unchecked:
+		return colors.RgbHexColorRiskStatusUnchecked()
+	case model.InDiscussion:
+		return colors.RgbHexColorRiskStatusInDiscussion()
+	case model.Accepted:
+		return colors.RgbHexColorRiskStatusAccepted()
+	case model.InProgress:
+		return colors.RgbHexColorRiskStatusInProgress()
+	case model.Mitigated:
+		return colors.RgbHexColorRiskStatusMitigated()
+	case model.FalsePositive:
+		return colors.RgbHexColorRiskStatusFalsePositive()
+	default:
+		return colors.Black
+	}
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
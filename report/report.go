@@ -2435,9 +2435,28 @@ func writeRiskTrackingStatus(risk model.Risk) {
 	} else {
 		pdf.Ln(-1)
 	}
+	if deadline := riskTrackingDeadline(tracking); len(deadline) > 0 {
+		colors.ColorRiskStatusUnchecked(pdf)
+		pdf.SetFont("Helvetica", "B", fontSizeSmall)
+		pdf.CellFormat(10, 4, "", "0", 0, "", false, 0, "")
+		pdf.CellFormat(170, 4, deadline, "0", 0, "B", false, 0, "")
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", fontSizeBody)
+	}
 	pdfColorBlack()
 }
 
+// riskTrackingDeadline describes an expired risk tracking or an overdue review (empty otherwise)
+func riskTrackingDeadline(tracking model.RiskTracking) string {
+	if tracking.Expired {
+		return tracking.ExpiredStatus.Title() + " expired on " + tracking.Expires.Format("2006-01-02")
+	}
+	if tracking.IsReviewOverdue(time.Now()) {
+		return "Review overdue since " + tracking.ReviewBy.Format("2006-01-02")
+	}
+	return ""
+}
+
 func createTechnicalAssets() {
 	uni := pdf.UnicodeTranslatorFromDescriptor("")
 	// category title
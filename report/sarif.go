@@ -1,6 +1,7 @@
 package report
 
 import (
+	"os"
 	"strconv"
 	"strings"
 
@@ -8,7 +9,9 @@ import (
 	"github.com/owenrumney/go-sarif/sarif"
 )
 
-func WriteOpenSarif(filename string) {
+// WriteOpenSarif marks the results of risks violating the policy (synthetic id -> violated rules) as errors,
+// with a baseline (baselined risks not being nil) only the new risks are written
+func WriteOpenSarif(filename string, policyViolations map[string][]string, baselinedRisks map[string]bool) {
 	report, err := sarif.New(sarif.Version210)
 	if err != nil {
 		panic(err)
@@ -30,6 +33,9 @@ func WriteOpenSarif(filename string) {
 		run.AddRule(category.Id).WithFullDescription(&description).WithName(category.Title)
 	}
 	for _, risk := range model.AllRisks() {
+		if baselinedRisks[risk.SyntheticId] {
+			continue
+		}
 		if risk.GetRiskTrackingStatusDefaultingUnchecked() != model.FalsePositive && risk.GetRiskTrackingStatusDefaultingUnchecked() != model.Mitigated {
 			var location = sarif.NewLocation()
 			if risk.MostRelevantTechnicalAssetId != "" {
@@ -45,19 +51,34 @@ func WriteOpenSarif(filename string) {
 			if err != nil {
 				panic(err)
 			}
-			run.AddResult(risk.SyntheticId).
-				WithLevel(getLevel(risk.Severity)).
+			level := getLevel(risk.Severity)
+			if _, violating := policyViolations[risk.SyntheticId]; violating {
+				level = "error"
+			}
+			result := run.AddResult(risk.SyntheticId).
+				WithLevel(level).
 				WithRule(sarif.NewReportingDescriptorReference().WithId(risk.CategoryId)).
 				WithMessage(
 					sarif.NewTextMessage(
 						strings.Title(risk.Category.Function.String()) + ": " + strings.Title(strings.ReplaceAll(strings.ReplaceAll(strings.ToLower(risk.Title), "<b>", ""), "</b>", "")) +
 							"\n\n" + rule.FullDescription.Text)).
 				WithLocation(location)
+			if baselinedRisks != nil {
+				result.WithBaselineState("new")
+			}
+			if rules, violating := policyViolations[risk.SyntheticId]; violating {
+				result.WithProperties(sarif.Properties{"policy-violations": rules})
+			}
 		}
 	}
 
 	report.AddRun(run)
-	if err := report.WriteFile(filename); err != nil {
+	file, err := os.Create(filename) // as report.WriteFile does not truncate an already existing file
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = file.Close() }()
+	if err := report.PrettyWrite(file); err != nil {
 		panic(err)
 	}
 }
@@ -8,7 +8,11 @@ import (
 var validIdSyntax = regexp.MustCompile(`^[a-zA-Z0-9\-]+$`)
 
 func CheckIdSyntax(id string) {
-	if !validIdSyntax.MatchString(id) {
+	if !IsValidIdSyntax(id) {
 		panic(errors.New("invalid id syntax used (only letters, numbers, and hyphen allowed): " + id))
 	}
 }
+
+func IsValidIdSyntax(id string) bool {
+	return validIdSyntax.MatchString(id)
+}
@@ -0,0 +1,2 @@
+#!/bin/sh
+dot -Tsvg $1 -o $2
\ No newline at end of file